@@ -2,14 +2,17 @@ package main
 
 import (
 	"flag"
+	"fmt"
 	"os"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 	"k8s.io/klog/v2"
 
-	"github.com/openshift/rebase/pkg/cmd"
-	"github.com/openshift/rebase/pkg/options"
+	"github.com/openshift/rebase/internal/cache"
+	"github.com/openshift/rebase/internal/cmd"
+	"github.com/openshift/rebase/internal/console"
+	"github.com/openshift/rebase/internal/options"
 )
 
 func main() {
@@ -25,18 +28,82 @@ func main() {
 }
 
 func NewRootCommand() *cobra.Command {
+	configPath := options.DefaultConfigPath()
+	quiet := false
+	logFile := ""
+	logFormat := console.LogFormatText
+	noCache := false
+	logging := flag.NewFlagSet("logging", flag.ContinueOnError)
+	klog.InitFlags(logging)
 	command := &cobra.Command{
-		Use:          "rebase",
-		Short:        "OpenShift helper tool for performing automatic kubernetes updates",
+		Use:   "rebase",
+		Short: "OpenShift helper tool for performing automatic kubernetes updates",
+		Long: `OpenShift helper tool for performing automatic kubernetes updates.
+
+Every flag can be also set by an environment variable prefixed with
+OPENSHIFT_REBASE_, with dashes replaced by underscores, eg.
+OPENSHIFT_REBASE_REPOSITORY sets --repository. Flags on the command line take
+precedence over environment variables, which take precedence over the config
+file. Credentials can be passed as OPENSHIFT_REBASE_GITHUB_TOKEN,
+OPENSHIFT_REBASE_JIRA_TOKEN, OPENSHIFT_REBASE_SMTP_USERNAME and
+OPENSHIFT_REBASE_SMTP_PASSWORD.`,
 		SilenceUsage: true,
+		PersistentPreRunE: func(c *cobra.Command, args []string) error {
+			if err := options.ApplyEnv(c); err != nil {
+				return err
+			}
+			config, err := options.LoadConfig(configPath, c.Flags().Changed("config"))
+			if err != nil {
+				return err
+			}
+			if err := config.Apply(c); err != nil {
+				return err
+			}
+			if noCache {
+				cache.Disable()
+			}
+			if err := console.Configure(quiet, logging); err != nil {
+				return err
+			}
+			switch logFormat {
+			case console.LogFormatText:
+			case console.LogFormatJSON:
+				console.SetLogger(console.NewJSONLogger(os.Stderr))
+			default:
+				return fmt.Errorf("invalid --log-format %q, expected %s or %s", logFormat, console.LogFormatText, console.LogFormatJSON)
+			}
+			if len(logFile) > 0 {
+				return console.StartLog(logFile)
+			}
+			return nil
+		},
 	}
+	command.PersistentFlags().BoolVarP(&quiet, "quiet", "q", quiet, "Print only errors and results, without progress and warnings")
+	command.PersistentFlags().StringVar(&logFile, "log-file", logFile, "File receiving a timestamped log of every executed git command, its output and all messages, apply and continue log into the state directory by default")
+	command.PersistentFlags().StringVar(&logFormat, "log-format", logFormat, "Format of messages and diagnostics printed to stderr, text or json, which logs JSON objects one per line")
+	command.PersistentFlags().BoolVar(&noCache, "no-cache", noCache, "Compute carries, resolutions of upstream picks and preflight conflicts again instead of reusing the results cached in the git directory")
+	command.PersistentFlags().StringVar(&configPath, "config", configPath, "YAML config file with defaults of flags, per command flags and environment variables, flags on the command line take precedence")
 	streams := options.IOStreams{In: os.Stdin, Out: os.Stdout, ErrOut: os.Stderr}
+	command.AddGroup(cmd.Groups()...)
 
 	command.AddCommand(cmd.NewCarriesCommand(streams))
+	command.AddCommand(cmd.NewPlanCommand(streams))
 	command.AddCommand(cmd.NewApplyCommand(streams))
+	command.AddCommand(cmd.NewContinueCommand(streams))
+	command.AddCommand(cmd.NewRollbackCommand(streams))
+	command.AddCommand(cmd.NewAbortCommand(streams))
+	command.AddCommand(cmd.NewUnlockCommand(streams))
+	command.AddCommand(cmd.NewStatusCommand(streams))
+	command.AddCommand(cmd.NewVerifyCommand(streams))
+	command.AddCommand(cmd.NewWatchCommand(streams))
+	command.AddCommand(cmd.NewBumpCommand(streams))
+	command.AddCommand(cmd.NewTUICommand(streams))
+	command.AddCommand(cmd.NewRiskCommand(streams))
+	command.AddCommand(cmd.NewDoctorCommand(streams))
+	command.AddCommand(cmd.NewVersionCommand(streams))
+	command.AddCommand(cmd.NewGoldenCommand(streams))
+	cmd.RegisterCompletions(command)
 
-	logging := flag.NewFlagSet("logging", flag.ContinueOnError)
-	klog.InitFlags(logging)
 	if vFlag := logging.Lookup("v"); vFlag != nil {
 		pf := pflag.PFlagFromGoFlag(vFlag)
 		command.PersistentFlags().AddFlag(pf)
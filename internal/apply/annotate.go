@@ -0,0 +1,30 @@
+package apply
+
+import (
+	"k8s.io/klog/v2"
+
+	"github.com/openshift/rebase/internal/console"
+	"github.com/openshift/rebase/internal/github"
+	"github.com/openshift/rebase/internal/report"
+)
+
+// annotate records the openshift/kubernetes pull request which introduced the
+// carry, its author and reviewers in the entry, when the carry conflicted they
+// are the ones to ask about it.
+func (c *Apply) annotate(entry *report.Entry, conflicted bool) {
+	if entry.Backport {
+		return
+	}
+	info, err := github.OpenShiftPullRequestForCommit(c.fork.OpenShift, entry.Original)
+	if info != nil {
+		entry.PullRequest = info.URL
+		entry.Author = info.Author
+		entry.Reviewers = info.Reviewers
+	}
+	if err != nil {
+		klog.Warningf("Looking up pull request of %s failed: %v", entry.Original, err)
+	}
+	if (conflicted || entry.Disposition.Conflicted()) && len(entry.PullRequest) > 0 {
+		console.VerbosefContext(c.ctx, "Carry %s was introduced in %s", entry.Original, entry.Origin())
+	}
+}
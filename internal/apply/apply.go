@@ -0,0 +1,1255 @@
+package apply
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/openshift/rebase/internal/cache"
+	"github.com/openshift/rebase/internal/carry"
+	"github.com/openshift/rebase/internal/console"
+	"github.com/openshift/rebase/internal/fork"
+	"github.com/openshift/rebase/internal/git"
+	"github.com/openshift/rebase/internal/github"
+	"github.com/openshift/rebase/internal/jira"
+	"github.com/openshift/rebase/internal/notify"
+	"github.com/openshift/rebase/internal/preflight"
+	"github.com/openshift/rebase/internal/progress"
+	"github.com/openshift/rebase/internal/report"
+	"github.com/openshift/rebase/internal/state"
+	"github.com/openshift/rebase/internal/status"
+	"github.com/openshift/rebase/internal/utils"
+	"k8s.io/klog/v2"
+)
+
+type Apply struct {
+	log           *carry.Log
+	from          string
+	fork          fork.Fork
+	repositoryDir string
+	stateDir      string
+	repository    git.Git
+	options       Options
+	carries       *fixedCarries
+	hooks         *hooks
+	generators    []generator
+	notifier      *notify.Notifier
+	status        *status.Server
+	owners        *owners
+	resolvers     []ConflictResolver
+	plan          *Plan
+	selector      func(plan *Plan) error
+	observer      func(runState *state.State, current string)
+	// sequenceStop is the carry the sequencer last stopped on
+	sequenceStop string
+	// interrupts catches signals while commits are picked
+	interrupts *interrupts
+	// ctx interrupts the run once cancelled, instead of signals
+	ctx context.Context
+	// out receives results, such as the summary, and errOut the progress
+	out    io.Writer
+	errOut io.Writer
+}
+
+// Options holds the settings controlling the apply flow.
+type Options struct {
+	// Preflight enables in-memory applicability check of all carries before
+	// creating the rebase branch.
+	Preflight bool
+	// PreflightOnly stops right after the applicability check.
+	PreflightOnly bool
+	// MappingFile is the path where the mapping of original carries to the
+	// commits on the rebase branch is written.
+	MappingFile string
+	// Mainline is the parent number used when picking merge commits,
+	// merge commits are skipped when 0.
+	Mainline int
+	// OursPaths is a list of path prefixes, when the conflicts of a carry are
+	// limited to these paths the pick is retried with ours strategy option.
+	OursPaths []string
+	// Resolvers is a list of name=command pairs, the commands are invoked to
+	// resolve conflicts of carries in the working tree.
+	Resolvers []string
+	// CarriesDirs is a list of directories holding fixed carries.
+	CarriesDirs []string
+	// TargetVersion is the kubernetes version being rebased to (eg. v1.31), used
+	// for looking up fixed carries in per-version directories.
+	TargetVersion string
+	// PrePickHooks are commands invoked before picking each carry.
+	PrePickHooks []string
+	// PostPickHooks are commands invoked after each picked carry.
+	PostPickHooks []string
+	// PostPhaseHooks are commands invoked after each phase of the apply flow
+	// (merge, carries, backports, additional, queue).
+	PostPhaseHooks []string
+	// HookPolicy decides what happens when a hook fails, either warn or stop.
+	HookPolicy string
+	// ProgressInterval is how often progress is logged when not running in a terminal.
+	ProgressInterval time.Duration
+	// Validate enables running gofmt and go vet on packages touched by each picked carry.
+	Validate bool
+	// ReportFile is the path where a markdown report describing the rebase is written.
+	ReportFile string
+	// HTMLReportFile is the path where a self-contained html report describing the rebase is written.
+	HTMLReportFile string
+	// Backports is a list of upstream commit SHAs or pull request numbers
+	// picked after the carries.
+	Backports []string
+	// ConflictsDir is a directory where conflicted files of every carry are saved for review.
+	ConflictsDir string
+	// Regenerate enables resolving conflicts limited to generated files by
+	// accepting GeneratedSide and re-running generators.
+	Regenerate bool
+	// Generators is a list of pattern=command pairs, used for regenerating files.
+	Generators []string
+	// GeneratedSide is the side accepted for conflicting generated files, ours or theirs.
+	GeneratedSide string
+	// DeferConflicts makes the run skip carries which do not apply cleanly and
+	// queue them for a second, manual pass.
+	DeferConflicts bool
+	// QueueFile is where the manual queue is written, it is read back when
+	// the manual pass starts, so that it can be reordered or trimmed.
+	// Defaults to a file next to the state file.
+	QueueFile string
+	// PushRemote is the remote, usually a fork, the finished rebase branch is pushed to.
+	PushRemote string
+	// DraftPR enables opening a draft pull request from the pushed branch.
+	DraftPR bool
+	// PRForkOwner is the owner of the fork the branch is pushed to.
+	PRForkOwner string
+	// PRLabels are labels added to the pull request.
+	PRLabels []string
+	// Annotate enables looking up the openshift/kubernetes pull request, its author
+	// and reviewers for every carry.
+	Annotate bool
+	// SuggestAssignees enables suggesting approvers from OWNERS files of the
+	// conflicted paths for carries requiring manual intervention.
+	SuggestAssignees bool
+	// Jira enables looking up status and priority of OCPBUGS bugs referenced by carries.
+	Jira bool
+	// NotifySlack is a slack webhook URL receiving run lifecycle notifications.
+	// Webhook URLs are secrets, they are not persisted in the state file and
+	// continue reads them from its flags again.
+	NotifySlack string `json:"-"`
+	// NotifyWebhook is a generic webhook URL receiving run lifecycle notifications as JSON.
+	NotifyWebhook string `json:"-"`
+	// MailServer is the SMTP server, as host:port, sending the run summary and
+	// the manual queue to MailTo.
+	MailServer string
+	// MailFrom is the sender address of the emails.
+	MailFrom string
+	// MailTo is the list of addresses receiving the emails.
+	MailTo []string
+	// StatusAddress is the address of the HTTP server reporting the state of
+	// the run, the server is disabled when empty.
+	StatusAddress string
+	// Force allows starting the run when a protected branch is checked out.
+	Force bool
+	// UnknownAction decides what happens with commits with unknown UPSTREAM action,
+	// one of fail, carry or skip.
+	UnknownAction string
+	// ToRef is the ref carries are read from, between its merge base with the
+	// starting tag and the ref, defaults to the openshift branch.
+	ToRef string
+	// PlanFile is the path of a plan, written by plan, which is executed instead
+	// of computing the actions from the carries.
+	PlanFile string
+	// Concurrency is the number of carries classified in parallel when making
+	// a plan, defaults to the number of CPUs.
+	Concurrency int
+	// SkipChecks are names of environment checks not run before starting.
+	SkipChecks []string
+	// Resume continues the run on the rebase branch created by a previous
+	// run, instead of failing because the branch exists.
+	Resume bool
+}
+
+const (
+	// UnknownActionFail stops the run on a commit with unknown action
+	UnknownActionFail = "fail"
+	// UnknownActionCarry treats a commit with unknown action as a carry
+	UnknownActionCarry = "carry"
+	// UnknownActionSkip skips a commit with unknown action, reporting it in the summary
+	UnknownActionSkip = "skip"
+)
+
+const (
+	carryAction = "<carry>"
+	dropAction  = "<drop>"
+	skipPatch   = "<skip>"
+	// mergedAction is an internal action for numbered picks already merged upstream
+	mergedAction = "<merged>"
+	// actionsCache is the kind of cached resolutions of numbered picks
+	actionsCache = "actions"
+	// conflictsCache is the kind of cached preflight simulations
+	conflictsCache = "conflicts"
+
+	// patchContextLines is the default number of context lines in a patch
+	patchContextLines = 3
+)
+
+func NewApply(from, repositoryDir string, f fork.Fork, options Options) *Apply {
+	log := carry.NewLog(from, repositoryDir, f)
+	log.SetTo(options.ToRef)
+	return &Apply{
+		log:           log,
+		from:          from,
+		fork:          f,
+		repositoryDir: repositoryDir,
+		options:       options,
+		out:           os.Stdout,
+		errOut:        os.Stderr,
+	}
+}
+
+// SetOutput prints results of the run to out and its progress to errOut,
+// instead of stdout and stderr.
+func (c *Apply) SetOutput(out, errOut io.Writer) {
+	c.out = out
+	c.errOut = errOut
+}
+
+// SetStateDir persists the state of the run in a given directory, eg. on a
+// volume of a container, instead of the git directory.
+func (c *Apply) SetStateDir(dir string) {
+	c.stateDir = dir
+}
+
+// upstreamBranch is the upstream branch of the fork
+func (c *Apply) upstreamBranch() string {
+	return c.fork.UpstreamRef()
+}
+
+// carriesRef is the ref carries are read from and merged into the rebase branch
+func (c *Apply) carriesRef() string {
+	if len(c.options.ToRef) > 0 {
+		return c.options.ToRef
+	}
+	return c.fork.OpenShiftBranch()
+}
+
+// SetMarkerSearch bounds the search of the rebase marker.
+func (c *Apply) SetMarkerSearch(search git.MarkerSearch) {
+	c.log.SetMarkerSearch(search)
+}
+
+// SetContext makes the run stop, as if it was interrupted, once the context is
+// cancelled, signals are not caught then.
+func (c *Apply) SetContext(ctx context.Context) {
+	c.ctx = ctx
+	c.log.SetContext(ctx)
+}
+
+// SetRepository shares an already opened repository, instead of opening the
+// repository directory again.
+func (c *Apply) SetRepository(repository git.Git) {
+	c.repository = repository
+	c.log.SetRepository(repository)
+}
+
+func (c *Apply) Run() error {
+	// this applies the steps from https://github.com/openshift/kubernetes/blob/master/REBASE.openshift.md
+	rebaseReport := &report.Report{From: c.from, TargetVersion: c.options.TargetVersion, Started: time.Now()}
+	repository, err := git.OpenShared(c.repository, c.repositoryDir, c.fork)
+	if err != nil {
+		return err
+	}
+	gitDir, err := repository.GitDir()
+	if err != nil {
+		return err
+	}
+	unlock, err := state.Acquire(gitDir, "apply")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	stateDir := state.DirOf(gitDir, c.stateDir)
+	if err := startLog(stateDir, "apply"); err != nil {
+		return err
+	}
+	if err := c.complete(); err != nil {
+		return err
+	}
+	defer c.status.Stop()
+	// the path is persisted, continue may run from another directory
+	if c.options.QueueFile, err = queuePath(stateDir, c.options.QueueFile); err != nil {
+		return err
+	}
+	originalRef, err := currentRef(repository)
+	if err != nil {
+		return fmt.Errorf("Error reading current HEAD: %w", err)
+	}
+	if protected, err := repository.IsProtected(originalRef); err != nil {
+		return err
+	} else if protected && !c.options.Force {
+		return fmt.Errorf("Refusing to start on protected branch %s, check out a different branch or use --force", originalRef)
+	}
+	branchName := fmt.Sprintf("rebase-%s", time.Now().Format(time.DateOnly))
+	if err := checkStartingHead(c.ctx, repository, originalRef, branchName, c.upstreamBranch()); err != nil {
+		return err
+	}
+	if resumed, err := c.resumeExisting(repository, stateDir, branchName); resumed || err != nil {
+		return err
+	}
+	if err := c.checkEnvironment(repository); err != nil {
+		return err
+	}
+	// TODO:
+	// 1. add fetching remotes
+	// 2. checkout upstream/master and print its sha
+	if rebaseReport.UpstreamSHA, err = repository.RevParse(c.upstreamBranch()); err != nil {
+		return err
+	}
+	if rebaseReport.OpenShiftSHA, err = repository.RevParse(c.carriesRef()); err != nil {
+		return err
+	}
+	stageStart := time.Now()
+	var commits []*object.Commit
+	if c.plan == nil && c.selector != nil {
+		if err := c.selectCommits(repository, stateDir); err != nil {
+			return err
+		}
+	}
+	if c.plan != nil {
+		if err := c.checkPlan(repository); err != nil {
+			return err
+		}
+		commits, err = c.plannedCommits(repository)
+	} else {
+		commits, err = c.log.GetCommits(repository)
+	}
+	if err != nil {
+		return fmt.Errorf("Error reading carries: %w", err)
+	}
+	rebaseReport.AddStage(report.StageLog, stageStart)
+	if c.options.Preflight || c.options.PreflightOnly {
+		stageStart = time.Now()
+		if err := c.preflight(repository, commits); err != nil {
+			return fmt.Errorf("Error running preflight check: %w", err)
+		}
+		rebaseReport.AddStage(report.StagePreflight, stageStart)
+		if c.options.PreflightOnly {
+			return nil
+		}
+	}
+	stageStart = time.Now()
+	if err := repository.CreateBranch(branchName, c.upstreamBranch()); err != nil {
+		return fmt.Errorf("Error creating rebase branch: %w", err)
+	}
+	rebaseReport.Branch = branchName
+	c.notifier.Notify(notify.Started, branchName, fmt.Sprintf("Rebase from %s started, %d commits to process", c.from, len(commits)))
+	currentFork := c.fork
+	runState := &state.State{
+		From:        c.from,
+		OriginalRef: originalRef,
+		Branch:      branchName,
+		Phase:       state.PhaseMerge,
+		Report:      rebaseReport,
+		Fork:        &currentFork,
+	}
+	for _, commit := range commits {
+		runState.Commits = append(runState.Commits, commit.Hash.String())
+	}
+	if runState.Options, err = json.Marshal(c.options); err != nil {
+		return err
+	}
+	if err := runState.Save(stateDir); err != nil {
+		return fmt.Errorf("Error saving state: %w", err)
+	}
+	if err := repository.Merge(c.carriesRef()); err != nil {
+		return fmt.Errorf("Error creating rebase branch: %w", err)
+	}
+	if err := c.hooks.runPostPhase(state.PhaseMerge); err != nil {
+		return err
+	}
+	rebaseReport.AddStage(report.StageMerge, stageStart)
+	runState.Phase = state.PhaseCarries
+	return c.resume(repository, stateDir, runState, commits)
+}
+
+// complete sets up the fixed carries and hooks, based on options
+func (c *Apply) complete() error {
+	var err error
+	c.carries, err = newFixedCarries(append(c.options.CarriesDirs, c.fork.CarriesDirs...), c.options.TargetVersion, c.repositoryDir)
+	if err != nil {
+		return fmt.Errorf("Error reading fixed carries directories: %w", err)
+	}
+	switch c.options.UnknownAction {
+	case "":
+		c.options.UnknownAction = UnknownActionSkip
+	case UnknownActionFail, UnknownActionCarry, UnknownActionSkip:
+	default:
+		return fmt.Errorf("invalid unknown action policy %q, expected one of: %s, %s, %s",
+			c.options.UnknownAction, UnknownActionFail, UnknownActionCarry, UnknownActionSkip)
+	}
+	if err := preflight.Validate(c.options.SkipChecks); err != nil {
+		return err
+	}
+	if c.options.DraftPR && (len(c.options.PushRemote) == 0 || len(c.options.PRForkOwner) == 0) {
+		return fmt.Errorf("opening a pull request requires both push remote and owner of the fork")
+	}
+	if c.generators, err = parseGenerators(append(c.options.Generators, c.fork.Generators...)); err != nil {
+		return err
+	}
+	if len(c.options.GeneratedSide) == 0 {
+		c.options.GeneratedSide = "ours"
+	}
+	resolvers, err := c.newResolvers()
+	if err != nil {
+		return err
+	}
+	// resolvers added by AddResolver come last
+	c.resolvers = append(resolvers, c.resolvers...)
+	var mailer *notify.Mailer
+	if len(c.options.MailTo) > 0 {
+		if mailer, err = notify.NewMailer(c.options.MailServer, c.options.MailFrom, c.options.MailTo); err != nil {
+			return err
+		}
+	}
+	if len(c.options.NotifySlack) > 0 || len(c.options.NotifyWebhook) > 0 || mailer != nil {
+		c.notifier = notify.New(c.options.NotifySlack, c.options.NotifyWebhook, mailer)
+	}
+	if len(c.options.StatusAddress) > 0 {
+		c.status = status.New(c.options.StatusAddress)
+		if err := c.status.Start(); err != nil {
+			return err
+		}
+	}
+	if len(c.options.PlanFile) > 0 {
+		if err := c.loadPlan(); err != nil {
+			return err
+		}
+	}
+	c.hooks, err = newHooks(c.repositoryDir, c.options)
+	return err
+}
+
+// resume continues the run from the point recorded in the state, picking
+// the remaining commits and applying additional carries.
+func (c *Apply) resume(repository git.Git, stateDir string, runState *state.State, commits []*object.Commit) error {
+	rebaseReport := runState.Report
+	defer c.writeReports(rebaseReport)
+	c.interrupts = catchInterrupts(c.ctx, stateDir)
+	defer c.interrupts.stop()
+	if runState.Phase == state.PhaseCarries {
+		stageStart := time.Now()
+		err := c.pickCommits(repository, commits, runState)
+		if saveErr := runState.Save(stateDir); saveErr != nil {
+			klog.Errorf("Saving state failed: %v", saveErr)
+		}
+		if err != nil {
+			return err
+		}
+		if err := c.hooks.runPostPhase(state.PhaseCarries); err != nil {
+			return err
+		}
+		rebaseReport.AddStage(report.StageCarries, stageStart)
+		runState.Phase = c.nextStage(state.PhaseCarries)
+		if err := runState.Save(stateDir); err != nil {
+			return fmt.Errorf("Error saving state: %w", err)
+		}
+	}
+	// stages after carries run in the order configured for the fork
+	stagesRun := false
+	for runState.Phase == state.PhaseBackports || runState.Phase == state.PhaseAdditional {
+		if err := c.runStage(repository, stateDir, runState); err != nil {
+			return err
+		}
+		stagesRun = true
+		runState.Phase = c.nextStage(runState.Phase)
+		if err := runState.Save(stateDir); err != nil {
+			return fmt.Errorf("Error saving state: %w", err)
+		}
+	}
+	if stagesRun && len(runState.Queue) > 0 {
+		// the mechanical pass is over, the manual pass starts with continue
+		runState.Phase = state.PhaseQueue
+		if err := runState.Save(stateDir); err != nil {
+			return fmt.Errorf("Error saving state: %w", err)
+		}
+		return c.writeQueue(repository, runState)
+	}
+	if runState.Phase == state.PhaseQueue {
+		stageStart := time.Now()
+		err := c.pickQueue(repository, runState)
+		if saveErr := runState.Save(stateDir); saveErr != nil {
+			klog.Errorf("Saving state failed: %v", saveErr)
+		}
+		if err != nil {
+			return err
+		}
+		if err := c.hooks.runPostPhase(state.PhaseQueue); err != nil {
+			return err
+		}
+		runState.Report.AddStage(report.StageQueue, stageStart)
+	}
+	runState.Phase = state.PhaseDone
+	c.updateStatus(runState, "")
+	if err := runState.Save(stateDir); err != nil {
+		return fmt.Errorf("Error saving state: %w", err)
+	}
+	var summary strings.Builder
+	rebaseReport.Summary(&summary)
+	c.notifier.Notify(notify.Finished, runState.Branch, summary.String())
+	err := c.openPullRequest(repository, runState)
+	if saveErr := runState.Save(stateDir); saveErr != nil {
+		klog.Errorf("Saving state failed: %v", saveErr)
+	}
+	return err
+}
+
+// nextStage returns the phase of the stage run after the given phase in the
+// order configured for the fork, or done when no stage is left.
+func (c *Apply) nextStage(phase string) string {
+	order := c.fork.StageOrder()
+	next := 0
+	for i, stage := range order {
+		if stage == phase {
+			next = i + 1
+		}
+	}
+	if next < len(order) {
+		return order[next]
+	}
+	return state.PhaseDone
+}
+
+// runStage runs the stage run after picking carries recorded in the state,
+// disabled stages are skipped.
+func (c *Apply) runStage(repository git.Git, stateDir string, runState *state.State) error {
+	stageStart := time.Now()
+	switch runState.Phase {
+	case state.PhaseBackports:
+		if !c.stageEnabled(fork.StageBackports) {
+			console.InfofContext(c.ctx, "Skipping %s stage %s", fork.StageBackports, c.stageDisabledBy())
+			return nil
+		}
+		err := c.pickBackports(repository, runState)
+		if saveErr := runState.Save(stateDir); saveErr != nil {
+			klog.Errorf("Saving state failed: %v", saveErr)
+		}
+		if err != nil {
+			return err
+		}
+		if err := c.hooks.runPostPhase(state.PhaseBackports); err != nil {
+			return err
+		}
+		if len(c.options.Backports) > 0 {
+			runState.Report.AddStage(report.StageBackports, stageStart)
+		}
+	case state.PhaseAdditional:
+		var additionalCarries []string
+		var err error
+		if c.plan != nil && c.stageEnabled(fork.StageAdditional) {
+			additionalCarries = c.plan.Additional
+		} else if c.stageEnabled(fork.StageAdditional) {
+			additionalCarries, err = c.carries.findAdditionalCarries()
+			if err != nil {
+				return fmt.Errorf("Error reading additional carries: %w", err)
+			}
+		} else {
+			console.InfofContext(c.ctx, "Skipping %s stage %s", fork.StageAdditional, c.stageDisabledBy())
+		}
+		for _, a := range additionalCarries {
+			console.InfofContext(c.ctx, "Found additional carry %s, applying...", a)
+			if err := repository.Apply(a); err != nil {
+				if err := repository.AbortApply(); err != nil {
+					klog.Errorf("Aborting apply failed: %v", err)
+				}
+				klog.Errorf("The additional fix %s stopped working  and requires manual intervention!", a)
+				return err
+			}
+		}
+		if err := c.hooks.runPostPhase(state.PhaseAdditional); err != nil {
+			return err
+		}
+		runState.Report.AddStage(report.StageAdditional, stageStart)
+	}
+	return nil
+}
+
+// writeReports writes all requested reports and prints run summary
+func (c *Apply) writeReports(rebaseReport *report.Report) {
+	if len(c.options.MappingFile) > 0 {
+		if err := rebaseReport.WriteMapping(c.options.MappingFile); err != nil {
+			klog.Errorf("Writing mapping to %s failed: %v", c.options.MappingFile, err)
+		}
+	}
+	if len(c.options.ReportFile) > 0 {
+		if err := rebaseReport.WriteMarkdown(c.options.ReportFile, c.fork); err != nil {
+			klog.Errorf("Writing report to %s failed: %v", c.options.ReportFile, err)
+		}
+	}
+	if len(c.options.HTMLReportFile) > 0 {
+		if err := rebaseReport.WriteHTML(c.options.HTMLReportFile, c.fork); err != nil {
+			klog.Errorf("Writing html report to %s failed: %v", c.options.HTMLReportFile, err)
+		}
+	}
+	rebaseReport.Summary(c.out)
+}
+
+// pickCommits processes the remaining commits on top of the current branch,
+// recording the results in the report and progress in the state.
+func (c *Apply) pickCommits(repository git.Git, commits []*object.Commit, runState *state.State) error {
+	bar := progress.New(c.errOut, len(commits)-runState.Next, c.options.ProgressInterval)
+	defer bar.Finish()
+	for ; runState.Next < len(commits); runState.Next++ {
+		if c.interrupted() {
+			return c.stopInterrupted(repository, "")
+		}
+		c.checkpoint(runState)
+		sequenced, err := c.sequencePicks(repository, commits, runState, bar)
+		if err != nil {
+			return err
+		}
+		if sequenced > 0 {
+			// the loop moves past the last sequenced commit
+			runState.Next += sequenced - 1
+			continue
+		}
+		commit := commits[runState.Next]
+		c.updateStatus(runState, commit.Hash.String())
+		klog.V(2).Infof("Processing %s: %q", commit.Hash.String(), utils.FormatMessage(commit.Message))
+		entry := report.Entry{Original: commit.Hash.String(), Message: utils.FormatMessage(commit.Message)}
+		start := time.Now()
+		head, err := repository.RevParse("HEAD")
+		if err != nil {
+			return err
+		}
+		err = c.pickCommit(repository, commit, &entry)
+		if err != nil && c.interrupted() {
+			// the pick failed because git was interrupted too
+			return c.stopInterrupted(repository, head)
+		}
+		entry.Duration = time.Since(start)
+		entry.Conflicts = conflictedFiles(err)
+		if c.options.Annotate && len(entry.Disposition) > 0 {
+			c.annotate(&entry, err != nil)
+		}
+		if entry.Bugs = jira.References(commit.Message); c.options.Jira {
+			jira.Enrich(entry.Bugs)
+		}
+		if err != nil && c.options.SuggestAssignees && len(entry.Disposition) > 0 && entry.Disposition != report.Unknown {
+			c.suggestAssignees(repository, commit, &entry)
+		}
+		if err != nil && c.options.DeferConflicts && len(entry.Disposition) > 0 && entry.Disposition != report.Unknown {
+			klog.Warningf("Deferring carry %s to the manual queue: %v", c.fork.CommitURL(commit.Hash.String()), err)
+			if err = discard(repository, head); err == nil {
+				entry.Disposition = report.Deferred
+				runState.Queue = append(runState.Queue, commit.Hash.String())
+			}
+		}
+		if len(entry.Disposition) > 0 {
+			runState.Report.Add(entry)
+		}
+		if err != nil {
+			if len(entry.Disposition) > 0 {
+				// the commit was acted upon and requires manual intervention,
+				// continue will resume with the next one
+				err = c.stopOn(repository, runState, commit.Hash.String(), err)
+				runState.Next++
+				klog.Errorf("Once resolved, run 'rebase continue' to proceed with the remaining carries.")
+			}
+			// TODO: abort only after 2-3 errors, maybe?
+			return err
+		}
+		bar.Step(entry.Disposition.Conflicted())
+	}
+	return nil
+}
+
+// minSequence is the shortest run of carries picked with a single cherry-pick
+const minSequence = 2
+
+// sequencePicks picks the run of plain carries starting at the next commit
+// with a single cherry-pick, saving the startup of git for every commit, and
+// returns the number of picked commits. Commits requiring any handling
+// besides the pick, eg. hooks or validation, and the commit the sequencer
+// stopped on are left for picking one by one.
+func (c *Apply) sequencePicks(repository git.Git, commits []*object.Commit, runState *state.State, bar *progress.Progress) (int, error) {
+	if len(c.hooks.prePick) > 0 || len(c.hooks.postPick) > 0 || c.options.Validate {
+		return 0, nil
+	}
+	if commits[runState.Next].Hash.String() == c.sequenceStop {
+		// the carry does not pick cleanly, it is picked on its own
+		return 0, nil
+	}
+	var (
+		shas    []string
+		reasons []string
+	)
+	for _, commit := range commits[runState.Next:] {
+		if isMerge(commit) {
+			break
+		}
+		var action, reason string
+		var err error
+		if c.plan != nil {
+			action, reason, err = c.planAction(commit)
+		} else {
+			action, reason, err = resolveAction(repository, c.fork, commit)
+		}
+		if err != nil || action != carryAction {
+			break
+		}
+		shas = append(shas, commit.Hash.String())
+		reasons = append(reasons, reason)
+	}
+	if len(shas) < minSequence {
+		return 0, nil
+	}
+	head, err := repository.RevParse("HEAD")
+	if err != nil {
+		return 0, err
+	}
+	c.updateStatus(runState, shas[0])
+	klog.V(2).Infof("Picking %d carries with a single cherry-pick...", len(shas))
+	start := time.Now()
+	picked, err := repository.CherryPickSequence(shas)
+	if err != nil {
+		klog.V(2).Infof("Picked %d of %d carries before the sequencer stopped: %v", picked, len(shas), err)
+		if picked < len(shas) {
+			c.sequenceStop = shas[picked]
+		}
+	}
+	if picked == 0 {
+		return 0, nil
+	}
+	newSHAs, err := repository.RevList("--reverse", head+"..HEAD")
+	if err != nil {
+		return 0, err
+	}
+	if len(newSHAs) != picked {
+		return 0, fmt.Errorf("Error mapping sequenced carries, %d picked, but %d new commits", picked, len(newSHAs))
+	}
+	duration := time.Since(start) / time.Duration(picked)
+	for i, commit := range commits[runState.Next : runState.Next+picked] {
+		entry := report.Entry{Original: commit.Hash.String(), Message: utils.FormatMessage(commit.Message), Reason: reasons[i],
+			Disposition: report.Picked, New: newSHAs[i], Duration: duration}
+		if c.options.Annotate {
+			c.annotate(&entry, false)
+		}
+		if entry.Bugs = jira.References(commit.Message); c.options.Jira {
+			jira.Enrich(entry.Bugs)
+		}
+		runState.Report.Add(entry)
+		bar.Step(false)
+	}
+	return picked, nil
+}
+
+// StoppedError is returned when the run stopped on a commit requiring manual
+// intervention, the run is resumed with continue once it is resolved.
+type StoppedError struct {
+	// Phase is the phase of the run, eg. carries or backports
+	Phase string
+	// Commit is the commit which stopped the run
+	Commit string
+	// Err is the cause, a *git.ConflictError when the commit did not apply
+	Err error
+}
+
+func (e *StoppedError) Error() string {
+	return fmt.Sprintf("The run stopped in %s phase on %s: %v", e.Phase, e.Commit, e.Err)
+}
+
+func (e *StoppedError) Unwrap() error {
+	return e.Err
+}
+
+// Conflicts returns the files with unresolved conflicts left by the commit.
+func (e *StoppedError) Conflicts() []string {
+	return conflictedFiles(e.Err)
+}
+
+// conflictedFiles returns the files with conflicts left by the failed pick
+func conflictedFiles(err error) []string {
+	var conflict *git.ConflictError
+	if errors.As(err, &conflict) {
+		return conflict.Files
+	}
+	return nil
+}
+
+// stopOn records in the state the commit which requires manual intervention,
+// returning the error stopping the run
+func (c *Apply) stopOn(repository git.Git, runState *state.State, sha string, cause error) error {
+	c.notifier.Notify(notify.Conflict, runState.Branch, fmt.Sprintf("%s requires manual intervention", c.fork.CommitURL(sha)))
+	runState.Current = sha
+	head, err := repository.RevParse("HEAD")
+	if err != nil {
+		klog.Errorf("Reading HEAD failed: %v", err)
+	}
+	runState.StoppedAt = head
+	// checks of the new commit stop the run once it is committed
+	entry := runState.Report.Find(sha)
+	runState.Committed = entry != nil && len(entry.New) > 0 && entry.New == head
+	c.updateStatus(runState, sha)
+	return &StoppedError{Phase: runState.Phase, Commit: sha, Err: cause}
+}
+
+// pickCommit processes a single commit, the outcome is recorded in entry
+func (c *Apply) pickCommit(repository git.Git, commit *object.Commit, entry *report.Entry) error {
+	var action, reason string
+	var err error
+	if c.plan != nil {
+		action, reason, err = c.planAction(commit)
+	} else {
+		action, reason, err = resolveAction(repository, c.fork, commit)
+	}
+	if err != nil {
+		return err
+	}
+	entry.Reason = reason
+	if action != carryAction && action != dropAction && action != mergedAction {
+		klog.Errorf("Unkown action on commit %s: %s", c.fork.CommitURL(commit.Hash.String()), action)
+		entry.UnknownAction = true
+		switch c.options.UnknownAction {
+		case UnknownActionFail:
+			entry.Disposition = report.Unknown
+			entry.Reason = fmt.Sprintf("unknown action %q", action)
+			return fmt.Errorf("unknown action %q on commit %s, fix the commit message or pick it manually", action, commit.Hash.String())
+		case UnknownActionCarry:
+			klog.Warningf("Treating commit %s as a carry.", commit.Hash.String())
+			entry.Reason = fmt.Sprintf("unknown action %q treated as carry", action)
+			action = carryAction
+		default:
+			entry.Disposition = report.Unknown
+			entry.Reason = fmt.Sprintf("unknown action %q", action)
+			return nil
+		}
+	}
+	if action == carryAction && isMerge(commit) && c.options.Mainline == 0 {
+		klog.Warningf("Skipping merge commit %s, use --mainline to pick it", c.fork.CommitURL(commit.Hash.String()))
+		entry.Disposition = report.Skipped
+		entry.Reason = "merge commit, mainline parent not specified"
+		return nil
+	}
+	switch action {
+	case mergedAction:
+		klog.V(1).Infof("Skipping commit %s - merged upstream.", commit.Hash.String())
+		entry.Disposition = report.Merged
+	case carryAction:
+		if err := c.hooks.runPrePick(entry.Original, entry.Message); err != nil {
+			return err
+		}
+		if err := c.carryFlow(repository, commit, entry); err != nil {
+			return err
+		}
+		if entry.Disposition == report.Skipped {
+			return nil
+		}
+		if entry.New, err = repository.RevParse("HEAD"); err != nil {
+			return err
+		}
+		if c.options.Validate {
+			if err := validateCommit(repository, c.repositoryDir, entry.New); err != nil {
+				klog.Errorf("Carry %s failed validation on the new base and requires manual intervention!", c.fork.CommitURL(commit.Hash.String()))
+				entry.Reason = "validation failed"
+				return err
+			}
+		}
+		return c.hooks.runPostPick(entry.Original, entry.Message, entry.New, string(entry.Disposition))
+	case dropAction:
+		klog.Warningf("Skipping drop commit %s", c.fork.CommitURL(commit.Hash.String()))
+		entry.Disposition = report.Dropped
+	}
+	return nil
+}
+
+// checkStartingHead explains what a detached HEAD means for a new run, which
+// always creates its own branch, so any commit may be checked out
+func checkStartingHead(ctx context.Context, repository git.Git, originalRef, branchName, upstream string) error {
+	branch, err := repository.CurrentBranch()
+	if err != nil {
+		return err
+	}
+	if len(branch) == 0 {
+		console.InfofContext(ctx, "HEAD is detached at %s, which is fine, the run creates %s from %s and rollback checks out %s again",
+			originalRef, branchName, upstream, originalRef)
+	}
+	return nil
+}
+
+// currentRef returns the checked out branch, or commit SHA when HEAD is detached
+func currentRef(repository git.Git) (string, error) {
+	branch, err := repository.CurrentBranch()
+	if err != nil || len(branch) > 0 {
+		return branch, err
+	}
+	return repository.RevParse("HEAD")
+}
+
+// isMerge returns true if the commit is a merge commit
+func isMerge(commit *object.Commit) bool {
+	return len(commit.ParentHashes) > 1
+}
+
+// commitMainline returns mainline parent number to use for picking the commit,
+// which is only set for merge commits
+func commitMainline(commit *object.Commit, mainline int) int {
+	if !isMerge(commit) {
+		return 0
+	}
+	return mainline
+}
+
+// resolveAction returns the action to take on a commit, numbered upstream picks
+// are checked against github and resolved to merged action, when the pull request
+// is merged and contained in upstream, or carry action otherwise. The returned
+// reason explains the resolution of numbered picks.
+func resolveAction(repository git.Git, f fork.Fork, commit *object.Commit) (string, string, error) {
+	action := f.Action(actionFromMessage(commit.Message))
+	number, err := strconv.Atoi(action)
+	if err != nil {
+		return action, "", nil
+	}
+	// the resolution only changes when upstream moves, looking up pull
+	// requests again on every run is slow and uses up the rate limit
+	var store *cache.Cache
+	key := ""
+	if gitDir, err := repository.GitDir(); err == nil {
+		if upstream, err := repository.RevParse(f.UpstreamRef()); err == nil {
+			store, key = cache.New(gitDir), cache.Key(strconv.Itoa(number), upstream)
+		}
+	}
+	var cached resolvedAction
+	if store != nil && store.Get(actionsCache, key, &cached) {
+		return cached.Action, cached.Reason, nil
+	}
+	action, reason, err := resolvePullRequest(repository, f, commit, number)
+	if err == nil && store != nil {
+		store.Put(actionsCache, key, resolvedAction{Action: action, Reason: reason})
+	}
+	return action, reason, err
+}
+
+// resolvedAction is a cached resolution of a numbered upstream pick
+type resolvedAction struct {
+	Action string `json:"action"`
+	Reason string `json:"reason"`
+}
+
+// resolvePullRequest resolves a numbered upstream pick by the state of the
+// upstream pull request
+func resolvePullRequest(repository git.Git, f fork.Fork, commit *object.Commit, number int) (string, string, error) {
+	sha, merged, err := github.MergedPullRequest(f.Upstream, number)
+	if github.IsNotFound(err) {
+		return carryAction, fmt.Sprintf("upstream pull request %d not found", number), nil
+	}
+	if err != nil {
+		return "", "", fmt.Errorf("Failed reading merge state for %s: %q: %w", commit.Hash.String(), utils.FormatMessage(commit.Message), err)
+	}
+	if !merged {
+		// in all other cases we just continue to carry a patch
+		return carryAction, "", nil
+	}
+	url := f.UpstreamPullURL(number)
+	contained, err := repository.IsAncestor(sha, f.UpstreamRef())
+	if err != nil {
+		klog.Warningf("Checking if %s is contained in %s failed, carrying %s: %v", url, f.UpstreamRef(), commit.Hash.String(), err)
+	}
+	if !contained {
+		return carryAction, fmt.Sprintf("%s merged, but not contained in %s", url, f.UpstreamRef()), nil
+	}
+	return mergedAction, fmt.Sprintf("merged upstream in %s", url), nil
+}
+
+// PlannedDisposition returns the disposition a commit gets when picked cleanly,
+// according to its UPSTREAM action in the fork.
+func PlannedDisposition(repository git.Git, f fork.Fork, commit *object.Commit, mainline int) (report.Disposition, error) {
+	action, _, err := resolveAction(repository, f, commit)
+	if err != nil {
+		return "", err
+	}
+	switch {
+	case action == mergedAction:
+		return report.Merged, nil
+	case action == dropAction:
+		return report.Dropped, nil
+	case action != carryAction:
+		return report.Unknown, nil
+	case isMerge(commit) && mainline == 0:
+		return report.Skipped, nil
+	}
+	return report.Picked, nil
+}
+
+// predictedConflict is a carry predicted to conflict with upstream
+type predictedConflict struct {
+	commit *object.Commit
+	files  []string
+	// fixed is the fixed carry which will be used, empty when there is none
+	fixed string
+}
+
+// predictConflicts simulates picking all carries on top of upstream in memory,
+// without touching any branch, and returns the carries predicted to conflict.
+func (c *Apply) predictConflicts(repository git.Git, commits []*object.Commit, upstream string) ([]predictedConflict, error) {
+	// the simulation only depends on the SHAs of upstream and the carries
+	var store *cache.Cache
+	key := ""
+	if gitDir, err := repository.GitDir(); err == nil {
+		if upstreamSHA, err := repository.RevParse(upstream); err == nil {
+			parts := []string{upstreamSHA, strconv.Itoa(c.options.Mainline), fmt.Sprint(c.fork.Actions)}
+			for _, commit := range commits {
+				parts = append(parts, commit.Hash.String())
+			}
+			store, key = cache.New(gitDir), cache.Key(parts...)
+		}
+	}
+	var conflictedFiles map[string][]string
+	if store == nil || !store.Get(conflictsCache, key, &conflictedFiles) {
+		var err error
+		if conflictedFiles, err = c.simulatePicks(repository, commits, upstream); err != nil {
+			return nil, err
+		}
+		if store != nil {
+			store.Put(conflictsCache, key, conflictedFiles)
+		}
+	}
+	var conflicts []predictedConflict
+	for _, commit := range commits {
+		files, ok := conflictedFiles[commit.Hash.String()]
+		if !ok {
+			continue
+		}
+		conflict := predictedConflict{commit: commit, files: files}
+		if patch, _, err := c.carries.findFixedCarry(commit.Hash.String()); err == nil {
+			conflict.fixed = patch
+		}
+		conflicts = append(conflicts, conflict)
+	}
+	return conflicts, nil
+}
+
+// simulatePicks picks carries on top of upstream in memory and returns the
+// files conflicting for each of the carries predicted to conflict
+func (c *Apply) simulatePicks(repository git.Git, commits []*object.Commit, upstream string) (map[string][]string, error) {
+	base := upstream
+	conflicts := make(map[string][]string)
+	for _, commit := range commits {
+		action, _, err := resolveAction(repository, c.fork, commit)
+		if err != nil {
+			return nil, err
+		}
+		if action != carryAction || (isMerge(commit) && c.options.Mainline == 0) {
+			continue
+		}
+		tree, files, err := repository.MergeTree(base, commit.Hash.String(), commitMainline(commit, c.options.Mainline))
+		if err != nil {
+			return nil, fmt.Errorf("Failed simulating pick of %s: %w", commit.Hash.String(), err)
+		}
+		if len(files) > 0 {
+			conflicts[commit.Hash.String()] = files
+			// conflicting carry is not applied, the following ones are checked
+			// against the last successfully simulated state
+			continue
+		}
+		base, err = repository.CommitTree(tree, base, commit.Message)
+		if err != nil {
+			return nil, fmt.Errorf("Failed simulating pick of %s: %w", commit.Hash.String(), err)
+		}
+	}
+	return conflicts, nil
+}
+
+// checkEnvironment reports all problems of the repository and the environment,
+// which would make the run fail midway
+func (c *Apply) checkEnvironment(repository git.Git) error {
+	options := preflight.Options{Skip: c.options.SkipChecks, Fork: c.fork}
+	if c.options.Preflight || c.options.PreflightOnly {
+		options.MinGitVersion = preflight.MinGitVersionMergeTree
+	}
+	problems := preflight.Run(repository, options)
+	for _, p := range problems {
+		console.ErrorfContext(c.ctx, "%s", p)
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("Found %d problems before starting, fix them or skip the checks with --skip-check", len(problems))
+	}
+	return nil
+}
+
+// preflight simulates picking all carries on top of upstream and prints the
+// list of carries predicted to conflict.
+func (c *Apply) preflight(repository git.Git, commits []*object.Commit) error {
+	console.InfofContext(c.ctx, "Running preflight check of %d commits against %s...", len(commits), c.upstreamBranch())
+	conflicts, err := c.predictConflicts(repository, commits, c.upstreamBranch())
+	if err != nil {
+		return err
+	}
+	for _, conflict := range conflicts {
+		fixed := conflict.fixed
+		if len(fixed) == 0 {
+			fixed = "none"
+		}
+		fmt.Fprintf(c.out, "%s\t%s\n\tfixed carry: %s\n", conflict.commit.Hash.String(), utils.FormatMessage(conflict.commit.Message), fixed)
+		for _, f := range conflict.files {
+			fmt.Fprintf(c.out, "\t%s\n", f)
+		}
+	}
+	console.InfofContext(c.ctx, "Preflight check found %d conflicting carries out of %d commits.", len(conflicts), len(commits))
+	return nil
+}
+
+// carryFlow implements the carry action, the outcome is recorded in entry
+func (c *Apply) carryFlow(repository git.Git, commit *object.Commit, entry *report.Entry) error {
+	klog.V(2).Infof("Initiating carry flow for %s...", commit.Hash.String())
+	mainline := commitMainline(commit, c.options.Mainline)
+	entry.Disposition = report.Failed
+	head, err := repository.RevParse("HEAD")
+	if err != nil {
+		return err
+	}
+	pickErr := repository.CherryPick(commit.Hash.String(), mainline)
+	if pickErr == nil {
+		entry.Disposition = report.Picked
+		return nil
+	}
+	if c.interrupted() {
+		// the pick failed because git was interrupted too, no resolver
+		// should run on what it left behind
+		if err := discard(repository, head); err != nil {
+			return err
+		}
+		return pickErr
+	}
+	switch git.OutcomeOf(pickErr) {
+	case git.OutcomeEmpty:
+		// the changes of the carry are already upstream
+		if err := discard(repository, head); err != nil {
+			return err
+		}
+		console.InfofContext(c.ctx, "Skipping %s, it has no changes left on top of upstream.", commit.Hash.String())
+		entry.Disposition = report.Skipped
+		entry.Reason = "no changes left, they are already upstream"
+		return nil
+	case git.OutcomeUnknownCommit, git.OutcomeInProgress:
+		// neither resolvers nor fixed carries can help, the pick did not start
+		return pickErr
+	}
+	console.InfofContext(c.ctx, "Encountered problems picking %s:", commit.Hash.String())
+	if err := repository.Status(); err != nil {
+		return err
+	}
+	var conflicts []string
+	var conflict *git.ConflictError
+	if errors.As(pickErr, &conflict) {
+		conflicts = conflict.Files
+	}
+	if len(c.options.ConflictsDir) > 0 && len(conflicts) > 0 {
+		if err := dumpConflicts(c.ctx, repository, c.repositoryDir, c.options.ConflictsDir, commit, conflicts); err != nil {
+			klog.Errorf("Saving conflicts failed: %v", err)
+		}
+	}
+	toResolve := Conflict{Commit: commit, Mainline: mainline, Files: conflicts, Ours: head, Theirs: commit.Hash.String()}
+	if resolved, err := c.resolve(repository, toResolve, entry); err != nil || resolved {
+		return err
+	}
+	klog.V(2).Infof("Looking for a fixed carry")
+	patch, skip, err := c.carries.findFixedCarry(commit.Hash.String())
+	if err != nil {
+		// TODO: it would be nice to get the problematic files listed here
+		// if the cherry-pick failed and there's no fixed carry try using:
+		// git cherry-pick --strategy=recursive --strategy-option theirs
+		resolution, err := (&theirsResolver{fork: c.fork}).Resolve(repository, toResolve)
+		if err == nil && resolution != nil {
+			entry.Disposition = resolution.Disposition
+			return nil
+		}
+		if err := discard(repository, head); err != nil {
+			return err
+		}
+		// pick once again, leaving the conflicts in place for manual resolution
+		pickErr := repository.CherryPick(commit.Hash.String(), mainline)
+		if pickErr == nil {
+			entry.Disposition = report.Picked
+			return nil
+		}
+		klog.Errorf("Carry %s requires manual intervention!", c.fork.CommitURL(commit.Hash.String()))
+		return pickErr
+	}
+	if skip {
+		console.InfofContext(c.ctx, "Found skip patch %s.", patch)
+		entry.Disposition = report.Skipped
+		return nil
+	}
+	console.InfofContext(c.ctx, "Found %s, applying...", patch)
+	if err := repository.Apply(patch); err != nil {
+		if err := repository.AbortApply(); err != nil {
+			klog.Errorf("Aborting apply failed: %v", err)
+		}
+		// the patch might be stale, retry with gradually reduced context
+		for lines := patchContextLines - 1; lines >= 0; lines-- {
+			if err := repository.ApplyWithContext(patch, lines); err == nil {
+				klog.Warningf("Current fix %s applied only with %d lines of context, it is stale and should be refreshed!", c.fixLocation(commit, patch), lines)
+				entry.Disposition = report.FixedFuzz
+				entry.Reason = fmt.Sprintf("stale fixed carry applied with %d lines of context", lines)
+				return nil
+			}
+			if err := repository.AbortApply(); err != nil {
+				klog.Errorf("Aborting apply failed: %v", err)
+			}
+		}
+		// TODO: it would be nice to get the problematic files listed here
+		// if the apply failed, try using 3-way merge before failing
+		applyErr := repository.Apply3Way(patch)
+		if applyErr == nil {
+			klog.Warningf("Current fix %s was picked auto-magically \\o/ - make sure to double check it!", c.fixLocation(commit, patch))
+			klog.Warningf("Current fix %s is stale and should be refreshed!", c.fixLocation(commit, patch))
+			entry.Disposition = report.Fixed3Way
+			entry.Reason = "stale fixed carry applied with 3-way merge"
+			return nil
+		}
+		// the failed 3-way merge leaves the conflicts in place for manual resolution
+		klog.Errorf("The current fix stopped working %s and requires manual intervention!", c.fixLocation(commit, patch))
+		klog.Errorf("The original carry was %s", c.fork.CommitURL(commit.Hash.String()))
+		return applyErr
+	}
+	entry.Disposition = report.Fixed
+	return nil
+}
+
+// fixLocation returns where the fixed carry of the commit is published by
+// the fork, or the patch file when the fork does not publish them
+func (c *Apply) fixLocation(commit *object.Commit, patch string) string {
+	if url := c.fork.FixedCarryURL(commit.Hash.String()); len(url) > 0 {
+		return url
+	}
+	return patch
+}
+
+// IsDropped returns true if the commit message marks the carry to be dropped
+// in the fork
+func IsDropped(f fork.Fork, message string) bool {
+	return f.Action(actionFromMessage(message)) == dropAction
+}
+
+// actionFromMessage parses the upstream action from commit message, returning
+// which action to take on a commit
+func actionFromMessage(message string) string {
+	return carry.ActionFromMessage(message)
+}
+
+// startLog writes the log of the run to a new file next to the state file,
+// unless a log file was already requested with --log-file
+func startLog(stateDir, command string) error {
+	if len(console.LogFile()) > 0 {
+		return nil
+	}
+	if err := console.StartLog(console.LogPath(state.LogDir(stateDir), command)); err != nil {
+		return err
+	}
+	if path := console.LogFile(); len(path) > 0 {
+		console.Infof("Logging the run to %s", path)
+	}
+	return nil
+}
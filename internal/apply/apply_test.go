@@ -0,0 +1,358 @@
+package apply
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+
+	"github.com/openshift/rebase/internal/fork"
+	"github.com/openshift/rebase/internal/git"
+	"github.com/openshift/rebase/internal/git/gittest"
+	"github.com/openshift/rebase/internal/preflight"
+	"github.com/openshift/rebase/internal/report"
+	"github.com/openshift/rebase/internal/state"
+)
+
+// newRepository returns a fake repository with upstream moved past the
+// starting tag v1.0.0 and the carries on top of the previous rebase onto the
+// tag in the openshift branch
+func newRepository(t *testing.T, carries ...string) (*gittest.Fake, []string) {
+	t.Helper()
+	repository := gittest.New(t.TempDir())
+	f := fork.Kubernetes
+	base := repository.Commits(f.UpstreamRef(), "initial commit")[0]
+	repository.SetRef("refs/tags/v1.0.0", base)
+	repository.Commits(f.UpstreamRef(), "upstream change")
+	// the openshift branch was last rebased onto the tag
+	previous := repository.AddCommit(gittest.Commit{Message: "UPSTREAM: <carry>: openshift: add a previous carry"})
+	marker := repository.AddCommit(gittest.Commit{Message: f.RebaseMarker() + " rebase-v1.0.0", Parents: []string{base, previous}})
+	repository.SetRef(f.OpenShiftRef(), marker)
+	shas := repository.Commits(f.OpenShiftRef(), carries...)
+	repository.SetRef("work", base)
+	if err := repository.Checkout("work"); err != nil {
+		t.Fatal(err)
+	}
+	return repository, shas
+}
+
+// newApply returns a run against the fake repository without checks of the
+// environment
+func newApply(repository git.Git, options Options) *Apply {
+	options.SkipChecks = preflight.Names()
+	c := NewApply("v1.0.0", "", fork.Kubernetes, options)
+	c.SetRepository(repository)
+	c.SetOutput(io.Discard, io.Discard)
+	return c
+}
+
+// dispositions returns dispositions of the commits in the report of the run
+func dispositions(t *testing.T, gitDir string) map[string]report.Disposition {
+	t.Helper()
+	runState, err := state.Load(gitDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := make(map[string]report.Disposition)
+	for _, entry := range runState.Report.Entries {
+		result[entry.Original] = entry.Disposition
+	}
+	return result
+}
+
+func checkDispositions(t *testing.T, gitDir string, expected map[string]report.Disposition) {
+	t.Helper()
+	actual := dispositions(t, gitDir)
+	for sha, disposition := range expected {
+		if actual[sha] != disposition {
+			t.Errorf("expected %s to be %s, got %q", sha, disposition, actual[sha])
+		}
+	}
+	if len(actual) != len(expected) {
+		t.Errorf("expected %d commits in the report, got %d: %v", len(expected), len(actual), actual)
+	}
+}
+
+func TestApply(t *testing.T) {
+	repository, shas := newRepository(t,
+		"UPSTREAM: <carry>: openshift: add a carry",
+		"UPSTREAM: <drop>: regenerate files",
+		"UPSTREAM: <carry>: openshift: add an empty carry",
+	)
+	repository.SetOutcome(shas[2], git.OutcomeEmpty)
+	if err := newApply(repository, Options{}).Run(); err != nil {
+		t.Fatal(err)
+	}
+	gitDir, _ := repository.GitDir()
+	checkDispositions(t, gitDir, map[string]report.Disposition{
+		shas[0]: report.Picked,
+		shas[1]: report.Dropped,
+		shas[2]: report.Skipped,
+	})
+}
+
+func TestApplyConflictContinue(t *testing.T) {
+	repository, shas := newRepository(t,
+		"UPSTREAM: <carry>: openshift: add a carry",
+		"UPSTREAM: <carry>: openshift: add a conflicting carry",
+		"UPSTREAM: <drop>: regenerate files",
+	)
+	repository.SetOutcome(shas[1], git.OutcomeConflict, "a.go")
+	if err := newApply(repository, Options{}).Run(); err == nil {
+		t.Fatal("expected the run to stop on the conflict")
+	}
+	gitDir, _ := repository.GitDir()
+	checkDispositions(t, gitDir, map[string]report.Disposition{
+		shas[0]: report.Picked,
+		shas[1]: report.Failed,
+	})
+	if inProgress, err := repository.InProgress(); err != nil || len(inProgress) == 0 {
+		t.Fatalf("expected the conflicting pick to be left in progress, got %q: %v", inProgress, err)
+	}
+
+	// resolve the conflict as a user would and continue the run
+	if err := repository.ResolveConflicts("ours", []string{"a.go"}); err != nil {
+		t.Fatal(err)
+	}
+	continueAction := NewContinue("", fork.Kubernetes)
+	continueAction.SetRepository(repository)
+	continueAction.SetOutput(io.Discard, io.Discard)
+	if err := continueAction.Run(); err != nil {
+		t.Fatal(err)
+	}
+	checkDispositions(t, gitDir, map[string]report.Disposition{
+		shas[0]: report.Picked,
+		shas[1]: report.Manual,
+		shas[2]: report.Dropped,
+	})
+}
+
+func TestApplyPostPhaseHooks(t *testing.T) {
+	repository, _ := newRepository(t, "UPSTREAM: <carry>: openshift: add a carry")
+	phases := filepath.Join(t.TempDir(), "phases")
+	options := Options{PostPhaseHooks: []string{`echo "$REBASE_PHASE" >> ` + phases}, HookPolicy: HookPolicyStop}
+	if err := newApply(repository, options).Run(); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(phases)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := strings.Join([]string{state.PhaseMerge, state.PhaseCarries, state.PhaseBackports, state.PhaseAdditional}, "\n") + "\n"
+	if string(data) != expected {
+		t.Errorf("expected hooks after phases %q, got %q", expected, data)
+	}
+}
+
+func TestPredictConflicts(t *testing.T) {
+	tests := []struct {
+		name string
+		// conflicts are the files conflicting for the commits by index
+		conflicts map[int][]string
+		// fixed are the commits by index having a fixed carry
+		fixed    []int
+		expected map[int][]string
+	}{
+		{
+			name: "clean carries",
+		},
+		{
+			name:      "conflicting carries",
+			conflicts: map[int][]string{1: {"a.go", "b.go"}, 2: {"c.go"}},
+			fixed:     []int{2},
+			expected:  map[int][]string{1: {"a.go", "b.go"}, 2: {"c.go"}},
+		},
+		{
+			name:      "dropped commits not simulated",
+			conflicts: map[int][]string{3: {"a.go"}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			repository, shas := newRepository(t,
+				"UPSTREAM: <carry>: openshift: add a carry",
+				"UPSTREAM: <carry>: openshift: add a conflicting carry",
+				"UPSTREAM: <carry>: openshift: add another conflicting carry",
+				"UPSTREAM: <drop>: regenerate files",
+			)
+			var commits []*object.Commit
+			for _, sha := range shas {
+				commit, err := repository.Commit(plumbing.NewHash(sha))
+				if err != nil {
+					t.Fatal(err)
+				}
+				commits = append(commits, commit)
+			}
+			for i, files := range test.conflicts {
+				repository.SetOutcome(shas[i], git.OutcomeConflict, files...)
+			}
+			dir := t.TempDir()
+			for _, i := range test.fixed {
+				if err := os.WriteFile(filepath.Join(dir, shas[i]+".patch"), []byte("patch"), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			c := newApply(repository, Options{})
+			var err error
+			if c.carries, err = newFixedCarries([]string{dir}, "", ""); err != nil {
+				t.Fatal(err)
+			}
+			conflicts, err := c.predictConflicts(repository, commits, c.upstreamBranch())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(conflicts) != len(test.expected) {
+				t.Fatalf("expected %d conflicting carries, got %d", len(test.expected), len(conflicts))
+			}
+			for _, conflict := range conflicts {
+				i := indexOf(shas, conflict.commit.Hash.String())
+				if !reflect.DeepEqual(conflict.files, test.expected[i]) {
+					t.Errorf("expected carry %d to conflict in %v, got %v", i, test.expected[i], conflict.files)
+				}
+				if expected := indexOf(test.fixed, i) >= 0; expected != (len(conflict.fixed) > 0) {
+					t.Errorf("expected carry %d to have a fixed carry %v, got %q", i, expected, conflict.fixed)
+				}
+			}
+		})
+	}
+}
+
+// staleFake applies stale patches only with a reduced context or 3-way merge
+type staleFake struct {
+	*gittest.Fake
+	// lines is the most lines of context the patch applies with, -1 when it
+	// applies with none of them
+	lines    int
+	threeWay bool
+}
+
+func (f *staleFake) ApplyWithContext(patch string, lines int) error {
+	if lines <= f.lines {
+		f.SetOutcome(patch, "")
+	}
+	return f.Fake.ApplyWithContext(patch, lines)
+}
+
+func (f *staleFake) Apply3Way(patch string) error {
+	if f.threeWay {
+		f.SetOutcome(patch, "")
+	}
+	return f.Fake.Apply3Way(patch)
+}
+
+func TestApplyStaleFixedCarry(t *testing.T) {
+	tests := []struct {
+		name     string
+		stale    bool
+		lines    int
+		threeWay bool
+		// applies are the attempts of applying the patch
+		applies  []string
+		expected report.Disposition
+	}{
+		{
+			name:     "patch applying cleanly",
+			applies:  []string{"am"},
+			expected: report.Fixed,
+		},
+		{
+			name:     "patch applying with reduced context",
+			stale:    true,
+			lines:    1,
+			applies:  []string{"am", "am -C2", "am -C1"},
+			expected: report.FixedFuzz,
+		},
+		{
+			name:     "patch applying with 3-way merge",
+			stale:    true,
+			lines:    -1,
+			threeWay: true,
+			applies:  []string{"am", "am -C2", "am -C1", "am -C0", "am --3way"},
+			expected: report.Fixed3Way,
+		},
+		{
+			name:     "patch not applying",
+			stale:    true,
+			lines:    -1,
+			applies:  []string{"am", "am -C2", "am -C1", "am -C0", "am --3way"},
+			expected: report.Failed,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fake, shas := newRepository(t, "UPSTREAM: <carry>: openshift: add a conflicting carry")
+			fake.SetOutcome(shas[0], git.OutcomeConflict, "a.go")
+			dir := t.TempDir()
+			patch := filepath.Join(dir, shas[0]+".patch")
+			if err := os.WriteFile(patch, []byte("patch"), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if test.stale {
+				fake.SetOutcome(patch, git.OutcomeConflict, "a.go")
+			}
+			repository := &staleFake{Fake: fake, lines: test.lines, threeWay: test.threeWay}
+			err := newApply(repository, Options{CarriesDirs: []string{dir}}).Run()
+			if (err != nil) != (test.expected == report.Failed) {
+				t.Fatalf("expected the run to stop %v, got %v", test.expected == report.Failed, err)
+			}
+			gitDir, _ := repository.GitDir()
+			checkDispositions(t, gitDir, map[string]report.Disposition{shas[0]: test.expected})
+			var applies []string
+			for _, call := range repository.Calls() {
+				if operation, ok := strings.CutSuffix(call, " "+patch); ok && strings.HasPrefix(operation, "am") {
+					applies = append(applies, operation)
+				}
+			}
+			if !reflect.DeepEqual(applies, test.applies) {
+				t.Errorf("expected %v, got %v", test.applies, applies)
+			}
+		})
+	}
+}
+
+// indexOf returns the index of the value, or -1 when it is missing
+func indexOf[T comparable](values []T, value T) int {
+	for i, v := range values {
+		if v == value {
+			return i
+		}
+	}
+	return -1
+}
+
+func TestBackportMessage(t *testing.T) {
+	const sha = "0123456789abcdef0123456789abcdef01234567"
+	tests := []struct {
+		name     string
+		message  string
+		expected string
+	}{
+		{
+			name:     "summary only",
+			message:  "Fix a bug\n",
+			expected: "UPSTREAM: 123: Fix a bug\n\n(cherry picked from commit " + sha + ")\n",
+		},
+		{
+			name:     "body and sign-off",
+			message:  "Fix a bug\n\nLonger description.\n\nSigned-off-by: Jane <jane@example.com>\n",
+			expected: "UPSTREAM: 123: Fix a bug\n\nLonger description.\n\nSigned-off-by: Jane <jane@example.com>\n\n(cherry picked from commit " + sha + ")\n",
+		},
+		{
+			name:     "trailer present",
+			message:  "Fix a bug\n\n(cherry picked from commit " + sha + ")\n",
+			expected: "UPSTREAM: 123: Fix a bug\n\n(cherry picked from commit " + sha + ")\n",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if message := backportMessage(test.message, "UPSTREAM: 123: Fix a bug", sha); message != test.expected {
+				t.Errorf("expected %q, got %q", test.expected, message)
+			}
+		})
+	}
+}
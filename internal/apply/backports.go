@@ -0,0 +1,144 @@
+package apply
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"k8s.io/klog/v2"
+
+	"github.com/openshift/rebase/internal/console"
+	"github.com/openshift/rebase/internal/fork"
+	"github.com/openshift/rebase/internal/git"
+	"github.com/openshift/rebase/internal/github"
+	"github.com/openshift/rebase/internal/report"
+	"github.com/openshift/rebase/internal/state"
+	"github.com/openshift/rebase/internal/utils"
+)
+
+// backport describes an upstream commit scheduled for picking after the carries
+type backport struct {
+	sha      string
+	message  string
+	mainline int
+}
+
+// BackportRefs returns the backports given as upstream commits rather than
+// pull request numbers.
+func BackportRefs(backports []string) []string {
+	var refs []string
+	for _, ref := range backports {
+		if _, err := strconv.Atoi(strings.TrimPrefix(ref, "#")); err != nil {
+			refs = append(refs, ref)
+		}
+	}
+	return refs
+}
+
+// resolveBackport resolves upstream commit SHA or pull request number into
+// a commit to pick, with a message following the UPSTREAM: <pr-number>: format
+func resolveBackport(repository git.Git, upstream fork.Repository, ref string) (*backport, error) {
+	var sha, title string
+	number, err := strconv.Atoi(strings.TrimPrefix(ref, "#"))
+	if err == nil {
+		if sha, title, err = github.PullRequest(upstream, number); err != nil {
+			return nil, fmt.Errorf("Failed reading pull request %d: %w", number, err)
+		}
+	} else {
+		if sha, err = repository.RevParse(ref + "^{commit}"); err != nil {
+			return nil, fmt.Errorf("Failed resolving %s, make sure upstream is fetched: %w", ref, err)
+		}
+		if number, err = github.PullRequestForCommit(upstream, sha); err != nil {
+			return nil, fmt.Errorf("Failed finding pull request for %s: %w", sha, err)
+		}
+	}
+	commit, err := repository.Commit(plumbing.NewHash(sha))
+	if err != nil {
+		return nil, fmt.Errorf("Failed reading commit %s, make sure upstream is fetched: %w", sha, err)
+	}
+	if len(title) == 0 {
+		title = utils.FormatMessage(commit.Message)
+	}
+	b := &backport{
+		sha:     sha,
+		message: fmt.Sprintf("UPSTREAM: %d: %s", number, title),
+	}
+	// pull requests merged with a merge commit are picked against the upstream branch
+	if isMerge(commit) {
+		b.mainline = 1
+	}
+	return b, nil
+}
+
+// pickBackports picks the remaining upstream backports, recording the results
+// in the report and progress in the state.
+func (c *Apply) pickBackports(repository git.Git, runState *state.State) error {
+	for ; runState.BackportsNext < len(c.options.Backports); runState.BackportsNext++ {
+		if c.interrupted() {
+			return c.stopInterrupted(repository, "")
+		}
+		c.checkpoint(runState)
+		ref := c.options.Backports[runState.BackportsNext]
+		c.updateStatus(runState, ref)
+		b, err := resolveBackport(repository, c.fork.Upstream, ref)
+		if err != nil {
+			return err
+		}
+		console.InfofContext(c.ctx, "Picking upstream backport %s as %q...", b.sha, b.message)
+		entry := report.Entry{Original: b.sha, Message: b.message, Backport: true, Disposition: report.Picked}
+		head, err := repository.RevParse("HEAD")
+		if err != nil {
+			return err
+		}
+		if err := repository.CherryPick(b.sha, b.mainline); err != nil {
+			if c.interrupted() {
+				return c.stopInterrupted(repository, head)
+			}
+			entry.Disposition = report.Failed
+			entry.Conflicts = conflictedFiles(err)
+			runState.Report.Add(entry)
+			err = c.stopOn(repository, runState, b.sha, err)
+			runState.BackportsNext++
+			klog.Errorf("Upstream backport %s requires manual intervention!", ref)
+			klog.Errorf("Once resolved, run 'rebase continue' to proceed.")
+			return err
+		}
+		if err := amendBackport(repository, b.message, b.sha); err != nil {
+			return err
+		}
+		if entry.New, err = repository.RevParse("HEAD"); err != nil {
+			return err
+		}
+		runState.Report.Add(entry)
+	}
+	return nil
+}
+
+// amendBackport replaces the summary of the picked backport in HEAD, keeping
+// the body of the upstream message, and appends the cherry picked from
+// trailer of the upstream commit
+func amendBackport(repository git.Git, summary, sha string) error {
+	head, err := repository.RevParse("HEAD")
+	if err != nil {
+		return err
+	}
+	commit, err := repository.Commit(plumbing.NewHash(head))
+	if err != nil {
+		return fmt.Errorf("Failed reading commit %s: %w", head, err)
+	}
+	return repository.AmendMessage(backportMessage(commit.Message, summary, sha))
+}
+
+// backportMessage returns message with the summary line replaced and the
+// cherry picked from trailer of sha, unless it is already present
+func backportMessage(message, summary, sha string) string {
+	lines := strings.Split(strings.TrimRight(message, "\n"), "\n")
+	lines[0] = summary
+	fixed := strings.Join(lines, "\n")
+	trailer := fmt.Sprintf("(cherry picked from commit %s)", sha)
+	if !strings.Contains(fixed, trailer) {
+		fixed += "\n\n" + trailer
+	}
+	return fixed + "\n"
+}
@@ -0,0 +1,191 @@
+package apply
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"k8s.io/klog/v2"
+)
+
+const (
+	defaultCarriesDir = "carries"
+	additionalDir     = "additional"
+	patchExtension    = ".patch"
+)
+
+// fixedCarries locates fixed carry patches. Each of the configured directories
+// can hold patches directly, or organized in per-version subdirectories
+// (eg. carries/v1.31/<sha>.patch), in which case the directory matching the
+// target version is searched first, falling back to older versions.
+type fixedCarries struct {
+	// roots holds the candidate directories per configured directory, in lookup order
+	roots [][]string
+}
+
+// version is a parsed vMAJOR.MINOR directory name
+type version struct {
+	name         string
+	major, minor int
+}
+
+// newFixedCarries returns the lookup of fixed carries in the directories,
+// relative ones are looked up in the current directory, and in the repository
+// directory, when the tool is not started from the directory holding them
+func newFixedCarries(dirs []string, targetVersion, repositoryDir string) (*fixedCarries, error) {
+	configured := len(dirs) > 0
+	if !configured {
+		dirs = []string{defaultCarriesDir}
+	}
+	var target *version
+	if len(targetVersion) > 0 {
+		var ok bool
+		if target, ok = parseVersion(targetVersion); !ok {
+			return nil, fmt.Errorf("invalid target version %q, expected vMAJOR.MINOR", targetVersion)
+		}
+	}
+	carries := &fixedCarries{}
+	for _, dir := range dirs {
+		dir, err := resolveCarriesDir(dir, repositoryDir)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := os.Stat(dir); errors.Is(err, os.ErrNotExist) && configured {
+			return nil, fmt.Errorf("carries directory %s does not exist", dir)
+		}
+		candidates, err := versionedDirs(dir, target)
+		if err != nil {
+			return nil, err
+		}
+		candidates = append(candidates, dir)
+		klog.V(2).Infof("Looking for fixed carries in %v", candidates)
+		carries.roots = append(carries.roots, candidates)
+	}
+	return carries, nil
+}
+
+// resolveCarriesDir returns the absolute path of a carries directory, relative
+// paths are resolved against the current directory, unless only the repository
+// directory holds them
+func resolveCarriesDir(dir, repositoryDir string) (string, error) {
+	dir = filepath.FromSlash(dir)
+	if filepath.IsAbs(dir) {
+		return filepath.Clean(dir), nil
+	}
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	if _, err := os.Stat(abs); err != nil && len(repositoryDir) > 0 {
+		inRepository := filepath.Join(repositoryDir, dir)
+		if _, err := os.Stat(inRepository); err == nil {
+			return filepath.Abs(inRepository)
+		}
+	}
+	return abs, nil
+}
+
+// versionedDirs returns per-version subdirectories of dir, which are not newer
+// than the target version, sorted from the newest
+func versionedDirs(dir string, target *version) ([]string, error) {
+	if target == nil {
+		return nil, nil
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var versions []*version
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		v, ok := parseVersion(e.Name())
+		if !ok || v.major > target.major || (v.major == target.major && v.minor > target.minor) {
+			continue
+		}
+		versions = append(versions, v)
+	}
+	sort.Slice(versions, func(i, j int) bool {
+		if versions[i].major != versions[j].major {
+			return versions[i].major > versions[j].major
+		}
+		return versions[i].minor > versions[j].minor
+	})
+	dirs := make([]string, 0, len(versions))
+	for _, v := range versions {
+		dirs = append(dirs, filepath.Join(dir, v.name))
+	}
+	return dirs, nil
+}
+
+// parseVersion parses vMAJOR.MINOR, patch version is accepted and ignored
+func parseVersion(name string) (*version, bool) {
+	parts := strings.Split(strings.TrimPrefix(name, "v"), ".")
+	if !strings.HasPrefix(name, "v") || len(parts) < 2 || len(parts) > 3 {
+		return nil, false
+	}
+	major, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return nil, false
+	}
+	minor, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return nil, false
+	}
+	return &version{name: name, major: major, minor: minor}, true
+}
+
+// findFixedCarry looks for fixed carry patches, named either <sha> or <sha>.patch.
+// Returns path to a file containing the carry, information whether to skip it
+// or not and an error.
+func (f *fixedCarries) findFixedCarry(carrySha string) (string, bool, error) {
+	for _, candidates := range f.roots {
+		for _, dir := range candidates {
+			for _, name := range []string{carrySha + patchExtension, carrySha} {
+				carryPath := filepath.Join(dir, name)
+				fileInfo, err := os.Stat(carryPath)
+				if err != nil {
+					continue
+				}
+				// empty fixed carry informs the patch was mislabeled
+				return carryPath, fileInfo.Size() == 0, nil
+			}
+		}
+	}
+	return "", false, fmt.Errorf("fixed carry for %s not found: %w", carrySha, os.ErrNotExist)
+}
+
+// findAdditionalCarries looks for additional carry patches which need to be applied,
+// from every configured directory the additional carries for the closest version are used.
+// Returns a list of files containing the carries and error.
+func (f *fixedCarries) findAdditionalCarries() ([]string, error) {
+	additionalCarries := []string{}
+	for _, candidates := range f.roots {
+		for _, dir := range candidates {
+			additionalPath := filepath.Join(dir, additionalDir)
+			files, err := os.ReadDir(additionalPath)
+			if err != nil {
+				if errors.Is(err, os.ErrNotExist) {
+					continue
+				}
+				return nil, err
+			}
+			for _, file := range files {
+				if file.IsDir() {
+					continue
+				}
+				additionalCarries = append(additionalCarries, filepath.Join(additionalPath, file.Name()))
+			}
+			break
+		}
+	}
+	return additionalCarries, nil
+}
@@ -0,0 +1,112 @@
+package apply
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFindFixedCarry(t *testing.T) {
+	root := t.TempDir()
+	// patches are named by the carries, fixed is the patch file of the carry
+	files := map[string]string{
+		"first/v1.30/fixed.patch":   "old fix",
+		"first/v1.31/fixed.patch":   "fix",
+		"first/v1.32/newer.patch":   "newer fix",
+		"first/v1.29/skipped.patch": "",
+		"first/unversioned":         "fix",
+		"second/other.patch":        "fix",
+	}
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	dirs := []string{filepath.Join(root, "first"), filepath.Join(root, "second")}
+	tests := []struct {
+		name    string
+		dirs    []string
+		target  string
+		carry   string
+		wantErr bool
+		// expected is the path of the patch relative to the root, empty when none is found
+		expected string
+		skip     bool
+	}{
+		{
+			name:     "target version",
+			target:   "v1.31",
+			carry:    "fixed",
+			expected: "first/v1.31/fixed.patch",
+		},
+		{
+			name:     "older version",
+			target:   "v1.31.2",
+			carry:    "skipped",
+			expected: "first/v1.29/skipped.patch",
+			skip:     true,
+		},
+		{
+			name:   "newer version ignored",
+			target: "v1.31",
+			carry:  "newer",
+		},
+		{
+			name:  "versions ignored without the target version",
+			carry: "fixed",
+		},
+		{
+			name:     "patch without extension",
+			target:   "v1.31",
+			carry:    "unversioned",
+			expected: "first/unversioned",
+		},
+		{
+			name:     "next directory",
+			target:   "v1.31",
+			carry:    "other",
+			expected: "second/other.patch",
+		},
+		{
+			name:    "invalid target version",
+			target:  "1.31",
+			wantErr: true,
+		},
+		{
+			name:    "missing directory",
+			dirs:    []string{filepath.Join(root, "missing")},
+			wantErr: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if test.dirs == nil {
+				test.dirs = dirs
+			}
+			carries, err := newFixedCarries(test.dirs, test.target, "")
+			if (err != nil) != test.wantErr {
+				t.Fatalf("newFixedCarries() error = %v, wantErr %v", err, test.wantErr)
+			}
+			if test.wantErr {
+				return
+			}
+			patch, skip, err := carries.findFixedCarry(test.carry)
+			if len(test.expected) == 0 {
+				if err == nil {
+					t.Errorf("expected no fixed carry, got %s", patch)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if expected := filepath.Join(root, test.expected); patch != expected || skip != test.skip {
+				t.Errorf("expected %s skipped %v, got %s skipped %v", expected, test.skip, patch, skip)
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package apply
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"k8s.io/klog/v2"
+
+	"github.com/openshift/rebase/internal/console"
+	"github.com/openshift/rebase/internal/git"
+)
+
+// conflict stages as stored in the index
+var conflictStages = []struct {
+	stage  int
+	suffix string
+}{
+	{stage: 1, suffix: ".base"},
+	{stage: 2, suffix: ".ours"},
+	{stage: 3, suffix: ".theirs"},
+}
+
+// dumpConflicts stores conflicted versions of files and the diff with conflict
+// markers under <conflictsDir>/<sha>/, so conflicts can be reviewed offline.
+// For every file there's .base, .ours (new base) and .theirs (carry) version,
+// when it exists, along with .merged holding the conflict markers.
+func dumpConflicts(ctx context.Context, repository git.Git, repositoryDir, conflictsDir string, commit *object.Commit, files []string) error {
+	dir := filepath.Join(conflictsDir, commit.Hash.String())
+	console.InfofContext(ctx, "Saving conflicts of %s to %s...", commit.Hash.String(), dir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(dir, "commit.txt"), []byte(fmt.Sprintf("commit %s\n\n%s", commit.Hash.String(), commit.Message)), 0644); err != nil {
+		return err
+	}
+	diff, err := repository.Diff()
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(dir, "conflicts.diff"), diff, 0644); err != nil {
+		return err
+	}
+	for _, f := range files {
+		target := filepath.Join(dir, filepath.FromSlash(f))
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return err
+		}
+		for _, s := range conflictStages {
+			content, err := repository.ShowStage(s.stage, f)
+			if err != nil {
+				// file might not exist in all stages, eg. when deleted
+				klog.V(2).Infof("No stage %d for %s: %v", s.stage, f, err)
+				continue
+			}
+			if err := os.WriteFile(target+s.suffix, content, 0644); err != nil {
+				return err
+			}
+		}
+		merged, err := os.ReadFile(filepath.Join(repositoryDir, filepath.FromSlash(f)))
+		if err != nil {
+			klog.V(2).Infof("No merged version for %s: %v", f, err)
+			continue
+		}
+		if err := os.WriteFile(target+".merged", merged, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,288 @@
+package apply
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"k8s.io/klog/v2"
+
+	"github.com/openshift/rebase/internal/console"
+	"github.com/openshift/rebase/internal/fork"
+	"github.com/openshift/rebase/internal/git"
+	"github.com/openshift/rebase/internal/report"
+	"github.com/openshift/rebase/internal/state"
+)
+
+type Continue struct {
+	repositoryDir string
+	stateDir      string
+	fork          fork.Fork
+	repository    git.Git
+	observer      func(runState *state.State, current string)
+	ctx           context.Context
+	notifySlack   string
+	notifyWebhook string
+	out           io.Writer
+	errOut        io.Writer
+}
+
+// NewContinue returns a continuation of the apply run in the repository, the
+// fork the run was started on is used instead of f, unless it was not recorded.
+func NewContinue(repositoryDir string, f fork.Fork) *Continue {
+	return &Continue{
+		repositoryDir: repositoryDir,
+		fork:          f,
+	}
+}
+
+// SetStateDir reads and persists the state of the run in a given directory
+// instead of the git directory.
+func (c *Continue) SetStateDir(dir string) {
+	c.stateDir = dir
+}
+
+// SetObserver registers a function observing the progress of the resumed run.
+func (c *Continue) SetObserver(observer func(runState *state.State, current string)) {
+	c.observer = observer
+}
+
+// SetContext makes the resumed run stop once the context is cancelled.
+func (c *Continue) SetContext(ctx context.Context) {
+	c.ctx = ctx
+}
+
+// SetOutput prints results of the resumed run to out and its progress to
+// errOut, instead of stdout and stderr.
+func (c *Continue) SetOutput(out, errOut io.Writer) {
+	c.out = out
+	c.errOut = errOut
+}
+
+// SetNotify sets the webhook URLs notified by the resumed run, they are not
+// persisted with the options of the run.
+func (c *Continue) SetNotify(slack, webhook string) {
+	c.notifySlack = slack
+	c.notifyWebhook = webhook
+}
+
+// SetRepository shares an already opened repository, which is also used by
+// the resumed apply run.
+func (c *Continue) SetRepository(repository git.Git) {
+	c.repository = repository
+}
+
+// Run finishes the in-progress pick of the commit which stopped the apply run,
+// records the manual resolution and proceeds with the remaining carries.
+func (c *Continue) Run() error {
+	repository, err := git.OpenShared(c.repository, c.repositoryDir, c.fork)
+	if err != nil {
+		return err
+	}
+	gitDir, err := repository.GitDir()
+	if err != nil {
+		return err
+	}
+	unlock, err := state.Acquire(gitDir, "continue")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	stateDir := state.DirOf(gitDir, c.stateDir)
+	if err := startLog(stateDir, "continue"); err != nil {
+		return err
+	}
+	runState, err := state.Load(stateDir)
+	if err != nil {
+		return err
+	}
+	runFork := runState.RunFork(c.fork)
+	if c.repository == nil && runFork.Name != c.fork.Name {
+		// the remotes are checked against the fork the run was started on
+		if repository, err = git.OpenGit(c.repositoryDir, runFork); err != nil {
+			return err
+		}
+	}
+	switch runState.Phase {
+	case state.PhaseDone:
+		return fmt.Errorf("The run on %s has already finished", runState.Branch)
+	case state.PhaseMerge:
+		return fmt.Errorf("The run stopped before picking carries, use rollback and start again")
+	}
+	if err := checkRunBranch(repository, runState.Branch); err != nil {
+		return err
+	}
+	if runState.Report == nil {
+		runState.Report = &report.Report{}
+	}
+	options := Options{}
+	if len(runState.Options) > 0 {
+		if err := json.Unmarshal(runState.Options, &options); err != nil {
+			return fmt.Errorf("Error reading apply options from state: %w", err)
+		}
+	}
+	options.NotifySlack, options.NotifyWebhook = c.notifySlack, c.notifyWebhook
+	applyAction := NewApply(runState.From, c.repositoryDir, runFork, options)
+	applyAction.SetStateDir(c.stateDir)
+	applyAction.SetRepository(repository)
+	applyAction.SetObserver(c.observer)
+	applyAction.SetContext(c.ctx)
+	if c.out != nil {
+		applyAction.SetOutput(c.out, c.errOut)
+	}
+	if err := applyAction.complete(); err != nil {
+		return err
+	}
+	defer applyAction.status.Stop()
+	return applyAction.continueRun(repository, stateDir, runState)
+}
+
+// checkRunBranch ensures the rebase branch of the run is checked out, since
+// the remaining commits are picked on top of HEAD
+func checkRunBranch(repository git.Git, branch string) error {
+	if len(branch) == 0 {
+		return nil
+	}
+	current, err := repository.CurrentBranch()
+	if err != nil {
+		return err
+	}
+	if current == branch {
+		return nil
+	}
+	if len(current) == 0 {
+		if current, err = repository.RevParse("HEAD"); err != nil {
+			return err
+		}
+		current = "detached HEAD at " + current
+	}
+	return fmt.Errorf("The run continues on its rebase branch %s, but %s is checked out, check out %s and run continue again", branch, current, branch)
+}
+
+// continueRun finishes the manual resolution of the commit which stopped the
+// run, if any, and proceeds with the remaining commits of the run
+func (c *Apply) continueRun(repository git.Git, stateDir string, runState *state.State) error {
+	if len(runState.Current) > 0 {
+		if err := c.resolveCurrent(repository, runState); err != nil {
+			return err
+		}
+		if err := runState.Save(stateDir); err != nil {
+			return fmt.Errorf("Error saving state: %w", err)
+		}
+	}
+	commits := make([]*object.Commit, 0, len(runState.Commits))
+	for _, sha := range runState.Commits {
+		commit, err := repository.Commit(plumbing.NewHash(sha))
+		if err != nil {
+			return fmt.Errorf("Error reading commit %s: %w", sha, err)
+		}
+		commits = append(commits, commit)
+	}
+	return c.resume(repository, stateDir, runState, commits)
+}
+
+// resumeExisting handles the rebase branch created by a previous run, which
+// is resumed when requested and its progress is valid, the returned bool is
+// true when the run was resumed
+func (c *Apply) resumeExisting(repository git.Git, stateDir, branchName string) (bool, error) {
+	branchSHA, err := repository.RevParse("refs/heads/" + branchName)
+	if err != nil {
+		// the branch does not exist
+		return false, nil
+	}
+	runState, err := state.Load(stateDir)
+	if errors.Is(err, state.ErrNotFound) || (err == nil && runState.Branch != branchName) {
+		return false, fmt.Errorf("Rebase branch %s already exists, but no run is in progress on it, delete or rename the branch to start again", branchName)
+	}
+	if err != nil {
+		return false, err
+	}
+	if runState.Fork != nil && runState.Fork.Name != c.fork.Name {
+		return false, fmt.Errorf("The run on %s was started on the %s fork, resume it with --fork %s", branchName, runState.Fork.Name, runState.Fork.Name)
+	}
+	switch runState.Phase {
+	case state.PhaseDone:
+		return false, fmt.Errorf("The run on %s has already finished, delete or rename the branch to start again", branchName)
+	case state.PhaseMerge:
+		return false, fmt.Errorf("The run on %s stopped before picking carries, use rollback and start again", branchName)
+	}
+	// the progress is valid when the branch holds the merge of the openshift branch
+	if runState.Report != nil && len(runState.Report.OpenShiftSHA) > 0 {
+		if merged, err := repository.IsAncestor(runState.Report.OpenShiftSHA, branchSHA); err != nil || !merged {
+			return false, fmt.Errorf("Rebase branch %s does not hold the progress of the run, use rollback and start again", branchName)
+		}
+	}
+	processed := fmt.Sprintf("%d of %d carries processed", runState.Next, len(runState.Commits))
+	if !c.options.Resume {
+		return false, fmt.Errorf("Rebase branch %s already has progress of a previous run (%s phase, %s), use --resume to continue it or rollback to start again",
+			branchName, runState.Phase, processed)
+	}
+	console.InfofContext(c.ctx, "Resuming the run on %s in %s phase, %s...", branchName, runState.Phase, processed)
+	if current, err := repository.CurrentBranch(); err != nil {
+		return false, err
+	} else if current != branchName {
+		if err := repository.Checkout(branchName); err != nil {
+			return false, fmt.Errorf("Error checking out %s: %w", branchName, err)
+		}
+	}
+	if runState.Report == nil {
+		runState.Report = &report.Report{}
+	}
+	return true, c.continueRun(repository, stateDir, runState)
+}
+
+// resolveCurrent finishes the in-progress operation on the commit which stopped
+// the run, and records the manual resolution in the report.
+func (c *Apply) resolveCurrent(repository git.Git, runState *state.State) error {
+	console.InfofContext(c.ctx, "Finishing manual resolution of %s...", runState.Current)
+	if err := repository.ContinueInProgress(); err != nil {
+		return fmt.Errorf("Error finishing manual resolution, make sure all conflicts are resolved: %w", err)
+	}
+	head, err := repository.RevParse("HEAD")
+	if err != nil {
+		return err
+	}
+	entry := runState.Report.Find(runState.Current)
+	if entry == nil {
+		runState.Report.Add(report.Entry{Original: runState.Current})
+		entry = runState.Report.Find(runState.Current)
+	}
+	skipped := head == runState.StoppedAt
+	if runState.Committed {
+		// the commit exists already, it is skipped by resetting it away
+		parent, err := repository.RevParse(runState.StoppedAt + "^")
+		if err != nil {
+			return err
+		}
+		skipped = head == parent
+	}
+	if skipped {
+		klog.Warningf("No new commit found, carry %s was skipped manually", c.fork.CommitURL(runState.Current))
+		entry.Disposition = report.Skipped
+		entry.New = ""
+		entry.Reason = "skipped during manual resolution"
+	} else {
+		if entry.Backport {
+			if err := amendBackport(repository, entry.Message, entry.Original); err != nil {
+				return err
+			}
+			if head, err = repository.RevParse("HEAD"); err != nil {
+				return err
+			}
+		}
+		entry.Disposition = report.Manual
+		entry.New = head
+		entry.Reason = "resolved manually"
+		if err := c.hooks.runPostPick(entry.Original, entry.Message, entry.New, string(entry.Disposition)); err != nil {
+			return err
+		}
+	}
+	runState.Current = ""
+	runState.StoppedAt = ""
+	runState.Committed = false
+	return nil
+}
@@ -0,0 +1,77 @@
+package apply
+
+import (
+	"fmt"
+
+	"k8s.io/klog/v2"
+
+	"github.com/openshift/rebase/internal/utils"
+)
+
+const (
+	// HookPolicyWarn logs hook failures and continues the run
+	HookPolicyWarn = "warn"
+	// HookPolicyStop stops the run on the first hook failure
+	HookPolicyStop = "stop"
+)
+
+// hooks runs user provided commands at certain points of the apply flow.
+// Every command is invoked through sh inside the repository directory, with
+// details about the current commit or phase exposed as REBASE_* environment variables.
+type hooks struct {
+	repositoryDir string
+	prePick       []string
+	postPick      []string
+	postPhase     []string
+	policy        string
+}
+
+func newHooks(repositoryDir string, options Options) (*hooks, error) {
+	policy := options.HookPolicy
+	if len(policy) == 0 {
+		policy = HookPolicyWarn
+	}
+	if policy != HookPolicyWarn && policy != HookPolicyStop {
+		return nil, fmt.Errorf("invalid hook policy %q, expected %s or %s", policy, HookPolicyWarn, HookPolicyStop)
+	}
+	return &hooks{
+		repositoryDir: repositoryDir,
+		prePick:       options.PrePickHooks,
+		postPick:      options.PostPickHooks,
+		postPhase:     options.PostPhaseHooks,
+		policy:        policy,
+	}, nil
+}
+
+// runPrePick runs hooks before picking a commit
+func (h *hooks) runPrePick(sha, message string) error {
+	return h.run(h.prePick, "REBASE_COMMIT="+sha, "REBASE_MESSAGE="+message)
+}
+
+// runPostPick runs hooks after a commit was picked as newSha
+func (h *hooks) runPostPick(sha, message, newSha, disposition string) error {
+	return h.run(h.postPick, "REBASE_COMMIT="+sha, "REBASE_MESSAGE="+message,
+		"REBASE_NEW_COMMIT="+newSha, "REBASE_DISPOSITION="+disposition)
+}
+
+// runPostPhase runs hooks after a phase of the apply flow finished, phases
+// are named the same as in the state, see state.Phase*
+func (h *hooks) runPostPhase(phase string) error {
+	return h.run(h.postPhase, "REBASE_PHASE="+phase)
+}
+
+func (h *hooks) run(commands []string, env ...string) error {
+	for _, command := range commands {
+		klog.V(2).Infof("Invoking hook %q with %v...", command, env)
+		output, err := utils.RunCommandWithEnv(h.repositoryDir, env, "sh", "-c", command)
+		if err == nil {
+			continue
+		}
+		if h.policy == HookPolicyStop {
+			klog.Errorf("Hook %q failed:\n%s", command, output)
+			return fmt.Errorf("hook %q failed: %w", command, err)
+		}
+		klog.Warningf("Hook %q failed: %v\n%s", command, err, output)
+	}
+	return nil
+}
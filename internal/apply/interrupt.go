@@ -0,0 +1,132 @@
+package apply
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
+
+	"k8s.io/klog/v2"
+
+	"github.com/openshift/rebase/internal/console"
+	"github.com/openshift/rebase/internal/git"
+	"github.com/openshift/rebase/internal/state"
+)
+
+// ErrInterrupted is returned when the run was interrupted by SIGINT or SIGTERM,
+// or its context was cancelled, the progress is saved, so that the run is
+// resumed with continue.
+var ErrInterrupted = errors.New("the run was interrupted")
+
+// interrupts catches SIGINT and SIGTERM during the run, so that the commit
+// being picked is finished or aborted before exiting, rather than leaving the
+// repository in the middle of a pick
+type interrupts struct {
+	signals  chan os.Signal
+	received atomic.Bool
+	// done stops watching the context of the run
+	done chan struct{}
+
+	lock sync.Mutex
+	// stateDir holds the state file
+	stateDir string
+	// checkpoint is the state before the commit being picked, it is saved
+	// when the run exits immediately
+	checkpoint []byte
+}
+
+// catchInterrupts starts catching the signals until stop is called, a second
+// signal saves the last checkpoint of the state and exits immediately. When
+// the run has a context, signals are left to the caller and the run is
+// interrupted by cancelling the context instead.
+func catchInterrupts(ctx context.Context, stateDir string) *interrupts {
+	i := &interrupts{stateDir: stateDir}
+	if ctx != nil {
+		i.done = make(chan struct{})
+		go func() {
+			select {
+			case <-ctx.Done():
+				i.received.Store(true)
+				console.WarningfContext(ctx, "The run was cancelled, stopping after the current commit")
+			case <-i.done:
+			}
+		}()
+		return i
+	}
+	i.signals = make(chan os.Signal, 1)
+	signal.Notify(i.signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		for s := range i.signals {
+			if i.received.Swap(true) {
+				i.exit(s)
+			}
+			console.WarningfContext(ctx, "Received %s, stopping after the current commit, interrupt again to exit immediately", s)
+		}
+	}()
+	return i
+}
+
+func (i *interrupts) stop() {
+	if i.done != nil {
+		close(i.done)
+		return
+	}
+	signal.Stop(i.signals)
+	close(i.signals)
+}
+
+// save records the state before picking the next commit, so that the progress
+// is not lost when the run exits immediately
+func (i *interrupts) save(runState *state.State) {
+	data, err := runState.Marshal()
+	if err != nil {
+		klog.Errorf("Saving the checkpoint of the state failed: %v", err)
+		return
+	}
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	i.checkpoint = data
+}
+
+// exit saves the last checkpoint of the state and exits, the pick in progress
+// and the lock of the repository are left behind
+func (i *interrupts) exit(s os.Signal) {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	if i.checkpoint != nil {
+		if err := state.Write(i.stateDir, i.checkpoint); err != nil {
+			klog.Errorf("Saving state failed: %v", err)
+		}
+	}
+	klog.Errorf("Received %s again, exiting immediately. The lock of the repository is left behind, the next run takes it over. "+
+		"Abort the pick in progress and run 'rebase continue' to resume the run, or 'rebase rollback' to start over.", s)
+	os.Exit(130)
+}
+
+// checkpoint saves the state before picking the next commit
+func (c *Apply) checkpoint(runState *state.State) {
+	if c.interrupts != nil && c.interrupts.signals != nil {
+		c.interrupts.save(runState)
+	}
+}
+
+// interrupted returns true once the run received a signal
+func (c *Apply) interrupted() bool {
+	return c.interrupts != nil && c.interrupts.received.Load()
+}
+
+// stopInterrupted discards the pick of the commit interrupted by the signal,
+// if any, so that it is picked again once the run is resumed
+func (c *Apply) stopInterrupted(repository git.Git, head string) error {
+	if len(head) > 0 {
+		if err := discard(repository, head); err != nil {
+			return fmt.Errorf("Error discarding the interrupted pick: %w", err)
+		}
+	}
+	console.WarningfContext(c.ctx, "The progress is saved, run 'rebase continue' to resume the run or 'rebase rollback' to start over.")
+	return ErrInterrupted
+}
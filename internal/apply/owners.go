@@ -0,0 +1,154 @@
+package apply
+
+import (
+	"bufio"
+	"bytes"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"k8s.io/klog/v2"
+
+	"github.com/openshift/rebase/internal/console"
+	"github.com/openshift/rebase/internal/git"
+	"github.com/openshift/rebase/internal/report"
+)
+
+// maxAssignees limits the number of suggested assignees of a single carry
+const maxAssignees = 3
+
+// parseOwnersList returns items of a top-level list, eg. approvers, from an
+// OWNERS file, only the simple block list format used in kubernetes is supported
+func parseOwnersList(data []byte, key string) []string {
+	var items []string
+	inList := false
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		trimmed := strings.TrimSpace(line)
+		if len(trimmed) == 0 {
+			continue
+		}
+		if line[0] != ' ' && line[0] != '-' {
+			inList = trimmed == key+":"
+			continue
+		}
+		if inList && strings.HasPrefix(trimmed, "- ") {
+			items = append(items, strings.Trim(strings.TrimSpace(strings.TrimPrefix(trimmed, "- ")), `"'`))
+		}
+	}
+	return items
+}
+
+// parseOwnersAliases returns the aliases defined in OWNERS_ALIASES
+func parseOwnersAliases(data []byte) map[string][]string {
+	aliases := make(map[string][]string)
+	alias := ""
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case len(trimmed) == 0, trimmed == "aliases:":
+		case strings.HasPrefix(trimmed, "- "):
+			if len(alias) > 0 {
+				aliases[alias] = append(aliases[alias], strings.Trim(strings.TrimSpace(strings.TrimPrefix(trimmed, "- ")), `"'`))
+			}
+		case strings.HasSuffix(trimmed, ":"):
+			alias = strings.TrimSuffix(trimmed, ":")
+		}
+	}
+	return aliases
+}
+
+// owners finds approvers of files in the nearest OWNERS files on HEAD
+type owners struct {
+	repository git.Git
+	aliases    map[string][]string
+	// approvers caches approvers by directory
+	approvers map[string][]string
+}
+
+func newOwners(repository git.Git) *owners {
+	o := &owners{repository: repository, approvers: make(map[string][]string)}
+	if data, err := repository.ShowFile("HEAD", "OWNERS_ALIASES"); err == nil {
+		o.aliases = parseOwnersAliases(data)
+	}
+	return o
+}
+
+// approversOf returns approvers from the nearest OWNERS file listing any,
+// with aliases expanded
+func (o *owners) approversOf(file string) []string {
+	dir := path.Dir(file)
+	for {
+		approvers, ok := o.approvers[dir]
+		if !ok {
+			if data, err := o.repository.ShowFile("HEAD", path.Join(dir, "OWNERS")); err == nil {
+				for _, a := range parseOwnersList(data, "approvers") {
+					if members, ok := o.aliases[a]; ok {
+						approvers = append(approvers, members...)
+					} else {
+						approvers = append(approvers, a)
+					}
+				}
+			}
+			o.approvers[dir] = approvers
+		}
+		if len(approvers) > 0 || dir == "." || dir == "/" {
+			return approvers
+		}
+		dir = path.Dir(dir)
+	}
+}
+
+// suggest returns approvers owning most of the files
+func (o *owners) suggest(files []string) []string {
+	counts := make(map[string]int)
+	for _, f := range files {
+		for _, a := range o.approversOf(f) {
+			counts[a]++
+		}
+	}
+	assignees := make([]string, 0, len(counts))
+	for a := range counts {
+		assignees = append(assignees, a)
+	}
+	sort.Slice(assignees, func(i, j int) bool {
+		if counts[assignees[i]] != counts[assignees[j]] {
+			return counts[assignees[i]] > counts[assignees[j]]
+		}
+		return assignees[i] < assignees[j]
+	})
+	if len(assignees) > maxAssignees {
+		assignees = assignees[:maxAssignees]
+	}
+	return assignees
+}
+
+// suggestAssignees records in the entry approvers of the conflicted files, or
+// of all files changed by the carry when the conflicts are not known.
+func (c *Apply) suggestAssignees(repository git.Git, commit *object.Commit, entry *report.Entry) {
+	files := entry.Conflicts
+	if len(files) == 0 {
+		var err error
+		if files, err = repository.ChangedFiles(commit.Hash.String()); err != nil {
+			klog.Warningf("Reading files of %s failed: %v", commit.Hash.String(), err)
+			return
+		}
+	}
+	if c.owners == nil {
+		c.owners = newOwners(repository)
+	}
+	entry.Assignees = c.owners.suggest(files)
+	if len(entry.Assignees) > 0 {
+		console.InfofContext(c.ctx, "Suggested assignees of %s: @%s", commit.Hash.String(), strings.Join(entry.Assignees, ", @"))
+	}
+}
@@ -0,0 +1,76 @@
+package apply
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseOwnersList(t *testing.T) {
+	tests := []struct {
+		name     string
+		owners   string
+		key      string
+		expected []string
+	}{
+		{
+			name: "approvers",
+			owners: `# See the OWNERS docs at https://go.k8s.io/owners
+
+reviewers:
+  - reviewer
+approvers:
+  - sig-node-approvers
+  - "quoted"
+  - 'single-quoted'
+emeritus_approvers:
+  - retired
+`,
+			key:      "approvers",
+			expected: []string{"sig-node-approvers", "quoted", "single-quoted"},
+		},
+		{
+			name:     "list items without indentation and comments",
+			owners:   "approvers:\n- first # lead\n# - commented\n-   second  \nlabels:\n- sig/node\n",
+			key:      "approvers",
+			expected: []string{"first", "second"},
+		},
+		{
+			name:     "key only as a prefix",
+			owners:   "approvers_extra:\n  - someone\n",
+			key:      "approvers",
+			expected: nil,
+		},
+		{
+			name:     "missing key",
+			owners:   "options:\n  no_parent_owners: true\n",
+			key:      "approvers",
+			expected: nil,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			items := parseOwnersList([]byte(test.owners), test.key)
+			if !reflect.DeepEqual(items, test.expected) {
+				t.Errorf("expected %q, got %q", test.expected, items)
+			}
+		})
+	}
+}
+
+func TestParseOwnersAliases(t *testing.T) {
+	aliases := parseOwnersAliases([]byte(`aliases:
+  sig-node-approvers:
+    - first
+    - "second" # lead
+  empty-alias:
+  sig-apps-approvers:
+    - third
+`))
+	expected := map[string][]string{
+		"sig-node-approvers": {"first", "second"},
+		"sig-apps-approvers": {"third"},
+	}
+	if !reflect.DeepEqual(aliases, expected) {
+		t.Errorf("expected %v, got %v", expected, aliases)
+	}
+}
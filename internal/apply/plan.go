@@ -0,0 +1,482 @@
+package apply
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"k8s.io/klog/v2"
+
+	"github.com/openshift/rebase/internal/console"
+	"github.com/openshift/rebase/internal/fork"
+	"github.com/openshift/rebase/internal/git"
+	"github.com/openshift/rebase/internal/state"
+	"github.com/openshift/rebase/internal/utils"
+)
+
+// Commands of a plan file
+const (
+	// PlanPick picks the carry, resolving conflicts with the configured resolvers and fixed carries
+	PlanPick = "pick"
+	// PlanRegenerate picks the carry, resolving conflicts in generated files by re-running generators
+	PlanRegenerate = "regenerate"
+	// PlanDrop does not pick the commit
+	PlanDrop = "drop"
+	// PlanBackport picks an upstream commit or pull request in the backports stage
+	PlanBackport = "backport"
+	// PlanApply applies an additional carry patch in the additional stage
+	PlanApply = "apply"
+	// PlanUpstream is the upstream commit the plan was made against
+	PlanUpstream = "upstream"
+	// PlanOpenShift is the openshift commit the plan was made against
+	PlanOpenShift = "openshift"
+	// PlanStage starts a stage run after picking the carries
+	PlanStage = "stage"
+)
+
+// PlanStep is a single carry processed by the plan.
+type PlanStep struct {
+	Command string
+	SHA     string
+	Message string
+}
+
+// Plan is the ordered list of actions of an apply run, which can be reviewed
+// and edited before executing it with apply.
+type Plan struct {
+	From      string
+	Upstream  string
+	OpenShift string
+	Steps     []PlanStep
+	// Stages are the stages run after picking carries, missing ones are skipped
+	Stages     []string
+	Backports  []string
+	Additional []string
+}
+
+// step returns the step processing a commit, nil when the plan does not contain it
+func (p *Plan) step(sha string) *PlanStep {
+	for i := range p.Steps {
+		if p.Steps[i].SHA == sha {
+			return &p.Steps[i]
+		}
+	}
+	return nil
+}
+
+func (p *Plan) hasStage(stage string) bool {
+	for _, s := range p.Stages {
+		if s == stage {
+			return true
+		}
+	}
+	return false
+}
+
+// Write saves the plan to a file, in the format read by ReadPlan.
+func (p *Plan) Write(path string) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Rebase plan from %s, generated %s\n", p.From, time.Now().Format(time.DateTime))
+	b.WriteString("#\n")
+	b.WriteString("# Commands:\n")
+	b.WriteString("#   pick <sha>        pick the carry, resolving conflicts with resolvers and fixed carries\n")
+	b.WriteString("#   regenerate <sha>  pick the carry, resolving conflicts in generated files by re-running generators\n")
+	b.WriteString("#   drop <sha>        do not pick the commit\n")
+	b.WriteString("#   stage <name>      run the stage after picking the carries, stages not listed are skipped\n")
+	b.WriteString("#   backport <ref>    pick an upstream commit or pull request in the backports stage\n")
+	b.WriteString("#   apply <patch>     apply an additional carry in the additional stage\n")
+	b.WriteString("#\n")
+	b.WriteString("# Lines can be edited, reordered or removed before running 'rebase apply --plan'.\n")
+	b.WriteString("# The run refuses to start when upstream or openshift moved since the plan was made.\n")
+	fmt.Fprintf(&b, "%s %s\n", PlanUpstream, p.Upstream)
+	fmt.Fprintf(&b, "%s %s\n", PlanOpenShift, p.OpenShift)
+	b.WriteString("\n")
+	for _, s := range p.Steps {
+		fmt.Fprintf(&b, "%s %s %s\n", s.Command, s.SHA, s.Message)
+	}
+	for _, stage := range p.Stages {
+		fmt.Fprintf(&b, "\n%s %s\n", PlanStage, stage)
+		switch stage {
+		case fork.StageBackports:
+			for _, ref := range p.Backports {
+				fmt.Fprintf(&b, "%s %s\n", PlanBackport, ref)
+			}
+		case fork.StageAdditional:
+			for _, patch := range p.Additional {
+				fmt.Fprintf(&b, "%s %s\n", PlanApply, patch)
+			}
+		}
+	}
+	return os.WriteFile(path, []byte(b.String()), 0644)
+}
+
+// ReadPlan reads a plan from a file written by Write.
+func ReadPlan(path string) (*Plan, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	p := &Plan{}
+	seen := make(map[string]bool)
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("%s:%d: missing argument of %s", path, line, fields[0])
+		}
+		switch command, arg := fields[0], fields[1]; command {
+		case PlanPick, PlanRegenerate, PlanDrop:
+			if seen[arg] {
+				return nil, fmt.Errorf("%s:%d: commit %s is listed more than once", path, line, arg)
+			}
+			seen[arg] = true
+			p.Steps = append(p.Steps, PlanStep{Command: command, SHA: arg, Message: strings.Join(fields[2:], " ")})
+		case PlanStage:
+			if arg != fork.StageBackports && arg != fork.StageAdditional {
+				return nil, fmt.Errorf("%s:%d: unknown stage %q, expected %s or %s", path, line, arg, fork.StageBackports, fork.StageAdditional)
+			}
+			p.Stages = append(p.Stages, arg)
+		case PlanBackport:
+			p.Backports = append(p.Backports, arg)
+		case PlanApply:
+			p.Additional = append(p.Additional, arg)
+		case PlanUpstream:
+			p.Upstream = arg
+		case PlanOpenShift:
+			p.OpenShift = arg
+		default:
+			return nil, fmt.Errorf("%s:%d: unknown command %q", path, line, command)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(p.Upstream) == 0 || len(p.OpenShift) == 0 {
+		return nil, fmt.Errorf("%s: %s and %s commits are required", path, PlanUpstream, PlanOpenShift)
+	}
+	return p, nil
+}
+
+// planFile is where the plan of a run with selected commits is persisted,
+// so that the selection is respected by continue
+const planFile = "openshift-rebase-plan.txt"
+
+// Planner computes the plan of an apply run, without modifying the repository.
+type Planner struct {
+	apply    *Apply
+	planFile string
+}
+
+// NewPlanner returns a planner writing the plan of an apply run with options
+// to planFile.
+func NewPlanner(from, repositoryDir string, f fork.Fork, options Options, planFile string) *Planner {
+	return &Planner{
+		apply:    NewApply(from, repositoryDir, f, options),
+		planFile: planFile,
+	}
+}
+
+// SetMarkerSearch bounds the search of the rebase marker.
+func (p *Planner) SetMarkerSearch(search git.MarkerSearch) {
+	p.apply.SetMarkerSearch(search)
+}
+
+// SetContext stops planning once the context is cancelled.
+func (p *Planner) SetContext(ctx context.Context) {
+	p.apply.SetContext(ctx)
+}
+
+// SetRepository shares an already opened repository.
+func (p *Planner) SetRepository(repository git.Git) {
+	p.apply.SetRepository(repository)
+}
+
+// SetSelector registers a function adjusting the plan before it is written,
+// eg. by letting the user select the carries interactively.
+func (p *Planner) SetSelector(selector func(plan *Plan) error) {
+	p.apply.SetSelector(selector)
+}
+
+func (p *Planner) Run() error {
+	plan, err := p.Plan()
+	if err != nil {
+		return err
+	}
+	if err := plan.Write(p.planFile); err != nil {
+		return fmt.Errorf("Error writing plan: %w", err)
+	}
+	console.InfofContext(p.apply.ctx, "Plan of %d commits written to %s", len(plan.Steps), p.planFile)
+	return nil
+}
+
+// Plan computes the plan of the run, adjusted by the selector, without
+// writing it.
+func (p *Planner) Plan() (*Plan, error) {
+	repository, err := git.OpenShared(p.apply.repository, p.apply.repositoryDir, p.apply.fork)
+	if err != nil {
+		return nil, err
+	}
+	gitDir, err := repository.GitDir()
+	if err != nil {
+		return nil, err
+	}
+	unlock, err := state.Acquire(gitDir, "plan")
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	if err := p.apply.complete(); err != nil {
+		return nil, err
+	}
+	defer p.apply.status.Stop()
+	originalRef, err := currentRef(repository)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading current HEAD: %w", err)
+	}
+	plan, err := p.apply.makePlan(repository)
+	// reading carries checks out the openshift branch
+	if err := repository.Checkout(originalRef); err != nil {
+		klog.Errorf("Restoring %s failed: %v", originalRef, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if p.apply.selector != nil {
+		if err := p.apply.selector(plan); err != nil {
+			return nil, err
+		}
+	}
+	return plan, nil
+}
+
+// makePlan computes the plan of the run from the current state of the repository
+func (c *Apply) makePlan(repository git.Git) (*Plan, error) {
+	plan := &Plan{From: c.from}
+	var err error
+	if plan.Upstream, err = repository.RevParse(c.upstreamBranch()); err != nil {
+		return nil, err
+	}
+	if plan.OpenShift, err = repository.RevParse(c.carriesRef()); err != nil {
+		return nil, err
+	}
+	commits, err := c.log.GetCommits(repository)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading carries: %w", err)
+	}
+	// classifying carries looks up pull requests and changed files of every
+	// commit, which is independent of the others
+	plan.Steps = make([]PlanStep, len(commits))
+	err = utils.Parallel(len(commits), c.options.Concurrency, func(i int) error {
+		command, err := c.planCommand(repository, commits[i])
+		if err != nil {
+			return err
+		}
+		plan.Steps[i] = PlanStep{Command: command, SHA: commits[i].Hash.String(), Message: utils.FormatMessage(commits[i].Message)}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, stage := range c.fork.StageOrder() {
+		if !c.fork.StageEnabled(stage) {
+			continue
+		}
+		plan.Stages = append(plan.Stages, stage)
+		switch stage {
+		case fork.StageBackports:
+			plan.Backports = c.options.Backports
+		case fork.StageAdditional:
+			if plan.Additional, err = c.carries.findAdditionalCarries(); err != nil {
+				return nil, fmt.Errorf("Error reading additional carries: %w", err)
+			}
+		}
+	}
+	return plan, nil
+}
+
+// planCommand returns the plan command for a commit, carries changing
+// generated files are regenerated when they conflict
+func (c *Apply) planCommand(repository git.Git, commit *object.Commit) (string, error) {
+	action, _, err := resolveAction(repository, c.fork, commit)
+	if err != nil {
+		return "", err
+	}
+	if action != carryAction && action != dropAction && action != mergedAction {
+		switch c.options.UnknownAction {
+		case UnknownActionFail:
+			return "", fmt.Errorf("unknown action %q on commit %s, fix the commit message or pick it manually", action, commit.Hash.String())
+		case UnknownActionCarry:
+			action = carryAction
+		default:
+			action = dropAction
+		}
+	}
+	if action != carryAction || (isMerge(commit) && c.options.Mainline == 0) {
+		return PlanDrop, nil
+	}
+	files, err := repository.ChangedFiles(commit.Hash.String())
+	if err != nil {
+		return "", fmt.Errorf("Error reading files changed by %s: %w", commit.Hash.String(), err)
+	}
+	for _, f := range files {
+		if _, generated := generatorsFor(c.generators, []string{f}); generated {
+			return PlanRegenerate, nil
+		}
+	}
+	return PlanPick, nil
+}
+
+// SetSelector registers a function adjusting the plan of the run before it
+// starts, eg. by letting the user select the carries interactively.
+func (c *Apply) SetSelector(selector func(plan *Plan) error) {
+	c.selector = selector
+}
+
+// FilterCarries returns a selector dropping the carries matching skip and,
+// unless only is empty, the carries not matching only. SHAs may be
+// abbreviated, each of them has to match a carry of the plan.
+func FilterCarries(skip, only []string) func(plan *Plan) error {
+	return func(plan *Plan) error {
+		matched := make(map[string]bool)
+		matches := func(sha string, prefixes []string) bool {
+			found := false
+			for _, prefix := range prefixes {
+				if strings.HasPrefix(sha, prefix) {
+					matched[prefix] = true
+					found = true
+				}
+			}
+			return found
+		}
+		for i := range plan.Steps {
+			skipped := matches(plan.Steps[i].SHA, skip)
+			if selected := matches(plan.Steps[i].SHA, only); skipped || (len(only) > 0 && !selected) {
+				plan.Steps[i].Command = PlanDrop
+			}
+		}
+		for _, prefix := range append(append([]string{}, skip...), only...) {
+			if !matched[prefix] {
+				return fmt.Errorf("carry %s is not in the plan", prefix)
+			}
+		}
+		return nil
+	}
+}
+
+// selectCommits plans the run and lets the selector adjust the plan, which is
+// saved next to the state, so that continue follows it
+func (c *Apply) selectCommits(repository git.Git, stateDir string) error {
+	plan, err := c.makePlan(repository)
+	if err != nil {
+		return err
+	}
+	if err := c.selector(plan); err != nil {
+		return err
+	}
+	c.options.PlanFile = filepath.Join(stateDir, planFile)
+	if err := plan.Write(c.options.PlanFile); err != nil {
+		return fmt.Errorf("Error writing plan: %w", err)
+	}
+	return c.loadPlan()
+}
+
+// loadPlan reads the plan file, the backports of the plan replace the ones
+// from options
+func (c *Apply) loadPlan() error {
+	plan, err := ReadPlan(c.options.PlanFile)
+	if err != nil {
+		return fmt.Errorf("Error reading plan: %w", err)
+	}
+	c.plan = plan
+	c.options.Backports = plan.Backports
+	return nil
+}
+
+// checkPlan verifies the plan was made against the current upstream and
+// openshift branches
+func (c *Apply) checkPlan(repository git.Git) error {
+	for _, ref := range []struct{ name, planned string }{
+		{name: c.upstreamBranch(), planned: c.plan.Upstream},
+		{name: c.carriesRef(), planned: c.plan.OpenShift},
+	} {
+		sha, err := repository.RevParse(ref.name)
+		if err != nil {
+			return err
+		}
+		if sha != ref.planned {
+			return fmt.Errorf("%s moved to %s since the plan was made against %s, make a new plan", ref.name, sha, ref.planned)
+		}
+	}
+	return nil
+}
+
+// planAction returns the action of a commit according to the plan
+func (c *Apply) planAction(commit *object.Commit) (string, string, error) {
+	step := c.plan.step(commit.Hash.String())
+	if step == nil {
+		return "", "", fmt.Errorf("commit %s is not part of the plan", commit.Hash.String())
+	}
+	if step.Command == PlanDrop {
+		if IsDropped(c.fork, commit.Message) {
+			return dropAction, "", nil
+		}
+		return dropAction, "dropped by plan", nil
+	}
+	return carryAction, "", nil
+}
+
+// resolversFor returns the resolvers for conflicts of a commit, the plan
+// can enable regenerating generated files for a single commit
+func (c *Apply) resolversFor(commit *object.Commit) []ConflictResolver {
+	if c.plan == nil || c.options.Regenerate {
+		return c.resolvers
+	}
+	if step := c.plan.step(commit.Hash.String()); step == nil || step.Command != PlanRegenerate {
+		return c.resolvers
+	}
+	return append([]ConflictResolver{&regenerateResolver{apply: c}}, c.resolvers...)
+}
+
+// plannedCommits returns the commits in the order of the plan
+func (c *Apply) plannedCommits(repository git.Git) ([]*object.Commit, error) {
+	commits := make([]*object.Commit, 0, len(c.plan.Steps))
+	for i := range c.plan.Steps {
+		step := &c.plan.Steps[i]
+		sha, err := repository.RevParse(step.SHA + "^{commit}")
+		if err != nil {
+			return nil, fmt.Errorf("Error resolving planned commit %s: %w", step.SHA, err)
+		}
+		commit, err := repository.Commit(plumbing.NewHash(sha))
+		if err != nil {
+			return nil, fmt.Errorf("Error reading planned commit %s: %w", step.SHA, err)
+		}
+		// abbreviated SHAs are expanded for looking up the steps
+		step.SHA = sha
+		commits = append(commits, commit)
+	}
+	return commits, nil
+}
+
+// stageEnabled checks if a stage run after picking carries is enabled for
+// the fork and listed in the plan, when there is one
+func (c *Apply) stageEnabled(stage string) bool {
+	return c.fork.StageEnabled(stage) && (c.plan == nil || c.plan.hasStage(stage))
+}
+
+// stageDisabledBy describes why stages can be disabled
+func (c *Apply) stageDisabledBy() string {
+	if c.plan != nil {
+		return fmt.Sprintf("disabled for %s or not listed in the plan", c.fork.Name)
+	}
+	return fmt.Sprintf("disabled for %s", c.fork.Name)
+}
@@ -0,0 +1,41 @@
+package apply
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/openshift/rebase/internal/console"
+	"github.com/openshift/rebase/internal/git"
+	"github.com/openshift/rebase/internal/github"
+	"github.com/openshift/rebase/internal/state"
+)
+
+// openPullRequest pushes the rebase branch to the configured remote, and opens
+// a draft pull request with the rebase report as its body.
+func (c *Apply) openPullRequest(repository git.Git, runState *state.State) error {
+	if len(c.options.PushRemote) == 0 {
+		return nil
+	}
+	console.InfofContext(c.ctx, "Pushing %s to %s...", runState.Branch, c.options.PushRemote)
+	if err := repository.Push(c.options.PushRemote, runState.Branch); err != nil {
+		return fmt.Errorf("Error pushing %s: %w", runState.Branch, err)
+	}
+	if !c.options.DraftPR {
+		return nil
+	}
+	title := "Rebase"
+	if len(c.options.TargetVersion) > 0 {
+		title = fmt.Sprintf("Rebase to %s %s", c.fork.Name, c.options.TargetVersion)
+	}
+	var body strings.Builder
+	runState.Report.Markdown(&body, c.fork)
+	url, err := github.CreateDraftPullRequest(c.fork.OpenShift, c.options.PRForkOwner+":"+runState.Branch, c.fork.OpenShift.Branch, title, body.String(), c.options.PRLabels)
+	if len(url) > 0 {
+		console.InfofContext(c.ctx, "Opened draft pull request %s", url)
+		runState.Report.PullRequest = url
+	}
+	if err != nil {
+		return fmt.Errorf("Error opening pull request: %w", err)
+	}
+	return nil
+}
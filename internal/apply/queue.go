@@ -0,0 +1,146 @@
+package apply
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"k8s.io/klog/v2"
+
+	"github.com/openshift/rebase/internal/console"
+	"github.com/openshift/rebase/internal/git"
+	"github.com/openshift/rebase/internal/jira"
+	"github.com/openshift/rebase/internal/notify"
+	"github.com/openshift/rebase/internal/report"
+	"github.com/openshift/rebase/internal/state"
+	"github.com/openshift/rebase/internal/utils"
+)
+
+// writeQueue writes the carries deferred to the manual queue, one per line as
+// <sha> <message>, in the order they were processed.
+func (c *Apply) writeQueue(repository git.Git, runState *state.State) error {
+	klog.Warningf("%d carries were deferred to the manual queue.", len(runState.Queue))
+	klog.Warningf("Run 'rebase continue' to process them one by one.")
+	var queue strings.Builder
+	for _, sha := range runState.Queue {
+		message := ""
+		if e := runState.Report.Find(sha); e != nil {
+			message = e.Message
+			if len(e.Assignees) > 0 {
+				message += " # assignees: @" + strings.Join(e.Assignees, ", @")
+			}
+		}
+		fmt.Fprintf(&queue, "%s %s\n", sha, message)
+	}
+	c.notifier.Notify(notify.Deferred, runState.Branch, fmt.Sprintf("Mechanical pass finished, %d carries were deferred to the manual queue:\n%s", len(runState.Queue), queue.String()))
+	if len(c.options.QueueFile) == 0 {
+		return nil
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Carries deferred during the mechanical pass of %s, processed in order\n", runState.Branch)
+	fmt.Fprintf(&b, "# by 'rebase continue'. Lines can be reordered or removed before that.\n")
+	b.WriteString(queue.String())
+	if err := os.WriteFile(c.options.QueueFile, []byte(b.String()), 0644); err != nil {
+		return fmt.Errorf("Error writing manual queue: %w", err)
+	}
+	console.InfofContext(c.ctx, "Manual queue written to %s", c.options.QueueFile)
+	return nil
+}
+
+// queuePath returns the absolute path of the manual queue file, the default
+// one when path is empty
+func queuePath(stateDir, path string) (string, error) {
+	if len(path) == 0 {
+		path = state.QueuePath(stateDir)
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", fmt.Errorf("Error resolving manual queue path: %w", err)
+	}
+	return abs, nil
+}
+
+// readQueue reads the list of commits from the manual queue file, missing
+// file means the queue from the state is used, while an emptied one means
+// that no carry is left to process.
+func readQueue(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+	queue := []string{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		queue = append(queue, fields[0])
+	}
+	return queue, scanner.Err()
+}
+
+// pickQueue processes carries from the manual queue, stopping on every one
+// which requires manual intervention.
+func (c *Apply) pickQueue(repository git.Git, runState *state.State) error {
+	if runState.QueueNext == 0 && len(c.options.QueueFile) > 0 {
+		queue, err := readQueue(c.options.QueueFile)
+		if err != nil {
+			return fmt.Errorf("Error reading manual queue: %w", err)
+		}
+		if queue != nil {
+			runState.Queue = queue
+		}
+	}
+	for ; runState.QueueNext < len(runState.Queue); runState.QueueNext++ {
+		if c.interrupted() {
+			return c.stopInterrupted(repository, "")
+		}
+		c.checkpoint(runState)
+		sha := runState.Queue[runState.QueueNext]
+		c.updateStatus(runState, sha)
+		commit, err := repository.Commit(plumbing.NewHash(sha))
+		if err != nil {
+			return fmt.Errorf("Error reading commit %s: %w", sha, err)
+		}
+		console.InfofContext(c.ctx, "Processing queued carry %d/%d %s...", runState.QueueNext+1, len(runState.Queue), sha)
+		entry := report.Entry{Original: sha, Message: utils.FormatMessage(commit.Message), Bugs: jira.References(commit.Message)}
+		head, err := repository.RevParse("HEAD")
+		if err != nil {
+			return err
+		}
+		err = c.pickCommit(repository, commit, &entry)
+		if err != nil && c.interrupted() {
+			return c.stopInterrupted(repository, head)
+		}
+		entry.Conflicts = conflictedFiles(err)
+		if err != nil && len(entry.Disposition) == 0 {
+			// the pick was not attempted, keep the carry deferred so that
+			// the next run retries it
+			return err
+		}
+		if err != nil && c.options.SuggestAssignees {
+			c.suggestAssignees(repository, commit, &entry)
+		}
+		if e := runState.Report.Find(sha); e != nil {
+			*e = entry
+		} else {
+			runState.Report.Add(entry)
+		}
+		if err != nil {
+			err = c.stopOn(repository, runState, sha, err)
+			runState.QueueNext++
+			klog.Errorf("Once resolved, run 'rebase continue' to proceed with the remaining queue.")
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package apply
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/openshift/rebase/internal/fork"
+	"github.com/openshift/rebase/internal/git"
+	"github.com/openshift/rebase/internal/report"
+)
+
+func TestReadQueue(t *testing.T) {
+	tests := []struct {
+		name     string
+		missing  bool
+		content  string
+		expected []string
+	}{
+		{
+			name:    "missing file",
+			missing: true,
+		},
+		{
+			name:     "comments and messages",
+			content:  "# Carries deferred during the mechanical pass\n\nabc UPSTREAM: <carry>: a # assignees: @jane\n  def\n# ghi\n",
+			expected: []string{"abc", "def"},
+		},
+		{
+			name:     "emptied queue",
+			content:  "# Carries deferred during the mechanical pass\n",
+			expected: []string{},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "queue")
+			if !test.missing {
+				if err := os.WriteFile(path, []byte(test.content), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			queue, err := readQueue(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(queue, test.expected) {
+				t.Errorf("expected %v, got %v", test.expected, queue)
+			}
+		})
+	}
+}
+
+func TestApplyManualQueue(t *testing.T) {
+	repository, shas := newRepository(t,
+		"UPSTREAM: <carry>: openshift: add a conflicting carry",
+		"UPSTREAM: <carry>: openshift: add a carry",
+		"UPSTREAM: <carry>: openshift: add another conflicting carry",
+	)
+	repository.SetOutcome(shas[0], git.OutcomeConflict, "a.go")
+	repository.SetOutcome(shas[2], git.OutcomeConflict, "b.go")
+	queueFile := filepath.Join(t.TempDir(), "queue")
+	if err := newApply(repository, Options{DeferConflicts: true, QueueFile: queueFile}).Run(); err != nil {
+		t.Fatal(err)
+	}
+	gitDir, _ := repository.GitDir()
+	checkDispositions(t, gitDir, map[string]report.Disposition{
+		shas[0]: report.Deferred,
+		shas[1]: report.Picked,
+		shas[2]: report.Deferred,
+	})
+	data, err := os.ReadFile(queueFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	queue, err := readQueue(queueFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []string{shas[0], shas[2]}; !reflect.DeepEqual(queue, expected) {
+		t.Fatalf("expected the conflicting carries in the queue %v, got %v", expected, queue)
+	}
+
+	// the queue is reordered, the first queued carry stops the manual pass
+	lines := strings.Split(string(data), "\n")
+	lines[2], lines[3] = lines[3], lines[2]
+	if err := os.WriteFile(queueFile, []byte(strings.Join(lines, "\n")), 0644); err != nil {
+		t.Fatal(err)
+	}
+	continueAction := NewContinue("", fork.Kubernetes)
+	continueAction.SetRepository(repository)
+	continueAction.SetOutput(io.Discard, io.Discard)
+	if err := continueAction.Run(); err == nil {
+		t.Fatal("expected the manual pass to stop on the conflicting carry")
+	}
+	checkDispositions(t, gitDir, map[string]report.Disposition{
+		shas[0]: report.Deferred,
+		shas[1]: report.Picked,
+		shas[2]: report.Failed,
+	})
+
+	// once resolved, the rest of the queue is processed
+	if err := repository.ResolveConflicts("ours", []string{"b.go"}); err != nil {
+		t.Fatal(err)
+	}
+	repository.SetOutcome(shas[0], "")
+	if err := continueAction.Run(); err != nil {
+		t.Fatal(err)
+	}
+	checkDispositions(t, gitDir, map[string]report.Disposition{
+		shas[0]: report.Picked,
+		shas[1]: report.Picked,
+		shas[2]: report.Manual,
+	})
+}
@@ -0,0 +1,114 @@
+package apply
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing/object"
+
+	"github.com/openshift/rebase/internal/console"
+	"github.com/openshift/rebase/internal/git"
+	"github.com/openshift/rebase/internal/utils"
+)
+
+// defaultGenerators maps generated files to the commands regenerating them in
+// the kubernetes repository.
+var defaultGenerators = []string{
+	"zz_generated*=hack/update-codegen.sh",
+	"*.pb.go=hack/update-generated-protobuf.sh",
+	"api/openapi-spec/**=hack/update-openapi-spec.sh",
+}
+
+// DefaultGeneratorCommands returns the commands of the default generators,
+// which are scripts of the kubernetes repository.
+func DefaultGeneratorCommands() []string {
+	commands := make([]string, 0, len(defaultGenerators))
+	for _, spec := range defaultGenerators {
+		_, command, _ := strings.Cut(spec, "=")
+		commands = append(commands, command)
+	}
+	return commands
+}
+
+// generator describes a command regenerating files matching pattern
+type generator struct {
+	pattern string
+	command string
+}
+
+func parseGenerators(specs []string) ([]generator, error) {
+	if len(specs) == 0 {
+		specs = defaultGenerators
+	}
+	generators := make([]generator, 0, len(specs))
+	for _, spec := range specs {
+		pattern, command, ok := strings.Cut(spec, "=")
+		if !ok || len(pattern) == 0 || len(command) == 0 {
+			return nil, fmt.Errorf("invalid generator %q, expected pattern=command", spec)
+		}
+		if _, err := path.Match(strings.TrimSuffix(pattern, "/**"), ""); err != nil {
+			return nil, fmt.Errorf("invalid generator pattern %q: %w", pattern, err)
+		}
+		generators = append(generators, generator{pattern: pattern, command: command})
+	}
+	return generators, nil
+}
+
+// matches checks if file is matched by the pattern, which is either a directory
+// prefix (dir/**), a path pattern (when it contains /), or a file name pattern
+func (g generator) matches(file string) bool {
+	if prefix, ok := strings.CutSuffix(g.pattern, "/**"); ok {
+		return strings.HasPrefix(file, prefix+"/")
+	}
+	name := path.Base(file)
+	if strings.Contains(g.pattern, "/") {
+		name = file
+	}
+	matched, _ := path.Match(g.pattern, name)
+	return matched
+}
+
+// generatorsFor returns commands required to regenerate all the files, false
+// is returned when any of the files is not a generated one
+func generatorsFor(generators []generator, files []string) ([]string, bool) {
+	var commands []string
+	seen := make(map[string]bool)
+	for _, f := range files {
+		found := false
+		for _, g := range generators {
+			if !g.matches(f) {
+				continue
+			}
+			found = true
+			if !seen[g.command] {
+				seen[g.command] = true
+				commands = append(commands, g.command)
+			}
+			break
+		}
+		if !found {
+			return nil, false
+		}
+	}
+	return commands, true
+}
+
+// regenerate resolves the in-progress cherry-pick, with conflicts limited to
+// generated files, by accepting configured side of the conflict, then re-running
+// generators and amending their output to the picked commit.
+func (c *Apply) regenerate(repository git.Git, commit *object.Commit, files, commands []string) error {
+	console.InfofContext(c.ctx, "Conflicts of %s limited to generated files, regenerating...", commit.Hash.String())
+	if err := repository.ResolveConflicts(c.options.GeneratedSide, files); err != nil {
+		return err
+	}
+	if err := repository.CommitResolved(); err != nil {
+		return err
+	}
+	for _, command := range commands {
+		if output, err := utils.RunCommand(c.repositoryDir, "sh", "-c", command); err != nil {
+			return fmt.Errorf("generator %q failed: %w\n%s", command, err, output)
+		}
+	}
+	return repository.AmendAll()
+}
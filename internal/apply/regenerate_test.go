@@ -0,0 +1,123 @@
+package apply
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/openshift/rebase/internal/git"
+	"github.com/openshift/rebase/internal/report"
+)
+
+func TestGeneratorsFor(t *testing.T) {
+	generators, err := parseGenerators(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name     string
+		files    []string
+		expected []string
+		ok       bool
+	}{
+		{
+			name:     "deepcopy and protobuf",
+			files:    []string{"pkg/apis/core/v1/zz_generated.conversion.go", "staging/src/k8s.io/api/core/v1/generated.pb.go", "pkg/apis/apps/zz_generated.deepcopy.go"},
+			expected: []string{"hack/update-codegen.sh", "hack/update-generated-protobuf.sh"},
+			ok:       true,
+		},
+		{
+			name:     "openapi spec",
+			files:    []string{"api/openapi-spec/swagger.json", "api/openapi-spec/v3/apis__apps__v1_openapi.json"},
+			expected: []string{"hack/update-openapi-spec.sh"},
+			ok:       true,
+		},
+		{
+			name:  "prefix of the directory",
+			files: []string{"api/openapi-spec.json"},
+		},
+		{
+			name:  "generated and hand written files",
+			files: []string{"pkg/apis/apps/zz_generated.deepcopy.go", "pkg/apis/apps/types.go"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			commands, ok := generatorsFor(generators, test.files)
+			if ok != test.ok || !reflect.DeepEqual(commands, test.expected) {
+				t.Errorf("expected %v %v, got %v %v", test.expected, test.ok, commands, ok)
+			}
+		})
+	}
+}
+
+func TestParseGenerators(t *testing.T) {
+	tests := []struct {
+		name    string
+		specs   []string
+		wantErr bool
+	}{
+		{
+			name:  "path and name patterns",
+			specs: []string{"pkg/generated/**=make generate", "*.pb.go=make protobuf"},
+		},
+		{
+			name:    "missing command",
+			specs:   []string{"*.pb.go="},
+			wantErr: true,
+		},
+		{
+			name:    "invalid pattern",
+			specs:   []string{"[*.go=make generate"},
+			wantErr: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := parseGenerators(test.specs); (err != nil) != test.wantErr {
+				t.Errorf("parseGenerators() error = %v, wantErr %v", err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestApplyRegenerate(t *testing.T) {
+	tests := []struct {
+		name  string
+		files []string
+		// generated is the output of the generators run
+		generated string
+		expected  report.Disposition
+	}{
+		{
+			name:      "conflicts limited to generated files",
+			files:     []string{"pkg/apis/apps/zz_generated.deepcopy.go", "pkg/apis/core/zz_generated.deepcopy.go"},
+			generated: "codegen\n",
+			expected:  report.PickedRegenerated,
+		},
+		{
+			name:     "conflicts in hand written files",
+			files:    []string{"pkg/apis/apps/zz_generated.deepcopy.go", "pkg/apis/apps/types.go"},
+			expected: report.Failed,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			repository, shas := newRepository(t, "UPSTREAM: <carry>: openshift: add a conflicting carry")
+			repository.SetOutcome(shas[0], git.OutcomeConflict, test.files...)
+			output := filepath.Join(t.TempDir(), "generated")
+			options := Options{Regenerate: true, Generators: []string{"zz_generated*=echo codegen >> " + output}}
+			err := newApply(repository, options).Run()
+			if (err != nil) != (test.expected == report.Failed) {
+				t.Fatalf("expected the run to stop %v, got %v", test.expected == report.Failed, err)
+			}
+			gitDir, _ := repository.GitDir()
+			checkDispositions(t, gitDir, map[string]report.Disposition{shas[0]: test.expected})
+			generated, _ := os.ReadFile(output)
+			if string(generated) != test.generated {
+				t.Errorf("expected generators to output %q, got %q", test.generated, generated)
+			}
+		})
+	}
+}
@@ -0,0 +1,221 @@
+package apply
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"k8s.io/klog/v2"
+
+	"github.com/openshift/rebase/internal/fork"
+	"github.com/openshift/rebase/internal/git"
+	"github.com/openshift/rebase/internal/report"
+	"github.com/openshift/rebase/internal/utils"
+)
+
+// Conflict describes a conflicting cherry-pick of a carry.
+type Conflict struct {
+	// Commit is the carry being picked
+	Commit *object.Commit
+	// Mainline is the parent number used for picking merge commits
+	Mainline int
+	// Files are the conflicted files
+	Files []string
+	// Ours is the commit the carry is picked onto
+	Ours string
+	// Theirs is the SHA of the carry
+	Theirs string
+}
+
+// Resolution describes how a conflict was resolved.
+type Resolution struct {
+	Disposition report.Disposition
+	Reason      string
+}
+
+// ConflictResolver resolves conflicts of carries. Resolve is invoked with the
+// conflicting cherry-pick in progress, and returns a resolution only when the
+// carry was committed, nil means the resolver does not handle the conflict.
+// Whatever a resolver leaves behind without resolving the conflict is discarded.
+type ConflictResolver interface {
+	// Name identifies the resolver
+	Name() string
+	Resolve(repository git.Git, conflict Conflict) (*Resolution, error)
+}
+
+// AddResolver registers a conflict resolver, which is tried after the built-in
+// and configured ones, before looking for a fixed carry.
+func (c *Apply) AddResolver(resolver ConflictResolver) {
+	c.resolvers = append(c.resolvers, resolver)
+}
+
+// newResolvers returns the resolvers enabled by options, regenerate and ours
+// come first, followed by the configured commands.
+func (c *Apply) newResolvers() ([]ConflictResolver, error) {
+	var resolvers []ConflictResolver
+	if c.options.Regenerate {
+		resolvers = append(resolvers, &regenerateResolver{apply: c})
+	}
+	// paths of the fork go to a copy, so that they are not persisted with the
+	// options and duplicated on continue
+	if paths := append(append([]string{}, c.options.OursPaths...), c.fork.OursPaths...); len(paths) > 0 {
+		resolvers = append(resolvers, &oursResolver{fork: c.fork, paths: paths})
+	}
+	for _, spec := range append(c.options.Resolvers, c.fork.Resolvers...) {
+		name, command, ok := strings.Cut(spec, "=")
+		if !ok || len(name) == 0 || len(command) == 0 {
+			return nil, fmt.Errorf("invalid resolver %q, expected name=command", spec)
+		}
+		resolvers = append(resolvers, &commandResolver{ctx: c.ctx, fork: c.fork, name: name, command: command, repositoryDir: c.repositoryDir})
+	}
+	return resolvers, nil
+}
+
+// resolve tries the resolvers one by one, returns true when one of them
+// resolved the conflict. Otherwise the conflicting pick is discarded.
+func (c *Apply) resolve(repository git.Git, conflict Conflict, entry *report.Entry) (bool, error) {
+	resolvers := c.resolversFor(conflict.Commit)
+	for i, resolver := range resolvers {
+		if i > 0 {
+			// bring the conflict back for the next resolver
+			_ = repository.CherryPick(conflict.Theirs, conflict.Mainline)
+		}
+		resolution, err := resolver.Resolve(repository, conflict)
+		if err != nil {
+			klog.Errorf("Resolving conflicts of %s with %s failed: %v", conflict.Theirs, resolver.Name(), err)
+		} else if resolution != nil {
+			entry.Disposition = resolution.Disposition
+			entry.Reason = resolution.Reason
+			return true, nil
+		}
+		if err := discard(repository, conflict.Ours); err != nil {
+			return false, err
+		}
+	}
+	if len(resolvers) == 0 {
+		return false, discard(repository, conflict.Ours)
+	}
+	return false, nil
+}
+
+// discard drops the in-progress operation and any commits created on top of head
+func discard(repository git.Git, head string) error {
+	if err := repository.AbortInProgress(); err != nil {
+		return err
+	}
+	return repository.ResetHard(head)
+}
+
+// regenerateResolver accepts one side of conflicts limited to generated files
+// and re-runs their generators.
+type regenerateResolver struct {
+	apply *Apply
+}
+
+func (r *regenerateResolver) Name() string {
+	return "regenerate"
+}
+
+func (r *regenerateResolver) Resolve(repository git.Git, conflict Conflict) (*Resolution, error) {
+	commands, ok := generatorsFor(r.apply.generators, conflict.Files)
+	if len(conflict.Files) == 0 || !ok {
+		return nil, nil
+	}
+	if err := r.apply.regenerate(repository, conflict.Commit, conflict.Files, commands); err != nil {
+		return nil, err
+	}
+	klog.Warningf("Carry %s was picked with regenerated %s - make sure to double check it!", r.apply.fork.CommitURL(conflict.Theirs), strings.Join(conflict.Files, ", "))
+	return &Resolution{Disposition: report.PickedRegenerated, Reason: fmt.Sprintf("regenerated using: %s", strings.Join(commands, ", "))}, nil
+}
+
+// oursResolver picks the carry with ours strategy option, when its conflicts
+// are limited to the paths.
+type oursResolver struct {
+	fork  fork.Fork
+	paths []string
+}
+
+func (r *oursResolver) Name() string {
+	return "ours"
+}
+
+func (r *oursResolver) Resolve(repository git.Git, conflict Conflict) (*Resolution, error) {
+	if len(conflict.Files) == 0 || !utils.HasPathPrefix(conflict.Files, r.paths) {
+		return nil, nil
+	}
+	klog.V(2).Infof("Conflicts limited to %v, retrying with ours strategy option", r.paths)
+	if err := repository.AbortInProgress(); err != nil {
+		return nil, err
+	}
+	if err := repository.OursCherryPick(conflict.Theirs, conflict.Mainline); err != nil {
+		return nil, nil
+	}
+	klog.Warningf("Carry %s was picked using ours strategy option for %s", r.fork.CommitURL(conflict.Theirs), strings.Join(conflict.Files, ", "))
+	return &Resolution{Disposition: report.PickedOurs, Reason: fmt.Sprintf("conflicts resolved with ours strategy option: %s", strings.Join(conflict.Files, ", "))}, nil
+}
+
+// theirsResolver picks the carry with recursive strategy and theirs option.
+type theirsResolver struct {
+	fork fork.Fork
+}
+
+func (r *theirsResolver) Name() string {
+	return "theirs"
+}
+
+func (r *theirsResolver) Resolve(repository git.Git, conflict Conflict) (*Resolution, error) {
+	if err := repository.AbortInProgress(); err != nil {
+		return nil, err
+	}
+	if err := repository.RetryCherryPick(conflict.Theirs, conflict.Mainline); err != nil {
+		return nil, nil
+	}
+	klog.Warningf("Carry %s was picked auto-magically \\o/ - make sure to double check it!", r.fork.CommitURL(conflict.Theirs))
+	return &Resolution{Disposition: report.PickedTheirs}, nil
+}
+
+// commandResolver runs an external command inside the repository, which is
+// expected to resolve the conflicts in the working tree and exit with 0.
+// The conflict is described by REBASE_COMMIT, REBASE_MESSAGE, REBASE_OURS,
+// REBASE_THEIRS and newline separated REBASE_FILES environment variables.
+type commandResolver struct {
+	// ctx kills the command once the run is cancelled
+	ctx           context.Context
+	fork          fork.Fork
+	name          string
+	command       string
+	repositoryDir string
+}
+
+func (r *commandResolver) Name() string {
+	return r.name
+}
+
+func (r *commandResolver) Resolve(repository git.Git, conflict Conflict) (*Resolution, error) {
+	env := []string{
+		"REBASE_COMMIT=" + conflict.Theirs,
+		"REBASE_MESSAGE=" + utils.FormatMessage(conflict.Commit.Message),
+		"REBASE_OURS=" + conflict.Ours,
+		"REBASE_THEIRS=" + conflict.Theirs,
+		"REBASE_FILES=" + strings.Join(conflict.Files, "\n"),
+	}
+	klog.V(2).Infof("Invoking resolver %s %q...", r.name, r.command)
+	if _, err := utils.RunCommandContext(r.ctx, r.repositoryDir, env, "sh", "-c", r.command); err != nil {
+		klog.V(2).Infof("Resolver %s did not resolve %s: %v", r.name, conflict.Theirs, err)
+		return nil, nil
+	}
+	if err := repository.StageAll(); err != nil {
+		return nil, err
+	}
+	if remaining, err := repository.ConflictedFiles(); err != nil {
+		return nil, err
+	} else if len(remaining) > 0 {
+		return nil, fmt.Errorf("conflicts remain in %s", strings.Join(remaining, ", "))
+	}
+	if err := repository.CommitResolved(); err != nil {
+		return nil, err
+	}
+	klog.Warningf("Carry %s was picked with conflicts resolved by %s - make sure to double check it!", r.fork.CommitURL(conflict.Theirs), r.name)
+	return &Resolution{Disposition: report.PickedResolved, Reason: fmt.Sprintf("conflicts resolved by %s", r.name)}, nil
+}
@@ -0,0 +1,113 @@
+package apply
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-git/go-git/v5/plumbing"
+
+	"github.com/openshift/rebase/internal/fork"
+	"github.com/openshift/rebase/internal/git"
+	"github.com/openshift/rebase/internal/git/gittest"
+	"github.com/openshift/rebase/internal/report"
+)
+
+// oursFake resolves the conflicts of picks with ours strategy option, unless
+// they are scripted to remain
+type oursFake struct {
+	*gittest.Fake
+	conflicting bool
+}
+
+func (f *oursFake) OursCherryPick(sha string, mainline int) error {
+	if !f.conflicting {
+		f.SetOutcome(sha, "")
+	}
+	return f.Fake.OursCherryPick(sha, mainline)
+}
+
+func TestApplyOursPaths(t *testing.T) {
+	tests := []struct {
+		name        string
+		files       []string
+		conflicting bool
+		expected    report.Disposition
+	}{
+		{
+			name:     "conflicts limited to the paths",
+			files:    []string{"openshift-hack/e2e/annotate.go", "openshift-hack/test.sh"},
+			expected: report.PickedOurs,
+		},
+		{
+			name:     "conflicts outside of the paths",
+			files:    []string{"openshift-hack/test.sh", "pkg/kubelet/kubelet.go"},
+			expected: report.Failed,
+		},
+		{
+			name:        "conflicts remaining with ours strategy option",
+			files:       []string{"openshift-hack/test.sh"},
+			conflicting: true,
+			expected:    report.Failed,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fake, shas := newRepository(t, "UPSTREAM: <carry>: openshift: add a conflicting carry")
+			fake.SetOutcome(shas[0], git.OutcomeConflict, test.files...)
+			repository := &oursFake{Fake: fake, conflicting: test.conflicting}
+			err := newApply(repository, Options{OursPaths: []string{"openshift-hack/"}}).Run()
+			if (err != nil) != (test.expected == report.Failed) {
+				t.Fatalf("expected the run to stop %v, got %v", test.expected == report.Failed, err)
+			}
+			gitDir, _ := repository.GitDir()
+			checkDispositions(t, gitDir, map[string]report.Disposition{shas[0]: test.expected})
+		})
+	}
+}
+
+func TestCommandResolver(t *testing.T) {
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		command  string
+		expected report.Disposition
+	}{
+		{
+			name:     "resolving command",
+			command:  `test "$REBASE_FILES" = "go.mod"`,
+			expected: report.PickedResolved,
+		},
+		{
+			name:    "failing command",
+			command: "exit 1",
+		},
+		{
+			name:    "cancelled run",
+			ctx:     cancelled,
+			command: "true",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			repository, shas := newRepository(t, "UPSTREAM: <carry>: openshift: add a carry")
+			commit, err := repository.Commit(plumbing.NewHash(shas[0]))
+			if err != nil {
+				t.Fatal(err)
+			}
+			resolver := &commandResolver{ctx: test.ctx, fork: fork.Kubernetes, name: "resolver", command: test.command, repositoryDir: t.TempDir()}
+			resolution, err := resolver.Resolve(repository, Conflict{Commit: commit, Theirs: shas[0], Files: []string{"go.mod"}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var disposition report.Disposition
+			if resolution != nil {
+				disposition = resolution.Disposition
+			}
+			if disposition != test.expected {
+				t.Errorf("expected %q, got %q", test.expected, disposition)
+			}
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package apply
+
+import (
+	"time"
+
+	"github.com/openshift/rebase/internal/report"
+	"github.com/openshift/rebase/internal/state"
+	"github.com/openshift/rebase/internal/status"
+)
+
+// SetObserver registers a function called with the state of the run whenever
+// its progress changes, current is the commit being processed. The state must
+// not be retained by the observer.
+func (c *Apply) SetObserver(observer func(runState *state.State, current string)) {
+	c.observer = observer
+}
+
+// updateStatus publishes the progress of the run on the status server, when
+// enabled, and to the observer, current is the commit being processed.
+func (c *Apply) updateStatus(runState *state.State, current string) {
+	if c.observer != nil {
+		c.observer(runState, current)
+	}
+	if c.status == nil {
+		return
+	}
+	s := status.Status{
+		Branch:             runState.Branch,
+		Phase:              runState.Phase,
+		Commits:            len(runState.Commits),
+		CommitsProcessed:   runState.Next,
+		Backports:          len(c.options.Backports),
+		BackportsProcessed: runState.BackportsNext,
+		Queue:              len(runState.Queue),
+		QueueProcessed:     runState.QueueNext,
+		Current:            current,
+		Stopped:            len(runState.Current) > 0 && runState.Current == current,
+	}
+	if runState.Report != nil {
+		s.Started = runState.Report.Started
+		s.Stages = append(s.Stages, runState.Report.Stages...)
+		s.Dispositions = make(map[report.Disposition]int)
+		var total time.Duration
+		for _, entry := range runState.Report.Entries {
+			s.Dispositions[entry.Disposition]++
+			total += entry.Duration
+			if entry.Disposition == report.Failed || entry.Disposition == report.Deferred {
+				s.Pending = append(s.Pending, entry.Original)
+			}
+		}
+		if len(runState.Report.Entries) > 0 {
+			s.AverageCommit = total / time.Duration(len(runState.Report.Entries))
+			s.Remaining = s.AverageCommit * time.Duration(len(runState.Commits)-runState.Next)
+		}
+	}
+	c.status.Update(s)
+}
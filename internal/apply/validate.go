@@ -0,0 +1,57 @@
+package apply
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"k8s.io/klog/v2"
+
+	"github.com/openshift/rebase/internal/git"
+	"github.com/openshift/rebase/internal/utils"
+)
+
+// validateCommit runs fast validations (gofmt and go vet) limited to the go
+// packages touched by the given commit, so that a carry which doesn't compile on
+// the new base is caught right away.
+func validateCommit(repository git.Git, repositoryDir, sha string) error {
+	files, err := repository.ChangedFiles(sha)
+	if err != nil {
+		return err
+	}
+	var goFiles []string
+	packages := make(map[string]bool)
+	for _, f := range files {
+		if !strings.HasSuffix(f, ".go") || strings.HasPrefix(f, "vendor/") {
+			continue
+		}
+		// deleted files are reported as changed, too
+		if _, err := os.Stat(filepath.Join(repositoryDir, f)); err != nil {
+			continue
+		}
+		goFiles = append(goFiles, f)
+		packages["./"+filepath.Dir(f)] = true
+	}
+	if len(goFiles) == 0 {
+		return nil
+	}
+	klog.V(2).Infof("Validating %d files in %d packages touched by %s...", len(goFiles), len(packages), sha)
+	output, err := utils.RunCommand(repositoryDir, "gofmt", append([]string{"-l"}, goFiles...)...)
+	if err != nil {
+		return fmt.Errorf("gofmt failed: %w\n%s", err, output)
+	}
+	if len(strings.TrimSpace(output)) > 0 {
+		return fmt.Errorf("files not formatted properly:\n%s", output)
+	}
+	dirs := make([]string, 0, len(packages))
+	for p := range packages {
+		dirs = append(dirs, p)
+	}
+	sort.Strings(dirs)
+	if output, err := utils.RunCommand(repositoryDir, "go", append([]string{"vet"}, dirs...)...); err != nil {
+		return fmt.Errorf("go vet failed: %w\n%s", err, output)
+	}
+	return nil
+}
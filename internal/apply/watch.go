@@ -0,0 +1,201 @@
+package apply
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"k8s.io/klog/v2"
+
+	"github.com/openshift/rebase/internal/console"
+	"github.com/openshift/rebase/internal/fork"
+	"github.com/openshift/rebase/internal/git"
+	"github.com/openshift/rebase/internal/metrics"
+	"github.com/openshift/rebase/internal/notify"
+	"github.com/openshift/rebase/internal/state"
+	"github.com/openshift/rebase/internal/utils"
+)
+
+// Watch periodically fetches upstream and checks which carries would conflict
+// with it, reporting the newly conflicting ones.
+type Watch struct {
+	apply    *Apply
+	upstream string
+	interval time.Duration
+	once     bool
+	metrics  *metrics.Server
+	// clean is the last upstream commit no carries conflicted with
+	clean string
+}
+
+// NewWatch returns a watch of carries against upstream, metrics are served on
+// metricsAddress, unless it is empty.
+func NewWatch(from, repositoryDir string, f fork.Fork, options Options, upstream string, interval time.Duration, once bool, metricsAddress string) *Watch {
+	if len(upstream) == 0 {
+		upstream = f.UpstreamRef()
+	}
+	w := &Watch{
+		apply:    NewApply(from, repositoryDir, f, options),
+		upstream: upstream,
+		interval: interval,
+		once:     once,
+	}
+	if len(metricsAddress) > 0 {
+		w.metrics = metrics.New(metricsAddress)
+	}
+	return w
+}
+
+// SetOutput prints newly conflicting carries to out and progress to errOut,
+// instead of stdout and stderr.
+func (w *Watch) SetOutput(out, errOut io.Writer) {
+	w.apply.SetOutput(out, errOut)
+}
+
+// SetMarkerSearch bounds the search of the rebase marker.
+func (w *Watch) SetMarkerSearch(search git.MarkerSearch) {
+	w.apply.SetMarkerSearch(search)
+}
+
+// SetContext stops waiting for the next check once the context is cancelled.
+func (w *Watch) SetContext(ctx context.Context) {
+	w.apply.SetContext(ctx)
+}
+
+// Run checks the carries against upstream every interval, until the context
+// is cancelled, failed checks are logged and retried in the next one, unless
+// running once.
+func (w *Watch) Run() error {
+	repository, err := git.OpenShared(w.apply.repository, w.apply.repositoryDir, w.apply.fork)
+	if err != nil {
+		return err
+	}
+	if err := w.apply.complete(); err != nil {
+		return err
+	}
+	if w.metrics != nil {
+		if err := w.metrics.Start(); err != nil {
+			return err
+		}
+		defer w.metrics.Stop()
+	}
+	ctx := w.apply.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	conflicting := make(map[string]bool)
+	for first := true; ; first = false {
+		err := w.check(repository, conflicting, first)
+		if err != nil {
+			w.metrics.Failed()
+		}
+		if w.once {
+			return err
+		}
+		if err != nil {
+			klog.Errorf("Checking carries against %s failed: %v", w.upstream, err)
+		}
+		klog.V(2).Infof("Next check in %s", w.interval)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(w.interval):
+		}
+	}
+}
+
+// check fetches remotes and predicts conflicts of carries with upstream,
+// conflicting is updated with the currently conflicting carries
+func (w *Watch) check(repository git.Git, conflicting map[string]bool, first bool) error {
+	start := time.Now()
+	gitDir, err := repository.GitDir()
+	if err != nil {
+		return err
+	}
+	// reading carries checks out the openshift branch, which must not happen
+	// in the middle of another run
+	unlock, err := state.Acquire(gitDir, "watch")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	for _, remote := range []string{w.apply.fork.UpstreamRemote(), w.apply.fork.OpenShiftRemote()} {
+		if err := repository.Fetch(remote); err != nil {
+			return fmt.Errorf("Error fetching %s: %w", remote, err)
+		}
+	}
+	commits, err := w.apply.log.GetCommits(repository)
+	if err != nil {
+		return fmt.Errorf("Error reading carries: %w", err)
+	}
+	console.InfofContext(w.apply.ctx, "Checking %d commits against %s...", len(commits), w.upstream)
+	conflicts, err := w.apply.predictConflicts(repository, commits, w.upstream)
+	if err != nil {
+		return err
+	}
+	current := make(map[string]bool, len(conflicts))
+	var newlyConflicting []string
+	for _, conflict := range conflicts {
+		sha := conflict.commit.Hash.String()
+		current[sha] = true
+		if conflicting[sha] {
+			continue
+		}
+		line := fmt.Sprintf("%s\t%s\t%s", sha, utils.FormatMessage(conflict.commit.Message), strings.Join(conflict.files, ", "))
+		if len(conflict.fixed) > 0 {
+			line += "\t(fixed carry " + conflict.fixed + ")"
+		}
+		newlyConflicting = append(newlyConflicting, line)
+	}
+	for sha := range conflicting {
+		if !current[sha] {
+			console.InfofContext(w.apply.ctx, "Carry %s no longer conflicts with %s", sha, w.upstream)
+			delete(conflicting, sha)
+		}
+	}
+	for sha := range current {
+		conflicting[sha] = true
+	}
+	console.InfofContext(w.apply.ctx, "%d carries conflict with %s, %d of them newly", len(conflicts), w.upstream, len(newlyConflicting))
+	drift, err := w.drift(repository, len(conflicts) == 0)
+	if err != nil {
+		return err
+	}
+	w.metrics.Succeeded(metrics.Check{Carries: len(commits), Conflicting: len(conflicts), Drift: drift, Duration: time.Since(start)})
+	if len(newlyConflicting) == 0 {
+		return nil
+	}
+	for _, line := range newlyConflicting {
+		fmt.Fprintln(w.apply.out, line)
+	}
+	message := fmt.Sprintf("%d carries newly conflict with %s:\n%s", len(newlyConflicting), w.upstream, strings.Join(newlyConflicting, "\n"))
+	if first {
+		message = fmt.Sprintf("%d carries conflict with %s:\n%s", len(newlyConflicting), w.upstream, strings.Join(newlyConflicting, "\n"))
+	}
+	w.apply.notifier.Notify(notify.Drift, w.upstream, message)
+	return nil
+}
+
+// drift returns the number of upstream commits since the last upstream commit
+// without conflicting carries, or since the starting version if there was none
+func (w *Watch) drift(repository git.Git, clean bool) (int, error) {
+	if clean {
+		sha, err := repository.RevParse(w.upstream)
+		if err != nil {
+			return 0, err
+		}
+		w.clean = sha
+		return 0, nil
+	}
+	since := w.clean
+	if len(since) == 0 {
+		since = w.apply.from
+	}
+	commits, err := repository.RevList(w.upstream, "^"+since)
+	if err != nil {
+		return 0, fmt.Errorf("Error counting upstream commits since %s: %w", since, err)
+	}
+	return len(commits), nil
+}
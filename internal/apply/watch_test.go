@@ -0,0 +1,68 @@
+package apply
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/openshift/rebase/internal/fork"
+	"github.com/openshift/rebase/internal/git"
+	"github.com/openshift/rebase/internal/preflight"
+)
+
+// syncBuffer is a buffer written by the watch and read by the test
+type syncBuffer struct {
+	lock sync.Mutex
+	buf  bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return b.buf.String()
+}
+
+func TestWatchCancelled(t *testing.T) {
+	repository, shas := newRepository(t,
+		"UPSTREAM: <carry>: openshift: add a carry",
+		"UPSTREAM: <carry>: openshift: add a conflicting carry",
+	)
+	repository.SetOutcome(shas[1], git.OutcomeConflict, "a.go")
+	w := NewWatch("v1.0.0", "", fork.Kubernetes, Options{SkipChecks: preflight.Names()}, "", time.Hour, false, "")
+	w.apply.SetRepository(repository)
+	out := &syncBuffer{}
+	w.SetOutput(out, io.Discard)
+	ctx, cancel := context.WithCancel(context.Background())
+	w.SetContext(ctx)
+	done := make(chan error)
+	go func() { done <- w.Run() }()
+	deadline := time.After(10 * time.Second)
+	for !strings.Contains(out.String(), shas[1]) {
+		select {
+		case err := <-done:
+			t.Fatalf("watch exited before reporting the conflict: %v", err)
+		case <-deadline:
+			t.Fatal("conflicting carry was not reported")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("watch did not stop once cancelled")
+	}
+}
@@ -0,0 +1,180 @@
+package bump
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/mod/modfile"
+	"golang.org/x/mod/semver"
+	"k8s.io/klog/v2"
+
+	"github.com/openshift/rebase/internal/console"
+	"github.com/openshift/rebase/internal/utils"
+)
+
+const (
+	kubernetesModule = "k8s.io/kubernetes"
+	stagingPrefix    = "k8s.io/"
+	// DefaultUpdateCommand updates the module graph of kubernetes itself,
+	// where the staging modules are replaced with local directories
+	DefaultUpdateCommand = "hack/update-vendor.sh"
+)
+
+// Options holds the settings controlling the dependency bump.
+type Options struct {
+	// TargetVersion is the kubernetes version the dependencies are bumped to, eg. v1.31.2.
+	TargetVersion string
+	// Modules are additional path@version requirements bumped together with
+	// the kubernetes modules, eg. sigs.k8s.io/controller-runtime@v0.19.0.
+	Modules []string
+	// UpdateCommand is the command updating the module graph of kubernetes itself.
+	UpdateCommand string
+	// NoCommit leaves the changes uncommitted.
+	NoCommit bool
+}
+
+// Bump updates k8s.io dependencies of a go module to a kubernetes version,
+// tidies and vendors the module and commits the result.
+type Bump struct {
+	repositoryDir string
+	options       Options
+}
+
+func NewBump(repositoryDir string, options Options) *Bump {
+	return &Bump{
+		repositoryDir: repositoryDir,
+		options:       options,
+	}
+}
+
+func (b *Bump) Run() error {
+	if !semver.IsValid(b.options.TargetVersion) || semver.Major(b.options.TargetVersion) != "v1" {
+		return fmt.Errorf("invalid target version %q, expected kubernetes version, eg. v1.31.2", b.options.TargetVersion)
+	}
+	if len(b.options.UpdateCommand) == 0 {
+		b.options.UpdateCommand = DefaultUpdateCommand
+	}
+	if !b.options.NoCommit {
+		if err := b.checkClean(); err != nil {
+			return err
+		}
+	}
+	goModPath := filepath.Join(b.repositoryDir, "go.mod")
+	data, err := os.ReadFile(goModPath)
+	if err != nil {
+		return fmt.Errorf("Error reading go.mod: %w", err)
+	}
+	mod, err := modfile.ParseLax(goModPath, data, nil)
+	if err != nil {
+		return fmt.Errorf("Error parsing go.mod: %w", err)
+	}
+	message := fmt.Sprintf("Bump k8s.io dependencies to %s", b.options.TargetVersion)
+	if mod.Module != nil && mod.Module.Mod.Path == kubernetesModule {
+		// staging modules are replaced with local directories, which were
+		// already rebased, only the rest of the module graph is updated
+		console.Infof("Updating the module graph of kubernetes using %s...", b.options.UpdateCommand)
+		if output, err := utils.RunCommand(b.repositoryDir, "sh", "-c", b.options.UpdateCommand); err != nil {
+			return fmt.Errorf("%q failed: %w\n%s", b.options.UpdateCommand, err, output)
+		}
+		message = "UPSTREAM: <drop>: " + b.options.UpdateCommand
+	} else {
+		requirements, err := b.requirements(mod)
+		if err != nil {
+			return err
+		}
+		if len(requirements) == 0 {
+			return fmt.Errorf("no k8s.io dependencies found in %s", goModPath)
+		}
+		for _, r := range requirements {
+			console.Infof("Bumping %s", r)
+			if output, err := utils.RunCommand(b.repositoryDir, "go", "mod", "edit", "-require="+r); err != nil {
+				return fmt.Errorf("Error bumping %s: %w\n%s", r, err, output)
+			}
+		}
+		if output, err := utils.RunCommand(b.repositoryDir, "go", "mod", "tidy"); err != nil {
+			return fmt.Errorf("go mod tidy failed: %w\n%s", err, output)
+		}
+		if _, err := os.Stat(filepath.Join(b.repositoryDir, "vendor")); err == nil {
+			if output, err := utils.RunCommand(b.repositoryDir, "go", "mod", "vendor"); err != nil {
+				return fmt.Errorf("go mod vendor failed: %w\n%s", err, output)
+			}
+		}
+	}
+	if b.options.NoCommit {
+		return nil
+	}
+	return b.commit(message)
+}
+
+// requirements returns the bumped path@version requirements, the staging
+// modules are versioned v0.minor.patch matching the kubernetes v1.minor.patch
+func (b *Bump) requirements(mod *modfile.File) ([]string, error) {
+	stagingVersion := "v0" + strings.TrimPrefix(b.options.TargetVersion, "v1")
+	extra := make(map[string]bool, len(b.options.Modules))
+	var requirements []string
+	for _, m := range b.options.Modules {
+		path, version, ok := strings.Cut(m, "@")
+		if !ok || len(path) == 0 || len(version) == 0 {
+			return nil, fmt.Errorf("invalid module %q, expected path@version", m)
+		}
+		extra[path] = true
+		requirements = append(requirements, m)
+	}
+	for _, require := range mod.Require {
+		r := require.Mod
+		if extra[r.Path] || !strings.HasPrefix(r.Path, stagingPrefix) {
+			continue
+		}
+		switch {
+		case r.Path == kubernetesModule:
+			requirements = append(requirements, r.Path+"@"+b.options.TargetVersion)
+		case isStagingVersion(r.Version):
+			requirements = append(requirements, r.Path+"@"+stagingVersion)
+		default:
+			// eg. k8s.io/klog or k8s.io/utils, which are released independently
+			klog.V(2).Infof("Leaving %s@%s, it is not a staging module", r.Path, r.Version)
+		}
+	}
+	return requirements, nil
+}
+
+// isStagingVersion checks if the version looks like a staging module release,
+// pseudo-versions of independently released modules are v0.0.0-...
+func isStagingVersion(version string) bool {
+	return semver.IsValid(version) && semver.Major(version) == "v0" && !strings.HasPrefix(version, "v0.0.")
+}
+
+// checkClean refuses to bump a repository with uncommitted changes, which
+// would otherwise end up in the bump commit
+func (b *Bump) checkClean() error {
+	output, err := utils.RunCommand(b.repositoryDir, "git", "status", "--porcelain")
+	if err != nil {
+		return fmt.Errorf("Error checking the worktree: %w\n%s", err, output)
+	}
+	if len(strings.TrimSpace(output)) > 0 {
+		return fmt.Errorf("the worktree has uncommitted changes, commit or stash them, or bump with --no-commit:\n%s", output)
+	}
+	return nil
+}
+
+// commit commits all changes in the repository, the repository does not need
+// to be a kubernetes fork, so plain git is used
+func (b *Bump) commit(message string) error {
+	if output, err := utils.RunCommand(b.repositoryDir, "git", "add", "--all"); err != nil {
+		return fmt.Errorf("Error adding changes: %w\n%s", err, output)
+	}
+	if output, err := utils.RunCommand(b.repositoryDir, "git", "diff", "--cached", "--quiet"); err == nil {
+		console.Infof("Dependencies are already up to date, nothing to commit")
+		return nil
+	} else if _, ok := err.(*exec.ExitError); !ok {
+		return fmt.Errorf("Error checking changes: %w\n%s", err, output)
+	}
+	if output, err := utils.RunCommand(b.repositoryDir, "git", "commit", "--quiet", "-m", message); err != nil {
+		return fmt.Errorf("Error committing changes: %w\n%s", err, output)
+	}
+	console.Infof("Committed %q", message)
+	return nil
+}
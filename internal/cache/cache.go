@@ -0,0 +1,105 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"k8s.io/klog/v2"
+)
+
+// dirName is the directory in the git directory holding cached results
+const dirName = "openshift-rebase-cache"
+
+// disabled makes every lookup miss and skips storing results
+var disabled bool
+
+// Disable turns off the cache, eg. when its results are suspected to be wrong.
+func Disable() {
+	disabled = true
+}
+
+// Cache stores results of expensive computations as JSON files, keyed by
+// the SHAs of commits and refs they were computed from, so that an entry is
+// never invalidated explicitly, a change of a ref results in a different key.
+type Cache struct {
+	dir string
+}
+
+// New returns the cache of the repository with the git directory.
+func New(gitDir string) *Cache {
+	return &Cache{dir: filepath.Join(gitDir, dirName)}
+}
+
+// Key returns a key identifying the parts, eg. SHAs of the relevant refs
+// and options affecting the result.
+func Key(parts ...string) string {
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\x00")))
+	return hex.EncodeToString(sum[:])
+}
+
+// Get reads a cached value of the kind into value, returns false on a miss.
+func (c *Cache) Get(kind, key string, value interface{}) bool {
+	if disabled {
+		return false
+	}
+	data, err := os.ReadFile(c.path(kind, key))
+	if err != nil {
+		return false
+	}
+	if err := json.Unmarshal(data, value); err != nil {
+		klog.Warningf("Ignoring malformed cache entry %s: %v", c.path(kind, key), err)
+		return false
+	}
+	klog.V(2).Infof("Using cached %s %s", kind, key)
+	return true
+}
+
+// Put stores the value of the kind, failures are only logged, since the
+// value is computed again on the next miss.
+func (c *Cache) Put(kind, key string, value interface{}) {
+	if disabled {
+		return
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		klog.Warningf("Error caching %s: %v", kind, err)
+		return
+	}
+	if err := os.MkdirAll(filepath.Join(c.dir, kind), 0755); err != nil {
+		klog.Warningf("Error creating cache directory: %v", err)
+		return
+	}
+	// written through a temporary file, so that concurrent runs never read
+	// a partial entry
+	tmp, err := os.CreateTemp(filepath.Join(c.dir, kind), key+".*")
+	if err != nil {
+		klog.Warningf("Error caching %s: %v", kind, err)
+		return
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		klog.Warningf("Error caching %s: %v", kind, err)
+		return
+	}
+	if err := tmp.Close(); err != nil {
+		klog.Warningf("Error caching %s: %v", kind, err)
+		return
+	}
+	if err := os.Rename(tmp.Name(), c.path(kind, key)); err != nil {
+		klog.Warningf("Error caching %s: %v", kind, err)
+	}
+}
+
+// Clear removes all cached results.
+func (c *Cache) Clear() error {
+	return os.RemoveAll(c.dir)
+}
+
+func (c *Cache) path(kind, key string) string {
+	return filepath.Join(c.dir, kind, key+".json")
+}
@@ -0,0 +1,249 @@
+package carry
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/openshift/rebase/internal/cache"
+	"github.com/openshift/rebase/internal/fork"
+	"github.com/openshift/rebase/internal/git"
+	"github.com/openshift/rebase/internal/output"
+	"github.com/openshift/rebase/internal/table"
+	"github.com/openshift/rebase/internal/utils"
+)
+
+const (
+	// stagingPrefix holds the staged repositories, each of them is a component
+	stagingPrefix = "staging/src/k8s.io/"
+	// filesCache is the kind of cached files changed by a commit
+	filesCache = "files"
+)
+
+// ListOptions filters the listed carries, empty fields match everything.
+type ListOptions struct {
+	// Action matches the upstream action of a carry, eg. carry or drop
+	Action string
+	// Path matches carries changing at least one file under the path
+	Path string
+	// Author matches a part of the author name or email, ignoring case
+	Author string
+	// Concurrency is the number of carries read in parallel, defaults to the
+	// number of CPUs
+	Concurrency int
+}
+
+// Entry describes a listed carry commit.
+type Entry struct {
+	SHA       string `json:"sha"`
+	Action    string `json:"action"`
+	Summary   string `json:"summary"`
+	Component string `json:"component"`
+	Files     int    `json:"files"`
+	Author    string `json:"author"`
+}
+
+// List prints carry commits from the rebase marker to the tip of the
+// openshift branch, together with the changed component and files.
+type List struct {
+	log           *Log
+	repositoryDir string
+	repository    git.Git
+	options       ListOptions
+	output        string
+	ctx           context.Context
+}
+
+// NewList returns the list of carries, printed in the output format, either
+// json, yaml or empty for a table.
+func NewList(from, repositoryDir string, f fork.Fork, options ListOptions, output string) *List {
+	return &List{
+		log:           NewLog(from, repositoryDir, f),
+		repositoryDir: repositoryDir,
+		options:       options,
+		output:        output,
+	}
+}
+
+// SetTo reads carries from the range between the merge base of the starting
+// tag and the ref.
+func (l *List) SetTo(ref string) {
+	l.log.SetTo(ref)
+}
+
+// SetMarkerSearch bounds the search of the rebase marker.
+func (l *List) SetMarkerSearch(search git.MarkerSearch) {
+	l.log.SetMarkerSearch(search)
+}
+
+// SetContext stops listing carries once the context is cancelled.
+func (l *List) SetContext(ctx context.Context) {
+	l.ctx = ctx
+	l.log.SetContext(ctx)
+}
+
+// SetRepository shares an already opened repository.
+func (l *List) SetRepository(repository git.Git) {
+	l.repository = repository
+	l.log.SetRepository(repository)
+}
+
+func (l *List) Run() error {
+	repository, err := git.OpenShared(l.repository, l.repositoryDir, l.log.fork)
+	if err != nil {
+		return err
+	}
+	entries, err := l.Entries(repository)
+	if err != nil {
+		return err
+	}
+	if output.IsMachine(l.output) {
+		return output.Write(os.Stdout, l.output, entries)
+	}
+	t := table.New(os.Stdout, "SHA", "ACTION", "COMPONENT", "FILES", "SUMMARY")
+	for _, e := range entries {
+		t.AddRow(actionColor(e.Action), e.SHA[:12], e.Action, e.Component, strconv.Itoa(e.Files), e.Summary)
+	}
+	return t.Flush()
+}
+
+// actionColor colors carries green, dropped commits grey and unknown actions red
+func actionColor(action string) table.Color {
+	switch strings.Trim(action, "<>") {
+	case "carry":
+		return table.Green
+	case "drop":
+		return table.Grey
+	}
+	return table.Red
+}
+
+// Entries returns carries matching the list options.
+func (l *List) Entries(repository git.Git) ([]Entry, error) {
+	commits, err := l.log.GetCommits(repository)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading carries: %w", err)
+	}
+	gitDir, err := repository.GitDir()
+	if err != nil {
+		return nil, err
+	}
+	store := cache.New(gitDir)
+	// commits are classified in parallel, skipped ones are left nil
+	matched := make([]*Entry, len(commits))
+	err = utils.Parallel(len(commits), l.options.Concurrency, func(i int) error {
+		if l.ctx != nil {
+			if err := l.ctx.Err(); err != nil {
+				return err
+			}
+		}
+		c := commits[i]
+		if !l.matchesAuthor(c.Author.Name, c.Author.Email) {
+			return nil
+		}
+		action := l.log.fork.Action(ActionFromMessage(c.Message))
+		if !l.matchesAction(action) {
+			return nil
+		}
+		// files changed by a commit never change, the SHA is the key
+		var files []string
+		if !store.Get(filesCache, c.Hash.String(), &files) {
+			var err error
+			files, err = repository.ChangedFiles(c.Hash.String())
+			if err != nil {
+				return fmt.Errorf("Error reading files changed by %s: %w", c.Hash, err)
+			}
+			store.Put(filesCache, c.Hash.String(), files)
+		}
+		if len(l.options.Path) > 0 && !anyUnder(files, l.options.Path) {
+			return nil
+		}
+		matched[i] = &Entry{
+			SHA:       c.Hash.String(),
+			Action:    action,
+			Summary:   utils.FormatMessage(c.Message),
+			Component: component(files),
+			Files:     len(files),
+			Author:    c.Author.Name,
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	entries := []Entry{}
+	for _, e := range matched {
+		if e != nil {
+			entries = append(entries, *e)
+		}
+	}
+	return entries, nil
+}
+
+func (l *List) matchesAuthor(name, email string) bool {
+	if len(l.options.Author) == 0 {
+		return true
+	}
+	author := strings.ToLower(l.options.Author)
+	return strings.Contains(strings.ToLower(name), author) || strings.Contains(strings.ToLower(email), author)
+}
+
+// matchesAction compares actions with or without the angle brackets
+func (l *List) matchesAction(action string) bool {
+	if len(l.options.Action) == 0 {
+		return true
+	}
+	return strings.Trim(action, "<>") == strings.Trim(l.options.Action, "<>")
+}
+
+// anyUnder checks if at least one of the files is the path or placed under it
+func anyUnder(files []string, dir string) bool {
+	dir = strings.TrimSuffix(dir, "/")
+	for _, f := range files {
+		if f == dir || strings.HasPrefix(f, dir+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// component returns the component most of the files belong to, which is the
+// staged repository or the first two directories of the path
+func component(files []string) string {
+	counts := make(map[string]int)
+	for _, f := range files {
+		counts[fileComponent(f)]++
+	}
+	components := make([]string, 0, len(counts))
+	for c := range counts {
+		components = append(components, c)
+	}
+	sort.Slice(components, func(i, j int) bool {
+		if counts[components[i]] != counts[components[j]] {
+			return counts[components[i]] > counts[components[j]]
+		}
+		return components[i] < components[j]
+	})
+	if len(components) == 0 {
+		return ""
+	}
+	return components[0]
+}
+
+func fileComponent(file string) string {
+	if strings.HasPrefix(file, stagingPrefix) {
+		name, _, _ := strings.Cut(strings.TrimPrefix(file, stagingPrefix), "/")
+		return stagingPrefix + name
+	}
+	parts := strings.Split(file, "/")
+	if len(parts) == 1 {
+		return "."
+	}
+	if len(parts) == 2 {
+		return parts[0]
+	}
+	return parts[0] + "/" + parts[1]
+}
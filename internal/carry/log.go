@@ -0,0 +1,389 @@
+package carry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	gitv5object "github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/openshift/rebase/internal/cache"
+	"github.com/openshift/rebase/internal/console"
+	"github.com/openshift/rebase/internal/fork"
+	"github.com/openshift/rebase/internal/git"
+	"github.com/openshift/rebase/internal/output"
+	"github.com/openshift/rebase/internal/utils"
+	"k8s.io/klog/v2"
+)
+
+const (
+	mergeMarker    = `Merge pull request #`
+	upstreamPrefix = "UPSTREAM: "
+	// carriesCache is the kind of cached carries
+	carriesCache = "carries"
+)
+
+var (
+	// actionRE matches the action on any line, ignoring case and whitespace,
+	// also when prefixed, eg. with [release-4.x] by cherry-pick bots
+	actionRE = regexp.MustCompile(`(?im)(?:^|[\s\[\]"'(*])upstream\s*:\s*(?P<action><\s*[\w-]+\s*>|\w+)\s*:`)
+)
+
+type Log struct {
+	from          string
+	to            string
+	fork          fork.Fork
+	repositoryDir string
+	repository    git.Git
+	output        string
+	ctx           context.Context
+	// markerSearch bounds the search of the rebase marker, by default all
+	// commits since the starting tag are searched
+	markerSearch git.MarkerSearch
+}
+
+// Carry describes a carry commit in machine-readable output.
+type Carry struct {
+	SHA       string    `json:"sha"`
+	Message   string    `json:"message"`
+	Author    string    `json:"author"`
+	Authored  time.Time `json:"authored"`
+	Committed time.Time `json:"committed"`
+}
+
+// NewLog returns the log of carries of the fork since the starting tag.
+func NewLog(from, repositoryDir string, f fork.Fork) *Log {
+	return &Log{
+		from:          from,
+		fork:          f,
+		repositoryDir: repositoryDir,
+	}
+}
+
+// SetOutput selects the output format, either json, yaml or empty for a
+// human-readable list.
+func (c *Log) SetOutput(format string) {
+	c.output = format
+}
+
+// SetTo reads carries from the range between the merge base of the starting
+// tag and the ref, instead of the openshift branch since the tag was created.
+func (c *Log) SetTo(ref string) {
+	c.to = ref
+}
+
+// SetRepository shares an already opened repository, instead of opening the
+// repository directory again.
+func (c *Log) SetRepository(repository git.Git) {
+	c.repository = repository
+}
+
+// SetContext stops reading carries once the context is cancelled.
+func (c *Log) SetContext(ctx context.Context) {
+	c.ctx = ctx
+}
+
+// SetMarkerSearch bounds the search of the rebase marker, instead of walking
+// all commits since the starting tag, carries then follow the latest marker
+// found within the bounds.
+func (c *Log) SetMarkerSearch(search git.MarkerSearch) {
+	c.markerSearch = search
+}
+
+func (c *Log) Run() error {
+	repository, err := git.OpenShared(c.repository, c.repositoryDir, c.fork)
+	if err != nil {
+		return err
+	}
+	commits, err := c.GetCommits(repository)
+	if err != nil {
+		return fmt.Errorf("Error reading carries: %w", err)
+	}
+	if output.IsMachine(c.output) {
+		carries := make([]Carry, 0, len(commits))
+		for _, c := range commits {
+			carries = append(carries, Carry{SHA: c.Hash.String(), Message: utils.FormatMessage(c.Message),
+				Author: c.Author.Name, Authored: c.Author.When, Committed: c.Committer.When})
+		}
+		return output.Write(os.Stdout, c.output, carries)
+	}
+	for _, c := range commits {
+		fmt.Printf("%s\t%s\t%-25s\t%s\t%s\n", c.Committer.When.Format(time.DateTime),
+			c.Author.When.Format(time.DateTime),
+			c.Author.Name, c.Hash.String(), utils.FormatMessage(c.Message))
+	}
+	return nil
+}
+
+func (c *Log) GetCommits(repository git.Git) ([]*gitv5object.Commit, error) {
+	if len(c.to) > 0 {
+		commits, err := c.cachedCarries(repository, latestMarkerWalk, []string{c.from, c.to}, func() ([]*gitv5object.Commit, error) {
+			if !c.markerSearch.IsZero() {
+				return c.boundedCarries(repository, c.to)
+			}
+			// the range holds the history of all previous rebases, the
+			// carries start at the latest marker
+			collector := newCollector(c.ctx, repository, c.fork.IsMarker, true, c.from, c.to)
+			if err := repository.ForEachInRange(c.from, c.to, collector.add); err != nil {
+				return nil, err
+			}
+			return collector.carries()
+		})
+		c.reportMissingMarker(err)
+		return commits, err
+	}
+	// the openshift branch stays checked out, as callers expect
+	if err := repository.Checkout(c.fork.OpenShiftBranch()); err != nil {
+		return nil, err
+	}
+	return c.GetCommitsOn(repository, "HEAD")
+}
+
+// GetCommitsOn returns carries on a given ref, which is read without checking
+// it out.
+func (c *Log) GetCommitsOn(repository git.Git, ref string) ([]*gitv5object.Commit, error) {
+	commits, err := c.cachedCarries(repository, firstMarkerWalk, []string{c.from, ref}, func() ([]*gitv5object.Commit, error) {
+		if !c.markerSearch.IsZero() {
+			return c.boundedCarries(repository, ref)
+		}
+		collector := newCollector(c.ctx, repository, c.fork.IsMarker, false, c.from, ref)
+		if err := repository.ForEachFromTagOn(c.from, ref, collector.add); err != nil {
+			return nil, err
+		}
+		return collector.carries()
+	})
+	c.reportMissingMarker(err)
+	return commits, err
+}
+
+// reportMissingMarker prints the likely causes of a missing rebase marker
+func (c *Log) reportMissingMarker(err error) {
+	if !errors.Is(err, git.ErrMarkerNotFound) {
+		return
+	}
+	marker := c.fork.Marker
+	if len(marker) == 0 {
+		marker = c.fork.RebaseMarker()
+	}
+	hints := []string{
+		fmt.Sprintf("--from %s is the upstream tag the openshift branch was last rebased onto", c.from),
+		"the openshift branch and the tags are fetched with full history, eg. git fetch --unshallow --tags",
+	}
+	if !c.markerSearch.IsZero() {
+		hints = append(hints, "--marker-depth, --marker-since and --first-parent cover the last rebase")
+	}
+	console.ErrorfContext(c.ctx, "The rebase marker %q was not found, check that:\n  %s", marker, strings.Join(hints, "\n  "))
+}
+
+// boundedCarries returns the carries following the latest marker on ref, the
+// marker is searched within the bounds of the marker search
+func (c *Log) boundedCarries(repository git.Git, ref string) ([]*gitv5object.Commit, error) {
+	marker, err := repository.FindMarker(ref, c.markerSearch, c.fork.IsMarker)
+	if err != nil {
+		return nil, err
+	}
+	collector := newCollector(c.ctx, repository, c.fork.IsMarker, true, marker.Hash.String(), ref)
+	if err := repository.ForEachInRange(marker.Hash.String(), ref, collector.add); err != nil {
+		return nil, err
+	}
+	if err := collector.add(marker); err != nil {
+		return nil, err
+	}
+	return collector.carries()
+}
+
+// walks of the history looking for carries, which differ in the marker the
+// carries start at
+const (
+	latestMarkerWalk = "latest-marker"
+	firstMarkerWalk  = "first-marker"
+)
+
+// cachedCarries returns the carries computed from the refs by the walk,
+// reusing the result of a previous run of the same walk on the same SHAs of
+// the refs
+func (c *Log) cachedCarries(repository git.Git, walk string, refs []string, compute func() ([]*gitv5object.Commit, error)) ([]*gitv5object.Commit, error) {
+	gitDir, err := repository.GitDir()
+	if err != nil {
+		return compute()
+	}
+	parts := []string{walk, c.fork.Name, c.fork.Marker, fmt.Sprintf("%+v", c.markerSearch)}
+	for _, ref := range refs {
+		sha, err := repository.RevParse(ref)
+		if err != nil {
+			return compute()
+		}
+		parts = append(parts, ref, sha)
+	}
+	store := cache.New(gitDir)
+	key := cache.Key(parts...)
+	var shas []string
+	if store.Get(carriesCache, key, &shas) {
+		commits := make([]*gitv5object.Commit, 0, len(shas))
+		for _, sha := range shas {
+			commit, err := repository.Commit(plumbing.NewHash(sha))
+			if err != nil {
+				klog.Warningf("Ignoring cached carries, reading %s failed: %v", sha, err)
+				commits = nil
+				break
+			}
+			commits = append(commits, commit)
+		}
+		if commits != nil {
+			return commits, nil
+		}
+	}
+	commits, err := compute()
+	if err != nil {
+		return nil, err
+	}
+	shas = make([]string, 0, len(commits))
+	for _, commit := range commits {
+		shas = append(shas, commit.Hash.String())
+	}
+	store.Put(carriesCache, key, shas)
+	return commits, nil
+}
+
+// candidate is a commit streamed before the rebase marker is known, either an
+// UPSTREAM commit or a merge of a pull request, whose parents are carries
+type candidate struct {
+	commit *gitv5object.Commit
+	// index is the position in the stream, which is newest first
+	index int
+}
+
+// before orders commits by commit date, commits with the same date by their
+// position in the stream, where children precede parents
+func before(a, b candidate) bool {
+	if !a.commit.Committer.When.Equal(b.commit.Committer.When) {
+		return a.commit.Committer.When.Before(b.commit.Committer.When)
+	}
+	return a.index > b.index
+}
+
+// collector collects carries from commits streamed newest first, holding only
+// the candidates in memory rather than the whole history
+type collector struct {
+	// ctx stops the stream once cancelled, when set
+	ctx          context.Context
+	repository   git.Git
+	isMarker     func(message string) bool
+	latestMarker bool
+	// base and ref are the range of streamed commits, reported when there is
+	// no marker, carries are ordered by the history down to base
+	base, ref  string
+	marker     *candidate
+	candidates []candidate
+	walked     int
+}
+
+// newCollector returns a collector of carries following either the first or
+// the latest rebase marker in the range of commits reachable from ref, but
+// not from base
+func newCollector(ctx context.Context, repository git.Git, isMarker func(message string) bool, latestMarker bool, base, ref string) *collector {
+	return &collector{ctx: ctx, repository: repository, isMarker: isMarker, latestMarker: latestMarker, base: base, ref: ref}
+}
+
+// add processes the next commit of the stream
+func (c *collector) add(commit *gitv5object.Commit) error {
+	if c.ctx != nil {
+		if err := c.ctx.Err(); err != nil {
+			return err
+		}
+	}
+	klog.V(5).Infof("Processing %s", commit)
+	current := candidate{commit: commit, index: c.walked}
+	c.walked++
+	if c.isMarker(commit.Message) {
+		if c.marker == nil || before(current, *c.marker) != c.latestMarker {
+			c.marker = &current
+		}
+		return nil
+	}
+	if strings.Contains(commit.Message, mergeMarker) || hasAction(commit.Message) {
+		c.candidates = append(c.candidates, current)
+	}
+	return nil
+}
+
+// carries returns the carries following the marker, ordered by commit date
+func (c *collector) carries() ([]*gitv5object.Commit, error) {
+	if c.marker == nil {
+		return nil, &git.MarkerNotFoundError{Range: c.base + ".." + c.ref, Walked: c.walked}
+	}
+	klog.V(2).Infof("Found rebase marker at %s", c.marker.commit)
+	var following []candidate
+	for _, candidate := range c.candidates {
+		if before(*c.marker, candidate) {
+			following = append(following, candidate)
+		}
+	}
+	ordered := make([]*gitv5object.Commit, 0, len(following))
+	for _, candidate := range following {
+		ordered = append(ordered, candidate.commit)
+	}
+	if err := git.SortByDate(c.repository, ordered, c.base); err != nil {
+		return nil, err
+	}
+	var carryCommits []*gitv5object.Commit
+	for _, commit := range ordered {
+		if !strings.Contains(commit.Message, mergeMarker) {
+			carryCommits = append(carryCommits, commit)
+			continue
+		}
+		// TODO: check if the commit being brought by merge commit is already included, this currently produces duplicates
+		// for some of the commits, but is required since some commits might be created before the rebase landed,
+		// but merged afterwards, so the --since in log will skip them, a good example from 4.11/1.24.is:
+		// 2022-06-09 00:31:24 -0400 -0400 OpenShift Merge Robot Merge pull request #1229 from rphillips/backports/109103 cb7147853d28e94e1e32674d535e53aec4d9946f
+		// 2022-03-29 23:53:02 +0800 +0800 DingShujie UPSTREAM: 109103: cpu manager policy set to none, no one remove container id from container map, lea ed4d3f61aaccbc2fbe383c4d6b9614e8d2ad3e16
+		for _, hash := range commit.ParentHashes {
+			ci, err := c.repository.Commit(hash)
+			if err != nil {
+				klog.Errorf("error reading commit %s: %v", hash, err)
+				continue
+			}
+			if strings.Contains(ci.Message, mergeMarker) || !hasAction(ci.Message) {
+				continue
+			}
+			carryCommits = append(carryCommits, ci)
+		}
+	}
+	return deduplicateCommits(carryCommits), nil
+}
+
+// deduplicateCommits is responsible for dropping duplicate commits from the result list,
+// but without modifying the original order of commits
+func deduplicateCommits(commits []*gitv5object.Commit) []*gitv5object.Commit {
+	uniqueSha := make(map[string]bool)
+	var filteredCommits []*gitv5object.Commit
+	for _, c := range commits {
+		if _, exists := uniqueSha[c.Hash.String()]; exists {
+			continue
+		}
+		uniqueSha[c.Hash.String()] = true
+		filteredCommits = append(filteredCommits, c)
+	}
+	return filteredCommits
+}
+
+// ActionFromMessage parses the upstream action from commit message, without
+// translating it using the fork vocabulary. The first action found is returned
+// lower-cased without whitespace, eg. <carry>, or empty string when there is none.
+func ActionFromMessage(message string) string {
+	matches := actionRE.FindStringSubmatch(message)
+	if matches == nil {
+		return ""
+	}
+	action := matches[actionRE.SubexpIndex("action")]
+	return strings.ToLower(strings.Join(strings.Fields(action), ""))
+}
+
+// hasAction checks if the commit message is an UPSTREAM commit
+func hasAction(message string) bool {
+	return strings.Contains(message, upstreamPrefix) || len(ActionFromMessage(message)) > 0
+}
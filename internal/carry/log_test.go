@@ -0,0 +1,143 @@
+package carry
+
+import (
+	"testing"
+
+	"github.com/openshift/rebase/internal/fork"
+	"github.com/openshift/rebase/internal/git"
+	"github.com/openshift/rebase/internal/git/gittest"
+)
+
+func TestActionFromMessage(t *testing.T) {
+	tests := []struct {
+		name    string
+		message string
+		action  string
+	}{
+		{
+			name:    "carry",
+			message: "UPSTREAM: <carry>: openshift: add a carry",
+			action:  "<carry>",
+		},
+		{
+			name:    "drop",
+			message: "UPSTREAM: <drop>: regenerate files",
+			action:  "<drop>",
+		},
+		{
+			name:    "upstream pull request",
+			message: "UPSTREAM: 12345: fix a bug",
+			action:  "12345",
+		},
+		{
+			name:    "lowercase",
+			message: "upstream: <Carry>: openshift: add a carry",
+			action:  "<carry>",
+		},
+		{
+			name:    "extra whitespace",
+			message: "UPSTREAM :  < carry > : openshift: add a carry",
+			action:  "<carry>",
+		},
+		{
+			name:    "release prefix",
+			message: "[release-4.14] UPSTREAM: <carry>: openshift: add a carry",
+			action:  "<carry>",
+		},
+		{
+			name:    "marker not on the first line",
+			message: "openshift: add a carry\n\nUPSTREAM: <carry>: openshift: add a carry",
+			action:  "<carry>",
+		},
+		{
+			name:    "body quoting another commit",
+			message: "UPSTREAM: <carry>: openshift: fix the carry\n\nThe fix replaces\n\"UPSTREAM: <drop>: regenerate files\"",
+			action:  "<carry>",
+		},
+		{
+			name:    "no action",
+			message: "Merge pull request #1 from openshift/branch",
+		},
+		{
+			name:    "upstream in a word",
+			message: "bump the version of nonupstream: <drop>: tools",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if action := ActionFromMessage(test.message); action != test.action {
+				t.Errorf("expected action %q, got %q", test.action, action)
+			}
+		})
+	}
+}
+
+func TestForkAction(t *testing.T) {
+	f := fork.Fork{Actions: map[string]string{"<Patch>": "carry", "<revert>": "drop"}}
+	tests := []struct {
+		message string
+		action  string
+	}{
+		{message: "UPSTREAM: <patch>: openshift: add a patch", action: "<carry>"},
+		{message: "upstream: <PATCH>: openshift: add a patch", action: "<carry>"},
+		{message: "UPSTREAM: <Revert>: openshift: revert a patch", action: "<drop>"},
+		{message: "UPSTREAM: <carry>: openshift: add a carry", action: "<carry>"},
+		{message: "UPSTREAM: 12345: fix a bug", action: "12345"},
+	}
+	for _, test := range tests {
+		if action := f.Action(ActionFromMessage(test.message)); action != test.action {
+			t.Errorf("expected action %q of %q, got %q", test.action, test.message, action)
+		}
+	}
+}
+
+func TestLogMarkerSearch(t *testing.T) {
+	f := fork.Kubernetes
+	repository := gittest.New(t.TempDir())
+	base := repository.Commits(f.UpstreamRef(), "initial commit")[0]
+	repository.SetRef("refs/tags/v1.0.0", base)
+	previous := repository.AddCommit(gittest.Commit{Message: "UPSTREAM: <carry>: openshift: add a previous carry"})
+	marker := repository.AddCommit(gittest.Commit{Message: f.RebaseMarker() + " rebase-v1.0.0", Parents: []string{base, previous}})
+	repository.SetRef(f.OpenShiftRef(), marker)
+	repository.Commits(f.OpenShiftRef(), "UPSTREAM: <carry>: openshift: add a carry", "UPSTREAM: <carry>: openshift: add another carry")
+
+	tests := []struct {
+		name    string
+		search  git.MarkerSearch
+		carries int
+		wantErr bool
+	}{
+		{
+			name:    "unbounded",
+			carries: 2,
+		},
+		{
+			name:    "marker within the bounds",
+			search:  git.MarkerSearch{MaxCommits: 3},
+			carries: 2,
+		},
+		{
+			name:    "marker past the bounds",
+			search:  git.MarkerSearch{MaxCommits: 2},
+			wantErr: true,
+		},
+	}
+	// the logs are created up front, so that each of them keeps its own search
+	logs := make([]*Log, len(tests))
+	for i, test := range tests {
+		logs[i] = NewLog("v1.0.0", "", f)
+		logs[i].SetMarkerSearch(test.search)
+		logs[i].SetRepository(repository)
+	}
+	for i, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			commits, err := logs[i].GetCommitsOn(repository, f.OpenShiftRef())
+			if (err != nil) != test.wantErr {
+				t.Fatalf("GetCommitsOn() error = %v, wantErr %v", err, test.wantErr)
+			}
+			if len(commits) != test.carries {
+				t.Errorf("expected %d carries, got %d", test.carries, len(commits))
+			}
+		})
+	}
+}
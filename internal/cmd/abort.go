@@ -0,0 +1,38 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/openshift/rebase/internal/options"
+	"github.com/openshift/rebase/internal/rollback"
+)
+
+type AbortOptions struct {
+	options.Common
+	KeepBranch bool
+}
+
+func NewAbortCommand(streams options.IOStreams) *cobra.Command {
+	o := &AbortOptions{Common: options.NewCommon(streams)}
+
+	cmd := &cobra.Command{
+		Use:          "abort --repository=/go/src/k8s.io/kubernetes",
+		Short:        "Abandons the apply run in progress, aborting any cherry-pick and restoring the previous checkout",
+		GroupID:      GroupRun,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(c *cobra.Command, args []string) error {
+			if err := o.Common.CompleteRepository(); err != nil {
+				return err
+			}
+			abortAction := rollback.NewRollback(o.Common.RepositoryDir, o.Common.Fork)
+			abortAction.SetStateDir(o.Common.StateDir)
+			abortAction.SetKeepBranch(o.KeepBranch)
+			return abortAction.Run()
+		},
+	}
+	o.Common.AddRepositoryFlags(cmd.Flags())
+	cmd.Flags().BoolVar(&o.KeepBranch, "keep-branch", o.KeepBranch, "Keep the rebase branch with the carries picked so far instead of deleting it")
+
+	return cmd
+}
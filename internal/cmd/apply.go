@@ -0,0 +1,125 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/openshift/rebase/internal/apply"
+	"github.com/openshift/rebase/internal/options"
+	"github.com/openshift/rebase/internal/preflight"
+	"github.com/openshift/rebase/internal/progress"
+	"github.com/openshift/rebase/internal/tui"
+)
+
+type ApplyOptions struct {
+	options.Common
+	apply.Options
+	Select bool
+	Skip   []string
+	Only   []string
+}
+
+func NewApplyCommand(streams options.IOStreams) *cobra.Command {
+	o := &ApplyOptions{Common: options.NewCommon(streams)}
+
+	cmd := &cobra.Command{
+		Use:          "apply --repository=/go/src/k8s.io/kubernetes --from=v1.26.0",
+		Short:        "Applies carry patches from a given version of kubernetes",
+		GroupID:      GroupRun,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(c *cobra.Command, args []string) error {
+			if err := o.Common.Complete(); err != nil {
+				return err
+			}
+			if err := o.Common.ValidateRefs("to-ref", o.ToRef); err != nil {
+				return err
+			}
+			if err := o.Common.ValidateRefs("backport", apply.BackportRefs(o.Backports)...); err != nil {
+				return err
+			}
+			applyAction := apply.NewApply(o.Common.From, o.Common.RepositoryDir, o.Common.Fork, o.Options)
+			applyAction.SetStateDir(o.Common.StateDir)
+			applyAction.SetMarkerSearch(o.Common.MarkerSearch)
+			if selector := carrySelector(o.Skip, o.Only, o.Select); selector != nil {
+				applyAction.SetSelector(selector)
+			}
+			return applyAction.Run()
+		},
+	}
+	o.Common.AddFlags(cmd.Flags())
+	cmd.Flags().StringVar(&o.ToRef, "to-ref", o.ToRef, "Ref carries are read from, between its merge base with the starting tag and the ref, defaults to the openshift branch")
+	cmd.Flags().BoolVar(&o.Preflight, "preflight", o.Preflight, "Check in memory which carries will conflict before creating the rebase branch (requires git 2.40+)")
+	cmd.Flags().BoolVar(&o.PreflightOnly, "preflight-only", o.PreflightOnly, "Only run the preflight check, without creating the rebase branch")
+	cmd.Flags().StringVar(&o.MappingFile, "mapping", o.MappingFile, "Path to a file where the mapping of original carries to the new commits is written")
+	cmd.Flags().IntVar(&o.Mainline, "mainline", o.Mainline, "Parent number used when picking merge commits, merge commits are skipped if not set")
+	cmd.Flags().IntVar(&o.Concurrency, "concurrency", o.Concurrency, "Number of carries classified in parallel when selecting carries, defaults to the number of CPUs")
+	cmd.Flags().StringSliceVar(&o.OursPaths, "ours-path", o.OursPaths, "Path prefixes (e.g. openshift-hack/), conflicts limited to them are resolved with ours strategy option")
+	cmd.Flags().StringSliceVar(&o.CarriesDirs, "carries-dir", o.CarriesDirs, "Directories holding fixed carries, defaults to carries in the current or the repository directory")
+	cmd.Flags().StringVar(&o.TargetVersion, "target-version", o.TargetVersion, "Kubernetes version being rebased to (eg. v1.31), selects per-version fixed carries directories")
+	cmd.Flags().StringArrayVar(&o.PrePickHooks, "pre-pick-hook", o.PrePickHooks, "Command invoked before picking each carry, REBASE_COMMIT and REBASE_MESSAGE describe the carry")
+	cmd.Flags().StringArrayVar(&o.PostPickHooks, "post-pick-hook", o.PostPickHooks, "Command invoked after each picked carry, additionally REBASE_NEW_COMMIT and REBASE_DISPOSITION describe the result")
+	cmd.Flags().StringArrayVar(&o.PostPhaseHooks, "post-phase-hook", o.PostPhaseHooks, "Command invoked after each phase (merge, carries, backports, additional, queue), REBASE_PHASE holds the phase name")
+	cmd.Flags().StringVar(&o.HookPolicy, "hook-policy", apply.HookPolicyWarn, "What to do when a hook fails, one of: warn, stop")
+	cmd.Flags().DurationVar(&o.ProgressInterval, "progress-interval", progress.DefaultInterval, "How often progress is logged when not running in a terminal")
+	cmd.Flags().BoolVar(&o.Validate, "validate", o.Validate, "Run gofmt and go vet on packages touched by each picked carry")
+	cmd.Flags().StringVar(&o.ReportFile, "report", o.ReportFile, "Path to a file where a markdown report describing the rebase is written")
+	cmd.Flags().StringVar(&o.HTMLReportFile, "html-report", o.HTMLReportFile, "Path to a file where a self-contained html dashboard with the carries, conflicts and timings is written, eg. as a CI artifact")
+	cmd.Flags().StringVar(&o.UnknownAction, "unknown-action", apply.UnknownActionSkip, "What to do with commits with unknown UPSTREAM action, one of: fail, carry, skip")
+	cmd.Flags().StringSliceVar(&o.Backports, "backport", o.Backports, "Upstream commit SHAs or pull request numbers picked after the carries as UPSTREAM: <pr-number>: commits")
+	cmd.Flags().StringVar(&o.ConflictsDir, "conflicts-dir", o.ConflictsDir, "Directory where conflicted files and diffs of every conflicting carry are saved for review")
+	cmd.Flags().BoolVar(&o.Force, "force", o.Force, "Allow starting when a protected branch (master, main, release-*) is checked out")
+	cmd.Flags().BoolVar(&o.Resume, "resume", o.Resume, "Continue the run on the rebase branch, when it already exists with progress of a previous run")
+	cmd.Flags().StringSliceVar(&o.SkipChecks, "skip-check", o.SkipChecks, fmt.Sprintf("Environment checks not run before starting, any of: %s", strings.Join(preflight.Names(), ", ")))
+	cmd.Flags().BoolVar(&o.Regenerate, "regenerate", o.Regenerate, "Resolve conflicts limited to generated files by re-running generators")
+	cmd.Flags().StringArrayVar(&o.Generators, "generator", o.Generators, "Generator used with --regenerate as pattern=command (eg. zz_generated*=hack/update-codegen.sh), defaults to kubernetes generators")
+	cmd.Flags().StringVar(&o.GeneratedSide, "generated-side", "ours", "Side accepted for conflicting generated files before regenerating, ours or theirs")
+	cmd.Flags().StringArrayVar(&o.Resolvers, "resolver", o.Resolvers, "Resolver as name=command, the command resolves conflicts of a carry in the working tree, described by REBASE_COMMIT, REBASE_OURS, REBASE_THEIRS and REBASE_FILES")
+	cmd.Flags().BoolVar(&o.DeferConflicts, "defer-conflicts", o.DeferConflicts, "Skip carries which do not apply cleanly and queue them for a manual pass, started with 'rebase continue'")
+	cmd.Flags().StringVar(&o.QueueFile, "queue-file", "", "File where carries deferred with --defer-conflicts are listed, it can be reordered or trimmed before the manual pass, defaults to a file in the git directory")
+	cmd.Flags().StringVar(&o.PushRemote, "push-remote", o.PushRemote, "Remote, usually a fork, the finished rebase branch is pushed to")
+	cmd.Flags().BoolVar(&o.DraftPR, "draft-pr", o.DraftPR, "Open a draft pull request against openshift/kubernetes from the pushed branch, with the rebase report as its body, requires GITHUB_TOKEN or a git credential helper for github.com")
+	cmd.Flags().StringVar(&o.PRForkOwner, "pr-fork-owner", o.PRForkOwner, "Owner of the fork the rebase branch is pushed to, used with --draft-pr")
+	cmd.Flags().StringSliceVar(&o.PRLabels, "pr-label", o.PRLabels, "Labels added to the draft pull request")
+	cmd.Flags().BoolVar(&o.Annotate, "annotate", o.Annotate, "Record the openshift/kubernetes pull request, author and reviewers of every carry in the reports, requires many github requests")
+	cmd.Flags().BoolVar(&o.SuggestAssignees, "suggest-assignees", o.SuggestAssignees, "Suggest approvers from OWNERS files of the conflicted paths for carries requiring manual intervention, recorded in the reports and the manual queue")
+	cmd.Flags().BoolVar(&o.Jira, "jira", o.Jira, "Look up status and priority of OCPBUGS bugs referenced by carries, uses JIRA_TOKEN when set")
+	cmd.Flags().StringVar(&o.NotifySlack, "notify-slack", o.NotifySlack, "Slack webhook URL notified when the run starts, stops on a conflict and finishes")
+	cmd.Flags().StringVar(&o.NotifyWebhook, "notify-webhook", o.NotifyWebhook, "Webhook URL receiving JSON notifications when the run starts, stops on a conflict and finishes")
+	cmd.Flags().StringSliceVar(&o.MailTo, "mail-to", o.MailTo, "Addresses receiving the run summary and the manual queue by email, SMTP_USERNAME and SMTP_PASSWORD authenticate to the server")
+	cmd.Flags().StringVar(&o.MailServer, "mail-server", "localhost:25", "SMTP server, as host:port, sending emails with --mail-to")
+	cmd.Flags().StringVar(&o.MailFrom, "mail-from", o.MailFrom, "Sender address of emails sent with --mail-to")
+	cmd.Flags().StringVar(&o.StatusAddress, "status-address", o.StatusAddress, "Address (eg. :8080) of an HTTP server reporting progress of the run as JSON, disabled if not set")
+	cmd.Flags().StringVar(&o.PlanFile, "plan", o.PlanFile, "Path to a plan written by the plan command, executed instead of computing the actions from the carries")
+	cmd.Flags().BoolVar(&o.Select, "select", o.Select, "Select the carries to pick from a checkbox list in the terminal before the run starts, ignored with --plan")
+	cmd.Flags().StringSliceVar(&o.Skip, "skip", o.Skip, "Carry SHAs dropped from the run, ignored with --plan")
+	cmd.Flags().StringSliceVar(&o.Only, "only", o.Only, "Carry SHAs picked by the run, the other carries are dropped, ignored with --plan")
+
+	return cmd
+}
+
+// carrySelector returns the selector of carries dropping the skipped ones and
+// the ones not selected by only, followed by the interactive selection, nil
+// when the plan is not adjusted
+func carrySelector(skip, only []string, interactive bool) func(plan *apply.Plan) error {
+	var selectors []func(plan *apply.Plan) error
+	if len(skip) > 0 || len(only) > 0 {
+		selectors = append(selectors, apply.FilterCarries(skip, only))
+	}
+	if interactive {
+		selectors = append(selectors, tui.SelectCarries)
+	}
+	if len(selectors) == 0 {
+		return nil
+	}
+	return func(plan *apply.Plan) error {
+		for _, selector := range selectors {
+			if err := selector(plan); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
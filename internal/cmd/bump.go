@@ -0,0 +1,39 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/openshift/rebase/internal/bump"
+	"github.com/openshift/rebase/internal/options"
+)
+
+type BumpOptions struct {
+	options.Common
+	bump.Options
+}
+
+func NewBumpCommand(streams options.IOStreams) *cobra.Command {
+	o := &BumpOptions{Common: options.NewCommon(streams)}
+
+	cmd := &cobra.Command{
+		Use:          "bump --repository=/go/src/github.com/openshift/repo --target-version=v1.31.2",
+		Short:        "Bumps k8s.io dependencies of a go module to a kubernetes version, runs go mod tidy and vendor and commits the result",
+		GroupID:      GroupMaintain,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(c *cobra.Command, args []string) error {
+			if err := o.Common.CompleteRepository(); err != nil {
+				return err
+			}
+			bumpAction := bump.NewBump(o.Common.RepositoryDir, o.Options)
+			return bumpAction.Run()
+		},
+	}
+	o.Common.AddRepositoryFlags(cmd.Flags())
+	cmd.Flags().StringVar(&o.TargetVersion, "target-version", o.TargetVersion, "Kubernetes version the dependencies are bumped to (eg. v1.31.2), staging modules are bumped to the matching v0 version")
+	cmd.Flags().StringArrayVar(&o.Modules, "module", o.Modules, "Additional path@version requirement bumped together, eg. sigs.k8s.io/controller-runtime@v0.19.0")
+	cmd.Flags().StringVar(&o.UpdateCommand, "update-command", bump.DefaultUpdateCommand, "Command updating the module graph, when bumping kubernetes itself")
+	cmd.Flags().BoolVar(&o.NoCommit, "no-commit", o.NoCommit, "Leave the changes uncommitted")
+
+	return cmd
+}
@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/openshift/rebase/internal/carry"
+	"github.com/openshift/rebase/internal/options"
+	"github.com/openshift/rebase/internal/output"
+)
+
+type CarriesOptions struct {
+	options.Common
+	ToRef  string
+	Output string
+}
+
+func NewCarriesCommand(streams options.IOStreams) *cobra.Command {
+	o := &CarriesOptions{Common: options.NewCommon(streams)}
+
+	cmd := &cobra.Command{
+		Use:          "carries --repository=/go/src/k8s.io/kubernetes --from=v1.26.0",
+		Short:        "Generates log of carry patches from a given version of kubernetes",
+		GroupID:      GroupInspect,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(c *cobra.Command, args []string) error {
+			if err := o.Common.Complete(); err != nil {
+				return err
+			}
+			if err := o.Common.ValidateRefs("to-ref", o.ToRef); err != nil {
+				return err
+			}
+			if err := output.Validate(o.Output); err != nil {
+				return err
+			}
+			carriesAction := carry.NewLog(o.Common.From, o.Common.RepositoryDir, o.Common.Fork)
+			carriesAction.SetTo(o.ToRef)
+			carriesAction.SetMarkerSearch(o.Common.MarkerSearch)
+			carriesAction.SetOutput(o.Output)
+			return carriesAction.Run()
+		},
+	}
+	o.Common.AddFlags(cmd.Flags())
+	cmd.Flags().StringVar(&o.ToRef, "to-ref", o.ToRef, "Ref carries are read from, between its merge base with the starting tag and the ref, defaults to the openshift branch")
+	cmd.Flags().StringVarP(&o.Output, "output", "o", o.Output, "Output format, json or yaml, a tab separated list if not set")
+
+	cmd.AddCommand(NewCarriesListCommand(streams))
+
+	return cmd
+}
+
+type CarriesListOptions struct {
+	options.Common
+	carry.ListOptions
+	ToRef  string
+	Output string
+}
+
+func NewCarriesListCommand(streams options.IOStreams) *cobra.Command {
+	o := &CarriesListOptions{Common: options.NewCommon(streams)}
+
+	cmd := &cobra.Command{
+		Use:          "list --repository=/go/src/k8s.io/kubernetes --from=v1.26.0",
+		Short:        "Lists carry patches since the rebase marker with their action, component and changed files",
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(c *cobra.Command, args []string) error {
+			if err := o.Common.Complete(); err != nil {
+				return err
+			}
+			if err := o.Common.ValidateRefs("to-ref", o.ToRef); err != nil {
+				return err
+			}
+			if err := output.Validate(o.Output); err != nil {
+				return err
+			}
+			listAction := carry.NewList(o.Common.From, o.Common.RepositoryDir, o.Common.Fork, o.ListOptions, o.Output)
+			listAction.SetTo(o.ToRef)
+			listAction.SetMarkerSearch(o.Common.MarkerSearch)
+			return listAction.Run()
+		},
+	}
+	o.Common.AddFlags(cmd.Flags())
+	cmd.Flags().StringVar(&o.ToRef, "to-ref", o.ToRef, "Ref carries are read from, between its merge base with the starting tag and the ref, defaults to the openshift branch")
+	cmd.Flags().StringVar(&o.Action, "action", o.Action, "List only carries with the upstream action, eg. carry or drop")
+	cmd.Flags().StringVar(&o.Path, "path", o.Path, "List only carries changing files under the path")
+	cmd.Flags().StringVar(&o.Author, "author", o.Author, "List only carries whose author name or email contains the value")
+	cmd.Flags().IntVar(&o.Concurrency, "concurrency", o.Concurrency, "Number of carries read in parallel, defaults to the number of CPUs")
+	cmd.Flags().StringVarP(&o.Output, "output", "o", o.Output, "Output format, json or yaml, a table if not set")
+
+	return cmd
+}
@@ -0,0 +1,164 @@
+package cmd
+
+import (
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/spf13/cobra"
+
+	"github.com/openshift/rebase/internal/apply"
+	"github.com/openshift/rebase/internal/carry"
+	"github.com/openshift/rebase/internal/console"
+	"github.com/openshift/rebase/internal/fork"
+	"github.com/openshift/rebase/internal/git"
+	"github.com/openshift/rebase/internal/output"
+	"github.com/openshift/rebase/internal/preflight"
+	"github.com/openshift/rebase/internal/utils"
+	"github.com/openshift/rebase/internal/verify"
+)
+
+type completionFunc func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective)
+
+var (
+	completeTags     = completeRefs("refs/tags")
+	completeBranches = completeRefs("refs/heads", "refs/remotes")
+	completeRevs     = completeRefs("refs/heads", "refs/remotes", "refs/tags")
+
+	completeVerifyFormats = completeValues(verify.FormatTable, verify.FormatJSON, verify.FormatYAML, verify.FormatMarkdown, verify.FormatJUnit)
+)
+
+// flagCompletions maps flag names to the completion of their values
+var flagCompletions = map[string]completionFunc{
+	"from":           completeTags,
+	"since-tag":      completeTags,
+	"to-ref":         completeRevs,
+	"to":             completeRevs,
+	"branch":         completeBranches,
+	"previous":       completeBranches,
+	"upstream":       completeRevs,
+	"published-ref":  completeRevs,
+	"fork":           completeValues(fork.Names()...),
+	"git-backend":    completeValues(git.Backends()...),
+	"format":         completeVerifyFormats,
+	"output":         completeValues(output.JSON, output.YAML),
+	"check":          completeValues(verify.CheckNames...),
+	"unknown-action": completeValues(apply.UnknownActionFail, apply.UnknownActionCarry, apply.UnknownActionSkip),
+	"hook-policy":    completeValues(apply.HookPolicyWarn, apply.HookPolicyStop),
+	"generated-side": completeValues("ours", "theirs"),
+	"action":         completeValues("carry", "drop"),
+	"skip-check":     completeValues(preflight.Names()...),
+	"log-format":     completeValues(console.LogFormatText, console.LogFormatJSON),
+	"skip":           completeCarries,
+	"only":           completeCarries,
+	"backport":       completeUpstreamCommits,
+}
+
+// maxCompletedCommits limits the upstream commits offered for backports
+const maxCompletedCommits = 100
+
+// RegisterCompletions registers completion of flag values of the command and
+// all its subcommands, refs are completed from the repository.
+func RegisterCompletions(cmd *cobra.Command) {
+	for name, complete := range flagCompletions {
+		if cmd.Flags().Lookup(name) != nil || cmd.PersistentFlags().Lookup(name) != nil {
+			// the error is returned only for already registered or missing flags
+			_ = cmd.RegisterFlagCompletionFunc(name, complete)
+		}
+	}
+	for _, c := range cmd.Commands() {
+		RegisterCompletions(c)
+	}
+}
+
+func completeValues(values ...string) completionFunc {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return values, cobra.ShellCompDirectiveNoFileComp
+	}
+}
+
+// completeRefs completes short names of refs matching patterns in the
+// repository given by the repository flag
+func completeRefs(patterns ...string) completionFunc {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		repository, _, err := openForCompletion(cmd)
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		refs, err := repository.Refs(patterns...)
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		return refs, cobra.ShellCompDirectiveNoFileComp
+	}
+}
+
+// completeCarries completes SHAs of carries since the tag given by the from
+// flag, described by their summaries. Carries are read from the ref given by
+// the to-ref flag or the openshift branch, which is not checked out.
+func completeCarries(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	from, _ := cmd.Flags().GetString("from")
+	repository, f, err := openForCompletion(cmd)
+	if len(from) == 0 || err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	to, _ := cmd.Flags().GetString("to-ref")
+	if len(to) == 0 {
+		to = f.OpenShiftRef()
+	}
+	list := carry.NewList(from, completionRepositoryDir(cmd), f, carry.ListOptions{}, "")
+	list.SetTo(to)
+	list.SetRepository(repository)
+	entries, err := list.Entries(repository)
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	completions := make([]string, 0, len(entries))
+	for _, e := range entries {
+		completions = append(completions, e.SHA+"\t"+e.Summary)
+	}
+	return completions, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeUpstreamCommits completes SHAs of the newest upstream commits since
+// the tag given by the from flag, described by their summaries
+func completeUpstreamCommits(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	from, _ := cmd.Flags().GetString("from")
+	repository, f, err := openForCompletion(cmd)
+	if len(from) == 0 || err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	var completions []string
+	err = repository.ForEachInRange(from, f.UpstreamRef(), func(c *object.Commit) error {
+		completions = append(completions, c.Hash.String()+"\t"+utils.FormatMessage(c.Message))
+		if len(completions) == maxCompletedCommits {
+			return git.ErrStop
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	return completions, cobra.ShellCompDirectiveNoFileComp
+}
+
+// openForCompletion opens the repository of the fork given by the fork flags
+func openForCompletion(cmd *cobra.Command) (git.Git, fork.Fork, error) {
+	f := fork.Kubernetes
+	name, _ := cmd.Flags().GetString("fork")
+	config, _ := cmd.Flags().GetString("fork-config")
+	if len(name) > 0 || len(config) > 0 {
+		var err error
+		if f, err = fork.Load(name, config); err != nil {
+			return nil, f, err
+		}
+	}
+	repository, err := git.OpenGit(completionRepositoryDir(cmd), f)
+	return repository, f, err
+}
+
+// completionRepositoryDir returns the repository given by the repository flag
+func completionRepositoryDir(cmd *cobra.Command) string {
+	dir, _ := cmd.Flags().GetString("repository")
+	if len(dir) == 0 {
+		dir = "."
+	}
+	return dir
+}
@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/openshift/rebase/internal/apply"
+	"github.com/openshift/rebase/internal/options"
+)
+
+type ContinueOptions struct {
+	options.Common
+	NotifySlack   string
+	NotifyWebhook string
+}
+
+func NewContinueCommand(streams options.IOStreams) *cobra.Command {
+	o := &ContinueOptions{Common: options.NewCommon(streams)}
+
+	cmd := &cobra.Command{
+		Use:          "continue --repository=/go/src/k8s.io/kubernetes",
+		Short:        "Continues apply run after manual conflict resolution",
+		GroupID:      GroupRun,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(c *cobra.Command, args []string) error {
+			if err := o.Common.CompleteRepository(); err != nil {
+				return err
+			}
+			continueAction := apply.NewContinue(o.Common.RepositoryDir, o.Common.Fork)
+			continueAction.SetStateDir(o.Common.StateDir)
+			continueAction.SetNotify(o.NotifySlack, o.NotifyWebhook)
+			return continueAction.Run()
+		},
+	}
+	o.Common.AddRepositoryFlags(cmd.Flags())
+	cmd.Flags().StringVar(&o.NotifySlack, "notify-slack", o.NotifySlack, "Slack webhook URL notified when the run stops on a conflict and finishes, pass the one given to apply")
+	cmd.Flags().StringVar(&o.NotifyWebhook, "notify-webhook", o.NotifyWebhook, "Webhook URL receiving JSON notifications when the run stops on a conflict and finishes, pass the one given to apply")
+
+	return cmd
+}
@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/openshift/rebase/internal/doctor"
+	"github.com/openshift/rebase/internal/options"
+	"github.com/openshift/rebase/internal/output"
+)
+
+type DoctorOptions struct {
+	options.Common
+	Output string
+}
+
+func NewDoctorCommand(streams options.IOStreams) *cobra.Command {
+	o := &DoctorOptions{Common: options.NewCommon(streams)}
+
+	cmd := &cobra.Command{
+		Use:   "doctor --repository=/go/src/k8s.io/kubernetes",
+		Short: "Checks git, the repository, credentials, scripts of the repository and the config file, printing how to fix the problems found",
+		Long: `Checks git, the repository, credentials, scripts of the repository and the config file, printing how to fix the problems found.
+
+Run it first when something does not work, and attach its output when
+reporting a problem. The command fails when any check fails, warnings only
+limit some features.`,
+		GroupID:      GroupMaintain,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(c *cobra.Command, args []string) error {
+			if err := o.Common.CompleteRepository(); err != nil {
+				return err
+			}
+			if err := output.Validate(o.Output); err != nil {
+				return err
+			}
+			configPath := ""
+			if flag := c.Flag("config"); flag != nil {
+				configPath = flag.Value.String()
+			}
+			return doctor.NewDoctor(o.Common.RepositoryDir, o.Common.Fork, configPath, o.Output).Run()
+		},
+	}
+	o.Common.AddRepositoryFlags(cmd.Flags())
+	cmd.Flags().StringVarP(&o.Output, "output", "o", o.Output, "Output format, json or yaml, a table if not set")
+
+	return cmd
+}
@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/openshift/rebase/internal/golden"
+	"github.com/openshift/rebase/internal/options"
+)
+
+type GoldenOptions struct {
+	options.Common
+	golden.Options
+	ToRef string
+}
+
+func NewGoldenCommand(streams options.IOStreams) *cobra.Command {
+	o := &GoldenOptions{Common: options.NewCommon(streams)}
+
+	cmd := &cobra.Command{
+		Use:   "golden --repository=/go/src/k8s.io/kubernetes --from=v1.26.0 --dir=testdata/v1.27",
+		Short: "Snapshots carries, the plan and the verification of a repository into golden files, or replays them with --replay",
+		Long: `Snapshots carries, the plan and the verification of a repository into golden files, or replays them with --replay.
+
+This is a development command, golden files of a past rebase are written once,
+and replayed after changing how carries are classified or planned, the replay
+fails printing the differences when the results changed.`,
+		Hidden:       true,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(c *cobra.Command, args []string) error {
+			if err := o.Common.Complete(); err != nil {
+				return err
+			}
+			if err := o.Common.ValidateRefs("to-ref", o.ToRef); err != nil {
+				return err
+			}
+			o.Apply.ToRef = o.ToRef
+			goldenAction := golden.NewGolden(o.Common.From, o.Common.RepositoryDir, o.ToRef, o.Common.Fork, o.Options)
+			goldenAction.SetMarkerSearch(o.Common.MarkerSearch)
+			return goldenAction.Run()
+		},
+	}
+	o.Common.AddFlags(cmd.Flags())
+	cmd.Flags().StringVar(&o.Dir, "dir", "testdata", "Directory holding the golden files")
+	cmd.Flags().BoolVar(&o.Replay, "replay", o.Replay, "Compare the results with the golden files instead of writing them")
+	cmd.Flags().StringVar(&o.ToRef, "to-ref", o.ToRef, "Ref carries are read from, between its merge base with the starting tag and the ref, defaults to the openshift branch")
+	cmd.Flags().StringSliceVar(&o.Apply.CarriesDirs, "carries-dir", o.Apply.CarriesDirs, "Directories holding fixed and additional carries, defaults to carries in the current or the repository directory")
+	cmd.Flags().StringVar(&o.Apply.TargetVersion, "target-version", o.Apply.TargetVersion, "Kubernetes version being rebased to (eg. v1.31), selects per-version fixed carries directories")
+	cmd.Flags().StringVar(&o.Verify.Branch, "branch", o.Verify.Branch, "Rebase branch being verified, defaults to the current branch")
+	cmd.Flags().BoolVar(&o.SkipVerify, "skip-verify", o.SkipVerify, "Do not verify the rebase branch, eg. when the repository has none")
+
+	return cmd
+}
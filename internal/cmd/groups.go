@@ -0,0 +1,22 @@
+package cmd
+
+import "github.com/spf13/cobra"
+
+// IDs of the groups commands are listed under in help
+const (
+	// GroupRun are commands performing and driving the rebase
+	GroupRun = "run"
+	// GroupInspect are commands inspecting carries and rebase branches
+	GroupInspect = "inspect"
+	// GroupMaintain are commands maintaining the repository and its dependants
+	GroupMaintain = "maintain"
+)
+
+// Groups returns the groups commands are listed under in help.
+func Groups() []*cobra.Group {
+	return []*cobra.Group{
+		{ID: GroupRun, Title: "Rebase run:"},
+		{ID: GroupInspect, Title: "Inspecting carries and rebase branches:"},
+		{ID: GroupMaintain, Title: "Maintenance:"},
+	}
+}
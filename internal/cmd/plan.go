@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/openshift/rebase/internal/apply"
+	"github.com/openshift/rebase/internal/options"
+)
+
+type PlanOptions struct {
+	options.Common
+	apply.Options
+	File   string
+	Select bool
+	Skip   []string
+	Only   []string
+}
+
+func NewPlanCommand(streams options.IOStreams) *cobra.Command {
+	o := &PlanOptions{Common: options.NewCommon(streams)}
+
+	cmd := &cobra.Command{
+		Use:          "plan --repository=/go/src/k8s.io/kubernetes --from=v1.26.0 --file=rebase-plan.txt",
+		Short:        "Writes the ordered actions of an apply run to a file, which can be reviewed and edited before running apply --plan",
+		GroupID:      GroupRun,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(c *cobra.Command, args []string) error {
+			if err := o.Common.Complete(); err != nil {
+				return err
+			}
+			if err := o.Common.ValidateRefs("to-ref", o.ToRef); err != nil {
+				return err
+			}
+			if err := o.Common.ValidateRefs("backport", apply.BackportRefs(o.Backports)...); err != nil {
+				return err
+			}
+			planner := apply.NewPlanner(o.Common.From, o.Common.RepositoryDir, o.Common.Fork, o.Options, o.File)
+			planner.SetMarkerSearch(o.Common.MarkerSearch)
+			if selector := carrySelector(o.Skip, o.Only, o.Select); selector != nil {
+				planner.SetSelector(selector)
+			}
+			return planner.Run()
+		},
+	}
+	o.Common.AddFlags(cmd.Flags())
+	cmd.Flags().StringVar(&o.ToRef, "to-ref", o.ToRef, "Ref carries are read from, between its merge base with the starting tag and the ref, defaults to the openshift branch")
+	cmd.Flags().StringVar(&o.File, "file", "rebase-plan.txt", "Path to a file where the plan is written")
+	cmd.Flags().BoolVar(&o.Select, "select", o.Select, "Select the carries to pick from a checkbox list in the terminal")
+	cmd.Flags().StringSliceVar(&o.Skip, "skip", o.Skip, "Carry SHAs planned to be dropped")
+	cmd.Flags().StringSliceVar(&o.Only, "only", o.Only, "Carry SHAs planned to be picked, the other carries are dropped")
+	cmd.Flags().IntVar(&o.Mainline, "mainline", o.Mainline, "Parent number used when picking merge commits, merge commits are dropped if not set")
+	cmd.Flags().IntVar(&o.Concurrency, "concurrency", o.Concurrency, "Number of carries classified in parallel, defaults to the number of CPUs")
+	cmd.Flags().StringSliceVar(&o.CarriesDirs, "carries-dir", o.CarriesDirs, "Directories holding fixed and additional carries, defaults to carries in the current or the repository directory")
+	cmd.Flags().StringVar(&o.TargetVersion, "target-version", o.TargetVersion, "Kubernetes version being rebased to (eg. v1.31), selects per-version fixed carries directories")
+	cmd.Flags().StringVar(&o.UnknownAction, "unknown-action", apply.UnknownActionSkip, "What to do with commits with unknown UPSTREAM action, one of: fail, carry (pick), skip (drop)")
+	cmd.Flags().StringSliceVar(&o.Backports, "backport", o.Backports, "Upstream commit SHAs or pull request numbers picked after the carries")
+	cmd.Flags().StringArrayVar(&o.Generators, "generator", o.Generators, "Generator as pattern=command, carries changing generated files are regenerated on conflicts, defaults to kubernetes generators")
+
+	return cmd
+}
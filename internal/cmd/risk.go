@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/openshift/rebase/internal/options"
+	"github.com/openshift/rebase/internal/output"
+	"github.com/openshift/rebase/internal/risk"
+)
+
+type RiskOptions struct {
+	options.Common
+	To     string
+	ToRef  string
+	Output string
+}
+
+func NewRiskCommand(streams options.IOStreams) *cobra.Command {
+	o := &RiskOptions{Common: options.NewCommon(streams)}
+
+	cmd := &cobra.Command{
+		Use:          "risk --repository=/go/src/k8s.io/kubernetes --from=v1.26.0 --to=v1.27.0",
+		Short:        "Scores carries by upstream churn in the packages they touch between two kubernetes versions",
+		GroupID:      GroupInspect,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(c *cobra.Command, args []string) error {
+			if err := o.Common.Complete(); err != nil {
+				return err
+			}
+			if err := o.Common.ValidateRefs("to-ref", o.ToRef); err != nil {
+				return err
+			}
+			if err := o.Common.ValidateRefs("to", o.To); err != nil {
+				return err
+			}
+			if err := output.Validate(o.Output); err != nil {
+				return err
+			}
+			riskAction := risk.NewRisk(o.Common.From, o.Common.RepositoryDir, o.Common.Fork, o.To, o.Output)
+			riskAction.SetCarriesTo(o.ToRef)
+			riskAction.SetMarkerSearch(o.Common.MarkerSearch)
+			return riskAction.Run()
+		},
+	}
+	o.Common.AddFlags(cmd.Flags())
+	cmd.Flags().StringVar(&o.ToRef, "to-ref", o.ToRef, "Ref carries are read from, between its merge base with the starting tag and the ref, defaults to the openshift branch")
+	cmd.Flags().StringVarP(&o.Output, "output", "o", o.Output, "Output format, json or yaml, a tab separated list if not set")
+	cmd.Flags().StringVar(&o.To, "to", o.To, "Kubernetes version being rebased to, defaults to the upstream branch of the fork")
+
+	return cmd
+}
@@ -0,0 +1,36 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/openshift/rebase/internal/options"
+	"github.com/openshift/rebase/internal/rollback"
+)
+
+type RollbackOptions struct {
+	options.Common
+}
+
+func NewRollbackCommand(streams options.IOStreams) *cobra.Command {
+	o := &RollbackOptions{Common: options.NewCommon(streams)}
+
+	cmd := &cobra.Command{
+		Use:          "rollback --repository=/go/src/k8s.io/kubernetes",
+		Aliases:      []string{"abort-run"},
+		Short:        "Rolls back a failed or abandoned apply run, restoring the original checkout",
+		GroupID:      GroupRun,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(c *cobra.Command, args []string) error {
+			if err := o.Common.CompleteRepository(); err != nil {
+				return err
+			}
+			rollbackAction := rollback.NewRollback(o.Common.RepositoryDir, o.Common.Fork)
+			rollbackAction.SetStateDir(o.Common.StateDir)
+			return rollbackAction.Run()
+		},
+	}
+	o.Common.AddRepositoryFlags(cmd.Flags())
+
+	return cmd
+}
@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/openshift/rebase/internal/options"
+	"github.com/openshift/rebase/internal/output"
+	"github.com/openshift/rebase/internal/status"
+)
+
+type StatusOptions struct {
+	options.Common
+	Output string
+}
+
+func NewStatusCommand(streams options.IOStreams) *cobra.Command {
+	o := &StatusOptions{Common: options.NewCommon(streams)}
+
+	cmd := &cobra.Command{
+		Use:          "status --repository=/go/src/k8s.io/kubernetes",
+		Short:        "Shows where the apply run in progress is and what to run next",
+		GroupID:      GroupRun,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(c *cobra.Command, args []string) error {
+			if err := o.Common.CompleteRepository(); err != nil {
+				return err
+			}
+			if err := output.Validate(o.Output); err != nil {
+				return err
+			}
+			show := status.NewShow(o.Common.RepositoryDir, o.Common.Fork, o.Output)
+			show.SetStateDir(o.Common.StateDir)
+			return show.Run()
+		},
+	}
+	o.Common.AddRepositoryFlags(cmd.Flags())
+	cmd.Flags().StringVarP(&o.Output, "output", "o", o.Output, "Output format, json or yaml, a human-readable summary if not set")
+
+	return cmd
+}
@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/openshift/rebase/internal/apply"
+	"github.com/openshift/rebase/internal/options"
+	"github.com/openshift/rebase/internal/preflight"
+	"github.com/openshift/rebase/internal/tui"
+)
+
+type TUIOptions struct {
+	options.Common
+	apply.Options
+}
+
+func NewTUICommand(streams options.IOStreams) *cobra.Command {
+	o := &TUIOptions{Common: options.NewCommon(streams)}
+
+	cmd := &cobra.Command{
+		Use:          "tui --repository=/go/src/k8s.io/kubernetes [--from=v1.26.0]",
+		Short:        "Drives the rebase interactively in the terminal, starting a new run with --from or showing the one in progress",
+		GroupID:      GroupRun,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(c *cobra.Command, args []string) error {
+			if err := o.Common.CompleteRepository(); err != nil {
+				return err
+			}
+			if err := o.Common.CompleteMarkerSearch(); err != nil {
+				return err
+			}
+			if err := o.Common.ValidateRefs("from", o.Common.From); err != nil {
+				return err
+			}
+			if err := o.Common.ValidateRefs("to-ref", o.ToRef); err != nil {
+				return err
+			}
+			tuiAction := tui.NewTUI(o.Common.From, o.Common.RepositoryDir, o.Common.Fork, o.Options)
+			tuiAction.SetStateDir(o.Common.StateDir)
+			tuiAction.SetMarkerSearch(o.Common.MarkerSearch)
+			return tuiAction.Run()
+		},
+	}
+	o.Common.AddFlags(cmd.Flags())
+	cmd.Flags().StringVar(&o.ToRef, "to-ref", o.ToRef, "Ref carries are read from, between its merge base with the starting tag and the ref, defaults to the openshift branch")
+	cmd.Flags().IntVar(&o.Mainline, "mainline", o.Mainline, "Parent number used when picking merge commits, merge commits are skipped if not set")
+	cmd.Flags().StringSliceVar(&o.OursPaths, "ours-path", o.OursPaths, "Path prefixes (e.g. openshift-hack/), conflicts limited to them are resolved with ours strategy option")
+	cmd.Flags().StringSliceVar(&o.CarriesDirs, "carries-dir", o.CarriesDirs, "Directories holding fixed carries, defaults to carries in the current or the repository directory")
+	cmd.Flags().StringVar(&o.TargetVersion, "target-version", o.TargetVersion, "Kubernetes version being rebased to (eg. v1.31), selects per-version fixed carries directories")
+	cmd.Flags().StringVar(&o.ReportFile, "report", o.ReportFile, "Path to a file where a markdown report describing the rebase is written")
+	cmd.Flags().StringVar(&o.UnknownAction, "unknown-action", apply.UnknownActionSkip, "What to do with commits with unknown UPSTREAM action, one of: fail, carry, skip")
+	cmd.Flags().StringVar(&o.HookPolicy, "hook-policy", apply.HookPolicyWarn, "What to do when a hook fails, one of: warn, stop")
+	cmd.Flags().StringVar(&o.GeneratedSide, "generated-side", "ours", "Side accepted for conflicting generated files before regenerating, ours or theirs")
+	cmd.Flags().StringVar(&o.QueueFile, "queue-file", "", "File where carries deferred with --defer-conflicts are listed, it can be reordered or trimmed before the manual pass, defaults to a file in the git directory")
+	cmd.Flags().BoolVar(&o.Force, "force", o.Force, "Allow starting when a protected branch (master, main, release-*) is checked out")
+	cmd.Flags().BoolVar(&o.Resume, "resume", o.Resume, "Continue the run on the rebase branch, when it already exists with progress of a previous run")
+	cmd.Flags().StringSliceVar(&o.SkipChecks, "skip-check", o.SkipChecks, fmt.Sprintf("Environment checks not run before starting, any of: %s", strings.Join(preflight.Names(), ", ")))
+
+	return cmd
+}
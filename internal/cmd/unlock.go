@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"errors"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/openshift/rebase/internal/console"
+	"github.com/openshift/rebase/internal/git"
+	"github.com/openshift/rebase/internal/options"
+	"github.com/openshift/rebase/internal/state"
+)
+
+type UnlockOptions struct {
+	options.Common
+}
+
+func NewUnlockCommand(streams options.IOStreams) *cobra.Command {
+	o := &UnlockOptions{Common: options.NewCommon(streams)}
+
+	cmd := &cobra.Command{
+		Use:          "unlock --repository=/go/src/k8s.io/kubernetes",
+		Short:        "Removes the lock of the repository left by a run which is no longer running, eg. in a killed container",
+		GroupID:      GroupRun,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(c *cobra.Command, args []string) error {
+			if err := o.Common.CompleteRepository(); err != nil {
+				return err
+			}
+			gitDir, err := git.GitDir(o.Common.RepositoryDir)
+			if err != nil {
+				return err
+			}
+			holder, err := state.Unlock(gitDir)
+			if errors.Is(err, state.ErrNotLocked) {
+				console.Infof("The repository is not locked")
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			console.Infof("Removed the lock of %s run (pid %d on %s, started %s)", holder.Command, holder.PID, holder.Host, holder.Started.Format(time.RFC3339))
+			return nil
+		},
+	}
+	o.Common.AddRepositoryFlags(cmd.Flags())
+
+	return cmd
+}
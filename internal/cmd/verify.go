@@ -0,0 +1,150 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/openshift/rebase/internal/git"
+	"github.com/openshift/rebase/internal/options"
+	"github.com/openshift/rebase/internal/verify"
+)
+
+type VerifyOptions struct {
+	options.Common
+	verify.Options
+}
+
+func NewVerifyCommand(streams options.IOStreams) *cobra.Command {
+	o := &VerifyOptions{Common: options.NewCommon(streams)}
+
+	cmd := &cobra.Command{
+		Use:   "verify --repository=/go/src/k8s.io/kubernetes --from=v1.26.0",
+		Short: "Verifies the rebase branch against the planned carries",
+		Long: `Verifies the rebase branch against the planned carries.
+
+Findings are remembered by the SHAs they were computed from, so repeated runs
+examine only new or amended commits, use --no-cache to verify everything again.`,
+		GroupID:      GroupInspect,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(c *cobra.Command, args []string) error {
+			if err := o.Common.Complete(); err != nil {
+				return err
+			}
+			if err := o.Common.ValidateRefs("branch", o.Branch); err != nil {
+				return err
+			}
+			if err := o.Common.ValidateRefs("upstream", o.Upstream); err != nil {
+				return err
+			}
+			if err := o.Common.ValidateRefs("previous", o.PreviousBranch); err != nil {
+				return err
+			}
+			verifyAction := verify.NewVerify(o.Common.From, o.Common.RepositoryDir, o.Common.Fork, o.Options)
+			verifyAction.SetMarkerSearch(o.Common.MarkerSearch)
+			return verifyAction.Run()
+		},
+	}
+	o.Common.AddFlags(cmd.Flags())
+	cmd.Flags().StringVar(&o.Branch, "branch", o.Branch, "Rebase branch to verify, defaults to the checked out one")
+	cmd.Flags().StringVar(&o.Upstream, "upstream", o.Upstream, "Upstream revision the rebase branch is based on, defaults to the upstream branch")
+	cmd.Flags().StringVar(&o.OverridesFile, "overrides", o.OverridesFile, "JSON file mapping original carry SHAs to overrides of their planned disposition and message")
+	cmd.Flags().StringVar(&o.PreviousBranch, "previous", o.PreviousBranch, "Rebase branch of the previous release, reports its carries missing on the verified branch without a drop record")
+	cmd.Flags().BoolVar(&o.Vendor, "vendor", o.Vendor, "Check that vendor/, go.mod, go.sum and vendor/modules.txt are consistent by re-vendoring in a temporary worktree")
+	cmd.Flags().StringVar(&o.VendorCommand, "vendor-command", "go mod vendor", "Command used to re-vendor the dependencies with --vendor")
+	cmd.Flags().BoolVar(&o.BisectBuild, "bisect-build", o.BisectBuild, "Build the branch and when it fails, bisect the first commit breaking the build, this is expensive")
+	cmd.Flags().StringVar(&o.BuildCommand, "build-command", "go build ./...", "Command used to build the tree with --bisect-build")
+	cmd.Flags().StringVar(&o.TargetVersion, "target-version", o.TargetVersion, "Kubernetes version of the rebase (eg. v1.27.3), enables checking that files changed by carries do not reference the starting version")
+	cmd.Flags().BoolVar(&o.Fix, "fix", o.Fix, "Rewrite commit messages with malformed UPSTREAM prefix, summary differing from the override, missing cherry picked from trailer or trailing whitespace before verifying")
+	cmd.Flags().StringSliceVar(&o.Checks, "check", o.Checks, fmt.Sprintf("Run only the named checks (%s), eg. --check=messages in CI", strings.Join(verify.CheckNames, ", ")))
+	cmd.Flags().StringVarP(&o.Format, "output", "o", verify.FormatTable, "Output format of the results: table, json, yaml, markdown or junit")
+	cmd.Flags().StringVar(&o.Format, "format", verify.FormatTable, "Output format of the results: table, json, yaml, markdown or junit")
+	_ = cmd.Flags().MarkDeprecated("format", "use --output instead")
+	// registered before the common completion of the output flag, which would not include all formats
+	_ = cmd.RegisterFlagCompletionFunc("output", completeVerifyFormats)
+	cmd.Flags().IntVar(&o.Mainline, "mainline", o.Mainline, "Parent number used for picking merge commits, merge commits are planned as skipped when not set")
+	cmd.Flags().StringVar(&o.PublishedStaging, "published-staging", o.PublishedStaging, "URL of published staging repositories with {module} placeholder (eg. https://github.com/openshift/kubernetes-{module}), compares them with staging directories")
+	cmd.Flags().StringVar(&o.PublishedRef, "published-ref", o.PublishedRef, "Branch or tag of the published staging repositories compared with --published-staging")
+	cmd.Flags().BoolVar(&o.APIDiff, "apidiff", o.APIDiff, "Compare exported Go APIs of packages changed by carries with the --previous rebase branch, reporting incompatible changes")
+	cmd.Flags().StringSliceVar(&o.APIPackages, "api-package", o.APIPackages, "Package directories (eg. openshift-kube-apiserver/admission/customresourcevalidation) compared with --apidiff, defaults to the ones changed by carries")
+
+	cmd.AddCommand(NewVerifyDiffCommand(streams))
+	cmd.AddCommand(NewVerifyImportCommand(streams))
+
+	return cmd
+}
+
+type VerifyDiffOptions struct {
+	options.Common
+	verify.Options
+}
+
+func NewVerifyDiffCommand(streams options.IOStreams) *cobra.Command {
+	o := &VerifyDiffOptions{Common: options.NewCommon(streams)}
+
+	cmd := &cobra.Command{
+		Use:   "diff --repository=/go/src/k8s.io/kubernetes BRANCH OTHER-BRANCH",
+		Short: "Compares two candidate rebase branches, showing carries picked or resolved differently",
+		Args:  cobra.ExactArgs(2),
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) >= 2 {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+			return completeBranches(cmd, args, toComplete)
+		},
+		SilenceUsage: true,
+		RunE: func(c *cobra.Command, args []string) error {
+			if err := o.Common.CompleteRepository(); err != nil {
+				return err
+			}
+			for _, branch := range args {
+				if _, err := git.ResolveRef(o.Common.RepositoryDir, branch); err != nil {
+					return fmt.Errorf("Error resolving branch: %w", err)
+				}
+			}
+			if err := o.Common.ValidateRefs("upstream", o.Upstream); err != nil {
+				return err
+			}
+			diffAction := verify.NewDiff(o.Common.RepositoryDir, args[0], args[1], o.Common.Fork, o.Options)
+			return diffAction.Run()
+		},
+	}
+	o.Common.AddRepositoryFlags(cmd.Flags())
+	cmd.Flags().StringVar(&o.Upstream, "upstream", o.Upstream, "Upstream revision the rebase branches are based on, defaults to the upstream branch")
+	cmd.Flags().StringVarP(&o.Format, "output", "o", verify.FormatTable, "Output format of the results: table, json, yaml, markdown or junit")
+	cmd.Flags().StringVar(&o.Format, "format", verify.FormatTable, "Output format of the results: table, json, yaml, markdown or junit")
+	_ = cmd.Flags().MarkDeprecated("format", "use --output instead")
+	// registered before the common completion of the output flag, which would not include all formats
+	_ = cmd.RegisterFlagCompletionFunc("output", completeVerifyFormats)
+
+	return cmd
+}
+
+type VerifyImportOptions struct {
+	options.Common
+	OverridesFile string
+}
+
+func NewVerifyImportCommand(streams options.IOStreams) *cobra.Command {
+	o := &VerifyImportOptions{Common: options.NewCommon(streams)}
+
+	cmd := &cobra.Command{
+		Use:          "import --repository=/go/src/k8s.io/kubernetes --overrides=overrides.json SPREADSHEET.csv",
+		Short:        "Imports sha, disposition and notes columns of a rebase tracking spreadsheet exported as CSV into overrides",
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		RunE: func(c *cobra.Command, args []string) error {
+			if err := o.Common.CompleteRepository(); err != nil {
+				return err
+			}
+			importAction := verify.NewImport(o.Common.RepositoryDir, args[0], o.OverridesFile, o.Common.Fork)
+			return importAction.Run()
+		},
+	}
+	o.Common.AddRepositoryFlags(cmd.Flags())
+	cmd.Flags().StringVar(&o.OverridesFile, "overrides", "overrides.json", "JSON overrides file written, existing overrides in it are kept")
+
+	return cmd
+}
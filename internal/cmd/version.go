@@ -0,0 +1,39 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/openshift/rebase/internal/options"
+	"github.com/openshift/rebase/internal/output"
+	"github.com/openshift/rebase/internal/version"
+)
+
+func NewVersionCommand(streams options.IOStreams) *cobra.Command {
+	var format string
+	cmd := &cobra.Command{
+		Use:          "version",
+		Short:        "Prints the version, commit and build date of the tool, to be included in bug reports",
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(c *cobra.Command, args []string) error {
+			if err := output.Validate(format); err != nil {
+				return err
+			}
+			info := version.Get()
+			if output.IsMachine(format) {
+				return output.Write(streams.Out, format, info)
+			}
+			fmt.Fprintf(streams.Out, "Version:    %s\n", info.Version)
+			fmt.Fprintf(streams.Out, "Commit:     %s\n", info.Commit)
+			fmt.Fprintf(streams.Out, "Build date: %s\n", info.BuildDate)
+			fmt.Fprintf(streams.Out, "go-git:     %s\n", info.GoGit)
+			fmt.Fprintf(streams.Out, "Go:         %s\n", info.Go)
+			fmt.Fprintf(streams.Out, "Platform:   %s\n", info.Platform)
+			return nil
+		},
+	}
+	cmd.Flags().StringVarP(&format, "output", "o", format, "Output format, json or yaml, a human-readable summary if not set")
+	return cmd
+}
@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/openshift/rebase/internal/apply"
+	"github.com/openshift/rebase/internal/options"
+)
+
+type WatchOptions struct {
+	options.Common
+	apply.Options
+	Upstream string
+	Interval time.Duration
+	Once     bool
+
+	MetricsAddress string
+}
+
+func NewWatchCommand(streams options.IOStreams) *cobra.Command {
+	o := &WatchOptions{Common: options.NewCommon(streams)}
+
+	cmd := &cobra.Command{
+		Use:          "watch --repository=/go/src/k8s.io/kubernetes --from=v1.26.0",
+		Short:        "Periodically fetches upstream and reports carries which newly conflict with it, use a dedicated clone since the openshift branch gets checked out",
+		GroupID:      GroupInspect,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(c *cobra.Command, args []string) error {
+			if err := o.Common.Complete(); err != nil {
+				return err
+			}
+			if err := o.Common.ValidateRefs("upstream", o.Upstream); err != nil {
+				return err
+			}
+			watchAction := apply.NewWatch(o.Common.From, o.Common.RepositoryDir, o.Common.Fork, o.Options, o.Upstream, o.Interval, o.Once, o.MetricsAddress)
+			watchAction.SetMarkerSearch(o.Common.MarkerSearch)
+			return watchAction.Run()
+		},
+	}
+	o.Common.AddFlags(cmd.Flags())
+	cmd.Flags().StringVar(&o.Upstream, "upstream", o.Upstream, "Upstream revision, branch or tag, the carries are checked against, defaults to the upstream branch")
+	cmd.Flags().DurationVar(&o.Interval, "interval", 6*time.Hour, "Interval between checks")
+	cmd.Flags().BoolVar(&o.Once, "once", o.Once, "Check only once and exit")
+	cmd.Flags().StringVar(&o.MetricsAddress, "metrics-address", o.MetricsAddress, "Address (eg. :9090) serving Prometheus metrics of carries, conflicts, upstream drift and check durations on /metrics, disabled if not set")
+	cmd.Flags().IntVar(&o.Mainline, "mainline", o.Mainline, "Parent number used when picking merge commits, merge commits are skipped if not set")
+	cmd.Flags().StringSliceVar(&o.CarriesDirs, "carries-dir", o.CarriesDirs, "Directories holding fixed carries, defaults to carries in the current or the repository directory")
+	cmd.Flags().StringVar(&o.TargetVersion, "target-version", o.TargetVersion, "Kubernetes version being rebased to (eg. v1.31), selects per-version fixed carries directories")
+	cmd.Flags().StringVar(&o.NotifySlack, "notify-slack", o.NotifySlack, "Slack webhook URL notified about newly conflicting carries")
+	cmd.Flags().StringVar(&o.NotifyWebhook, "notify-webhook", o.NotifyWebhook, "Webhook URL receiving JSON notifications about newly conflicting carries")
+	cmd.Flags().StringSliceVar(&o.MailTo, "mail-to", o.MailTo, "Addresses receiving emails about newly conflicting carries, SMTP_USERNAME and SMTP_PASSWORD authenticate to the server")
+	cmd.Flags().StringVar(&o.MailServer, "mail-server", "localhost:25", "SMTP server, as host:port, sending emails with --mail-to")
+	cmd.Flags().StringVar(&o.MailFrom, "mail-from", o.MailFrom, "Sender address of emails sent with --mail-to")
+
+	return cmd
+}
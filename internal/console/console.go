@@ -0,0 +1,206 @@
+package console
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/go-logr/logr"
+	"k8s.io/klog/v2"
+)
+
+// Level controls how much user-facing output is printed.
+type Level int
+
+const (
+	// Quiet prints only errors and results
+	Quiet Level = iota
+	// Normal prints progress of the command and warnings
+	Normal
+	// Verbose additionally prints details, it is selected with -v
+	Verbose
+)
+
+const (
+	warningPrefix = "Warning: "
+	errorPrefix   = "Error: "
+)
+
+var (
+	lock  sync.Mutex
+	level = Normal
+	// output receives messages and klog diagnostics instead of stderr, once set
+	output io.Writer
+)
+
+// Configure selects the level of user-facing output and limits klog, which
+// is meant for diagnostics, to warnings and errors, or only errors when
+// quiet. With -v, klog logs everything, as it is requested explicitly.
+func Configure(quiet bool, flags *flag.FlagSet) error {
+	lock.Lock()
+	defer lock.Unlock()
+	klogFlags = flags
+	switch {
+	case quiet:
+		level = Quiet
+	case klog.V(1).Enabled():
+		level = Verbose
+		return nil
+	default:
+		level = Normal
+	}
+	threshold := "WARNING"
+	if quiet {
+		threshold = "ERROR"
+	}
+	if err := flags.Set("stderrthreshold", threshold); err != nil {
+		return err
+	}
+	klog.LogToStderr(false)
+	// messages below the threshold are dropped rather than written to files
+	klog.SetOutput(io.Discard)
+	return nil
+}
+
+// CurrentLevel returns the selected level.
+func CurrentLevel() Level {
+	lock.Lock()
+	defer lock.Unlock()
+	return level
+}
+
+// Infof prints progress of the command, unless quiet.
+func Infof(format string, args ...interface{}) {
+	printf(context.Background(), Normal, "", format, args...)
+}
+
+// Warningf prints a warning, unless quiet.
+func Warningf(format string, args ...interface{}) {
+	printf(context.Background(), Normal, warningPrefix, format, args...)
+}
+
+// Verbosef prints details, only with -v.
+func Verbosef(format string, args ...interface{}) {
+	printf(context.Background(), Verbose, "", format, args...)
+}
+
+// Errorf prints an error, also when quiet.
+func Errorf(format string, args ...interface{}) {
+	printf(context.Background(), Quiet, errorPrefix, format, args...)
+}
+
+// InfofContext prints progress of a run, unless quiet, where the context of
+// the run directs it.
+func InfofContext(ctx context.Context, format string, args ...interface{}) {
+	printf(ctx, Normal, "", format, args...)
+}
+
+// WarningfContext prints a warning of a run, unless quiet, where the context
+// of the run directs it.
+func WarningfContext(ctx context.Context, format string, args ...interface{}) {
+	printf(ctx, Normal, warningPrefix, format, args...)
+}
+
+// VerbosefContext prints details of a run, only with -v, where the context of
+// the run directs it.
+func VerbosefContext(ctx context.Context, format string, args ...interface{}) {
+	printf(ctx, Verbose, "", format, args...)
+}
+
+// ErrorfContext prints an error of a run, also when quiet, where the context
+// of the run directs it.
+func ErrorfContext(ctx context.Context, format string, args ...interface{}) {
+	printf(ctx, Quiet, errorPrefix, format, args...)
+}
+
+// destinationKey is the context key of the destination of messages
+type destinationKey struct{}
+
+// destination receives messages of a single run, instead of the output or
+// logger of the process
+type destination struct {
+	out    io.Writer
+	logger *logr.Logger
+}
+
+// WithOutput returns a copy of ctx, which directs messages printed with it
+// to w, eg. so that runs of a tool embedding the engine do not print to
+// stderr.
+func WithOutput(ctx context.Context, w io.Writer) context.Context {
+	return context.WithValue(ctx, destinationKey{}, destination{out: w})
+}
+
+// WithLogger returns a copy of ctx, which passes messages printed with it to
+// the logger, eg. one of a tool embedding the engine. Unlike SetLogger, klog
+// diagnostics are left alone.
+func WithLogger(ctx context.Context, l logr.Logger) context.Context {
+	return context.WithValue(ctx, destinationKey{}, destination{logger: &l})
+}
+
+// SetOutput writes messages and klog diagnostics to w instead of stderr, eg.
+// into the log file of the terminal UI, which owns the screen. klog no longer
+// writes anything to stderr afterwards, diagnostics go to the log file of the
+// run once it is started.
+func SetOutput(w io.Writer) error {
+	lock.Lock()
+	defer lock.Unlock()
+	output = w
+	if klogFlags != nil {
+		for name, value := range map[string]string{"stderrthreshold": "FATAL", "alsologtostderr": "false"} {
+			if err := klogFlags.Set(name, value); err != nil {
+				return err
+			}
+		}
+	}
+	klog.LogToStderr(false)
+	if logFile == nil {
+		klog.SetOutput(w)
+	}
+	return nil
+}
+
+// contextDestination returns the destination of messages of the context,
+// when there is one
+func contextDestination(ctx context.Context) (destination, bool) {
+	if ctx == nil {
+		return destination{}, false
+	}
+	d, ok := ctx.Value(destinationKey{}).(destination)
+	return d, ok
+}
+
+// currentOutput returns the writer of messages, stderr unless redirected
+func currentOutput() io.Writer {
+	lock.Lock()
+	defer lock.Unlock()
+	if output == nil {
+		return os.Stderr
+	}
+	return output
+}
+
+// printf writes a line to stderr, stdout is left for results, or to the
+// output set by SetOutput, or passes it to the logger, when one is set. The
+// destination of the context takes precedence over both.
+func printf(ctx context.Context, minimum Level, prefix, format string, args ...interface{}) {
+	writeLog(prefix, format, args...)
+	if CurrentLevel() < minimum {
+		return
+	}
+	if d, ok := contextDestination(ctx); ok {
+		if d.logger != nil {
+			logMessage(d.logger, minimum, prefix, fmt.Sprintf(format, args...))
+			return
+		}
+		fmt.Fprintf(d.out, prefix+format+"\n", args...)
+		return
+	}
+	if l := currentLogger(); l != nil {
+		logMessage(l, minimum, prefix, fmt.Sprintf(format, args...))
+		return
+	}
+	fmt.Fprintf(currentOutput(), prefix+format+"\n", args...)
+}
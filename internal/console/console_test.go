@@ -0,0 +1,40 @@
+package console
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestContextDestination(t *testing.T) {
+	tests := []struct {
+		name     string
+		context  func(out *bytes.Buffer) context.Context
+		expected string
+	}{
+		{
+			name: "output of the run",
+			context: func(out *bytes.Buffer) context.Context {
+				return WithOutput(context.Background(), out)
+			},
+			expected: "Warning: carry abc conflicts\n",
+		},
+		{
+			name: "logger of the run",
+			context: func(out *bytes.Buffer) context.Context {
+				return WithLogger(context.Background(), NewJSONLogger(out))
+			},
+			expected: `"level":"warning","msg":"carry abc conflicts"`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var out bytes.Buffer
+			WarningfContext(test.context(&out), "carry %s conflicts", "abc")
+			if !strings.Contains(out.String(), test.expected) {
+				t.Errorf("expected %q, got %q", test.expected, out.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package console
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"k8s.io/klog/v2"
+)
+
+// logVerbosity makes klog log executed git commands and their output, so
+// that the log file is complete
+const logVerbosity = 3
+
+var (
+	logFile   *os.File
+	klogFlags interface {
+		Set(name, value string) error
+	}
+)
+
+// StartLog writes a timestamped log of the run to the file, in addition to
+// the console: klog diagnostics including every executed git command and
+// its output, and all user-facing messages regardless of the level. Only one
+// log is written, later calls are ignored. When a logger is set, klog
+// diagnostics are written to both the logger and the file, limited by -v, so
+// that the logger does not receive every git command.
+func StartLog(path string) error {
+	lock.Lock()
+	defer lock.Unlock()
+	if logFile != nil || klogFlags == nil {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("Error creating log directory: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("Error opening log file: %w", err)
+	}
+	if logger != nil {
+		logFile = f
+		return nil
+	}
+	if !klog.V(logVerbosity).Enabled() {
+		if err := klogFlags.Set("v", strconv.Itoa(logVerbosity)); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	if level == Verbose && output == nil {
+		// diagnostics were requested explicitly, keep them on stderr
+		if err := klogFlags.Set("alsologtostderr", "true"); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	klog.LogToStderr(false)
+	klog.SetOutput(f)
+	logFile = f
+	return nil
+}
+
+// LogPath returns the path of a new log file of the command in the directory.
+func LogPath(dir, command string) string {
+	return filepath.Join(dir, fmt.Sprintf("%s-%s.log", command, time.Now().UTC().Format("20060102T150405Z")))
+}
+
+// LogFile returns the path of the log file, or empty when none is written.
+func LogFile() string {
+	lock.Lock()
+	defer lock.Unlock()
+	if logFile == nil {
+		return ""
+	}
+	return logFile.Name()
+}
+
+// currentLogFile returns the log file, nil when none is written
+func currentLogFile() *os.File {
+	lock.Lock()
+	defer lock.Unlock()
+	return logFile
+}
+
+// writeLog appends a user-facing message to the log file
+func writeLog(prefix, format string, args ...interface{}) {
+	lock.Lock()
+	defer lock.Unlock()
+	if logFile == nil {
+		return
+	}
+	fmt.Fprintf(logFile, "%s console] %s\n", time.Now().Format("0102 15:04:05.000000"), fmt.Sprintf(prefix+format, args...))
+}
@@ -0,0 +1,185 @@
+package console
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// LogFormatText prints messages as plain text and klog diagnostics in the klog format
+	LogFormatText = "text"
+	// LogFormatJSON logs messages and diagnostics as JSON objects, one per line
+	LogFormatJSON = "json"
+)
+
+// logger receives user-facing messages and klog diagnostics instead of
+// stderr, once set
+var logger *logr.Logger
+
+// SetLogger routes user-facing messages and klog diagnostics to the logger,
+// eg. one of a tool embedding the rebase engine. Messages are still limited
+// by the selected level and diagnostics by -v, warnings are logged with
+// level=warning, and diagnostics with the caller logging them. Diagnostics
+// are also written to the log file, once started.
+func SetLogger(l logr.Logger) {
+	lock.Lock()
+	defer lock.Unlock()
+	logger = &l
+	klog.SetLoggerWithOptions(l, klog.WriteKlogBuffer(func(line []byte) {
+		// the file is looked up apart from writing, klog calls this holding
+		// its own lock
+		if f := currentLogFile(); f != nil {
+			f.Write(line)
+		}
+		logDiagnostic(l, line)
+	}))
+}
+
+// HasLogger returns true when messages are passed to a logger set by
+// SetLogger, rather than printed.
+func HasLogger() bool {
+	return currentLogger() != nil
+}
+
+// currentLogger returns the logger set by SetLogger, nil when messages are
+// printed to stderr
+func currentLogger() *logr.Logger {
+	lock.Lock()
+	defer lock.Unlock()
+	return logger
+}
+
+// logDiagnostic passes a klog line to the logger, the severity and the
+// caller are read from the header of the line, eg. W0102 15:04:05.000000 1 apply.go:10] message
+func logDiagnostic(l logr.Logger, line []byte) {
+	text := strings.TrimSuffix(string(line), "\n")
+	header, message, found := strings.Cut(text, "] ")
+	fields := strings.Fields(header)
+	if !found || len(fields) == 0 {
+		l.Info(text)
+		return
+	}
+	caller := fields[len(fields)-1]
+	switch header[0] {
+	case 'E', 'F':
+		l.Error(nil, message, "caller", caller)
+	case 'W':
+		l.Info(message, "level", "warning", "caller", caller)
+	default:
+		l.Info(message, "caller", caller)
+	}
+}
+
+// logMessage passes a user-facing message to the logger
+func logMessage(l *logr.Logger, minimum Level, prefix, message string) {
+	switch {
+	case prefix == errorPrefix:
+		l.Error(nil, message)
+	case prefix == warningPrefix:
+		l.Info(message, "level", "warning")
+	case minimum == Verbose:
+		l.V(1).Info(message)
+	default:
+		l.Info(message)
+	}
+}
+
+// NewJSONLogger returns a logger writing every message to w as a JSON object
+// on its own line, with the time, level, verbosity, message, error and the
+// key and value pairs of the message.
+func NewJSONLogger(w io.Writer) logr.Logger {
+	return logr.New(&jsonSink{out: w, lock: &sync.Mutex{}})
+}
+
+// jsonSink is the logr sink of NewJSONLogger, verbosity is left to klog and
+// the console level
+type jsonSink struct {
+	out    io.Writer
+	lock   *sync.Mutex
+	name   string
+	values []interface{}
+}
+
+func (s *jsonSink) Init(logr.RuntimeInfo) {}
+
+func (s *jsonSink) Enabled(int) bool {
+	return true
+}
+
+func (s *jsonSink) Info(level int, msg string, keysAndValues ...interface{}) {
+	s.write("info", level, msg, nil, keysAndValues)
+}
+
+func (s *jsonSink) Error(err error, msg string, keysAndValues ...interface{}) {
+	s.write("error", 0, msg, err, keysAndValues)
+}
+
+func (s *jsonSink) WithValues(keysAndValues ...interface{}) logr.LogSink {
+	values := append(append([]interface{}{}, s.values...), keysAndValues...)
+	return &jsonSink{out: s.out, lock: s.lock, name: s.name, values: values}
+}
+
+func (s *jsonSink) WithName(name string) logr.LogSink {
+	if len(s.name) > 0 {
+		name = s.name + "/" + name
+	}
+	return &jsonSink{out: s.out, lock: s.lock, name: name, values: s.values}
+}
+
+// write marshals the message, key and value pairs of the message override
+// the ones of the logger and the default level
+func (s *jsonSink) write(level string, verbosity int, msg string, err error, keysAndValues []interface{}) {
+	entry := map[string]interface{}{
+		"ts":    time.Now().UTC().Format(time.RFC3339Nano),
+		"level": level,
+		// klog passes formatted lines
+		"msg": strings.TrimSuffix(msg, "\n"),
+	}
+	if verbosity > 0 {
+		entry["v"] = verbosity
+	}
+	if len(s.name) > 0 {
+		entry["logger"] = s.name
+	}
+	if err != nil {
+		entry["error"] = err.Error()
+	}
+	for _, pairs := range [][]interface{}{s.values, keysAndValues} {
+		for i := 0; i+1 < len(pairs); i += 2 {
+			entry[fmt.Sprint(pairs[i])] = jsonValue(pairs[i+1])
+		}
+	}
+	var line bytes.Buffer
+	encoder := json.NewEncoder(&line)
+	// messages hold arrows and URLs, which are kept readable
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(entry); err != nil {
+		line.Reset()
+		_ = encoder.Encode(map[string]string{"level": "error", "msg": fmt.Sprintf("Error marshalling log entry %q: %v", msg, err)})
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.out.Write(line.Bytes())
+}
+
+// jsonValue returns the value, or its text when it is not marshalled as JSON
+func jsonValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case error:
+		return v.Error()
+	case fmt.Stringer:
+		return v.String()
+	}
+	if _, err := json.Marshal(value); err != nil {
+		return fmt.Sprint(value)
+	}
+	return value
+}
@@ -0,0 +1,95 @@
+package container
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"k8s.io/klog/v2"
+)
+
+const (
+	// EnabledEnv enables the container mode without passing --container,
+	// it is set in the container image
+	EnabledEnv = "OPENSHIFT_REBASE_CONTAINER"
+	// SecretsDir is where credentials mounted from secrets are looked up
+	SecretsDir = "/var/run/secrets/openshift-rebase"
+
+	defaultName  = "OpenShift Rebase"
+	defaultEmail = "openshift-rebase@localhost"
+)
+
+// credentials are read from <NAME>_FILE or from SecretsDir when not set in
+// the environment
+var credentials = []string{"GITHUB_TOKEN", "JIRA_TOKEN", "SMTP_PASSWORD"}
+
+// Requested returns true when the container mode is enabled by the environment.
+func Requested() bool {
+	enabled, _ := strconv.ParseBool(os.Getenv(EnabledEnv))
+	return enabled
+}
+
+// Setup prepares the process for running inside of a container with the
+// repository mounted: git runs without any prompts and ignores the system
+// and global configuration, which belong to the image rather than the user,
+// and credentials are read from mounted secret files.
+func Setup() error {
+	env := map[string]string{
+		"GIT_CONFIG_NOSYSTEM": "1",
+		"GIT_CONFIG_GLOBAL":   os.DevNull,
+		"GIT_TERMINAL_PROMPT": "0",
+		"GIT_EDITOR":          "true",
+		// the mounted repository is usually owned by a different user
+		"GIT_CONFIG_COUNT":   "1",
+		"GIT_CONFIG_KEY_0":   "safe.directory",
+		"GIT_CONFIG_VALUE_0": "*",
+	}
+	for name, value := range env {
+		if err := os.Setenv(name, value); err != nil {
+			return err
+		}
+	}
+	identity := map[string]string{
+		"GIT_AUTHOR_NAME":     defaultName,
+		"GIT_AUTHOR_EMAIL":    defaultEmail,
+		"GIT_COMMITTER_NAME":  defaultName,
+		"GIT_COMMITTER_EMAIL": defaultEmail,
+	}
+	for name, value := range identity {
+		if len(os.Getenv(name)) > 0 {
+			continue
+		}
+		if err := os.Setenv(name, value); err != nil {
+			return err
+		}
+	}
+	for _, name := range credentials {
+		if err := loadCredential(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadCredential sets the credential env variable from <NAME>_FILE, or from
+// a file named after the lowercase variable in SecretsDir
+func loadCredential(name string) error {
+	if len(os.Getenv(name)) > 0 {
+		return nil
+	}
+	path := os.Getenv(name + "_FILE")
+	if len(path) == 0 {
+		path = filepath.Join(SecretsDir, strings.ToLower(name))
+		if _, err := os.Stat(path); err != nil {
+			return nil
+		}
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("Error reading %s from %s: %w", name, path, err)
+	}
+	klog.V(2).Infof("Using %s from %s", name, path)
+	return os.Setenv(name, strings.TrimSpace(string(data)))
+}
@@ -0,0 +1,83 @@
+package credentials
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+
+	"k8s.io/klog/v2"
+)
+
+// Host is the host credentials are looked up for
+const Host = "github.com"
+
+// TokenEnvs are the environment variables holding a github token, in the
+// order of precedence
+var TokenEnvs = []string{"GITHUB_TOKEN", "GH_TOKEN"}
+
+var (
+	once  sync.Once
+	token string
+)
+
+// GitHubToken returns the github token from the environment, or from the git
+// credential helpers configured for github.com, eg. by 'gh auth setup-git'.
+// Empty is returned when there is none, the lookup is done only once.
+func GitHubToken() string {
+	once.Do(func() {
+		if token = envToken(); len(token) > 0 {
+			return
+		}
+		token = helperToken()
+	})
+	return token
+}
+
+// envToken returns the token from the first set variable of TokenEnvs
+func envToken() string {
+	for _, name := range TokenEnvs {
+		if value := os.Getenv(name); len(value) > 0 {
+			klog.V(3).Infof("Using github token from %s", name)
+			return value
+		}
+	}
+	return ""
+}
+
+// helperToken asks the configured git credential helpers for a password of
+// github.com, without prompting the user
+func helperToken() string {
+	cmd := exec.Command("git", "credential", "fill")
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0", "GIT_ASKPASS=true", "SSH_ASKPASS=true")
+	cmd.Stdin = strings.NewReader("protocol=https\nhost=" + Host + "\n\n")
+	output, err := cmd.Output()
+	if err != nil {
+		klog.V(3).Infof("No github token from git credential helpers: %v", err)
+		return ""
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		if password, ok := strings.CutPrefix(scanner.Text(), "password="); ok && len(password) > 0 {
+			klog.V(3).Infof("Using github token from git credential helpers")
+			return password
+		}
+	}
+	return ""
+}
+
+// GitArgs returns git options which make git authenticate https requests to
+// github.com with the token from the environment. The token itself is not
+// part of the arguments, it is read by the helper from the environment.
+// Credential helpers configured by the user are consulted first.
+func GitArgs() []string {
+	for _, name := range TokenEnvs {
+		if len(os.Getenv(name)) > 0 {
+			helper := `!f() { test "$1" = get && echo username=x-access-token && echo "password=$` + name + `"; }; f`
+			return []string{"-c", "credential.https://" + Host + ".helper=" + helper}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,249 @@
+// Package doctor diagnoses the environment the tool runs in, the first thing
+// to run when a problem is reported.
+package doctor
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/openshift/rebase/internal/apply"
+	"github.com/openshift/rebase/internal/bump"
+	"github.com/openshift/rebase/internal/credentials"
+	"github.com/openshift/rebase/internal/fork"
+	"github.com/openshift/rebase/internal/git"
+	"github.com/openshift/rebase/internal/jira"
+	"github.com/openshift/rebase/internal/options"
+	"github.com/openshift/rebase/internal/output"
+	"github.com/openshift/rebase/internal/preflight"
+	"github.com/openshift/rebase/internal/table"
+)
+
+// Status is the outcome of a check.
+type Status string
+
+const (
+	// StatusPass is a check which found no problem
+	StatusPass Status = "pass"
+	// StatusWarn is a check which found a problem limiting some features
+	StatusWarn Status = "warn"
+	// StatusFail is a check which found a problem breaking runs
+	StatusFail Status = "fail"
+	// StatusSkip is a check which could not run, since an earlier one failed
+	StatusSkip Status = "skip"
+)
+
+// Check is the outcome of a single check of the environment.
+type Check struct {
+	Name    string `json:"name"`
+	Status  Status `json:"status"`
+	Message string `json:"message,omitempty"`
+	// Hint tells how to fix the problem
+	Hint string `json:"hint,omitempty"`
+}
+
+// hints of the problems found by preflight checks, in addition to the ones
+// in their messages
+var preflightHints = map[string]string{
+	preflight.CheckWorktree:   "apply starts only from a clean working tree",
+	preflight.CheckInProgress: "use 'rebase continue' or 'rebase rollback' when it is a stopped run",
+	preflight.CheckDiskSpace:  "free space on the disk of the git directory, eg. by running git gc",
+	preflight.CheckRemotes:    "eg. with ssh -T git@github.com for ssh remotes",
+}
+
+// Doctor checks git, the repository, credentials, scripts of the repository
+// and the configuration of the tool.
+type Doctor struct {
+	repositoryDir string
+	fork          fork.Fork
+	configPath    string
+	output        string
+}
+
+// NewDoctor returns the checks of the repository and the config file, printed
+// in the output format, either json, yaml or empty for a table.
+func NewDoctor(repositoryDir string, f fork.Fork, configPath, output string) *Doctor {
+	return &Doctor{
+		repositoryDir: repositoryDir,
+		fork:          f,
+		configPath:    configPath,
+		output:        output,
+	}
+}
+
+func (d *Doctor) Run() error {
+	checks := d.Checks()
+	if output.IsMachine(d.output) {
+		if err := output.Write(os.Stdout, d.output, checks); err != nil {
+			return err
+		}
+	} else {
+		t := table.New(os.Stdout, "CHECK", "STATUS", "DETAILS")
+		for _, c := range checks {
+			details := c.Message
+			if len(c.Hint) > 0 {
+				details += " - " + c.Hint
+			}
+			t.AddRow(statusColor(c.Status), c.Name, string(c.Status), details)
+		}
+		if err := t.Flush(); err != nil {
+			return err
+		}
+	}
+	failed := 0
+	for _, c := range checks {
+		if c.Status == StatusFail {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d checks failed", failed, len(checks))
+	}
+	return nil
+}
+
+// statusColor colors passed checks green, warnings yellow, failures red and
+// skipped checks grey
+func statusColor(status Status) table.Color {
+	switch status {
+	case StatusPass:
+		return table.Green
+	case StatusWarn:
+		return table.Yellow
+	case StatusSkip:
+		return table.Grey
+	}
+	return table.Red
+}
+
+// Checks runs all checks, the ones needing the repository are skipped when
+// it cannot be opened.
+func (d *Doctor) Checks() []Check {
+	checks := []Check{checkGitVersion()}
+	repository, err := git.OpenGit(d.repositoryDir, d.fork)
+	checks = append(checks, checkRepository(d.repositoryDir, d.fork, err))
+	for _, name := range []string{preflight.CheckWorktree, preflight.CheckInProgress, preflight.CheckDiskSpace, preflight.CheckRemotes} {
+		if err != nil {
+			checks = append(checks, Check{Name: name, Status: StatusSkip, Message: "the repository could not be opened"})
+			continue
+		}
+		checks = append(checks, checkPreflight(repository, d.fork, name))
+	}
+	return append(checks,
+		checkGitHubToken(),
+		checkJiraToken(),
+		checkScripts(d.repositoryDir),
+		checkConfig(d.configPath),
+	)
+}
+
+func checkGitVersion() Check {
+	check := Check{Name: preflight.CheckGitVersion}
+	version, err := git.Version()
+	switch {
+	case err != nil:
+		check.Status, check.Message = StatusFail, fmt.Sprintf("running git failed: %v", err)
+		check.Hint = fmt.Sprintf("install git %s or newer", preflight.MinGitVersion)
+	case preflight.CompareVersions(version, preflight.MinGitVersion) < 0:
+		check.Status, check.Message = StatusFail, fmt.Sprintf("git %s is older than the required %s", version, preflight.MinGitVersion)
+		check.Hint = fmt.Sprintf("install git %s or newer", preflight.MinGitVersion)
+	case preflight.CompareVersions(version, preflight.MinGitVersionMergeTree) < 0:
+		check.Status, check.Message = StatusWarn, fmt.Sprintf("git %s does not simulate picks in memory", version)
+		check.Hint = fmt.Sprintf("install git %s or newer to use apply --preflight", preflight.MinGitVersionMergeTree)
+	default:
+		check.Status, check.Message = StatusPass, fmt.Sprintf("git %s", version)
+	}
+	return check
+}
+
+// checkRepository reports the error opening the repository, which checks that
+// the remotes of the fork are configured
+func checkRepository(repositoryDir string, current fork.Fork, err error) Check {
+	if err != nil {
+		return Check{
+			Name:    "repository",
+			Status:  StatusFail,
+			Message: fmt.Sprintf("opening %s failed: %v", repositoryDir, err),
+			Hint: fmt.Sprintf("run in the repository or pass --repository, the %s remote has to point to %s and the %s remote to %s, or pass --upstream-remote and --openshift-remote",
+				current.UpstreamRemote(), current.Upstream.URL(), current.OpenShiftRemote(), current.OpenShift.URL()),
+		}
+	}
+	return Check{
+		Name:    "repository",
+		Status:  StatusPass,
+		Message: fmt.Sprintf("%s, fork %s with %s and %s remotes", repositoryDir, current.Name, current.UpstreamRemote(), current.OpenShiftRemote()),
+	}
+}
+
+func checkPreflight(repository git.Git, f fork.Fork, name string) Check {
+	problems := preflight.RunCheck(repository, name, preflight.Options{Fork: f})
+	if len(problems) == 0 {
+		return Check{Name: name, Status: StatusPass}
+	}
+	messages := make([]string, 0, len(problems))
+	for _, p := range problems {
+		messages = append(messages, p.Message)
+	}
+	return Check{Name: name, Status: StatusFail, Message: strings.Join(messages, "; "), Hint: preflightHints[name]}
+}
+
+func checkGitHubToken() Check {
+	check := Check{Name: "github-token", Status: StatusPass, Message: "found"}
+	if len(credentials.GitHubToken()) == 0 {
+		check.Status, check.Message = StatusWarn, "no github token, api requests are limited and pull requests cannot be opened"
+		check.Hint = fmt.Sprintf("set %s or configure a git credential helper for %s, eg. with gh auth setup-git", strings.Join(credentials.TokenEnvs, " or "), credentials.Host)
+	}
+	return check
+}
+
+func checkJiraToken() Check {
+	check := Check{Name: "jira-token", Status: StatusPass, Message: "found"}
+	if len(os.Getenv(jira.TokenEnv)) == 0 {
+		check.Status, check.Message = StatusWarn, "no jira token, --jira reads only public bugs"
+		check.Hint = fmt.Sprintf("set %s", jira.TokenEnv)
+	}
+	return check
+}
+
+// checkScripts checks that the scripts run by default by apply --regenerate
+// and bump are present and executable in the repository
+func checkScripts(repositoryDir string) Check {
+	var missing []string
+	for _, script := range append(apply.DefaultGeneratorCommands(), bump.DefaultUpdateCommand) {
+		info, err := os.Stat(filepath.Join(repositoryDir, filepath.FromSlash(script)))
+		if err != nil || info.Mode()&0111 == 0 {
+			missing = append(missing, script)
+		}
+	}
+	if len(missing) > 0 {
+		return Check{
+			Name:    "scripts",
+			Status:  StatusWarn,
+			Message: fmt.Sprintf("%s missing or not executable", strings.Join(missing, ", ")),
+			Hint:    "apply --regenerate and bump run them by default, pass --generator and --update-command when the repository has other scripts",
+		}
+	}
+	return Check{Name: "scripts", Status: StatusPass}
+}
+
+func checkConfig(path string) Check {
+	check := Check{Name: "config", Status: StatusPass}
+	if len(path) == 0 {
+		check.Message = "no config file"
+		return check
+	}
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		check.Message = fmt.Sprintf("no config file at %s", path)
+		return check
+	}
+	config, err := options.LoadConfig(path, true)
+	if err != nil {
+		check.Status, check.Message = StatusFail, err.Error()
+		check.Hint = "fix the YAML of the config file, or pass another one with --config"
+		return check
+	}
+	check.Message = fmt.Sprintf("%s with %d flags, %d commands and %d environment variables", path, len(config.Flags), len(config.Commands), len(config.Env))
+	return check
+}
@@ -0,0 +1,247 @@
+// Package e2e builds small synthetic upstream and fork repositories with real
+// git, and drives apply and verify against them, so that the rebase logic can
+// be exercised end to end without a kubernetes clone or network access.
+package e2e
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/openshift/rebase/internal/apply"
+	"github.com/openshift/rebase/internal/fork"
+	"github.com/openshift/rebase/internal/preflight"
+	"github.com/openshift/rebase/internal/report"
+	"github.com/openshift/rebase/internal/state"
+	"github.com/openshift/rebase/internal/verify"
+)
+
+const (
+	// upstreamBranch holds the upstream history, published as the upstream remote branch
+	upstreamBranch = "upstream"
+	// forkBranch holds the fork history, published as the openshift remote branch
+	forkBranch = "fork"
+	// workBranch is checked out when a run starts
+	workBranch = "work"
+	// carriesDir is the directory, in the git directory, holding fixed
+	// carries of runs, so that they are not looked up in the current directory
+	carriesDir = "e2e-carries"
+)
+
+// Files maps paths to their content, a file with empty content is removed.
+type Files map[string]string
+
+// Repository is a synthetic repository of a fork, holding the
+// upstream history and the fork history with carries on top of it. Both are
+// published as the remote branches the tool reads, without any remotes
+// being reachable.
+type Repository struct {
+	// Dir is the working tree of the repository
+	Dir string
+	// fork is the fork the repository belongs to
+	fork fork.Fork
+	// clock is the date of the last commit, every commit is a second later,
+	// so that the order of commits is always the same
+	clock time.Time
+}
+
+// New initializes a repository of the fork in dir with an upstream base
+// commit tagged with tag, which the fork branch starts from.
+func New(dir, tag string, f fork.Fork) (*Repository, error) {
+	r := &Repository{Dir: dir, fork: f, clock: time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC)}
+	current := f
+	for _, args := range [][]string{
+		{"init", "--quiet", "--initial-branch", upstreamBranch},
+		{"config", "user.name", "e2e"},
+		{"config", "user.email", "e2e@example.com"},
+		{"remote", "add", current.UpstreamRemote(), current.Upstream.SSHURL()},
+		{"remote", "add", current.OpenShiftRemote(), current.OpenShift.SSHURL()},
+	} {
+		if _, err := r.Git(args...); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := r.Upstream("upstream base", Files{"README.md": "base\n"}); err != nil {
+		return nil, err
+	}
+	if _, err := r.Git("tag", "--annotate", "--message", tag, tag); err != nil {
+		return nil, err
+	}
+	if _, err := r.Git("branch", forkBranch); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(r.CarriesDir(), 0755); err != nil {
+		return nil, err
+	}
+	return r, r.publish(forkBranch, current.OpenShiftRemote(), current.OpenShift.Branch)
+}
+
+// CarriesDir returns the directory holding fixed carries of runs, which don't
+// set carries directories, eg. additional/0001-fix.patch.
+func (r *Repository) CarriesDir() string {
+	return filepath.Join(r.Dir, ".git", carriesDir)
+}
+
+// Git runs git in the repository and returns its trimmed output.
+func (r *Repository) Git(args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = r.Dir
+	date := r.clock.Format(time.RFC3339)
+	cmd.Env = append(os.Environ(), "GIT_AUTHOR_DATE="+date, "GIT_COMMITTER_DATE="+date)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("git %s failed: %w: %s", strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// Upstream commits the files to the upstream branch and returns the SHA.
+func (r *Repository) Upstream(message string, files Files) (string, error) {
+	return r.commitOn(upstreamBranch, message, files, r.fork.UpstreamRemote(), r.fork.Upstream.Branch)
+}
+
+// Marker merges the upstream branch into the fork with the rebase marker of
+// the fork, carries committed afterwards are picked by apply.
+func (r *Repository) Marker() (string, error) {
+	if _, err := r.Git("checkout", "--quiet", forkBranch); err != nil {
+		return "", err
+	}
+	r.clock = r.clock.Add(time.Second)
+	message := r.fork.RebaseMarker() + " rebase"
+	if _, err := r.Git("merge", "--quiet", "--strategy", "ours", "--no-edit", "--allow-unrelated-histories", "--message", message, upstreamBranch); err != nil {
+		return "", err
+	}
+	return r.published(forkBranch, r.fork.OpenShiftRemote(), r.fork.OpenShift.Branch)
+}
+
+// Carry commits the files to the fork as an UPSTREAM commit with the action,
+// eg. <carry>, <drop> or a pull request number, and returns the SHA.
+func (r *Repository) Carry(action, summary string, files Files) (string, error) {
+	return r.Fork(fmt.Sprintf("UPSTREAM: %s: %s", action, summary), files)
+}
+
+// Fork commits the files to the fork with the message and returns the SHA.
+func (r *Repository) Fork(message string, files Files) (string, error) {
+	return r.commitOn(forkBranch, message, files, r.fork.OpenShiftRemote(), r.fork.OpenShift.Branch)
+}
+
+// commitOn commits the files to the branch, which is published as the
+// remote branch
+func (r *Repository) commitOn(branch, message string, files Files, remote, remoteBranch string) (string, error) {
+	if _, err := r.Git("checkout", "--quiet", branch); err != nil {
+		// the upstream branch is unborn until the first commit
+		if _, headErr := r.Git("rev-parse", "--verify", "HEAD"); headErr == nil {
+			return "", err
+		}
+	}
+	paths := make([]string, 0, len(files))
+	for path := range files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		full := filepath.Join(r.Dir, path)
+		if len(files[path]) == 0 {
+			if err := os.Remove(full); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return "", err
+			}
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+			return "", err
+		}
+		if err := os.WriteFile(full, []byte(files[path]), 0644); err != nil {
+			return "", err
+		}
+	}
+	if _, err := r.Git("add", "--all"); err != nil {
+		return "", err
+	}
+	r.clock = r.clock.Add(time.Second)
+	if _, err := r.Git("commit", "--quiet", "--allow-empty", "--message", message); err != nil {
+		return "", err
+	}
+	return r.published(branch, remote, remoteBranch)
+}
+
+// published publishes the branch and returns its SHA
+func (r *Repository) published(branch, remote, remoteBranch string) (string, error) {
+	if err := r.publish(branch, remote, remoteBranch); err != nil {
+		return "", err
+	}
+	return r.Git("rev-parse", branch)
+}
+
+// publish points the remote branch to the branch, as if it was fetched
+func (r *Repository) publish(branch, remote, remoteBranch string) error {
+	_, err := r.Git("update-ref", fmt.Sprintf("refs/remotes/%s/%s", remote, remoteBranch), branch)
+	return err
+}
+
+// Checkout checks out a new work branch at the fork, where runs start from.
+func (r *Repository) Checkout() error {
+	_, err := r.Git("checkout", "--quiet", "-B", workBranch, forkBranch)
+	return err
+}
+
+// Apply runs apply from the tag with the options and returns the state of the
+// run, which holds its report, along with the error which stopped it. The
+// remotes are not checked, since they are not reachable.
+func (r *Repository) Apply(from string, options apply.Options) (*state.State, error) {
+	if err := r.Checkout(); err != nil {
+		return nil, err
+	}
+	options.SkipChecks = append(options.SkipChecks, preflight.CheckRemotes)
+	if len(options.CarriesDirs) == 0 {
+		options.CarriesDirs = []string{r.CarriesDir()}
+	}
+	runErr := apply.NewApply(from, r.Dir, r.fork, options).Run()
+	return r.state(runErr)
+}
+
+// Continue resumes the stopped run, once its conflicts are resolved, and
+// returns the state of the run along with the error which stopped it.
+func (r *Repository) Continue() (*state.State, error) {
+	runErr := apply.NewContinue(r.Dir, r.fork).Run()
+	return r.state(runErr)
+}
+
+// state loads the state of the last run, returning the error of the run
+func (r *Repository) state(runErr error) (*state.State, error) {
+	gitDir, err := r.Git("rev-parse", "--absolute-git-dir")
+	if err != nil {
+		return nil, err
+	}
+	runState, err := state.Load(gitDir)
+	if err != nil {
+		return nil, errors.Join(runErr, err)
+	}
+	return runState, runErr
+}
+
+// Verify verifies the branch, or the checked out branch when empty, from
+// the tag and returns the findings.
+func (r *Repository) Verify(from string, options verify.Options) (*verify.Result, error) {
+	return verify.NewVerify(from, r.Dir, r.fork, options).Check()
+}
+
+// Dispositions returns the disposition of every commit in the report of the
+// run by the summary of the commit, eg. "UPSTREAM: <carry>: add d" -> picked.
+func Dispositions(runState *state.State) map[string]report.Disposition {
+	dispositions := make(map[string]report.Disposition)
+	if runState == nil || runState.Report == nil {
+		return dispositions
+	}
+	for _, entry := range runState.Report.Entries {
+		dispositions[entry.Message] = entry.Disposition
+	}
+	return dispositions
+}
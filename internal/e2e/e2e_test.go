@@ -0,0 +1,97 @@
+package e2e
+
+import (
+	"os/exec"
+	"testing"
+
+	"github.com/openshift/rebase/internal/apply"
+	"github.com/openshift/rebase/internal/fork"
+	"github.com/openshift/rebase/internal/report"
+	"github.com/openshift/rebase/internal/state"
+	"github.com/openshift/rebase/internal/verify"
+)
+
+func TestApplyContinueVerify(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	r, err := New(t.TempDir(), "v1.0.0", fork.Kubernetes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the previous rebase carried f, which upstream does not have
+	if _, err := r.Fork("openshift: add f", Files{"f.txt": "f\n"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Upstream("upstream change", Files{"b.txt": "upstream\n"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Marker(); err != nil {
+		t.Fatal(err)
+	}
+	for _, carry := range []struct {
+		action, summary string
+		files           Files
+	}{
+		{action: "<carry>", summary: "add d", files: Files{"d.txt": "carry\n"}},
+		{action: "<drop>", summary: "drop me", files: Files{"e.txt": "drop\n"}},
+		{action: "<carry>", summary: "modify deleted f", files: Files{"f.txt": "fork\n"}},
+	} {
+		if _, err := r.Carry(carry.action, carry.summary, carry.files); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := r.Upstream("upstream change 2", Files{"b.txt": "upstream 2\n"}); err != nil {
+		t.Fatal(err)
+	}
+
+	runState, err := r.Apply("v1.0.0", apply.Options{})
+	if err == nil {
+		t.Fatal("expected the run to stop on the conflict")
+	}
+	checkDispositions(t, runState, map[string]report.Disposition{
+		"UPSTREAM: <carry>: add d":            report.Picked,
+		"UPSTREAM: <drop>: drop me":           report.Dropped,
+		"UPSTREAM: <carry>: modify deleted f": report.Failed,
+	})
+
+	// resolve the conflict by keeping the file of the fork
+	if _, err := r.Git("add", "f.txt"); err != nil {
+		t.Fatal(err)
+	}
+	runState, err = r.Continue()
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkDispositions(t, runState, map[string]report.Disposition{
+		"UPSTREAM: <carry>: add d":            report.Picked,
+		"UPSTREAM: <drop>: drop me":           report.Dropped,
+		"UPSTREAM: <carry>: modify deleted f": report.Manual,
+	})
+
+	result, err := r.Verify("v1.0.0", verify.Options{Branch: runState.Branch})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Checks) == 0 {
+		t.Error("expected verify to run checks")
+	}
+	for _, finding := range result.Findings {
+		t.Errorf("unexpected finding of %s check on %s: %s", finding.Check, finding.Commit, finding.Message)
+	}
+}
+
+// checkDispositions compares dispositions in the report of the run by the
+// summaries of the commits
+func checkDispositions(t *testing.T, runState *state.State, expected map[string]report.Disposition) {
+	t.Helper()
+	actual := Dispositions(runState)
+	for summary, disposition := range expected {
+		if actual[summary] != disposition {
+			t.Errorf("expected %q to be %s, got %q", summary, disposition, actual[summary])
+		}
+	}
+	if len(actual) != len(expected) {
+		t.Errorf("expected %d commits in the report, got %d: %v", len(expected), len(actual), actual)
+	}
+}
@@ -0,0 +1,300 @@
+package fork
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// StageBackports is picking additional upstream backports
+	StageBackports = "backports"
+	// StageAdditional is applying additional carries
+	StageAdditional = "additional"
+)
+
+// stages are the optional stages of apply, run after picking carries
+var stages = []string{StageBackports, StageAdditional}
+
+// Repository describes a github repository and its main branch.
+type Repository struct {
+	Owner  string `json:"owner"`
+	Repo   string `json:"repo"`
+	Branch string `json:"branch"`
+}
+
+// GitHubHost hosts the upstream and openshift repositories.
+const GitHubHost = "github.com"
+
+// URL returns the github URL of the repository.
+func (r Repository) URL() string {
+	return fmt.Sprintf("https://%s/%s/%s", GitHubHost, r.Owner, r.Repo)
+}
+
+// SSHURL returns the URL of the repository for cloning over ssh.
+func (r Repository) SSHURL() string {
+	return fmt.Sprintf("git@%s:%s/%s.git", GitHubHost, r.Owner, r.Repo)
+}
+
+// Remotes names the git remotes of the upstream and openshift repositories.
+type Remotes struct {
+	Upstream  string `json:"upstream,omitempty"`
+	OpenShift string `json:"openshift,omitempty"`
+}
+
+// Fork describes a carried openshift fork of an upstream project and the
+// procedure of its rebase, the upstream and openshift remotes have to point
+// to the respective repositories.
+type Fork struct {
+	// Name of the forked project, eg. kubernetes
+	Name string `json:"name"`
+	// Upstream is the upstream repository
+	Upstream Repository `json:"upstream"`
+	// OpenShift is the openshift fork of the upstream repository
+	OpenShift Repository `json:"openshift"`
+	// ManualSteps are the steps of the rebase not handled by the tool
+	ManualSteps []string `json:"manualSteps,omitempty"`
+	// Remotes are the names of the git remotes, default to upstream and openshift
+	Remotes Remotes `json:"remotes,omitempty"`
+	// Marker is a regular expression matching the message of the commit which
+	// starts the carries, defaults to the merge of the openshift branch
+	Marker string `json:"marker,omitempty"`
+	// Actions maps additional UPSTREAM actions, eg. <patch>, to carry or drop
+	Actions map[string]string `json:"actions,omitempty"`
+	// Stages are the enabled stages run after picking carries, in the order
+	// they are run, defaults to all of them in the default order
+	Stages []string `json:"stages,omitempty"`
+	// OursPaths are path prefixes, conflicts limited to them are resolved with
+	// ours strategy option, in addition to the ones passed to apply
+	OursPaths []string `json:"oursPaths,omitempty"`
+	// Generators are pattern=command pairs used for regenerating files, in
+	// addition to the ones passed to apply
+	Generators []string `json:"generators,omitempty"`
+	// Resolvers are name=command pairs invoked to resolve conflicts of carries,
+	// in addition to the ones passed to apply
+	Resolvers []string `json:"resolvers,omitempty"`
+	// CarriesDirs are directories holding fixed carries, in addition to the
+	// ones passed to apply, relative ones are resolved against the directory
+	// of the configuration file
+	CarriesDirs []string `json:"carriesDirs,omitempty"`
+	// CarriesURL is the web location of the fixed carries, eg. a directory of
+	// a github repository, used when reporting stale or broken fixes
+	CarriesURL string `json:"carriesURL,omitempty"`
+
+	markerRE *regexp.Regexp
+}
+
+// Kubernetes is the openshift/kubernetes fork, used by default.
+var Kubernetes = Fork{
+	Name:       "kubernetes",
+	Upstream:   Repository{Owner: "kubernetes", Repo: "kubernetes", Branch: "master"},
+	OpenShift:  Repository{Owner: "openshift", Repo: "kubernetes", Branch: "master"},
+	CarriesURL: "https://github.com/soltysh/rebase/tree/main/carries",
+	ManualSteps: []string{
+		"Update `go.mod` dependencies and run `go mod tidy && go mod vendor`",
+		"Run `make update` and commit generated files",
+		"Bump kubernetes version in `openshift-hack` files",
+		"Run `make` and `make test` to verify the rebase",
+		"Open the rebase PR against openshift/kubernetes",
+	},
+}
+
+// builtin are the forks known without a configuration file
+var builtin = map[string]Fork{
+	Kubernetes.Name: Kubernetes,
+	"etcd": {
+		Name:      "etcd",
+		Upstream:  Repository{Owner: "etcd-io", Repo: "etcd", Branch: "main"},
+		OpenShift: Repository{Owner: "openshift", Repo: "etcd", Branch: "openshift-main"},
+	},
+	"coredns": {
+		Name:      "coredns",
+		Upstream:  Repository{Owner: "coredns", Repo: "coredns", Branch: "master"},
+		OpenShift: Repository{Owner: "openshift", Repo: "coredns", Branch: "master"},
+	},
+}
+
+// Load returns a builtin fork by name, or reads the fork and its rebase
+// procedure from a YAML configuration file when path is set, JSON is read
+// as well, since it is a subset of YAML.
+func Load(name, path string) (Fork, error) {
+	if len(path) == 0 {
+		f, ok := builtin[name]
+		if !ok {
+			return Fork{}, fmt.Errorf("unknown fork %q, expected one of: %s, or use a configuration file", name, strings.Join(Names(), ", "))
+		}
+		return f, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Fork{}, err
+	}
+	f := Fork{}
+	if err := yaml.Unmarshal(data, &f); err != nil {
+		return Fork{}, fmt.Errorf("malformed fork configuration %s: %w", path, err)
+	}
+	for _, r := range []*Repository{&f.Upstream, &f.OpenShift} {
+		if len(r.Owner) == 0 || len(r.Repo) == 0 {
+			return Fork{}, fmt.Errorf("fork configuration %s requires owner and repo of both upstream and openshift repositories", path)
+		}
+		if len(r.Branch) == 0 {
+			r.Branch = "master"
+		}
+	}
+	if len(f.Name) == 0 {
+		f.Name = f.Upstream.Repo
+	}
+	for action, meaning := range f.Actions {
+		if meaning != "carry" && meaning != "drop" {
+			return Fork{}, fmt.Errorf("invalid meaning %q of action %s in fork configuration %s, expected carry or drop", meaning, action, path)
+		}
+	}
+	for i, stage := range f.Stages {
+		if !contains(stages, stage) {
+			return Fork{}, fmt.Errorf("unknown stage %q in fork configuration %s, expected one of: %s", stage, path, strings.Join(stages, ", "))
+		}
+		if contains(f.Stages[:i], stage) {
+			return Fork{}, fmt.Errorf("stage %q is listed twice in fork configuration %s", stage, path)
+		}
+	}
+	for i, dir := range f.CarriesDirs {
+		if !filepath.IsAbs(dir) {
+			f.CarriesDirs[i] = filepath.Join(filepath.Dir(path), filepath.FromSlash(dir))
+		}
+	}
+	return f, nil
+}
+
+func contains(list []string, value string) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// Names returns the names of builtin forks.
+func Names() []string {
+	names := make([]string, 0, len(builtin))
+	for name := range builtin {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// UpstreamRemote returns the name of the upstream git remote.
+func (f Fork) UpstreamRemote() string {
+	if len(f.Remotes.Upstream) == 0 {
+		return "upstream"
+	}
+	return f.Remotes.Upstream
+}
+
+// OpenShiftRemote returns the name of the openshift git remote.
+func (f Fork) OpenShiftRemote() string {
+	if len(f.Remotes.OpenShift) == 0 {
+		return "openshift"
+	}
+	return f.Remotes.OpenShift
+}
+
+// UpstreamRef returns the remote tracking ref of the upstream branch.
+func (f Fork) UpstreamRef() string {
+	return "refs/remotes/" + f.UpstreamRemote() + "/" + f.Upstream.Branch
+}
+
+// OpenShiftBranch returns the remote tracking branch of the openshift fork.
+func (f Fork) OpenShiftBranch() string {
+	return f.OpenShiftRemote() + "/" + f.OpenShift.Branch
+}
+
+// OpenShiftRef returns the remote tracking ref of the openshift branch.
+func (f Fork) OpenShiftRef() string {
+	return "refs/remotes/" + f.OpenShiftBranch()
+}
+
+// UnmarshalJSON reads the fork and compiles its marker, both of a
+// configuration file and of a fork persisted in the state of a run.
+func (f *Fork) UnmarshalJSON(data []byte) error {
+	type plain Fork
+	if err := json.Unmarshal(data, (*plain)(f)); err != nil {
+		return err
+	}
+	if len(f.Marker) == 0 {
+		return nil
+	}
+	var err error
+	if f.markerRE, err = regexp.Compile(f.Marker); err != nil {
+		return fmt.Errorf("invalid marker: %w", err)
+	}
+	return nil
+}
+
+// RebaseMarker is the message prefix of the merge of the openshift branch,
+// which marks the start of carries of a rebase.
+func (f Fork) RebaseMarker() string {
+	return fmt.Sprintf("Merge remote-tracking branch '%s' into", f.OpenShiftBranch())
+}
+
+// IsMarker checks if the commit message marks the start of carries.
+func (f Fork) IsMarker(message string) bool {
+	if f.markerRE != nil {
+		return f.markerRE.MatchString(message)
+	}
+	return strings.Contains(message, f.RebaseMarker())
+}
+
+// Action translates an UPSTREAM action using the configured vocabulary,
+// ignoring case, actions not configured are returned unchanged.
+func (f Fork) Action(action string) string {
+	for configured, meaning := range f.Actions {
+		if strings.EqualFold(configured, action) {
+			return "<" + meaning + ">"
+		}
+	}
+	return action
+}
+
+// StageEnabled checks if a stage run after picking carries is enabled.
+func (f Fork) StageEnabled(stage string) bool {
+	return len(f.Stages) == 0 || contains(f.Stages, stage)
+}
+
+// StageOrder returns all stages run after picking carries, the enabled ones
+// in the configured order, followed by the disabled ones.
+func (f Fork) StageOrder() []string {
+	order := append([]string{}, f.Stages...)
+	for _, stage := range stages {
+		if !contains(order, stage) {
+			order = append(order, stage)
+		}
+	}
+	return order
+}
+
+// CommitURL returns the URL of a commit in the openshift fork.
+func (f Fork) CommitURL(sha string) string {
+	return f.OpenShift.URL() + "/commit/" + sha
+}
+
+// FixedCarryURL returns the web location of the fixed carry of a commit, or
+// empty when the fork does not publish its fixed carries.
+func (f Fork) FixedCarryURL(sha string) string {
+	if len(f.CarriesURL) == 0 {
+		return ""
+	}
+	return strings.TrimSuffix(f.CarriesURL, "/") + "/" + sha
+}
+
+// UpstreamPullURL returns the URL of an upstream pull request.
+func (f Fork) UpstreamPullURL(number int) string {
+	return fmt.Sprintf("%s/pull/%d", f.Upstream.URL(), number)
+}
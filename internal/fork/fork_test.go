@@ -0,0 +1,88 @@
+package fork
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  string
+		stages  []string
+		order   []string
+		wantErr bool
+	}{
+		{
+			name: "yaml",
+			config: `upstream:
+  owner: etcd-io
+  repo: etcd
+  branch: main
+openshift:
+  owner: openshift
+  repo: etcd
+stages:
+- additional
+- backports
+`,
+			stages: []string{StageAdditional, StageBackports},
+			order:  []string{StageAdditional, StageBackports},
+		},
+		{
+			name:   "json",
+			config: `{"upstream": {"owner": "etcd-io", "repo": "etcd"}, "openshift": {"owner": "openshift", "repo": "etcd"}, "stages": ["additional"]}`,
+			stages: []string{StageAdditional},
+			order:  []string{StageAdditional, StageBackports},
+		},
+		{
+			name: "all stages",
+			config: `upstream: {owner: etcd-io, repo: etcd}
+openshift: {owner: openshift, repo: etcd}
+`,
+			order: []string{StageBackports, StageAdditional},
+		},
+		{
+			name: "duplicate stage",
+			config: `upstream: {owner: etcd-io, repo: etcd}
+openshift: {owner: openshift, repo: etcd}
+stages: [backports, backports]
+`,
+			wantErr: true,
+		},
+		{
+			name: "unknown stage",
+			config: `upstream: {owner: etcd-io, repo: etcd}
+openshift: {owner: openshift, repo: etcd}
+stages: [merge]
+`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "fork.yaml")
+			if err := os.WriteFile(path, []byte(tt.config), 0644); err != nil {
+				t.Fatal(err)
+			}
+			f, err := Load("", path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Load() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if f.Name != "etcd" || f.OpenShift.Branch != "master" {
+				t.Errorf("Load() = %+v, expected name etcd and default branch", f)
+			}
+			if !reflect.DeepEqual(f.Stages, tt.stages) {
+				t.Errorf("Stages = %v, expected %v", f.Stages, tt.stages)
+			}
+			if order := f.StageOrder(); !reflect.DeepEqual(order, tt.order) {
+				t.Errorf("StageOrder() = %v, expected %v", order, tt.order)
+			}
+		})
+	}
+}
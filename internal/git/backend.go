@@ -0,0 +1,223 @@
+package git
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+
+	gitv5 "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	gitv5object "github.com/go-git/go-git/v5/plumbing/object"
+	"k8s.io/klog/v2"
+)
+
+// Backends serving read operations of the repository.
+const (
+	// BackendGoGit reads objects through go-git
+	BackendGoGit = "go-git"
+	// BackendCLI reads objects with the git binary, which is faster on very
+	// large repositories
+	BackendCLI = "cli"
+)
+
+// Backends returns the names of the backends.
+func Backends() []string {
+	return []string{BackendGoGit, BackendCLI}
+}
+
+// selectedBackend is the backend used by repositories opened by OpenGit from
+// now on
+var selectedBackend = BackendGoGit
+
+// SetBackend selects the backend serving read operations of repositories
+// opened by OpenGit, ie. by the commands.
+func SetBackend(name string) error {
+	if err := checkBackend(name); err != nil {
+		return err
+	}
+	selectedBackend = name
+	return nil
+}
+
+// checkBackend returns an error unless name is one of the backends
+func checkBackend(name string) error {
+	for _, b := range Backends() {
+		if name == b {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown git backend %q, expected one of: %s", name, strings.Join(Backends(), ", "))
+}
+
+// backend reads objects and configuration of the repository, both
+// implementations return identical results
+type backend interface {
+	// commits returns commit objects of the shas, in the same order
+	commits(shas []string) ([]*gitv5object.Commit, error)
+	// remoteURLs returns the URLs of a remote
+	remoteURLs(name string) ([]string, error)
+	// branchRemote returns the remote tracked by a branch, or empty string
+	branchRemote(branch string) (string, error)
+	// currentBranch returns the checked out branch, or empty string when HEAD is detached
+	currentBranch() (string, error)
+}
+
+// newBackend returns the named backend of the repository
+func newBackend(git *git, name string) backend {
+	if name == BackendCLI {
+		return &cliBackend{git: git}
+	}
+	return &goGitBackend{repository: git.repository}
+}
+
+// goGitBackend reads the repository through go-git, which is not safe for
+// concurrent use, so that the repository can be shared by goroutines
+type goGitBackend struct {
+	lock       sync.Mutex
+	repository *gitv5.Repository
+}
+
+func (b *goGitBackend) commits(shas []string) ([]*gitv5object.Commit, error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	commits := make([]*gitv5object.Commit, 0, len(shas))
+	for _, sha := range shas {
+		c, err := b.repository.CommitObject(plumbing.NewHash(sha))
+		if err != nil {
+			return nil, err
+		}
+		commits = append(commits, c)
+	}
+	return commits, nil
+}
+
+func (b *goGitBackend) remoteURLs(name string) ([]string, error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	remote, err := b.repository.Remote(name)
+	if err != nil {
+		return nil, err
+	}
+	return remote.Config().URLs, nil
+}
+
+func (b *goGitBackend) branchRemote(branch string) (string, error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	config, err := b.repository.Config()
+	if err != nil {
+		return "", err
+	}
+	if branchConfig, ok := config.Branches[branch]; ok {
+		return branchConfig.Remote, nil
+	}
+	return "", nil
+}
+
+func (b *goGitBackend) currentBranch() (string, error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	head, err := b.repository.Head()
+	if err != nil {
+		return "", err
+	}
+	if !head.Name().IsBranch() {
+		return "", nil
+	}
+	return head.Name().Short(), nil
+}
+
+// cliBackend reads the repository with the git binary
+type cliBackend struct {
+	git *git
+}
+
+// commits reads all commits with a single cat-file process and decodes them
+// with go-git, so that they are the same as the ones read by go-git
+func (b *cliBackend) commits(shas []string) ([]*gitv5object.Commit, error) {
+	if len(shas) == 0 {
+		return []*gitv5object.Commit{}, nil
+	}
+	cmd := gitCommand("cat-file", "--batch")
+	klog.V(2).Infof("Invoking %s for %d objects...", cmd, len(shas))
+	cmd.Dir = b.git.path
+	cmd.Stdin = strings.NewReader(strings.Join(shas, "\n") + "\n")
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, err
+	}
+	reader := bufio.NewReader(bytes.NewReader(output))
+	commits := make([]*gitv5object.Commit, 0, len(shas))
+	for _, sha := range shas {
+		header, err := reader.ReadString('\n')
+		if err != nil {
+			return nil, fmt.Errorf("Error reading %s: %w", sha, err)
+		}
+		fields := strings.Fields(header)
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("object %s not found", sha)
+		}
+		if fields[1] != "commit" {
+			return nil, fmt.Errorf("object %s is a %s, not a commit", sha, fields[1])
+		}
+		size, err := strconv.ParseInt(fields[2], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("Error reading %s: %w", sha, err)
+		}
+		obj := &plumbing.MemoryObject{}
+		obj.SetType(plumbing.CommitObject)
+		if _, err := io.CopyN(obj, reader, size); err != nil {
+			return nil, fmt.Errorf("Error reading %s: %w", sha, err)
+		}
+		// the content is followed by a newline
+		if _, err := reader.ReadByte(); err != nil {
+			return nil, fmt.Errorf("Error reading %s: %w", sha, err)
+		}
+		c := &gitv5object.Commit{}
+		if err := c.Decode(obj); err != nil {
+			return nil, fmt.Errorf("Error decoding %s: %w", sha, err)
+		}
+		commits = append(commits, c)
+	}
+	return commits, nil
+}
+
+func (b *cliBackend) remoteURLs(name string) ([]string, error) {
+	output, err := b.git.outputGit("config", "--get-all", "remote."+name+".url")
+	if err != nil {
+		return nil, gitv5.ErrRemoteNotFound
+	}
+	return splitLines(output), nil
+}
+
+func (b *cliBackend) branchRemote(branch string) (string, error) {
+	output, err := b.git.outputGit("config", "--get", "branch."+branch+".remote")
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+		// the key is not set
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+func (b *cliBackend) currentBranch() (string, error) {
+	output, err := b.git.outputGit("symbolic-ref", "-q", "--short", "HEAD")
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+		// HEAD is detached
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(output)), nil
+}
@@ -0,0 +1,1365 @@
+package git
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/klog/v2"
+
+	gitv5 "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	gitv5object "github.com/go-git/go-git/v5/plumbing/object"
+
+	"github.com/openshift/rebase/internal/console"
+	"github.com/openshift/rebase/internal/credentials"
+	"github.com/openshift/rebase/internal/fork"
+)
+
+// Git provides an interface for interacting with a git repository.
+type Git interface {
+	// AbortCherryPick aborts the current cherry-pick command
+	AbortCherryPick() error
+	// AbortApply aborts the current apply command
+	AbortApply() error
+	// AmendAll adds all changes in the working tree to the last commit
+	AmendAll() error
+	// AmendMessage replaces the message of the last commit
+	AmendMessage(message string) error
+	// Apply a patch
+	Apply(patch string) error
+	// Apply a patch with 3-way merge, returns *ConflictError when it stops
+	Apply3Way(patch string) error
+	// ApplyWithContext applies a patch requiring only given number of context lines to match
+	ApplyWithContext(patch string, lines int) error
+	// Checkout the specified remote
+	Checkout(remote string) error
+	// CreateBranch creates a named branch based on remote
+	CreateBranch(name, remote string) error
+	// CurrentBranch returns the name of the checked out branch, or empty string when HEAD is detached
+	CurrentBranch() (string, error)
+	// DeleteBranch forcefully deletes a named branch
+	DeleteBranch(name string) error
+	// ChangedFiles returns the list of files modified by a commit
+	ChangedFiles(sha string) ([]string, error)
+	// CherryPick invokes the cherry-pick command, mainline selects the parent number for merge
+	// commits and is ignored when 0, returns *ConflictError classifying the outcome when it stops
+	CherryPick(sha string, mainline int) error
+	// CherryPickSequence picks the commits with a single cherry-pick invocation, returning the number
+	// of commits picked before the sequencer stopped, the stopped pick is discarded
+	CherryPickSequence(shas []string) (int, error)
+	// ResolveConflicts resolves conflicts in files by accepting one side, either ours or theirs
+	ResolveConflicts(side string, files []string) error
+	// StageAll adds all changes in the working tree to the index
+	StageAll() error
+	// CommitResolved commits the resolved cherry-pick with the original message,
+	// even when the result is empty
+	CommitResolved() error
+	// ResetHard resets the current branch and the working tree to the given revision
+	ResetHard(rev string) error
+	// RevParse returns the SHA of the given revision
+	RevParse(rev string) (string, error)
+	// RevList returns the SHAs of commits selected by rev-list arguments
+	RevList(args ...string) ([]string, error)
+	// TreeWithCommits returns the tree resulting from applying diffs of commits
+	// on top of base, without touching the working tree, index or any refs
+	TreeWithCommits(base string, commits []string) (string, error)
+	// RebaseWithTodo rebases branch onto base with an interactive rebase, using
+	// the todo file as the list of instructions, aborting the rebase on failure
+	RebaseWithTodo(base, branch, todo string) error
+	// Push pushes a branch to a remote
+	Push(remote, branch string) error
+	// Fetch fetches a remote, including tags
+	Fetch(remote string) error
+	// FetchRef fetches a single ref from a repository URL and returns its commit
+	FetchRef(url, ref string) (string, error)
+	// IsAncestor checks if commit is an ancestor of rev
+	IsAncestor(commit, rev string) (bool, error)
+	// AddWorktree checks out a revision in a new detached worktree at path
+	AddWorktree(path, rev string) error
+	// RemoveWorktree removes the worktree at path, discarding any changes in it
+	RemoveWorktree(path string) error
+	// ShowFile returns the content of the file at a given revision
+	ShowFile(rev, path string) ([]byte, error)
+	// ListTree returns the names of entries in a directory at a given revision
+	ListTree(rev, dir string) ([]string, error)
+	// GrepFiles returns files at a revision containing fixed string pattern, limited to paths
+	GrepFiles(rev, pattern string, paths []string) ([]string, error)
+	// DiffNames returns the list of files differing between two revisions
+	DiffNames(from, to string) ([]string, error)
+	// Refs returns short names of refs matching the patterns, eg. refs/tags
+	Refs(patterns ...string) ([]string, error)
+	// LogNumStat returns lines changed in every file by non-merge commits reachable from to, but not from from
+	LogNumStat(from, to string) ([]FileChange, error)
+	// PatchID returns the stable patch ID of a commit, or empty string for empty commits
+	PatchID(sha string) (string, error)
+	// RetryCherryPick invokes the cherry-pick command with recursive strategy and theirs option,
+	// returns *ConflictError when it stops
+	RetryCherryPick(sha string, mainline int) error
+	// OursCherryPick invokes the cherry-pick command with ours strategy option, returns *ConflictError when it stops
+	OursCherryPick(sha string, mainline int) error
+	// ConflictedFiles returns the list of files with unresolved conflicts
+	ConflictedFiles() ([]string, error)
+	// AbortInProgress aborts any in-progress cherry-pick, am or merge operation
+	AbortInProgress() error
+	// ContinueInProgress continues an in-progress cherry-pick or am operation, after conflicts were resolved
+	ContinueInProgress() error
+	// InProgress returns the name of the in-progress operation, either cherry-pick, am, rebase or merge, or empty string when there is none
+	InProgress() (string, error)
+	// UncommittedFiles returns tracked files with changes in the index or the working tree
+	UncommittedFiles() ([]string, error)
+	// RemoteReachable checks that the remote repository can be listed without prompting for credentials
+	RemoteReachable(remote string) error
+	// Commit returns commit for a given has
+	Commit(hash plumbing.Hash) (*gitv5object.Commit, error)
+	// CommitTree creates a commit object from tree with a given parent, without updating any refs
+	CommitTree(tree, parent, message string) (string, error)
+	// LogFromTag returns the commits on HEAD descending from the provided tag
+	LogFromTag(tag string) ([]*gitv5object.Commit, error)
+	// ForEachFromTag calls fn for the commits on HEAD descending from the provided tag, newest first
+	ForEachFromTag(tag string, fn func(*gitv5object.Commit) error) error
+	// ForEachFromTagOn calls fn for the commits on ref descending from the provided tag, newest first
+	ForEachFromTagOn(tag, ref string, fn func(*gitv5object.Commit) error) error
+	// LogRange returns the commits on to, which are not reachable from the merge base of since and to
+	LogRange(since, to string) ([]*gitv5object.Commit, error)
+	// ForEachInRange calls fn for the commits on to, which are not reachable from the merge base of since and to, newest first
+	ForEachInRange(since, to string, fn func(*gitv5object.Commit) error) error
+	// FindMarker returns the latest commit reachable from ref with a message matching isMarker
+	FindMarker(ref string, search MarkerSearch, isMarker func(message string) bool) (*gitv5object.Commit, error)
+	// Diff returns the diff of the working tree against the index
+	Diff() ([]byte, error)
+	// IsProtected checks if the branch is a shared branch, which must not be modified,
+	// ie. master, main or release-* branch tracking openshift remote
+	IsProtected(branch string) (bool, error)
+	// GitDir returns the absolute path to the repository's git directory
+	GitDir() (string, error)
+	// Merge remote branch
+	Merge(remote string) error
+	// MergeTree performs an in-memory cherry-pick of commit on top of base, returning
+	// the resulting tree and a list of conflicting files, mainline selects the
+	// parent number for merge commits and is ignored when 0
+	MergeTree(base, commit string, mainline int) (string, []string, error)
+	// ShowStage returns the content of the file at a given index stage
+	ShowStage(stage int, path string) ([]byte, error)
+	// Status prints current status of repository
+	Status() error
+}
+
+// OpenGit opens path as a git repository, ensuring that remotes contain
+// both upstream and openshift remotes of the fork properly configured.
+func OpenGit(path string, f fork.Fork) (Git, error) {
+	return OpenGitWithBackend(path, f, selectedBackend)
+}
+
+// OpenGitWithBackend opens path as a git repository like OpenGit, read
+// operations are served by the named backend instead of the one selected by
+// SetBackend.
+func OpenGitWithBackend(path string, f fork.Fork, backendName string) (Git, error) {
+	if err := checkBackend(backendName); err != nil {
+		return nil, err
+	}
+	klog.V(2).Infof("Using %s as git repository", path)
+	repository, err := gitv5.PlainOpen(path)
+	if err != nil {
+		return nil, err
+	}
+	gitRepo := &git{repository: repository, path: path, fork: f}
+	gitRepo.backend = newBackend(gitRepo, backendName)
+	klog.V(2).Infof("Checking if openshift and upstream remotes are configured..")
+	if err := gitRepo.checkRemotes(); err != nil {
+		return nil, err
+	}
+	return gitRepo, nil
+}
+
+// OpenShared returns the repository already opened by the caller, or opens the
+// one at path when there is none, so that modules share a single repository.
+func OpenShared(repository Git, path string, f fork.Fork) (Git, error) {
+	if repository != nil {
+		return repository, nil
+	}
+	return OpenGit(path, f)
+}
+
+// GitDir returns the git directory of the repository at path, without
+// opening it and checking its remotes.
+func GitDir(path string) (string, error) {
+	cmd := gitCommand("rev-parse", "--absolute-git-dir")
+	klog.V(2).Infof("Invoking %s...", cmd)
+	cmd.Dir = path
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("%s is not a git repository: %w", path, err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// ResolveRef resolves a ref given by the user, ie. a tag, a branch, or a full or
+// abbreviated SHA, in the repository at path to the SHA of the commit.
+func ResolveRef(path, ref string) (string, error) {
+	if len(strings.TrimSpace(ref)) == 0 || strings.HasPrefix(ref, "-") {
+		return "", fmt.Errorf("%q is not a valid ref", ref)
+	}
+	cmd := gitCommand("rev-parse", "--verify", ref+"^{commit}")
+	klog.V(2).Infof("Invoking %s...", cmd)
+	cmd.Dir = path
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		// eg. an ambiguous abbreviation is explained on the first line
+		reason, _, _ := strings.Cut(strings.TrimSpace(stderr.String()), "\n")
+		if strings.Contains(reason, "ambiguous") {
+			return "", fmt.Errorf("%s is ambiguous, use a longer SHA or the full ref: %s", ref, reason)
+		}
+		return "", fmt.Errorf("%s is not a commit, tag or branch in %s, make sure it is fetched", ref, path)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+type git struct {
+	path       string
+	repository *gitv5.Repository
+	// fork names the upstream and openshift remotes
+	fork fork.Fork
+	// backend serves reads of objects and configuration
+	backend backend
+	// commitGraph looks up the commit-graph once
+	commitGraph sync.Once
+}
+
+// checkRemotes ensures both openshift and upstream remotes are properly configured
+func (git *git) checkRemotes() error {
+	for _, remote := range []struct {
+		name       string
+		repository fork.Repository
+	}{
+		{
+			name:       git.fork.OpenShiftRemote(),
+			repository: git.fork.OpenShift,
+		},
+		{
+			name:       git.fork.UpstreamRemote(),
+			repository: git.fork.Upstream,
+		},
+	} {
+		urls, err := git.backend.remoteURLs(remote.name)
+		if err != nil {
+			return err
+		}
+		// URLs the URLs of a remote repository. It must be non-empty. Fetch will
+		// always use the first URL, while push will use all of them.
+		if len(urls) == 0 {
+			return fmt.Errorf("no fetch URLs, remote=%s", remote.name)
+		}
+		fetchURL := urls[0]
+		// TODO: add auto-updating remotes if the above are missing, there's CreateRemote function
+		parsed, err := ParseRemoteURL(fetchURL)
+		if err != nil {
+			return fmt.Errorf("fetch URL does not match, remote=%s: %w", remote.name, err)
+		}
+		if !parsed.Is(fork.GitHubHost, remote.repository.Owner, remote.repository.Repo) {
+			return fmt.Errorf("fetch URL does not match, remote=%s url=%s expected=%s or %s",
+				remote.name, fetchURL, remote.repository.URL(), remote.repository.SSHURL())
+		}
+		klog.V(2).Infof("%s -> %s - OK", remote.name, fetchURL)
+	}
+	return nil
+}
+
+// ErrStop stops iterating commits without an error when returned by the callback.
+var ErrStop = errors.New("stop iteration")
+
+// streamBatch is the number of commits read at once while iterating, so that
+// the memory stays flat regardless of the length of the history
+const streamBatch = 100
+
+// LogFromTag returns the commits on HEAD descending from the provided tag,
+// the history is bounded by ancestry, so that neither backdated commits are
+// missed nor unrelated commits from before the tag are walked
+func (git *git) LogFromTag(tag string) ([]*gitv5object.Commit, error) {
+	return collect(func(fn func(*gitv5object.Commit) error) error {
+		return git.ForEachFromTag(tag, fn)
+	})
+}
+
+// ForEachFromTag calls fn for the commits on HEAD descending from the tag,
+// newest first, without holding them in memory
+func (git *git) ForEachFromTag(tag string, fn func(*gitv5object.Commit) error) error {
+	return git.ForEachFromTagOn(tag, "HEAD", fn)
+}
+
+// ForEachFromTagOn calls fn for the commits on ref descending from the tag,
+// newest first, the ref does not need to be checked out
+func (git *git) ForEachFromTagOn(tag, ref string, fn func(*gitv5object.Commit) error) error {
+	tagCommit, err := git.RevParse(tag + "^{commit}")
+	if err != nil {
+		return fmt.Errorf("tag %s not found: %w", tag, err)
+	}
+	// the walk is left to git, which uses the commit-graph, walking the
+	// history through the go-git object store takes minutes on a full clone
+	git.ensureCommitGraph()
+	return git.forEachRevList([]string{"--ancestry-path", ref, "^" + tagCommit}, fn)
+}
+
+// LogRange returns the commits on to, which are not reachable from the merge base of since and to
+func (git *git) LogRange(since, to string) ([]*gitv5object.Commit, error) {
+	return collect(func(fn func(*gitv5object.Commit) error) error {
+		return git.ForEachInRange(since, to, fn)
+	})
+}
+
+// ForEachInRange calls fn for the commits on to, which are not reachable from
+// the merge base of since and to, newest first, without holding them in memory
+func (git *git) ForEachInRange(since, to string, fn func(*gitv5object.Commit) error) error {
+	git.ensureCommitGraph()
+	output, err := git.outputGit("merge-base", since, to)
+	if err != nil {
+		return fmt.Errorf("no merge base of %s and %s: %w", since, to, err)
+	}
+	base := strings.TrimSpace(string(output))
+	klog.V(2).Infof("Merge base of %s and %s is %s", since, to, base)
+	return git.forEachRevList([]string{base + ".." + to}, fn)
+}
+
+// collect gathers the commits iterated by forEach into a slice
+func collect(forEach func(fn func(*gitv5object.Commit) error) error) ([]*gitv5object.Commit, error) {
+	commits := make([]*gitv5object.Commit, 0)
+	err := forEach(func(c *gitv5object.Commit) error {
+		commits = append(commits, c)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return commits, nil
+}
+
+// forEachRevList calls fn for the commits listed by rev-list, reading them in
+// batches as rev-list outputs them, returning ErrStop from fn ends the walk
+func (git *git) forEachRevList(args []string, fn func(*gitv5object.Commit) error) error {
+	cmd := gitCommand(append([]string{"rev-list"}, args...)...)
+	klog.V(2).Infof("Invoking %s...", cmd)
+	cmd.Dir = git.path
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	batch := make([]string, 0, streamBatch)
+	flush := func() error {
+		commits, err := git.commits(batch)
+		if err != nil {
+			return err
+		}
+		batch = batch[:0]
+		for _, c := range commits {
+			if err := fn(c); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	scanner := bufio.NewScanner(stdout)
+	for err == nil && scanner.Scan() {
+		if batch = append(batch, scanner.Text()); len(batch) == streamBatch {
+			err = flush()
+		}
+	}
+	if err == nil {
+		err = scanner.Err()
+	}
+	if err == nil && len(batch) > 0 {
+		err = flush()
+	}
+	if err != nil {
+		// the rest of the output is not read, rev-list is not waited for
+		cmd.Process.Kill()
+		cmd.Wait()
+		if errors.Is(err, ErrStop) {
+			return nil
+		}
+		return err
+	}
+	if err := cmd.Wait(); err != nil {
+		return fmt.Errorf("%s failed: %w: %s", cmd, err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+// ErrMarkerNotFound is returned when none of the searched commits is the rebase marker.
+var ErrMarkerNotFound = errors.New("rebase marker not found")
+
+// MarkerNotFoundError describes the commits searched for the rebase marker,
+// it matches ErrMarkerNotFound.
+type MarkerNotFoundError struct {
+	// Range is the searched range of commits
+	Range string
+	// Walked is the number of commits searched
+	Walked int
+}
+
+func (e *MarkerNotFoundError) Error() string {
+	return fmt.Sprintf("%v within %d commits of %s", ErrMarkerNotFound, e.Walked, e.Range)
+}
+
+func (e *MarkerNotFoundError) Unwrap() error {
+	return ErrMarkerNotFound
+}
+
+// Outcome classifies why a cherry-pick or a 3-way apply stopped, each of them
+// requires a different reaction.
+type Outcome string
+
+const (
+	// OutcomeConflict left files with unresolved conflicts
+	OutcomeConflict Outcome = "conflict"
+	// OutcomeEmpty is a pick with no changes left, eg. they are already upstream
+	OutcomeEmpty Outcome = "empty"
+	// OutcomeUnknownCommit is a pick of a commit missing in the repository
+	OutcomeUnknownCommit Outcome = "unknown-commit"
+	// OutcomeInProgress was refused, because another operation is in progress
+	OutcomeInProgress Outcome = "in-progress"
+	// OutcomeFailed is any other failure
+	OutcomeFailed Outcome = "failed"
+)
+
+// ConflictError is returned when a cherry-pick or a 3-way apply stops, the
+// conflicts are left in the working tree for manual resolution.
+type ConflictError struct {
+	// Operation is the git operation which stopped, eg. cherry-pick
+	Operation string
+	// Commit is the picked commit, or the applied patch
+	Commit string
+	// Outcome tells why the operation stopped
+	Outcome Outcome
+	// Files are the files with unresolved conflicts, empty when the operation
+	// stopped for another reason, eg. the pick became empty
+	Files []string
+	// Err is the error of the git command
+	Err error
+}
+
+func (e *ConflictError) Error() string {
+	switch e.Outcome {
+	case OutcomeConflict:
+		return fmt.Sprintf("git %s of %s stopped on conflicts in %s: %v", e.Operation, e.Commit, strings.Join(e.Files, ", "), e.Err)
+	case OutcomeEmpty:
+		return fmt.Sprintf("git %s of %s left no changes, they are already present", e.Operation, e.Commit)
+	case OutcomeUnknownCommit:
+		return fmt.Sprintf("git %s of %s failed, the commit is unknown, make sure it is fetched", e.Operation, e.Commit)
+	case OutcomeInProgress:
+		return fmt.Sprintf("git %s of %s was refused, another operation is in progress, finish or abort it first", e.Operation, e.Commit)
+	}
+	return fmt.Sprintf("git %s of %s failed: %v", e.Operation, e.Commit, e.Err)
+}
+
+func (e *ConflictError) Unwrap() error {
+	return e.Err
+}
+
+// OutcomeOf returns the outcome of the operation which failed with err, or
+// OutcomeFailed when it is not a *ConflictError, and empty when err is nil.
+func OutcomeOf(err error) Outcome {
+	if err == nil {
+		return ""
+	}
+	var conflict *ConflictError
+	if errors.As(err, &conflict) {
+		return conflict.Outcome
+	}
+	return OutcomeFailed
+}
+
+// conflictError describes the failed operation with the conflicts it left,
+// classified by the combined output of the git command
+func (git *git) conflictError(operation, commit string, output []byte, err error) error {
+	if err == nil {
+		return nil
+	}
+	conflict := &ConflictError{Operation: operation, Commit: commit, Outcome: OutcomeFailed, Err: err}
+	message := string(output)
+	switch {
+	// conflicts left by a previous operation must not be attributed to this one
+	case strings.Contains(message, "already in progress"), strings.Contains(message, "because you have unmerged files"):
+		conflict.Outcome = OutcomeInProgress
+		return conflict
+	case strings.Contains(message, "bad revision"), strings.Contains(message, "bad object"), strings.Contains(message, "unknown revision"):
+		conflict.Outcome = OutcomeUnknownCommit
+		return conflict
+	case strings.Contains(message, "is now empty"):
+		conflict.Outcome = OutcomeEmpty
+		return conflict
+	}
+	files, filesErr := git.ConflictedFiles()
+	if filesErr != nil {
+		klog.V(2).Infof("Reading conflicted files failed: %v", filesErr)
+	}
+	if len(files) > 0 {
+		conflict.Outcome, conflict.Files = OutcomeConflict, files
+	}
+	return conflict
+}
+
+// pickGit runs an operation which may stop, ie. cherry-pick or am, and returns
+// a *ConflictError classifying why it stopped
+func (git *git) pickGit(operation, commit string, args ...string) error {
+	cmd := gitCommand(args...)
+	klog.V(2).Infof("Invoking %s...", cmd)
+	cmd.Dir = git.path
+	output, err := cmd.CombinedOutput()
+	klog.V(3).Info(string(output))
+	if err != nil {
+		klog.V(2).Infof("%s failed: %v", cmd, err)
+	}
+	return git.conflictError(operation, commit, output, err)
+}
+
+// MarkerSearch bounds the search of the rebase marker, zero values mean no bound.
+type MarkerSearch struct {
+	// MaxCommits is the number of commits searched
+	MaxCommits int
+	// Since excludes commits committed before the time
+	Since time.Time
+	// FirstParent follows only the first parent of merges, skipping the
+	// history of merged pull requests
+	FirstParent bool
+}
+
+// IsZero returns true when the search is not bounded.
+func (s MarkerSearch) IsZero() bool {
+	return s.MaxCommits == 0 && s.Since.IsZero() && !s.FirstParent
+}
+
+// FindMarker returns the latest commit reachable from ref with a message matching isMarker
+func (git *git) FindMarker(ref string, search MarkerSearch, isMarker func(message string) bool) (*gitv5object.Commit, error) {
+	args := []string{}
+	if search.MaxCommits > 0 {
+		args = append(args, fmt.Sprintf("--max-count=%d", search.MaxCommits))
+	}
+	if !search.Since.IsZero() {
+		args = append(args, fmt.Sprintf("--since=%d", search.Since.Unix()))
+	}
+	if search.FirstParent {
+		args = append(args, "--first-parent")
+	}
+	git.ensureCommitGraph()
+	var marker *gitv5object.Commit
+	walked := 0
+	err := git.forEachRevList(append(args, ref), func(c *gitv5object.Commit) error {
+		walked++
+		if isMarker(c.Message) {
+			marker = c
+			return ErrStop
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if marker != nil {
+		klog.V(2).Infof("Found rebase marker at %s after %d commits", marker.Hash, walked)
+		return marker, nil
+	}
+	searched := ref
+	if !search.Since.IsZero() {
+		searched += fmt.Sprintf(" since %s", search.Since.Format(time.DateOnly))
+	}
+	return nil, &MarkerNotFoundError{Range: searched, Walked: walked}
+}
+
+// commits reads commit objects of the shas
+func (git *git) commits(shas []string) ([]*gitv5object.Commit, error) {
+	return git.backend.commits(shas)
+}
+
+// ensureCommitGraph writes the commit-graph with bloom filters of changed
+// paths, unless the repository already has one, it speeds up history walks
+// and path-limited logs considerably
+func (git *git) ensureCommitGraph() {
+	git.commitGraph.Do(git.writeCommitGraph)
+}
+
+// writeCommitGraph writes the commit-graph, unless there is one
+func (git *git) writeCommitGraph() {
+	for _, p := range []string{"objects/info/commit-graph", "objects/info/commit-graphs"} {
+		output, err := git.outputGit("rev-parse", "--git-path", p)
+		if err != nil {
+			return
+		}
+		graph := strings.TrimSpace(string(output))
+		if !filepath.IsAbs(graph) {
+			graph = filepath.Join(git.path, graph)
+		}
+		if _, err := os.Stat(graph); err == nil {
+			return
+		}
+	}
+	console.Infof("Writing commit-graph to speed up walking the history, this is done only once")
+	if err := git.runGit("commit-graph", "write", "--reachable", "--changed-paths"); err != nil {
+		klog.Warningf("Error writing commit-graph, walking the history will be slower: %v", err)
+	}
+}
+
+// Checkout the specified remote
+func (git *git) Checkout(remote string) error {
+	return git.runGit("checkout", remote)
+}
+
+// Commit returns commit for a given has
+// TODO: can we pass has as a string?
+func (git *git) Commit(hash plumbing.Hash) (*gitv5object.Commit, error) {
+	commits, err := git.backend.commits([]string{hash.String()})
+	if err != nil {
+		return nil, err
+	}
+	return commits[0], nil
+}
+
+// CreateBranch creates a named branch based on remote
+func (git *git) CreateBranch(name, remote string) error {
+	if err := git.ensureNotProtected(name); err != nil {
+		return err
+	}
+	return git.runGit("checkout", "-b", name, remote)
+}
+
+// IsProtected checks if the branch is a shared branch, which must not be modified,
+// ie. master, main or release-* branch tracking openshift remote
+func (git *git) IsProtected(branch string) (bool, error) {
+	if branch == "master" || branch == "main" {
+		return true, nil
+	}
+	if !strings.HasPrefix(branch, "release-") {
+		return false, nil
+	}
+	remote, err := git.backend.branchRemote(branch)
+	if err != nil {
+		return false, err
+	}
+	return remote == git.fork.OpenShiftRemote(), nil
+}
+
+// ensureNotProtected returns an error when the branch is protected
+func (git *git) ensureNotProtected(branch string) error {
+	protected, err := git.IsProtected(branch)
+	if err != nil {
+		return err
+	}
+	if protected {
+		return fmt.Errorf("refusing to modify protected branch %s", branch)
+	}
+	return nil
+}
+
+// CurrentBranch returns the name of the checked out branch, or empty string when HEAD is detached
+func (git *git) CurrentBranch() (string, error) {
+	branch, err := git.backend.currentBranch()
+	if errors.Is(err, plumbing.ErrReferenceNotFound) {
+		// go-git fails on a branch without commits, eg. in a fresh clone
+		return "", fmt.Errorf("HEAD does not point to any commit, check out a branch of %s", git.path)
+	}
+	return branch, err
+}
+
+// DeleteBranch forcefully deletes a named branch
+func (git *git) DeleteBranch(name string) error {
+	if err := git.ensureNotProtected(name); err != nil {
+		return err
+	}
+	return git.runGit("branch", "-D", name)
+}
+
+// GitDir returns the absolute path to the repository's git directory
+func (git *git) GitDir() (string, error) {
+	output, err := git.outputGit("rev-parse", "--absolute-git-dir")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// AbortInProgress aborts any in-progress cherry-pick, am, rebase or merge operation
+func (git *git) AbortInProgress() error {
+	gitDir, err := git.GitDir()
+	if err != nil {
+		return err
+	}
+	for _, op := range []struct {
+		marker string
+		abort  []string
+	}{
+		{marker: "CHERRY_PICK_HEAD", abort: []string{"cherry-pick", "--abort"}},
+		{marker: "sequencer", abort: []string{"cherry-pick", "--abort"}},
+		{marker: "rebase-apply", abort: []string{"am", "--abort"}},
+		{marker: "rebase-merge", abort: []string{"rebase", "--abort"}},
+		{marker: "MERGE_HEAD", abort: []string{"merge", "--abort"}},
+	} {
+		if _, err := os.Stat(filepath.Join(gitDir, op.marker)); err != nil {
+			continue
+		}
+		klog.V(2).Infof("Found %s, aborting...", op.marker)
+		if err := git.runGit(op.abort...); err != nil {
+			return fmt.Errorf("git %s failed: %w", strings.Join(op.abort, " "), err)
+		}
+	}
+	return nil
+}
+
+// ContinueInProgress continues an in-progress cherry-pick or am operation, after conflicts were resolved
+func (git *git) ContinueInProgress() error {
+	gitDir, err := git.GitDir()
+	if err != nil {
+		return err
+	}
+	for _, op := range []struct {
+		marker string
+		resume []string
+	}{
+		{marker: "CHERRY_PICK_HEAD", resume: []string{"-c", "core.editor=true", "cherry-pick", "--continue"}},
+		{marker: "rebase-apply", resume: []string{"am", "--continue"}},
+	} {
+		if _, err := os.Stat(filepath.Join(gitDir, op.marker)); err != nil {
+			continue
+		}
+		klog.V(2).Infof("Found %s, continuing...", op.marker)
+		if err := git.runGit(op.resume...); err != nil {
+			return fmt.Errorf("git %s failed: %w", strings.Join(op.resume, " "), err)
+		}
+	}
+	return nil
+}
+
+// InProgress returns the name of the in-progress operation, either cherry-pick, am, rebase or merge, or empty string when there is none
+func (git *git) InProgress() (string, error) {
+	gitDir, err := git.GitDir()
+	if err != nil {
+		return "", err
+	}
+	for _, op := range []struct {
+		marker string
+		name   string
+	}{
+		{marker: "CHERRY_PICK_HEAD", name: "cherry-pick"},
+		{marker: "sequencer", name: "cherry-pick"},
+		{marker: "rebase-apply", name: "am"},
+		{marker: "rebase-merge", name: "rebase"},
+		{marker: "MERGE_HEAD", name: "merge"},
+	} {
+		if _, err := os.Stat(filepath.Join(gitDir, op.marker)); err == nil {
+			return op.name, nil
+		}
+	}
+	return "", nil
+}
+
+// Merge remote branch
+func (git *git) Merge(remote string) error {
+	return git.runGit("merge", "--strategy", "ours", remote, "--no-edit")
+}
+
+// MergeTree performs an in-memory cherry-pick of commit on top of base, returning
+// the resulting tree and a list of conflicting files, mainline selects the
+// parent number for merge commits and is ignored when 0
+func (git *git) MergeTree(base, commit string, mainline int) (string, []string, error) {
+	if mainline == 0 {
+		mainline = 1
+	}
+	output, err := git.outputGit("merge-tree", "--write-tree", "--name-only", "--no-messages",
+		fmt.Sprintf("--merge-base=%s^%d", commit, mainline), base, commit)
+	lines := strings.Split(strings.TrimSpace(string(output)), "\n")
+	if err != nil {
+		// exit code 1 is returned when there are conflicts, everything else is an error
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) || exitErr.ExitCode() != 1 {
+			return "", nil, err
+		}
+		return lines[0], lines[1:], nil
+	}
+	return lines[0], nil, nil
+}
+
+// CommitTree creates a commit object from tree with a given parent, without updating any refs
+func (git *git) CommitTree(tree, parent, message string) (string, error) {
+	output, err := git.outputGit("commit-tree", tree, "-p", parent, "-m", message)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// ResolveConflicts resolves conflicts in files by accepting one side, either ours or theirs
+func (git *git) ResolveConflicts(side string, files []string) error {
+	if side != "ours" && side != "theirs" {
+		return fmt.Errorf("invalid conflict side %q, expected ours or theirs", side)
+	}
+	for _, f := range files {
+		// when the file was deleted on the selected side, checkout fails and
+		// the resolution is to remove the file
+		if err := git.runGit("checkout", "--"+side, "--", f); err != nil {
+			if err := git.runGit("rm", "--quiet", "--", f); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := git.runGit("add", "--", f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// StageAll adds all changes in the working tree to the index
+func (git *git) StageAll() error {
+	return git.runGit("add", "--all")
+}
+
+// CommitResolved commits the resolved cherry-pick with the original message,
+// even when the result is empty
+func (git *git) CommitResolved() error {
+	return git.runGit("commit", "--allow-empty", "--no-edit")
+}
+
+// ResetHard resets the current branch and the working tree to the given revision
+func (git *git) ResetHard(rev string) error {
+	return git.runGit("reset", "--hard", rev)
+}
+
+// RevParse returns the SHA of the given revision
+func (git *git) RevParse(rev string) (string, error) {
+	output, err := git.outputGit("rev-parse", "--verify", rev)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// RevList returns the SHAs of commits selected by rev-list arguments
+func (git *git) RevList(args ...string) ([]string, error) {
+	output, err := git.outputGit(append([]string{"rev-list"}, args...)...)
+	if err != nil {
+		return nil, err
+	}
+	return splitLines(output), nil
+}
+
+// TreeWithCommits returns the tree resulting from applying diffs of commits
+// on top of base, without touching the working tree, index or any refs
+func (git *git) TreeWithCommits(base string, commits []string) (string, error) {
+	dir, err := os.MkdirTemp("", "rebase-index-")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(dir)
+	env := []string{"GIT_INDEX_FILE=" + filepath.Join(dir, "index")}
+	if _, err := git.inputGit(env, nil, "read-tree", base); err != nil {
+		return "", err
+	}
+	for _, sha := range commits {
+		diff, err := git.outputGit("diff-tree", "-p", "--binary", "--no-color", sha)
+		if err != nil {
+			return "", err
+		}
+		if _, err := git.inputGit(env, diff, "apply", "--cached"); err != nil {
+			return "", fmt.Errorf("applying diff of %s failed: %w", sha, err)
+		}
+	}
+	output, err := git.inputGit(env, nil, "write-tree")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// RebaseWithTodo rebases branch onto base with an interactive rebase, using
+// the todo file as the list of instructions, aborting the rebase on failure
+func (git *git) RebaseWithTodo(base, branch, todo string) error {
+	if err := git.ensureNotProtected(branch); err != nil {
+		return err
+	}
+	// git passes the file to edit to the editor run by the shell, the todo is
+	// passed in the environment, so that its path is not parsed by the shell
+	env := []string{`GIT_SEQUENCE_EDITOR=cp "$OPENSHIFT_REBASE_TODO"`, "OPENSHIFT_REBASE_TODO=" + todo}
+	if output, err := git.inputGit(env, nil, "rebase", "--interactive", "--keep-empty", base, branch); err != nil {
+		if err := git.runGit("rebase", "--abort"); err != nil {
+			klog.Errorf("Aborting rebase failed: %v", err)
+		}
+		return fmt.Errorf("rebase failed: %w\n%s", err, output)
+	}
+	return nil
+}
+
+// Fetch fetches a remote, including tags
+func (git *git) Fetch(remote string) error {
+	return git.runGit(append(credentials.GitArgs(), "fetch", "--tags", remote)...)
+}
+
+// FetchRef fetches a single ref from a repository URL and returns its commit,
+// no local refs are created
+func (git *git) FetchRef(url, ref string) (string, error) {
+	if err := git.runGit(append(credentials.GitArgs(), "fetch", "--no-tags", url, ref)...); err != nil {
+		return "", err
+	}
+	return git.RevParse("FETCH_HEAD^{commit}")
+}
+
+// Push pushes a branch to a remote
+func (git *git) Push(remote, branch string) error {
+	if err := git.ensureNotProtected(branch); err != nil {
+		return err
+	}
+	if remote == git.fork.OpenShiftRemote() || remote == git.fork.UpstreamRemote() {
+		return fmt.Errorf("refusing to push to %s remote, push to a fork instead", remote)
+	}
+	return git.runGit(append(credentials.GitArgs(), "push", "--force-with-lease", remote, branch)...)
+}
+
+// IsAncestor checks if commit is an ancestor of rev
+func (git *git) IsAncestor(commit, rev string) (bool, error) {
+	_, err := git.outputGit("merge-base", "--is-ancestor", commit, rev)
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// AddWorktree checks out a revision in a new detached worktree at path
+func (git *git) AddWorktree(path, rev string) error {
+	return git.runGit("worktree", "add", "--detach", path, rev)
+}
+
+// RemoveWorktree removes the worktree at path, discarding any changes in it
+func (git *git) RemoveWorktree(path string) error {
+	return git.runGit("worktree", "remove", "--force", path)
+}
+
+// ShowFile returns the content of the file at a given revision
+func (git *git) ShowFile(rev, path string) ([]byte, error) {
+	return git.outputGit("show", rev+":"+path)
+}
+
+// ListTree returns the names of entries in a directory at a given revision
+func (git *git) ListTree(rev, dir string) ([]string, error) {
+	output, err := git.outputGit("ls-tree", "--name-only", rev, strings.TrimSuffix(dir, "/")+"/")
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, l := range splitLines(output) {
+		names = append(names, path.Base(l))
+	}
+	return names, nil
+}
+
+// GrepFiles returns files at a revision containing fixed string pattern, limited to paths
+func (git *git) GrepFiles(rev, pattern string, paths []string) ([]string, error) {
+	if len(paths) == 0 {
+		return nil, nil
+	}
+	args := append([]string{"grep", "--files-with-matches", "--fixed-strings", "-e", pattern, rev, "--"}, paths...)
+	output, err := git.outputGit(args...)
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+			// no match
+			return nil, nil
+		}
+		return nil, err
+	}
+	var files []string
+	for _, l := range splitLines(output) {
+		files = append(files, strings.TrimPrefix(l, rev+":"))
+	}
+	return files, nil
+}
+
+// DiffNames returns the list of files differing between two revisions
+func (git *git) DiffNames(from, to string) ([]string, error) {
+	output, err := git.outputGit("diff", "--name-only", from, to)
+	if err != nil {
+		return nil, err
+	}
+	return splitLines(output), nil
+}
+
+// Refs returns short names of refs matching the patterns, eg. refs/tags
+func (git *git) Refs(patterns ...string) ([]string, error) {
+	output, err := git.outputGit(append([]string{"for-each-ref", "--format=%(refname:short)"}, patterns...)...)
+	if err != nil {
+		return nil, err
+	}
+	return splitLines(output), nil
+}
+
+// FileChange describes lines changed in a file by a single commit
+type FileChange struct {
+	Commit  string
+	Path    string
+	Added   int
+	Deleted int
+}
+
+// LogNumStat returns lines changed in every file by non-merge commits
+// reachable from to, but not from from, binary files count as no lines
+func (git *git) LogNumStat(from, to string) ([]FileChange, error) {
+	output, err := git.outputGit("log", "--no-merges", "--no-renames", "--numstat", "--format=commit %H", from+".."+to)
+	if err != nil {
+		return nil, err
+	}
+	var changes []FileChange
+	commit := ""
+	for _, line := range splitLines(output) {
+		if sha, ok := strings.CutPrefix(line, "commit "); ok {
+			commit = sha
+			continue
+		}
+		fields := strings.SplitN(line, "\t", 3)
+		if len(fields) != 3 {
+			continue
+		}
+		added, _ := strconv.Atoi(fields[0])
+		deleted, _ := strconv.Atoi(fields[1])
+		changes = append(changes, FileChange{Commit: commit, Path: fields[2], Added: added, Deleted: deleted})
+	}
+	return changes, nil
+}
+
+// PatchID returns the stable patch ID of a commit, or empty string for empty commits
+func (git *git) PatchID(sha string) (string, error) {
+	diff, err := git.outputGit("show", "--format=", sha)
+	if err != nil {
+		return "", err
+	}
+	output, err := git.inputGit(nil, diff, "patch-id", "--stable")
+	if err != nil {
+		return "", err
+	}
+	fields := strings.Fields(string(output))
+	if len(fields) == 0 {
+		return "", nil
+	}
+	return fields[0], nil
+}
+
+// CherryPick invokes the cherry-pick command, mainline selects the parent
+// number for merge commits and is ignored when 0
+func (git *git) CherryPick(sha string, mainline int) error {
+	return git.pickGit("cherry-pick", sha, append([]string{"cherry-pick", sha}, mainlineArgs(mainline)...)...)
+}
+
+// CherryPickSequence picks the commits in order with a single cherry-pick
+// invocation, when the sequencer stops, the stopped pick is discarded and the
+// commits picked before it are kept, the number of picked commits is returned
+// along with the error which stopped the sequencer
+func (git *git) CherryPickSequence(shas []string) (int, error) {
+	head, err := git.RevParse("HEAD")
+	if err != nil {
+		return 0, err
+	}
+	_, pickErr := git.inputGit(nil, []byte(strings.Join(shas, "\n")+"\n"), "cherry-pick", "--stdin")
+	if pickErr == nil {
+		return len(shas), nil
+	}
+	klog.V(2).Infof("Sequencer stopped: %v", pickErr)
+	if err := git.runGit("cherry-pick", "--quit"); err != nil {
+		return 0, err
+	}
+	if err := git.ResetHard("HEAD"); err != nil {
+		return 0, err
+	}
+	picked, err := git.RevList(head + "..HEAD")
+	if err != nil {
+		return 0, err
+	}
+	return len(picked), pickErr
+}
+
+// RetryCherryPick invokes the cherry-pick command with recursive strategy and theirs option
+func (git *git) RetryCherryPick(sha string, mainline int) error {
+	return git.pickGit("cherry-pick", sha, append([]string{"cherry-pick", sha, "--strategy", "recursive", "--strategy-option", "theirs"},
+		mainlineArgs(mainline)...)...)
+}
+
+// OursCherryPick invokes the cherry-pick command with ours strategy option
+func (git *git) OursCherryPick(sha string, mainline int) error {
+	// the carry can become empty when all its changes are discarded, keep it anyway
+	return git.pickGit("cherry-pick", sha, append([]string{"cherry-pick", sha, "--strategy-option", "ours", "--keep-redundant-commits"}, mainlineArgs(mainline)...)...)
+}
+
+// ChangedFiles returns the list of files modified by a commit
+func (git *git) ChangedFiles(sha string) ([]string, error) {
+	output, err := git.outputGit("diff-tree", "--no-commit-id", "--name-only", "-r", "--first-parent", sha)
+	if err != nil {
+		return nil, err
+	}
+	return splitLines(output), nil
+}
+
+// ConflictedFiles returns the list of files with unresolved conflicts
+func (git *git) ConflictedFiles() ([]string, error) {
+	output, err := git.outputGit("diff", "--name-only", "--diff-filter=U")
+	if err != nil {
+		return nil, err
+	}
+	return splitLines(output), nil
+}
+
+// UncommittedFiles returns tracked files with changes in the index or the working tree
+func (git *git) UncommittedFiles() ([]string, error) {
+	output, err := git.outputGit("status", "--porcelain", "--untracked-files=no")
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, line := range splitLines(output) {
+		// each line is the two letter status followed by the path
+		if len(line) > 3 {
+			files = append(files, line[3:])
+		}
+	}
+	return files, nil
+}
+
+// RemoteReachable checks that the remote repository can be listed without prompting for credentials
+func (git *git) RemoteReachable(remote string) error {
+	env := []string{"GIT_TERMINAL_PROMPT=0"}
+	if len(os.Getenv("GIT_SSH_COMMAND")) == 0 {
+		env = append(env, "GIT_SSH_COMMAND=ssh -o BatchMode=yes")
+	}
+	if _, err := git.inputGit(env, nil, append(credentials.GitArgs(), "ls-remote", remote, "HEAD")...); err != nil {
+		return fmt.Errorf("remote %s is not reachable: %w", remote, err)
+	}
+	return nil
+}
+
+// Version returns the version of the git binary, eg. 2.39.5
+func Version() (string, error) {
+	output, err := gitCommand("version").Output()
+	if err != nil {
+		return "", err
+	}
+	// git version 2.39.5, possibly followed by a platform suffix
+	fields := strings.Fields(string(output))
+	if len(fields) < 3 {
+		return "", fmt.Errorf("unexpected output of git version: %s", output)
+	}
+	return fields[2], nil
+}
+
+// mainlineArgs returns cherry-pick arguments selecting the mainline parent
+func mainlineArgs(mainline int) []string {
+	if mainline == 0 {
+		return nil
+	}
+	return []string{"--mainline", strconv.Itoa(mainline)}
+}
+
+// AbortCherryPick invokes the cherry-pick command
+func (git *git) AbortCherryPick() error {
+	return git.runGit("cherry-pick", "--abort")
+}
+
+// AmendAll adds all changes in the working tree to the last commit
+func (git *git) AmendAll() error {
+	if err := git.runGit("add", "--all"); err != nil {
+		return err
+	}
+	return git.runGit("commit", "--amend", "--allow-empty", "--no-edit")
+}
+
+// AmendMessage replaces the message of the last commit
+func (git *git) AmendMessage(message string) error {
+	return git.runGit("commit", "--amend", "--allow-empty", "-m", message)
+}
+
+// Apply a patch
+func (git *git) Apply(patch string) error {
+	return git.runGit("am", patch)
+}
+
+// Apply a patch with 3-way merge
+func (git *git) Apply3Way(patch string) error {
+	return git.pickGit("am", patch, "am", "--3way", patch)
+}
+
+// ApplyWithContext applies a patch requiring only given number of context lines to match
+func (git *git) ApplyWithContext(patch string, lines int) error {
+	return git.runGit("am", fmt.Sprintf("-C%d", lines), patch)
+}
+
+// AbortApply a patch
+func (git *git) AbortApply() error {
+	return git.runGit("am", "--abort")
+}
+
+// Diff returns the diff of the working tree against the index
+func (git *git) Diff() ([]byte, error) {
+	return git.outputGit("diff")
+}
+
+// ShowStage returns the content of the file at a given index stage
+func (git *git) ShowStage(stage int, path string) ([]byte, error) {
+	return git.outputGit("show", fmt.Sprintf(":%d:%s", stage, path))
+}
+
+// Status prints current status of repository
+func (git *git) Status() error {
+	// TODO runGit should return error and outputs separately
+	return git.runGit("status")
+}
+
+// gitCommand returns the git command with the arguments, messages of git are
+// not translated, so that outcomes are told by them regardless of the locale
+func gitCommand(args ...string) *exec.Cmd {
+	cmd := exec.Command("git", args...)
+	cmd.Env = append(os.Environ(), "LC_ALL=C")
+	return cmd
+}
+
+func (git *git) runGit(args ...string) error {
+	cmd := gitCommand(args...)
+	klog.V(2).Infof("Invoking %s...", cmd)
+	cmd.Dir = git.path
+	var (
+		output []byte
+		err    error
+	)
+	output, err = cmd.CombinedOutput()
+	klog.V(3).Info(string(output))
+	if err != nil {
+		klog.V(2).Infof("%s failed: %v", cmd, err)
+	}
+	return err
+}
+
+// outputGit works similarly to runGit, but returns the standard output
+func (git *git) outputGit(args ...string) ([]byte, error) {
+	cmd := gitCommand(args...)
+	klog.V(2).Infof("Invoking %s...", cmd)
+	cmd.Dir = git.path
+	output, err := cmd.Output()
+	klog.V(3).Info(string(output))
+	if err != nil {
+		klog.V(2).Infof("%s failed: %v", cmd, err)
+	}
+	return output, err
+}
+
+// inputGit works similarly to outputGit, but additionally sets environment
+// variables and passes input to the standard input of the command
+func (git *git) inputGit(env []string, input []byte, args ...string) ([]byte, error) {
+	cmd := gitCommand(args...)
+	klog.V(2).Infof("Invoking %s...", cmd)
+	cmd.Dir = git.path
+	cmd.Env = append(cmd.Env, env...)
+	cmd.Stdin = bytes.NewReader(input)
+	output, err := cmd.Output()
+	klog.V(3).Info(string(output))
+	if err != nil {
+		klog.V(2).Infof("%s failed: %v", cmd, err)
+	}
+	return output, err
+}
+
+// splitLines splits command output into a list of non-empty lines
+func splitLines(output []byte) []string {
+	var lines []string
+	for _, l := range strings.Split(string(output), "\n") {
+		if len(strings.TrimSpace(l)) > 0 {
+			lines = append(lines, l)
+		}
+	}
+	return lines
+}
+
+// CommitsByDate sorts a list of commits by commit date, commits with the same
+// date by author date and then by hash, so that the order does not depend on
+// the order the commits were read in
+type CommitsByDate []*gitv5object.Commit
+
+func (s CommitsByDate) Len() int      { return len(s) }
+func (s CommitsByDate) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+
+func (s CommitsByDate) Less(i, j int) bool {
+	if comparison := s[i].Committer.When.Compare(s[j].Committer.When); comparison != 0 {
+		return comparison < 0
+	}
+	// during rebase we frequently rebase the PR several times, this will cause
+	// a group of several commits to have identical commit date, to ensure proper
+	// ordering in those cases we will fallback to original author date
+	if comparison := s[i].Author.When.Compare(s[j].Author.When); comparison != 0 {
+		return comparison < 0
+	}
+	return s[i].Hash.String() < s[j].Hash.String()
+}
+
+// SortByDate sorts the commits by commit date. Commits with the same date, eg.
+// created by bots within a second, are ordered topologically, so that parents
+// precede children, the others the same as CommitsByDate. The history is
+// walked once for all of them, down to base, which is not an ancestor of any
+// of the commits, eg. the tag they follow, or to the root when empty.
+func SortByDate(repository Git, commits []*gitv5object.Commit, base string) error {
+	sort.Sort(CommitsByDate(commits))
+	var groups [][]*gitv5object.Commit
+	var tied []*gitv5object.Commit
+	for start := 0; start < len(commits); {
+		end := start + 1
+		for end < len(commits) && commits[end].Committer.When.Equal(commits[start].Committer.When) {
+			end++
+		}
+		if end-start > 1 {
+			groups = append(groups, commits[start:end])
+			tied = append(tied, commits[start:end]...)
+		}
+		start = end
+	}
+	if len(groups) == 0 {
+		return nil
+	}
+	positions, err := topologicalPositions(repository, tied, base)
+	if err != nil {
+		return err
+	}
+	for _, group := range groups {
+		sort.SliceStable(group, func(i, j int) bool {
+			return positions[group[i].Hash.String()] < positions[group[j].Hash.String()]
+		})
+	}
+	return nil
+}
+
+// topologicalPositions returns the positions of the commits in the
+// topological order of their history down to base, listed by a single rev-list
+func topologicalPositions(repository Git, commits []*gitv5object.Commit, base string) (map[string]int, error) {
+	args := []string{"--topo-order", "--reverse"}
+	if len(base) > 0 {
+		args = append(args, "^"+base)
+	}
+	for _, c := range commits {
+		args = append(args, c.Hash.String())
+	}
+	shas, err := repository.RevList(args...)
+	if err != nil {
+		return nil, fmt.Errorf("Error ordering commits topologically: %w", err)
+	}
+	positions := make(map[string]int, len(shas))
+	for i, sha := range shas {
+		positions[sha] = i
+	}
+	return positions, nil
+}
@@ -0,0 +1,129 @@
+package git_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	gitv5object "github.com/go-git/go-git/v5/plumbing/object"
+
+	"github.com/openshift/rebase/internal/git"
+	"github.com/openshift/rebase/internal/git/gittest"
+)
+
+// countingFake counts rev-list invocations of the fake repository
+type countingFake struct {
+	*gittest.Fake
+	revLists int
+	args     []string
+}
+
+func (f *countingFake) RevList(args ...string) ([]string, error) {
+	f.revLists++
+	f.args = args
+	return f.Fake.RevList(args...)
+}
+
+func TestSortByDate(t *testing.T) {
+	second := time.Date(2023, time.May, 1, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name string
+		// commits are added in order, each on top of the named parents,
+		// with the date offset from second in seconds
+		commits  []string
+		parents  map[string][]string
+		offsets  map[string]int
+		expected []string
+		revLists int
+		// base is the commit the history is walked down to
+		base string
+	}{
+		{
+			name:     "different dates",
+			commits:  []string{"a", "b", "c"},
+			parents:  map[string][]string{"b": {"a"}, "c": {"b"}},
+			offsets:  map[string]int{"a": 0, "b": 1, "c": 2},
+			expected: []string{"a", "b", "c"},
+		},
+		{
+			name:     "chain within a second",
+			commits:  []string{"a", "b", "c", "d"},
+			parents:  map[string][]string{"b": {"a"}, "c": {"b"}, "d": {"c"}},
+			expected: []string{"a", "b", "c", "d"},
+			revLists: 1,
+		},
+		{
+			name:     "merge within a second",
+			commits:  []string{"base", "left", "right", "merge"},
+			parents:  map[string][]string{"left": {"base"}, "right": {"base"}, "merge": {"left", "right"}},
+			offsets:  map[string]int{"base": -1},
+			expected: []string{"base", "left", "right", "merge"},
+			revLists: 1,
+		},
+		{
+			name:     "groups in different seconds listed once",
+			commits:  []string{"a", "b", "c", "d"},
+			parents:  map[string][]string{"b": {"a"}, "c": {"b"}, "d": {"c"}},
+			offsets:  map[string]int{"c": 1, "d": 1},
+			expected: []string{"a", "b", "c", "d"},
+			revLists: 1,
+		},
+		{
+			name:     "history walked down to the base",
+			commits:  []string{"tag", "a", "b"},
+			parents:  map[string][]string{"a": {"tag"}, "b": {"a"}},
+			offsets:  map[string]int{"tag": -1},
+			base:     "tag",
+			expected: []string{"tag", "a", "b"},
+			revLists: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repository := &countingFake{Fake: gittest.New(t.TempDir())}
+			shas := make(map[string]string)
+			names := make(map[string]string)
+			var commits []*gitv5object.Commit
+			for _, name := range tt.commits {
+				var parents []string
+				for _, p := range tt.parents[name] {
+					parents = append(parents, shas[p])
+				}
+				when := second.Add(time.Duration(tt.offsets[name]) * time.Second)
+				shas[name] = repository.AddCommit(gittest.Commit{Message: name, Parents: parents, When: when})
+				names[shas[name]] = name
+			}
+			// the input order is the reverse of the history, so that the
+			// expected order is not the one the commits were given in
+			for i := len(tt.commits) - 1; i >= 0; i-- {
+				commits = append(commits, commitOf(t, repository.Fake, shas[tt.commits[i]]))
+			}
+			if err := git.SortByDate(repository, commits, shas[tt.base]); err != nil {
+				t.Fatal(err)
+			}
+			var sorted []string
+			for _, c := range commits {
+				sorted = append(sorted, names[c.Hash.String()])
+			}
+			if !reflect.DeepEqual(sorted, tt.expected) {
+				t.Errorf("SortByDate() = %v, expected %v", sorted, tt.expected)
+			}
+			if repository.revLists != tt.revLists {
+				t.Errorf("SortByDate() listed revisions %d times, expected %d", repository.revLists, tt.revLists)
+			}
+			if len(tt.base) > 0 && (len(repository.args) < 3 || repository.args[2] != "^"+shas[tt.base]) {
+				t.Errorf("SortByDate() listed revisions with %v, expected them to exclude the base", repository.args)
+			}
+		})
+	}
+}
+
+func commitOf(t *testing.T, repository *gittest.Fake, sha string) *gitv5object.Commit {
+	t.Helper()
+	commit, err := repository.Commit(plumbing.NewHash(sha))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return commit
+}
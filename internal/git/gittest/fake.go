@@ -0,0 +1,1060 @@
+// Package gittest provides an in-memory implementation of git.Git, so that
+// code driving the repository can be exercised without a real repository.
+package gittest
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	gitv5object "github.com/go-git/go-git/v5/plumbing/object"
+
+	"github.com/openshift/rebase/internal/git"
+)
+
+// ErrUnsupported is returned by operations the fake does not simulate.
+var ErrUnsupported = errors.New("not supported by the fake repository")
+
+// Commit describes a commit added to the fake repository.
+type Commit struct {
+	// Message is the full commit message
+	Message string
+	// Parents are SHAs or refs of the parents, the first one is the mainline
+	Parents []string
+	// Files are the files changed by the commit
+	Files []string
+	// Author is the name of the author, defaults to tester
+	Author string
+	// When is the author and commit date, defaults to a second after the
+	// previously added commit
+	When time.Time
+}
+
+// Outcome is the scripted result of picking or applying a commit or patch.
+type Outcome struct {
+	// Outcome is why the operation stops, see git.Outcome
+	Outcome git.Outcome
+	// Files are the conflicted files left by git.OutcomeConflict
+	Files []string
+}
+
+// Fake is an in-memory repository implementing git.Git. Commits are added with
+// AddCommit and refs with SetRef, picks succeed unless their outcome is
+// scripted with SetOutcome. It is safe for concurrent use.
+type Fake struct {
+	lock   sync.Mutex
+	gitDir string
+	// commits are all commits by SHA
+	commits map[string]*gitv5object.Commit
+	// files are the files changed by commits by SHA
+	files map[string][]string
+	// refs are full ref names, eg. refs/heads/main, mapped to SHAs
+	refs map[string]string
+	// branch is the checked out branch, empty when HEAD is detached at detached
+	branch   string
+	detached string
+	// outcomes are the scripted outcomes by SHA or patch
+	outcomes map[string]Outcome
+	// inProgress is the operation stopped on conflicts
+	inProgress string
+	conflicts  []string
+	// picking is the commit or patch whose pick is in progress
+	picking     string
+	uncommitted []string
+	remotes     map[string]error
+	calls       []string
+	last        time.Time
+	created     int
+}
+
+var _ git.Git = &Fake{}
+
+// New returns an empty repository with main checked out, gitDir is returned
+// as the git directory, where the state and caches are written.
+func New(gitDir string) *Fake {
+	return &Fake{
+		gitDir:   gitDir,
+		commits:  make(map[string]*gitv5object.Commit),
+		files:    make(map[string][]string),
+		refs:     make(map[string]string),
+		branch:   "main",
+		outcomes: make(map[string]Outcome),
+		remotes:  make(map[string]error),
+		last:     time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC),
+	}
+}
+
+// AddCommit adds a commit and returns its SHA, refs are not updated.
+func (f *Fake) AddCommit(c Commit) string {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	var parents []plumbing.Hash
+	for _, p := range c.Parents {
+		sha, err := f.resolve(p)
+		if err != nil {
+			panic(fmt.Sprintf("unknown parent %s of %q: %v", p, c.Message, err))
+		}
+		parents = append(parents, plumbing.NewHash(sha))
+	}
+	return f.add(c, parents)
+}
+
+// Commits adds a chain of commits with the messages on top of ref, which is
+// moved to the last one, and returns their SHAs.
+func (f *Fake) Commits(ref string, messages ...string) []string {
+	var shas []string
+	for _, message := range messages {
+		sha := f.AddCommit(Commit{Message: message, Parents: f.parentsOf(ref)})
+		f.SetRef(ref, sha)
+		shas = append(shas, sha)
+	}
+	return shas
+}
+
+// parentsOf returns the commit ref points to as the only parent, or none
+// when the ref does not exist yet
+func (f *Fake) parentsOf(ref string) []string {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if sha, err := f.resolve(ref); err == nil {
+		return []string{sha}
+	}
+	return nil
+}
+
+func (f *Fake) add(c Commit, parents []plumbing.Hash) string {
+	f.created++
+	when := c.When
+	if when.IsZero() {
+		when = f.last.Add(time.Second)
+	}
+	if when.After(f.last) {
+		f.last = when
+	}
+	author := c.Author
+	if len(author) == 0 {
+		author = "tester"
+	}
+	sum := sha1.Sum([]byte(fmt.Sprintf("%d\x00%s\x00%v", f.created, c.Message, parents)))
+	sha := hex.EncodeToString(sum[:])
+	signature := gitv5object.Signature{Name: author, Email: author + "@example.com", When: when}
+	f.commits[sha] = &gitv5object.Commit{
+		Hash:         plumbing.NewHash(sha),
+		Message:      c.Message,
+		Author:       signature,
+		Committer:    signature,
+		ParentHashes: parents,
+	}
+	f.files[sha] = c.Files
+	return sha
+}
+
+// SetRef points a ref to the revision, short names are branches, unless
+// they start with refs/, eg. refs/tags/v1.0.0 or refs/remotes/upstream/master.
+func (f *Fake) SetRef(ref, rev string) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	sha, err := f.resolve(rev)
+	if err != nil {
+		panic(fmt.Sprintf("unknown revision %s: %v", rev, err))
+	}
+	f.refs[fullRef(ref)] = sha
+}
+
+// SetOutcome scripts the outcome of picking the commit, or applying the patch.
+func (f *Fake) SetOutcome(shaOrPatch string, outcome git.Outcome, files ...string) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.outcomes[shaOrPatch] = Outcome{Outcome: outcome, Files: files}
+}
+
+// SetUncommitted sets the files reported with uncommitted changes.
+func (f *Fake) SetUncommitted(files ...string) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.uncommitted = files
+}
+
+// SetRemoteError makes the remote unreachable with the error.
+func (f *Fake) SetRemoteError(remote string, err error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.remotes[remote] = err
+}
+
+// Calls returns the operations changing the repository in the order they were
+// invoked, eg. "cherry-pick <sha>" or "checkout main".
+func (f *Fake) Calls() []string {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return append([]string(nil), f.calls...)
+}
+
+func (f *Fake) record(format string, args ...interface{}) {
+	f.calls = append(f.calls, fmt.Sprintf(format, args...))
+}
+
+func fullRef(ref string) string {
+	if strings.HasPrefix(ref, "refs/") {
+		return ref
+	}
+	return "refs/heads/" + ref
+}
+
+// head returns the SHA of HEAD, empty when there are no commits
+func (f *Fake) head() string {
+	if len(f.branch) > 0 {
+		return f.refs[fullRef(f.branch)]
+	}
+	return f.detached
+}
+
+func (f *Fake) setHead(sha string) {
+	if len(f.branch) > 0 {
+		f.refs[fullRef(f.branch)] = sha
+		return
+	}
+	f.detached = sha
+}
+
+// resolve returns the SHA of a revision, ie. HEAD, a full or abbreviated SHA, a
+// ref with or without the refs/ prefix, optionally followed by ^{commit}, ^N or ~N
+func (f *Fake) resolve(rev string) (string, error) {
+	rev = strings.TrimSuffix(rev, "^{commit}")
+	if i := strings.LastIndexAny(rev, "^~"); i > 0 {
+		sha, err := f.resolve(rev[:i])
+		if err != nil {
+			return "", err
+		}
+		n := 1
+		if len(rev) > i+1 {
+			if n, err = strconv.Atoi(rev[i+1:]); err != nil {
+				return "", fmt.Errorf("invalid revision %s", rev)
+			}
+		}
+		if rev[i] == '^' {
+			return f.parent(sha, n)
+		}
+		for ; n > 0; n-- {
+			if sha, err = f.parent(sha, 1); err != nil {
+				return "", err
+			}
+		}
+		return sha, nil
+	}
+	if rev == "HEAD" {
+		if sha := f.head(); len(sha) > 0 {
+			return sha, nil
+		}
+		return "", fmt.Errorf("HEAD does not point to any commit")
+	}
+	for _, candidate := range []string{rev, "refs/heads/" + rev, "refs/tags/" + rev, "refs/remotes/" + rev} {
+		if sha, ok := f.refs[candidate]; ok {
+			return sha, nil
+		}
+	}
+	var matches []string
+	if len(rev) >= 4 {
+		for sha := range f.commits {
+			if strings.HasPrefix(sha, rev) {
+				matches = append(matches, sha)
+			}
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return "", fmt.Errorf("unknown revision %s", rev)
+	case 1:
+		return matches[0], nil
+	}
+	return "", fmt.Errorf("short SHA %s is ambiguous", rev)
+}
+
+func (f *Fake) parent(sha string, n int) (string, error) {
+	if n == 0 {
+		return sha, nil
+	}
+	c := f.commits[sha]
+	if c == nil || len(c.ParentHashes) < n {
+		return "", fmt.Errorf("%s has no parent %d", sha, n)
+	}
+	return c.ParentHashes[n-1].String(), nil
+}
+
+// reachable returns the SHAs reachable from the revisions, including them
+func (f *Fake) reachable(shas ...string) map[string]bool {
+	seen := make(map[string]bool)
+	queue := append([]string(nil), shas...)
+	for len(queue) > 0 {
+		sha := queue[0]
+		queue = queue[1:]
+		if seen[sha] || f.commits[sha] == nil {
+			continue
+		}
+		seen[sha] = true
+		for _, p := range f.commits[sha].ParentHashes {
+			queue = append(queue, p.String())
+		}
+	}
+	return seen
+}
+
+// newestFirst orders the commits the same as rev-list, by commit date with
+// children before their parents
+func (f *Fake) newestFirst(set map[string]bool) []*gitv5object.Commit {
+	commits := make([]*gitv5object.Commit, 0, len(set))
+	for sha := range set {
+		commits = append(commits, f.commits[sha])
+	}
+	depths := make(map[plumbing.Hash]int, len(commits))
+	for _, c := range commits {
+		depths[c.Hash] = len(f.reachable(c.Hash.String()))
+	}
+	sort.Slice(commits, func(i, j int) bool {
+		if !commits[i].Committer.When.Equal(commits[j].Committer.When) {
+			return commits[i].Committer.When.After(commits[j].Committer.When)
+		}
+		if depths[commits[i].Hash] != depths[commits[j].Hash] {
+			return depths[commits[i].Hash] > depths[commits[j].Hash]
+		}
+		return commits[i].Hash.String() > commits[j].Hash.String()
+	})
+	return commits
+}
+
+// mergeBase returns the newest common ancestor of a and b
+func (f *Fake) mergeBase(a, b string) (string, error) {
+	common, other := f.reachable(a), f.reachable(b)
+	for sha := range common {
+		if !other[sha] {
+			delete(common, sha)
+		}
+	}
+	if commits := f.newestFirst(common); len(commits) > 0 {
+		return commits[0].Hash.String(), nil
+	}
+	return "", fmt.Errorf("no merge base of %s and %s", a, b)
+}
+
+// pick records a commit with the message and files of the picked commit or
+// patch on top of HEAD, unless its scripted outcome stops it
+func (f *Fake) pick(operation, shaOrPatch, message string, files []string) error {
+	if len(f.inProgress) > 0 {
+		return &git.ConflictError{Operation: operation, Commit: shaOrPatch, Outcome: git.OutcomeInProgress, Err: fmt.Errorf("%s is in progress", f.inProgress)}
+	}
+	outcome, scripted := f.outcomes[shaOrPatch]
+	if scripted && len(outcome.Outcome) > 0 {
+		err := &git.ConflictError{Operation: operation, Commit: shaOrPatch, Outcome: outcome.Outcome, Err: fmt.Errorf("exit status 1")}
+		switch outcome.Outcome {
+		case git.OutcomeConflict:
+			err.Files = outcome.Files
+			f.inProgress, f.conflicts, f.picking = operation, outcome.Files, shaOrPatch
+		case git.OutcomeEmpty:
+			f.inProgress, f.picking = operation, shaOrPatch
+		}
+		return err
+	}
+	f.commit(message, files)
+	return nil
+}
+
+// commit adds a commit on top of HEAD and moves HEAD to it
+func (f *Fake) commit(message string, files []string) {
+	var parents []plumbing.Hash
+	if head := f.head(); len(head) > 0 {
+		parents = []plumbing.Hash{plumbing.NewHash(head)}
+	}
+	f.setHead(f.add(Commit{Message: message, Files: files}, parents))
+}
+
+// AbortCherryPick aborts the current cherry-pick command
+func (f *Fake) AbortCherryPick() error {
+	return f.AbortInProgress()
+}
+
+// AbortApply aborts the current apply command
+func (f *Fake) AbortApply() error {
+	return f.AbortInProgress()
+}
+
+// AmendAll adds all changes in the working tree to the last commit
+func (f *Fake) AmendAll() error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.record("amend")
+	f.uncommitted = nil
+	return nil
+}
+
+// AmendMessage replaces the message of the last commit
+func (f *Fake) AmendMessage(message string) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.record("amend -m %s", message)
+	head := f.commits[f.head()]
+	if head == nil {
+		return fmt.Errorf("HEAD does not point to any commit")
+	}
+	f.setHead(f.add(Commit{Message: message, Files: f.files[head.Hash.String()], Author: head.Author.Name, When: head.Author.When}, head.ParentHashes))
+	return nil
+}
+
+// Apply a patch
+func (f *Fake) Apply(patch string) error {
+	return f.apply("am", patch)
+}
+
+// Apply3Way applies a patch with 3-way merge
+func (f *Fake) Apply3Way(patch string) error {
+	return f.apply("am --3way", patch)
+}
+
+// ApplyWithContext applies a patch requiring only given number of context lines to match
+func (f *Fake) ApplyWithContext(patch string, lines int) error {
+	return f.apply(fmt.Sprintf("am -C%d", lines), patch)
+}
+
+func (f *Fake) apply(operation, patch string) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.record("%s %s", operation, patch)
+	return f.pick("am", patch, "Applied "+patch, nil)
+}
+
+// Checkout the specified remote
+func (f *Fake) Checkout(remote string) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.record("checkout %s", remote)
+	if _, ok := f.refs[fullRef(remote)]; ok && !strings.HasPrefix(remote, "refs/") {
+		f.branch = remote
+		return nil
+	}
+	sha, err := f.resolve(remote)
+	if err != nil {
+		return err
+	}
+	f.branch, f.detached = "", sha
+	return nil
+}
+
+// CreateBranch creates a named branch based on remote
+func (f *Fake) CreateBranch(name, remote string) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.record("checkout -b %s %s", name, remote)
+	if _, ok := f.refs[fullRef(name)]; ok {
+		return fmt.Errorf("a branch named %s already exists", name)
+	}
+	sha, err := f.resolve(remote)
+	if err != nil {
+		return err
+	}
+	f.refs[fullRef(name)] = sha
+	f.branch = name
+	return nil
+}
+
+// CurrentBranch returns the name of the checked out branch, or empty string when HEAD is detached
+func (f *Fake) CurrentBranch() (string, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return f.branch, nil
+}
+
+// DeleteBranch forcefully deletes a named branch
+func (f *Fake) DeleteBranch(name string) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.record("branch -D %s", name)
+	if _, ok := f.refs[fullRef(name)]; !ok {
+		return fmt.Errorf("branch %s not found", name)
+	}
+	delete(f.refs, fullRef(name))
+	return nil
+}
+
+// ChangedFiles returns the list of files modified by a commit
+func (f *Fake) ChangedFiles(sha string) ([]string, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	resolved, err := f.resolve(sha)
+	if err != nil {
+		return nil, err
+	}
+	return append([]string(nil), f.files[resolved]...), nil
+}
+
+// CherryPick picks the commit, unless its outcome is scripted
+func (f *Fake) CherryPick(sha string, mainline int) error {
+	return f.cherryPick("cherry-pick", sha)
+}
+
+// RetryCherryPick picks the commit with theirs option, unless its outcome is scripted
+func (f *Fake) RetryCherryPick(sha string, mainline int) error {
+	return f.cherryPick("cherry-pick --strategy-option theirs", sha)
+}
+
+// OursCherryPick picks the commit with ours option, unless its outcome is scripted
+func (f *Fake) OursCherryPick(sha string, mainline int) error {
+	return f.cherryPick("cherry-pick --strategy-option ours", sha)
+}
+
+func (f *Fake) cherryPick(operation, sha string) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.record("%s %s", operation, sha)
+	resolved, err := f.resolve(sha)
+	if err != nil {
+		return &git.ConflictError{Operation: "cherry-pick", Commit: sha, Outcome: git.OutcomeUnknownCommit, Err: err}
+	}
+	c := f.commits[resolved]
+	return f.pick("cherry-pick", resolved, c.Message, f.files[resolved])
+}
+
+// CherryPickSequence picks the commits until the first one with a scripted
+// outcome, which is discarded
+func (f *Fake) CherryPickSequence(shas []string) (int, error) {
+	for i, sha := range shas {
+		if err := f.CherryPick(sha, 0); err != nil {
+			f.AbortInProgress()
+			return i, err
+		}
+	}
+	return len(shas), nil
+}
+
+// ResolveConflicts resolves conflicts in files by accepting one side, either ours or theirs
+func (f *Fake) ResolveConflicts(side string, files []string) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if side != "ours" && side != "theirs" {
+		return fmt.Errorf("invalid conflict side %q, expected ours or theirs", side)
+	}
+	f.record("checkout --%s %s", side, strings.Join(files, " "))
+	var remaining []string
+	for _, c := range f.conflicts {
+		if !contains(files, c) {
+			remaining = append(remaining, c)
+		}
+	}
+	f.conflicts = remaining
+	return nil
+}
+
+// StageAll adds all changes in the working tree to the index
+func (f *Fake) StageAll() error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.record("add --all")
+	f.conflicts = nil
+	return nil
+}
+
+// CommitResolved commits the resolved pick with the original message
+func (f *Fake) CommitResolved() error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.record("commit")
+	return f.finish()
+}
+
+// finish commits the pick in progress once its conflicts are resolved
+func (f *Fake) finish() error {
+	if len(f.conflicts) > 0 {
+		return fmt.Errorf("unresolved conflicts in %s", strings.Join(f.conflicts, ", "))
+	}
+	message, files := "Applied "+f.picking, []string(nil)
+	if c := f.commits[f.picking]; c != nil {
+		message, files = c.Message, f.files[f.picking]
+	}
+	if len(f.picking) > 0 {
+		f.commit(message, files)
+	}
+	f.inProgress, f.picking = "", ""
+	return nil
+}
+
+// ResetHard resets the current branch and the working tree to the given revision
+func (f *Fake) ResetHard(rev string) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.record("reset --hard %s", rev)
+	sha, err := f.resolve(rev)
+	if err != nil {
+		return err
+	}
+	f.setHead(sha)
+	f.uncommitted, f.conflicts = nil, nil
+	return nil
+}
+
+// RevParse returns the SHA of the given revision
+func (f *Fake) RevParse(rev string) (string, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return f.resolve(rev)
+}
+
+// RevList supports revisions, A..B ranges, ^ exclusions, --count, --reverse
+// and --topo-order, which is the order of all listed commits
+func (f *Fake) RevList(args ...string) ([]string, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	var include, exclude []string
+	count, reverse := false, false
+	for _, arg := range args {
+		switch {
+		case arg == "--count":
+			count = true
+		case arg == "--reverse":
+			reverse = true
+		case arg == "--topo-order":
+		case strings.HasPrefix(arg, "-"):
+			return nil, fmt.Errorf("rev-list %s: %w", arg, ErrUnsupported)
+		case strings.Contains(arg, ".."):
+			from, to, _ := strings.Cut(arg, "..")
+			exclude, include = append(exclude, from), append(include, to)
+		case strings.HasPrefix(arg, "^"):
+			exclude = append(exclude, arg[1:])
+		default:
+			include = append(include, arg)
+		}
+	}
+	set, err := f.revisions(include, exclude)
+	if err != nil {
+		return nil, err
+	}
+	if count {
+		return []string{strconv.Itoa(len(set))}, nil
+	}
+	commits := f.newestFirst(set)
+	shas := make([]string, 0, len(commits))
+	for _, c := range commits {
+		shas = append(shas, c.Hash.String())
+	}
+	if reverse {
+		for i, j := 0, len(shas)-1; i < j; i, j = i+1, j-1 {
+			shas[i], shas[j] = shas[j], shas[i]
+		}
+	}
+	return shas, nil
+}
+
+// revisions returns the commits reachable from include, but not from exclude
+func (f *Fake) revisions(include, exclude []string) (map[string]bool, error) {
+	resolve := func(revs []string) ([]string, error) {
+		var shas []string
+		for _, rev := range revs {
+			sha, err := f.resolve(rev)
+			if err != nil {
+				return nil, err
+			}
+			shas = append(shas, sha)
+		}
+		return shas, nil
+	}
+	included, err := resolve(include)
+	if err != nil {
+		return nil, err
+	}
+	excluded, err := resolve(exclude)
+	if err != nil {
+		return nil, err
+	}
+	set := f.reachable(included...)
+	for sha := range f.reachable(excluded...) {
+		delete(set, sha)
+	}
+	return set, nil
+}
+
+// TreeWithCommits returns a tree named after the base and the commits
+func (f *Fake) TreeWithCommits(base string, commits []string) (string, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	sum := sha1.Sum([]byte(base + strings.Join(commits, "")))
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// RebaseWithTodo is not supported
+func (f *Fake) RebaseWithTodo(base, branch, todo string) error {
+	return ErrUnsupported
+}
+
+// Push records the push of a branch to a remote
+func (f *Fake) Push(remote, branch string) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.record("push %s %s", remote, branch)
+	return f.remotes[remote]
+}
+
+// Fetch records the fetch of a remote
+func (f *Fake) Fetch(remote string) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.record("fetch %s", remote)
+	return f.remotes[remote]
+}
+
+// FetchRef is not supported
+func (f *Fake) FetchRef(url, ref string) (string, error) {
+	return "", ErrUnsupported
+}
+
+// IsAncestor checks if commit is an ancestor of rev
+func (f *Fake) IsAncestor(commit, rev string) (bool, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	ancestor, err := f.resolve(commit)
+	if err != nil {
+		return false, err
+	}
+	descendant, err := f.resolve(rev)
+	if err != nil {
+		return false, err
+	}
+	return f.reachable(descendant)[ancestor], nil
+}
+
+// AddWorktree is not supported
+func (f *Fake) AddWorktree(path, rev string) error {
+	return ErrUnsupported
+}
+
+// RemoveWorktree is not supported
+func (f *Fake) RemoveWorktree(path string) error {
+	return ErrUnsupported
+}
+
+// ShowFile is not supported, the fake does not hold file contents
+func (f *Fake) ShowFile(rev, path string) ([]byte, error) {
+	return nil, ErrUnsupported
+}
+
+// ListTree is not supported, the fake does not hold file contents
+func (f *Fake) ListTree(rev, dir string) ([]string, error) {
+	return nil, ErrUnsupported
+}
+
+// GrepFiles is not supported, the fake does not hold file contents
+func (f *Fake) GrepFiles(rev, pattern string, paths []string) ([]string, error) {
+	return nil, ErrUnsupported
+}
+
+// DiffNames returns the files changed by the commits on to, which are not on from
+func (f *Fake) DiffNames(from, to string) ([]string, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	set, err := f.revisions([]string{to}, []string{from})
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for sha := range set {
+		for _, file := range f.files[sha] {
+			if !contains(names, file) {
+				names = append(names, file)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// Refs returns short names of refs matching the patterns, eg. refs/tags
+func (f *Fake) Refs(patterns ...string) ([]string, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	var names []string
+	for ref := range f.refs {
+		for _, pattern := range patterns {
+			if strings.HasPrefix(ref, strings.TrimSuffix(pattern, "/")+"/") {
+				names = append(names, strings.TrimPrefix(ref, strings.TrimSuffix(pattern, "/")+"/"))
+				break
+			}
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// LogNumStat is not supported, the fake does not hold file contents
+func (f *Fake) LogNumStat(from, to string) ([]git.FileChange, error) {
+	return nil, ErrUnsupported
+}
+
+// PatchID returns the message and files of the commit as its patch ID
+func (f *Fake) PatchID(sha string) (string, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	resolved, err := f.resolve(sha)
+	if err != nil {
+		return "", err
+	}
+	if len(f.files[resolved]) == 0 {
+		return "", nil
+	}
+	sum := sha1.Sum([]byte(strings.Join(f.files[resolved], "\x00")))
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// ConflictedFiles returns the list of files with unresolved conflicts
+func (f *Fake) ConflictedFiles() ([]string, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return append([]string(nil), f.conflicts...), nil
+}
+
+// AbortInProgress aborts any in-progress operation
+func (f *Fake) AbortInProgress() error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if len(f.inProgress) > 0 {
+		f.record("%s --abort", f.inProgress)
+	}
+	f.inProgress, f.conflicts, f.picking = "", nil, ""
+	return nil
+}
+
+// ContinueInProgress commits the in-progress pick once its conflicts are resolved
+func (f *Fake) ContinueInProgress() error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if len(f.inProgress) == 0 {
+		return nil
+	}
+	f.record("%s --continue", f.inProgress)
+	return f.finish()
+}
+
+// InProgress returns the name of the in-progress operation
+func (f *Fake) InProgress() (string, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return f.inProgress, nil
+}
+
+// UncommittedFiles returns the files set by SetUncommitted
+func (f *Fake) UncommittedFiles() ([]string, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return append([]string(nil), f.uncommitted...), nil
+}
+
+// RemoteReachable returns the error set by SetRemoteError
+func (f *Fake) RemoteReachable(remote string) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return f.remotes[remote]
+}
+
+// Commit returns commit for a given hash
+func (f *Fake) Commit(hash plumbing.Hash) (*gitv5object.Commit, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if c := f.commits[hash.String()]; c != nil {
+		return c, nil
+	}
+	return nil, plumbing.ErrObjectNotFound
+}
+
+// CommitTree creates a commit with a given parent, without updating any refs
+func (f *Fake) CommitTree(tree, parent, message string) (string, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	sha, err := f.resolve(parent)
+	if err != nil {
+		return "", err
+	}
+	return f.add(Commit{Message: message}, []plumbing.Hash{plumbing.NewHash(sha)}), nil
+}
+
+// LogFromTag returns the commits on HEAD descending from the provided tag
+func (f *Fake) LogFromTag(tag string) ([]*gitv5object.Commit, error) {
+	var commits []*gitv5object.Commit
+	err := f.ForEachFromTag(tag, func(c *gitv5object.Commit) error {
+		commits = append(commits, c)
+		return nil
+	})
+	return commits, err
+}
+
+// ForEachFromTag calls fn for the commits on HEAD descending from the provided tag, newest first
+func (f *Fake) ForEachFromTag(tag string, fn func(*gitv5object.Commit) error) error {
+	return f.ForEachFromTagOn(tag, "HEAD", fn)
+}
+
+// ForEachFromTagOn calls fn for the commits on ref descending from the provided tag, newest first
+func (f *Fake) ForEachFromTagOn(tag, ref string, fn func(*gitv5object.Commit) error) error {
+	f.lock.Lock()
+	tagSHA, err := f.resolve(tag)
+	if err != nil {
+		f.lock.Unlock()
+		return fmt.Errorf("tag %s not found: %w", tag, err)
+	}
+	set, err := f.revisions([]string{ref}, []string{tagSHA})
+	if err != nil {
+		f.lock.Unlock()
+		return err
+	}
+	// the ancestry path holds only descendants of the tag
+	for sha := range set {
+		if !f.reachable(sha)[tagSHA] {
+			delete(set, sha)
+		}
+	}
+	commits := f.newestFirst(set)
+	f.lock.Unlock()
+	return forEach(commits, fn)
+}
+
+// LogRange returns the commits on to, which are not reachable from the merge base of since and to
+func (f *Fake) LogRange(since, to string) ([]*gitv5object.Commit, error) {
+	var commits []*gitv5object.Commit
+	err := f.ForEachInRange(since, to, func(c *gitv5object.Commit) error {
+		commits = append(commits, c)
+		return nil
+	})
+	return commits, err
+}
+
+// ForEachInRange calls fn for the commits on to, which are not reachable from the merge base of since and to, newest first
+func (f *Fake) ForEachInRange(since, to string, fn func(*gitv5object.Commit) error) error {
+	f.lock.Lock()
+	commits, err := f.inRange(since, to)
+	f.lock.Unlock()
+	if err != nil {
+		return err
+	}
+	return forEach(commits, fn)
+}
+
+func (f *Fake) inRange(since, to string) ([]*gitv5object.Commit, error) {
+	sinceSHA, err := f.resolve(since)
+	if err != nil {
+		return nil, err
+	}
+	toSHA, err := f.resolve(to)
+	if err != nil {
+		return nil, err
+	}
+	base, err := f.mergeBase(sinceSHA, toSHA)
+	if err != nil {
+		return nil, err
+	}
+	set, err := f.revisions([]string{toSHA}, []string{base})
+	if err != nil {
+		return nil, err
+	}
+	return f.newestFirst(set), nil
+}
+
+// forEach calls fn for the commits, returning git.ErrStop ends the walk
+func forEach(commits []*gitv5object.Commit, fn func(*gitv5object.Commit) error) error {
+	for _, c := range commits {
+		if err := fn(c); err != nil {
+			if errors.Is(err, git.ErrStop) {
+				return nil
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// FindMarker returns the latest commit reachable from ref with a message matching isMarker
+func (f *Fake) FindMarker(ref string, search git.MarkerSearch, isMarker func(message string) bool) (*gitv5object.Commit, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	sha, err := f.resolve(ref)
+	if err != nil {
+		return nil, err
+	}
+	walked := 0
+	for _, c := range f.newestFirst(f.reachable(sha)) {
+		if search.MaxCommits > 0 && walked >= search.MaxCommits {
+			break
+		}
+		if !search.Since.IsZero() && c.Committer.When.Before(search.Since) {
+			continue
+		}
+		walked++
+		if isMarker(c.Message) {
+			return c, nil
+		}
+	}
+	return nil, &git.MarkerNotFoundError{Range: ref, Walked: walked}
+}
+
+// Diff returns an empty diff, the fake has no working tree
+func (f *Fake) Diff() ([]byte, error) {
+	return nil, nil
+}
+
+// IsProtected checks if the branch is master, main or a release-* branch
+func (f *Fake) IsProtected(branch string) (bool, error) {
+	return branch == "master" || branch == "main" || strings.HasPrefix(branch, "release-"), nil
+}
+
+// GitDir returns the directory passed to New
+func (f *Fake) GitDir() (string, error) {
+	return f.gitDir, nil
+}
+
+// Merge records a merge commit of remote with ours strategy
+func (f *Fake) Merge(remote string) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.record("merge %s", remote)
+	sha, err := f.resolve(remote)
+	if err != nil {
+		return err
+	}
+	parents := []plumbing.Hash{plumbing.NewHash(f.head()), plumbing.NewHash(sha)}
+	f.setHead(f.add(Commit{Message: fmt.Sprintf("Merge remote-tracking branch '%s'", remote)}, parents))
+	return nil
+}
+
+// MergeTree simulates the pick of commit on top of base, returning the
+// scripted conflicts of the commit
+func (f *Fake) MergeTree(base, commit string, mainline int) (string, []string, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	resolved, err := f.resolve(commit)
+	if err != nil {
+		return "", nil, err
+	}
+	sum := sha1.Sum([]byte(base + resolved))
+	if outcome := f.outcomes[resolved]; outcome.Outcome == git.OutcomeConflict {
+		return "", outcome.Files, nil
+	}
+	return hex.EncodeToString(sum[:]), nil, nil
+}
+
+// ShowStage is not supported, the fake does not hold file contents
+func (f *Fake) ShowStage(stage int, path string) ([]byte, error) {
+	return nil, ErrUnsupported
+}
+
+// Status does nothing, the fake has no working tree
+func (f *Fake) Status() error {
+	return nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,83 @@
+package git
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// initRepository creates a repository with a commit changing a file, which
+// is also present on main, so that picking it again leaves no changes
+func initRepository(t *testing.T) (string, string) {
+	t.Helper()
+	dir := t.TempDir()
+	run := func(args ...string) string {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		output, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Skipf("running git %s failed: %v\n%s", strings.Join(args, " "), err, output)
+		}
+		return strings.TrimSpace(string(output))
+	}
+	write := func(content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, "file"), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	run("init", "--initial-branch", "main")
+	write("initial\n")
+	run("add", "file")
+	run("commit", "--message", "initial commit")
+	run("checkout", "--quiet", "-b", "feature")
+	write("changed\n")
+	run("commit", "--all", "--message", "change the file")
+	sha := run("rev-parse", "HEAD")
+	run("checkout", "--quiet", "main")
+	run("merge", "--quiet", "--ff-only", "feature")
+	return dir, sha
+}
+
+func TestPickOutcomeLocale(t *testing.T) {
+	// git translates its messages to german, unless the locale is overridden
+	t.Setenv("LC_ALL", "C.UTF-8")
+	t.Setenv("LANGUAGE", "de")
+	for _, name := range []string{"GIT_AUTHOR_NAME", "GIT_COMMITTER_NAME"} {
+		t.Setenv(name, "test")
+	}
+	for _, name := range []string{"GIT_AUTHOR_EMAIL", "GIT_COMMITTER_EMAIL"} {
+		t.Setenv(name, "test@example.com")
+	}
+	dir, sha := initRepository(t)
+	tests := []struct {
+		name     string
+		commit   string
+		expected Outcome
+	}{
+		{
+			name:     "empty pick",
+			commit:   sha,
+			expected: OutcomeEmpty,
+		},
+		{
+			name:     "unknown commit",
+			commit:   "0123456789abcdef0123456789abcdef01234567",
+			expected: OutcomeUnknownCommit,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			repository := &git{path: dir}
+			err := repository.pickGit("cherry-pick", test.commit, "cherry-pick", test.commit)
+			if outcome := OutcomeOf(err); outcome != test.expected {
+				t.Errorf("expected %v, got %v: %v", test.expected, outcome, err)
+			}
+			// the empty pick is left in progress
+			exec.Command("git", "-C", dir, "cherry-pick", "--abort").Run()
+		})
+	}
+}
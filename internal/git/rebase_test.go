@@ -0,0 +1,55 @@
+package git
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRebaseWithTodo(t *testing.T) {
+	for _, name := range []string{"GIT_AUTHOR_NAME", "GIT_COMMITTER_NAME"} {
+		t.Setenv(name, "test")
+	}
+	for _, name := range []string{"GIT_AUTHOR_EMAIL", "GIT_COMMITTER_EMAIL"} {
+		t.Setenv(name, "test@example.com")
+	}
+	tests := []struct {
+		name string
+		dir  string
+	}{
+		{
+			name: "plain path",
+			dir:  "todo",
+		},
+		{
+			name: "path with quotes and spaces",
+			dir:  `it's a "todo" $HOME`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir, sha := initRepository(t)
+			todo := filepath.Join(t.TempDir(), test.dir, "git-rebase-todo")
+			if err := os.MkdirAll(filepath.Dir(todo), 0755); err != nil {
+				t.Fatal(err)
+			}
+			// the commit is reworded, so that the todo is known to be used
+			if err := os.WriteFile(todo, []byte(fmt.Sprintf("pick %s\nexec git commit --amend --message reworded\n", sha)), 0644); err != nil {
+				t.Fatal(err)
+			}
+			repository := &git{path: dir}
+			if err := repository.RebaseWithTodo(sha+"^", "feature", todo); err != nil {
+				t.Fatal(err)
+			}
+			message, err := repository.outputGit("log", "-1", "--format=%s", "feature")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.TrimSpace(string(message)) != "reworded" {
+				t.Errorf("expected the todo to be used, got %q", message)
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package git
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// RemoteURL is a remote URL reduced to the parts identifying the repository.
+type RemoteURL struct {
+	// Host is the lower-cased host without the user and port
+	Host string
+	// Owner is the organization or user owning the repository
+	Owner string
+	// Repo is the name of the repository without the .git suffix
+	Repo string
+}
+
+func (r RemoteURL) String() string {
+	return fmt.Sprintf("%s/%s/%s", r.Host, r.Owner, r.Repo)
+}
+
+// Is checks if the URL points to the repository of the owner on the host,
+// owners and repositories are compared ignoring case, same as github does.
+func (r RemoteURL) Is(host, owner, repo string) bool {
+	return r.Host == strings.ToLower(host) && strings.EqualFold(r.Owner, owner) && strings.EqualFold(r.Repo, repo)
+}
+
+// ParseRemoteURL parses https, ssh and git URLs, as well as the scp-like
+// [user@]host:owner/repo syntax used for ssh by git.
+func ParseRemoteURL(rawURL string) (RemoteURL, error) {
+	rawURL = strings.TrimSpace(rawURL)
+	var host, path string
+	if strings.Contains(rawURL, "://") {
+		u, err := url.Parse(rawURL)
+		if err != nil {
+			return RemoteURL{}, fmt.Errorf("%q is not a valid remote URL: %w", rawURL, err)
+		}
+		switch u.Scheme {
+		case "https", "http", "ssh", "git", "git+ssh", "ssh+git":
+		default:
+			return RemoteURL{}, fmt.Errorf("%q uses unsupported scheme %s", rawURL, u.Scheme)
+		}
+		host, path = u.Hostname(), u.Path
+	} else {
+		// the scp-like syntax has no scheme and a colon before the first slash
+		colon := strings.Index(rawURL, ":")
+		if colon < 0 || strings.Contains(rawURL[:colon], "/") {
+			return RemoteURL{}, fmt.Errorf("%q is not a remote URL", rawURL)
+		}
+		host, path = rawURL[:colon], rawURL[colon+1:]
+		if at := strings.LastIndex(host, "@"); at >= 0 {
+			host = host[at+1:]
+		}
+	}
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	if len(host) == 0 || len(parts) != 2 || len(parts[0]) == 0 || len(parts[1]) == 0 {
+		return RemoteURL{}, fmt.Errorf("%q does not point to a repository, expected host/owner/repo", rawURL)
+	}
+	return RemoteURL{
+		Host:  strings.ToLower(host),
+		Owner: parts[0],
+		Repo:  strings.TrimSuffix(parts[1], ".git"),
+	}, nil
+}
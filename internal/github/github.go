@@ -0,0 +1,172 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/google/go-github/v56/github"
+	"k8s.io/klog/v2"
+
+	"github.com/openshift/rebase/internal/credentials"
+	"github.com/openshift/rebase/internal/fork"
+)
+
+func newClient() *github.Client {
+	client := github.NewClient(nil)
+	if token := credentials.GitHubToken(); len(token) > 0 {
+		client = client.WithAuthToken(token)
+	} else {
+		klog.V(3).Infof("Using the default github token, which might rate limit your requests!")
+	}
+	return client
+}
+
+func logRate(response *github.Response) {
+	if response != nil {
+		klog.V(3).Infof("Remaining rate with current token is %s", response.Rate.String())
+	}
+}
+
+// ErrNotMerged is returned for pull requests, which are not merged.
+var ErrNotMerged = errors.New("not merged")
+
+// PullRequest returns the merge commit SHA and title of a pull request of the
+// repository, returns ErrNotMerged if the pull request is not merged.
+func PullRequest(repository fork.Repository, number int) (string, string, error) {
+	client := newClient()
+	pr, response, err := client.PullRequests.Get(context.Background(), repository.Owner, repository.Repo, number)
+	logRate(response)
+	if err != nil {
+		return "", "", err
+	}
+	if !pr.GetMerged() {
+		return "", "", fmt.Errorf("pull request %d is %w", number, ErrNotMerged)
+	}
+	return pr.GetMergeCommitSHA(), pr.GetTitle(), nil
+}
+
+// PullRequestForCommit returns the number of the pull request of the repository
+// which introduced the commit.
+func PullRequestForCommit(repository fork.Repository, sha string) (int, error) {
+	client := newClient()
+	prs, response, err := client.PullRequests.ListPullRequestsWithCommit(context.Background(), repository.Owner, repository.Repo, sha, nil)
+	logRate(response)
+	if err != nil {
+		return 0, err
+	}
+	if len(prs) == 0 {
+		return 0, fmt.Errorf("no pull request found for commit %s", sha)
+	}
+	return prs[0].GetNumber(), nil
+}
+
+// PullRequestFiles returns the list of files modified by a pull request of the
+// repository.
+func PullRequestFiles(repository fork.Repository, number int) ([]string, error) {
+	client := newClient()
+	var files []string
+	opts := &github.ListOptions{PerPage: 100}
+	for {
+		page, response, err := client.PullRequests.ListFiles(context.Background(), repository.Owner, repository.Repo, number, opts)
+		logRate(response)
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range page {
+			files = append(files, f.GetFilename())
+		}
+		if response.NextPage == 0 {
+			return files, nil
+		}
+		opts.Page = response.NextPage
+	}
+}
+
+// IsNotFound checks if the error was caused by a missing github resource.
+func IsNotFound(err error) bool {
+	var errorResponse *github.ErrorResponse
+	return errors.As(err, &errorResponse) && errorResponse.Response != nil &&
+		errorResponse.Response.StatusCode == http.StatusNotFound
+}
+
+// CreateDraftPullRequest opens a draft pull request against the repository
+// from head, which is in owner:branch form for forks, and returns its URL.
+func CreateDraftPullRequest(repository fork.Repository, head, base, title, body string, labels []string) (string, error) {
+	if len(credentials.GitHubToken()) == 0 {
+		return "", fmt.Errorf("a github token is required to open a pull request, set GITHUB_TOKEN or configure a git credential helper for github.com")
+	}
+	client := newClient()
+	pr, response, err := client.PullRequests.Create(context.Background(), repository.Owner, repository.Repo, &github.NewPullRequest{
+		Title: github.String(title),
+		Head:  github.String(head),
+		Base:  github.String(base),
+		Body:  github.String(body),
+		Draft: github.Bool(true),
+	})
+	logRate(response)
+	if err != nil {
+		return "", err
+	}
+	if len(labels) > 0 {
+		_, response, err = client.Issues.AddLabelsToIssue(context.Background(), repository.Owner, repository.Repo, pr.GetNumber(), labels)
+		logRate(response)
+		if err != nil {
+			return pr.GetHTMLURL(), fmt.Errorf("pull request %s was opened, but adding labels failed: %w", pr.GetHTMLURL(), err)
+		}
+	}
+	return pr.GetHTMLURL(), nil
+}
+
+// MergedPullRequest returns whether a pull request of the repository is merged,
+// and its merge commit SHA when it is.
+func MergedPullRequest(repository fork.Repository, number int) (string, bool, error) {
+	sha, _, err := PullRequest(repository, number)
+	if errors.Is(err, ErrNotMerged) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	return sha, true, nil
+}
+
+// PullRequestInfo describes a pull request of the openshift fork.
+type PullRequestInfo struct {
+	URL       string
+	Author    string
+	Reviewers []string
+}
+
+// OpenShiftPullRequestForCommit returns the pull request of the openshift
+// repository of the fork which introduced the commit, with its author and
+// reviewers.
+func OpenShiftPullRequestForCommit(repository fork.Repository, sha string) (*PullRequestInfo, error) {
+	client := newClient()
+	prs, response, err := client.PullRequests.ListPullRequestsWithCommit(context.Background(), repository.Owner, repository.Repo, sha, nil)
+	logRate(response)
+	if err != nil {
+		return nil, err
+	}
+	if len(prs) == 0 {
+		return nil, fmt.Errorf("no pull request found for commit %s", sha)
+	}
+	pr := prs[0]
+	info := &PullRequestInfo{URL: pr.GetHTMLURL(), Author: pr.GetUser().GetLogin()}
+	reviews, response, err := client.PullRequests.ListReviews(context.Background(), repository.Owner, repository.Repo, pr.GetNumber(), &github.ListOptions{PerPage: 100})
+	logRate(response)
+	if err != nil {
+		return info, err
+	}
+	seen := make(map[string]bool)
+	for _, r := range reviews {
+		login := r.GetUser().GetLogin()
+		if len(login) == 0 || login == info.Author || seen[login] {
+			continue
+		}
+		seen[login] = true
+		info.Reviewers = append(info.Reviewers, login)
+	}
+	return info, nil
+}
@@ -0,0 +1,250 @@
+// Package golden snapshots the carries, the plan and the verification of a
+// repository into golden files, and checks later runs against them, so that
+// changes to classifying and planning carries can be validated against the
+// data of real rebases.
+package golden
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/openshift/rebase/internal/apply"
+	"github.com/openshift/rebase/internal/cache"
+	"github.com/openshift/rebase/internal/carry"
+	"github.com/openshift/rebase/internal/console"
+	"github.com/openshift/rebase/internal/fork"
+	"github.com/openshift/rebase/internal/git"
+	"github.com/openshift/rebase/internal/verify"
+)
+
+const (
+	carriesFile = "carries.json"
+	planFile    = "plan.json"
+	verifyFile  = "verify.json"
+
+	// maxDifferences limits the lines printed for a golden file which differs
+	maxDifferences = 20
+)
+
+// Options holds the settings of snapshotting a repository.
+type Options struct {
+	// Dir is the directory holding the golden files.
+	Dir string
+	// Replay compares the snapshot with the golden files, instead of writing them.
+	Replay bool
+	// Apply holds the settings the plan is made with.
+	Apply apply.Options
+	// Verify holds the settings of verifying the rebase branch.
+	Verify verify.Options
+	// SkipVerify leaves out verifying the rebase branch, eg. when the
+	// repository has none.
+	SkipVerify bool
+}
+
+// Golden writes or checks golden files of a repository.
+type Golden struct {
+	from          string
+	repositoryDir string
+	to            string
+	fork          fork.Fork
+	options       Options
+	markerSearch  git.MarkerSearch
+}
+
+// NewGolden returns the golden files of the repository in repositoryDir of
+// the fork, with carries from the tag to the ref to, which defaults to the
+// openshift branch.
+func NewGolden(from, repositoryDir, to string, f fork.Fork, options Options) *Golden {
+	return &Golden{
+		from:          from,
+		repositoryDir: repositoryDir,
+		to:            to,
+		fork:          f,
+		options:       options,
+	}
+}
+
+// SetMarkerSearch bounds the search of the rebase marker.
+func (g *Golden) SetMarkerSearch(search git.MarkerSearch) {
+	g.markerSearch = search
+}
+
+// snapshot is the content of a golden file
+type snapshot struct {
+	name string
+	data []byte
+}
+
+// Run snapshots the repository and writes the golden files, or with Replay
+// compares the snapshot with them and fails listing the differences.
+func (g *Golden) Run() error {
+	// cached carries and verification results would hide changes of the logic
+	cache.Disable()
+	snapshots, err := g.snapshots(context.Background())
+	if err != nil {
+		return err
+	}
+	if !g.options.Replay {
+		if err := os.MkdirAll(g.options.Dir, 0755); err != nil {
+			return fmt.Errorf("Error creating golden directory: %w", err)
+		}
+		for _, s := range snapshots {
+			if err := os.WriteFile(filepath.Join(g.options.Dir, s.name), s.data, 0644); err != nil {
+				return fmt.Errorf("Error writing golden file: %w", err)
+			}
+		}
+		console.Infof("Wrote %d golden files to %s", len(snapshots), g.options.Dir)
+		return nil
+	}
+	differ := 0
+	for _, s := range snapshots {
+		path := filepath.Join(g.options.Dir, s.name)
+		golden, err := os.ReadFile(path)
+		if errors.Is(err, os.ErrNotExist) {
+			console.Errorf("%s is missing, write it by running golden without --replay", path)
+			differ++
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("Error reading golden file: %w", err)
+		}
+		if bytes.Equal(golden, s.data) {
+			console.Infof("%s matches", path)
+			continue
+		}
+		console.Errorf("%s differs, - golden, + current:\n%s", path, differences(golden, s.data))
+		differ++
+	}
+	if differ > 0 {
+		return fmt.Errorf("%d of %d golden files differ", differ, len(snapshots))
+	}
+	return nil
+}
+
+// snapshots computes the content of the golden files
+func (g *Golden) snapshots(ctx context.Context) ([]snapshot, error) {
+	repository, err := git.OpenGit(g.repositoryDir, g.fork)
+	if err != nil {
+		return nil, err
+	}
+	carries, err := g.carries(ctx, repository)
+	if err != nil {
+		return nil, err
+	}
+	options := g.options.Apply
+	if len(options.ToRef) == 0 {
+		options.ToRef = g.to
+	}
+	planner := apply.NewPlanner(g.from, g.repositoryDir, g.fork, options, "")
+	planner.SetRepository(repository)
+	planner.SetMarkerSearch(g.markerSearch)
+	planner.SetContext(ctx)
+	plan, err := planner.Plan()
+	if err != nil {
+		return nil, err
+	}
+	// patches are found in a carries directory, which is not at the same
+	// path on every machine
+	for i, patch := range plan.Additional {
+		if relative, err := filepath.Rel(g.repositoryDir, patch); err == nil && !strings.HasPrefix(relative, "..") {
+			plan.Additional[i] = filepath.ToSlash(relative)
+		} else {
+			plan.Additional[i] = filepath.Base(patch)
+		}
+	}
+	snapshots, err := appendSnapshot(nil, carriesFile, carries)
+	if err != nil {
+		return nil, err
+	}
+	if snapshots, err = appendSnapshot(snapshots, planFile, plan); err != nil {
+		return nil, err
+	}
+	if g.options.SkipVerify {
+		return snapshots, nil
+	}
+	v := verify.NewVerify(g.from, g.repositoryDir, g.fork, g.options.Verify)
+	v.SetRepository(repository)
+	v.SetMarkerSearch(g.markerSearch)
+	v.SetContext(ctx)
+	result, err := v.Check()
+	if err != nil {
+		return nil, err
+	}
+	return appendSnapshot(snapshots, verifyFile, result)
+}
+
+// carries lists the carries, restoring the checkout afterwards, since
+// reading them checks out the openshift branch
+func (g *Golden) carries(ctx context.Context, repository git.Git) ([]carry.Entry, error) {
+	originalRef, err := repository.CurrentBranch()
+	if err != nil {
+		return nil, err
+	}
+	if len(originalRef) == 0 {
+		if originalRef, err = repository.RevParse("HEAD"); err != nil {
+			return nil, err
+		}
+	}
+	list := carry.NewList(g.from, g.repositoryDir, g.fork, carry.ListOptions{Concurrency: g.options.Apply.Concurrency}, "")
+	list.SetTo(g.to)
+	list.SetRepository(repository)
+	list.SetMarkerSearch(g.markerSearch)
+	list.SetContext(ctx)
+	carries, err := list.Entries(repository)
+	if checkoutErr := repository.Checkout(originalRef); checkoutErr != nil {
+		return nil, errors.Join(err, fmt.Errorf("Error restoring %s: %w", originalRef, checkoutErr))
+	}
+	return carries, err
+}
+
+// appendSnapshot appends the value marshalled as the golden file with the name
+func appendSnapshot(snapshots []snapshot, name string, value interface{}) ([]snapshot, error) {
+	var data bytes.Buffer
+	encoder := json.NewEncoder(&data)
+	// summaries hold <carry> and <drop>, which are kept readable
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(value); err != nil {
+		return nil, fmt.Errorf("Error marshalling %s: %w", name, err)
+	}
+	return append(snapshots, snapshot{name: name, data: data.Bytes()}), nil
+}
+
+// differences returns lines missing in current and lines new in current,
+// in the order of the files, at most maxDifferences of them
+func differences(golden, current []byte) string {
+	count := func(data []byte) map[string]int {
+		lines := make(map[string]int)
+		for _, line := range strings.Split(string(data), "\n") {
+			lines[line]++
+		}
+		return lines
+	}
+	goldenLines, currentLines := count(golden), count(current)
+	var b strings.Builder
+	printed := 0
+	write := func(prefix string, data []byte, other map[string]int) {
+		for _, line := range strings.Split(string(data), "\n") {
+			if other[line] > 0 {
+				other[line]--
+				continue
+			}
+			if printed == maxDifferences {
+				b.WriteString("...\n")
+			}
+			if printed < maxDifferences {
+				fmt.Fprintf(&b, "%s %s\n", prefix, line)
+			}
+			printed++
+		}
+	}
+	write("-", golden, currentLines)
+	write("+", current, goldenLines)
+	return strings.TrimSuffix(b.String(), "\n")
+}
@@ -0,0 +1,73 @@
+package golden
+
+import (
+	"flag"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/openshift/rebase/internal/apply"
+	"github.com/openshift/rebase/internal/e2e"
+	"github.com/openshift/rebase/internal/fork"
+)
+
+var update = flag.Bool("update", false, "Write the golden files instead of replaying them")
+
+// rebaseBranch is the rebase branch, which is verified
+const rebaseBranch = "rebase"
+
+// newRepository builds a repository with carries picked cleanly, dropped and
+// already upstream on top of the previous rebase onto v1.0.0, and rebases
+// them onto upstream
+func newRepository(t *testing.T) *e2e.Repository {
+	t.Helper()
+	r, err := e2e.New(t.TempDir(), "v1.0.0", fork.Kubernetes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Upstream("upstream change", e2e.Files{"b.txt": "upstream\n"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Marker(); err != nil {
+		t.Fatal(err)
+	}
+	for _, carry := range []struct {
+		action, summary string
+		files           e2e.Files
+	}{
+		{action: "<carry>", summary: "add d", files: e2e.Files{"d.txt": "carry\n"}},
+		{action: "<drop>", summary: "drop me", files: e2e.Files{"e.txt": "drop\n"}},
+		{action: "<carry>", summary: "already upstream", files: e2e.Files{"c.txt": "upstream 2\n"}},
+	} {
+		if _, err := r.Carry(carry.action, carry.summary, carry.files); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := r.Upstream("upstream change 2", e2e.Files{"c.txt": "upstream 2\n"}); err != nil {
+		t.Fatal(err)
+	}
+	// the rebase branch is renamed, so that its name does not depend on the date
+	runState, err := r.Apply("v1.0.0", apply.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Git("branch", "--move", "--force", runState.Branch, rebaseBranch); err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+// TestReplay replays the golden files in testdata, run with -update to write
+// them after an intended change of the results.
+func TestReplay(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	r := newRepository(t)
+	options := Options{Dir: filepath.Join("testdata", "e2e"), Replay: !*update}
+	options.Apply.CarriesDirs = []string{r.CarriesDir()}
+	options.Verify.Branch = rebaseBranch
+	if err := NewGolden("v1.0.0", r.Dir, "", fork.Kubernetes, options).Run(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,97 @@
+package jira
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"regexp"
+	"time"
+
+	"k8s.io/klog/v2"
+
+	"github.com/openshift/rebase/internal/report"
+)
+
+const (
+	jiraURL     = "https://issues.redhat.com"
+	bugzillaURL = "https://bugzilla.redhat.com/show_bug.cgi?id="
+	jiraTimeout = 30 * time.Second
+	// TokenEnv is the environment variable holding the jira token
+	TokenEnv = "JIRA_TOKEN"
+)
+
+var (
+	jiraRE     = regexp.MustCompile(`\b(OCPBUGS-[0-9]+)\b`)
+	bugzillaRE = regexp.MustCompile(`(?i)(?:\bbug[ :]+|\brhbz ?#|show_bug\.cgi\?id=)([0-9]{6,8})\b`)
+)
+
+// References returns the OCPBUGS and Bugzilla references found in a commit message.
+func References(message string) []report.Bug {
+	var bugs []report.Bug
+	seen := make(map[string]bool)
+	for _, m := range jiraRE.FindAllStringSubmatch(message, -1) {
+		if !seen[m[1]] {
+			seen[m[1]] = true
+			bugs = append(bugs, report.Bug{Key: m[1], URL: jiraURL + "/browse/" + m[1]})
+		}
+	}
+	for _, m := range bugzillaRE.FindAllStringSubmatch(message, -1) {
+		key := "BZ-" + m[1]
+		if !seen[key] {
+			seen[key] = true
+			bugs = append(bugs, report.Bug{Key: key, URL: bugzillaURL + m[1]})
+		}
+	}
+	return bugs
+}
+
+type issue struct {
+	Fields struct {
+		Status struct {
+			Name string `json:"name"`
+		} `json:"status"`
+		Priority struct {
+			Name string `json:"name"`
+		} `json:"priority"`
+	} `json:"fields"`
+}
+
+// Enrich reads status and priority of the jira bugs, bugzilla bugs are left
+// intact. Failures are only logged, since the lookup is informative.
+func Enrich(bugs []report.Bug) {
+	client := &http.Client{Timeout: jiraTimeout}
+	for i := range bugs {
+		if !jiraRE.MatchString(bugs[i].Key) {
+			continue
+		}
+		if err := enrich(client, &bugs[i]); err != nil {
+			klog.Warningf("Reading %s from jira failed: %v", bugs[i].Key, err)
+		}
+	}
+}
+
+func enrich(client *http.Client, bug *report.Bug) error {
+	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/rest/api/2/issue/%s?fields=status,priority", jiraURL, bug.Key), nil)
+	if err != nil {
+		return err
+	}
+	if token := os.Getenv(TokenEnv); len(token) > 0 {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	i := issue{}
+	if err := json.NewDecoder(resp.Body).Decode(&i); err != nil {
+		return err
+	}
+	bug.Status = i.Fields.Status.Name
+	bug.Priority = i.Fields.Priority.Name
+	return nil
+}
@@ -0,0 +1,61 @@
+package jira
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/openshift/rebase/internal/report"
+)
+
+func TestReferences(t *testing.T) {
+	tests := []struct {
+		name     string
+		message  string
+		expected []report.Bug
+	}{
+		{
+			name:    "no references",
+			message: "UPSTREAM: <carry>: openshift: add a carry\n\nSee OCPBUGS-, bug in the kubelet 12345",
+		},
+		{
+			name:    "jira bugs listed once",
+			message: "UPSTREAM: <carry>: OCPBUGS-123: fix the kubelet\n\nFixes OCPBUGS-123 and OCPBUGS-4567.",
+			expected: []report.Bug{
+				{Key: "OCPBUGS-123", URL: "https://issues.redhat.com/browse/OCPBUGS-123"},
+				{Key: "OCPBUGS-4567", URL: "https://issues.redhat.com/browse/OCPBUGS-4567"},
+			},
+		},
+		{
+			name:    "jira key within a word",
+			message: "UPSTREAM: <carry>: XOCPBUGS-123 and OCPBUGS-123a",
+		},
+		{
+			name:    "bugzilla bugs",
+			message: "UPSTREAM: <carry>: Bug 1234567: fix\n\nrhbz#2345678\nhttps://bugzilla.redhat.com/show_bug.cgi?id=3456789\nbug: 1234567",
+			expected: []report.Bug{
+				{Key: "BZ-1234567", URL: "https://bugzilla.redhat.com/show_bug.cgi?id=1234567"},
+				{Key: "BZ-2345678", URL: "https://bugzilla.redhat.com/show_bug.cgi?id=2345678"},
+				{Key: "BZ-3456789", URL: "https://bugzilla.redhat.com/show_bug.cgi?id=3456789"},
+			},
+		},
+		{
+			name:    "jira bugs before bugzilla bugs",
+			message: "UPSTREAM: <carry>: bug 1234567\n\nOCPBUGS-1",
+			expected: []report.Bug{
+				{Key: "OCPBUGS-1", URL: "https://issues.redhat.com/browse/OCPBUGS-1"},
+				{Key: "BZ-1234567", URL: "https://bugzilla.redhat.com/show_bug.cgi?id=1234567"},
+			},
+		},
+		{
+			name:    "bugzilla number of unexpected length",
+			message: "UPSTREAM: <carry>: bug 12345 and bug 123456789",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if bugs := References(test.message); !reflect.DeepEqual(bugs, test.expected) {
+				t.Errorf("expected %v, got %v", test.expected, bugs)
+			}
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/openshift/rebase/internal/utils"
+)
+
+const namespace = "openshift_rebase"
+
+// Check is the result of a single check of carries against upstream.
+type Check struct {
+	// Carries is the number of carries checked
+	Carries int
+	// Conflicting is the number of carries conflicting with upstream
+	Conflicting int
+	// Drift is the number of upstream commits since the last check without conflicts
+	Drift int
+	// Duration is how long the check took
+	Duration time.Duration
+}
+
+// Server serves metrics of the watch daemon in the Prometheus text format.
+type Server struct {
+	lock        sync.Mutex
+	check       Check
+	successes   int
+	failures    int
+	lastSuccess time.Time
+	server      *utils.HTTPServer
+}
+
+func New(address string) *Server {
+	s := &Server{}
+	s.server = utils.NewHTTPServer("metrics", address, "/metrics", s.serveMetrics)
+	return s
+}
+
+// Start listens on the address and serves the metrics in the background.
+func (s *Server) Start() error {
+	return s.server.Start()
+}
+
+// Stop closes the server.
+func (s *Server) Stop() {
+	if s == nil {
+		return
+	}
+	s.server.Stop()
+}
+
+// Succeeded records a successful check.
+func (s *Server) Succeeded(check Check) {
+	if s == nil {
+		return
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.check = check
+	s.successes++
+	s.lastSuccess = time.Now()
+}
+
+// Failed records a failed check.
+func (s *Server) Failed() {
+	if s == nil {
+		return
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.failures++
+}
+
+func (s *Server) serveMetrics(w http.ResponseWriter, r *http.Request) {
+	s.lock.Lock()
+	check, successes, failures, lastSuccess := s.check, s.successes, s.failures, s.lastSuccess
+	s.lock.Unlock()
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	gauge(w, "carries", "Number of carries checked against upstream.", float64(check.Carries))
+	gauge(w, "conflicting_carries", "Number of carries conflicting with upstream.", float64(check.Conflicting))
+	gauge(w, "upstream_drift_commits", "Number of upstream commits since the last check without conflicting carries.", float64(check.Drift))
+	gauge(w, "check_duration_seconds", "Duration of the last successful check.", check.Duration.Seconds())
+	lastSuccessSeconds := 0.0
+	if !lastSuccess.IsZero() {
+		lastSuccessSeconds = float64(lastSuccess.Unix())
+	}
+	gauge(w, "last_success_timestamp_seconds", "Time of the last successful check.", lastSuccessSeconds)
+	fmt.Fprintf(w, "# HELP %s_checks_total Number of checks of carries against upstream.\n", namespace)
+	fmt.Fprintf(w, "# TYPE %s_checks_total counter\n", namespace)
+	fmt.Fprintf(w, "%s_checks_total{result=\"success\"} %d\n", namespace, successes)
+	fmt.Fprintf(w, "%s_checks_total{result=\"failure\"} %d\n", namespace, failures)
+}
+
+func gauge(w io.Writer, name, help string, value float64) {
+	fmt.Fprintf(w, "# HELP %s_%s %s\n", namespace, name, help)
+	fmt.Fprintf(w, "# TYPE %s_%s gauge\n", namespace, name)
+	fmt.Fprintf(w, "%s_%s %g\n", namespace, name, value)
+}
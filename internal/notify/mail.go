@@ -0,0 +1,61 @@
+package notify
+
+import (
+	"fmt"
+	"net"
+	"net/smtp"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	// smtpUsernameEnv and smtpPasswordEnv hold the credentials of the SMTP
+	// server, no authentication is done when the username is not set
+	smtpUsernameEnv = "SMTP_USERNAME"
+	smtpPasswordEnv = "SMTP_PASSWORD"
+)
+
+// mailedEvents are the events worth an email, the others are too frequent
+var mailedEvents = map[Event]bool{
+	Deferred: true,
+	Finished: true,
+	Drift:    true,
+}
+
+// Mailer sends run lifecycle events by email through an SMTP server.
+type Mailer struct {
+	server string
+	from   string
+	to     []string
+}
+
+// NewMailer returns a mailer sending emails from an address to a list of
+// addresses through an SMTP server, given as host:port.
+func NewMailer(server, from string, to []string) (*Mailer, error) {
+	if _, _, err := net.SplitHostPort(server); err != nil {
+		return nil, fmt.Errorf("invalid SMTP server %q, expected host:port: %w", server, err)
+	}
+	if len(from) == 0 {
+		return nil, fmt.Errorf("sender address of emails is required")
+	}
+	return &Mailer{server: server, from: from, to: to}, nil
+}
+
+// Send mails a plain text message.
+func (m *Mailer) Send(subject, body string) error {
+	var auth smtp.Auth
+	if username := os.Getenv(smtpUsernameEnv); len(username) > 0 {
+		host, _, _ := net.SplitHostPort(m.server)
+		auth = smtp.PlainAuth("", username, os.Getenv(smtpPasswordEnv), host)
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", m.from)
+	fmt.Fprintf(&b, "To: %s\r\n", strings.Join(m.to, ", "))
+	fmt.Fprintf(&b, "Subject: %s\r\n", subject)
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&b, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&b, "Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	b.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+	return smtp.SendMail(m.server, auth, m.from, m.to, []byte(b.String()))
+}
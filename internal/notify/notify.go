@@ -0,0 +1,94 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"k8s.io/klog/v2"
+)
+
+const notifyTimeout = 10 * time.Second
+
+// Event is a lifecycle event of a run.
+type Event string
+
+const (
+	// Started is sent when a run starts
+	Started Event = "started"
+	// Conflict is sent when a commit requires manual intervention
+	Conflict Event = "conflict"
+	// Deferred is sent when the mechanical pass deferred carries to the manual queue
+	Deferred Event = "deferred"
+	// Finished is sent when a run finishes
+	Finished Event = "finished"
+	// Drift is sent when carries start conflicting with upstream
+	Drift Event = "drift"
+)
+
+// Notifier posts run lifecycle events to a slack webhook and a generic
+// webhook, and mails the important ones, whichever is configured.
+type Notifier struct {
+	slackURL   string
+	webhookURL string
+	mailer     *Mailer
+	client     *http.Client
+}
+
+func New(slackURL, webhookURL string, mailer *Mailer) *Notifier {
+	return &Notifier{
+		slackURL:   slackURL,
+		webhookURL: webhookURL,
+		mailer:     mailer,
+		client:     &http.Client{Timeout: notifyTimeout},
+	}
+}
+
+// webhookPayload is posted to the generic webhook
+type webhookPayload struct {
+	Event   Event  `json:"event"`
+	Branch  string `json:"branch"`
+	Message string `json:"message"`
+}
+
+// Notify posts the event, failures are only logged, so that they never
+// interrupt the run.
+func (n *Notifier) Notify(event Event, branch, message string) {
+	if n == nil {
+		return
+	}
+	if len(n.slackURL) > 0 {
+		text := fmt.Sprintf("*openshift-rebase* `%s` %s\n%s", branch, event, message)
+		if err := n.post(n.slackURL, map[string]string{"text": text}); err != nil {
+			klog.Warningf("Posting %s notification to slack failed: %v", event, err)
+		}
+	}
+	if len(n.webhookURL) > 0 {
+		if err := n.post(n.webhookURL, webhookPayload{Event: event, Branch: branch, Message: message}); err != nil {
+			klog.Warningf("Posting %s notification to webhook failed: %v", event, err)
+		}
+	}
+	if n.mailer != nil && mailedEvents[event] {
+		if err := n.mailer.Send(fmt.Sprintf("[openshift-rebase] %s %s", branch, event), message); err != nil {
+			klog.Warningf("Mailing %s notification failed: %v", event, err)
+		}
+	}
+}
+
+func (n *Notifier) post(url string, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	resp, err := n.client.Post(url, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,162 @@
+package options
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/pflag"
+
+	"github.com/openshift/rebase/internal/container"
+	"github.com/openshift/rebase/internal/fork"
+	"github.com/openshift/rebase/internal/git"
+)
+
+// Common provides the standard flags and options used in all commands.
+type Common struct {
+	IOStreams
+
+	// kubernetes repository directory, as specified by the user or current working dir
+	RepositoryDir string
+
+	// kubernetes tag, from which to act on
+	From string
+	// MarkerDepth, MarkerSince and FirstParent bound the search of the rebase marker
+	MarkerDepth int
+	MarkerSince string
+	FirstParent bool
+	// MarkerSearch is the search of the rebase marker, resolved from the flags
+	MarkerSearch git.MarkerSearch
+
+	// ForkName is the name of a builtin fork the repository belongs to
+	ForkName string
+	// ForkConfig is a YAML file describing the fork, overrides ForkName
+	ForkConfig string
+	// UpstreamRemote and OpenShiftRemote override the git remotes of the fork
+	UpstreamRemote  string
+	OpenShiftRemote string
+
+	// Container enables defaults for running inside of a container
+	Container bool
+	// StateDir is where the state of the run is persisted, defaults to the git directory
+	StateDir string
+	// GitBackend serves read operations of the repository, either go-git or cli
+	GitBackend string
+
+	// Fork is the fork the repository belongs to, resolved from the flags
+	Fork fork.Fork
+}
+
+func NewCommon(streams IOStreams) Common {
+	return Common{
+		IOStreams: streams,
+	}
+}
+
+func (o *Common) AddFlags(flags *pflag.FlagSet) {
+	o.AddRepositoryFlags(flags)
+	flags.StringVar(&o.From, "from", o.From, "Kubernetes starting version tag")
+	flags.StringVar(&o.From, "since-tag", o.From, "Kubernetes starting version tag, same as --from")
+	flags.IntVar(&o.MarkerDepth, "marker-depth", o.MarkerDepth, "Maximum number of commits searched for the rebase marker, all commits since the starting tag are searched if not set")
+	flags.StringVar(&o.MarkerSince, "marker-since", o.MarkerSince, "Date (YYYY-MM-DD), commits committed before it are not searched for the rebase marker")
+	flags.BoolVar(&o.FirstParent, "first-parent", o.FirstParent, "Search for the rebase marker following only the first parent of merges")
+}
+
+// AddRepositoryFlags adds only the repository flag, for commands which don't
+// need the starting version.
+func (o *Common) AddRepositoryFlags(flags *pflag.FlagSet) {
+	flags.StringVar(&o.RepositoryDir, "repository", o.RepositoryDir, "Kubernetes repository directory, or current if none specified")
+	flags.StringVar(&o.ForkName, "fork", fork.Kubernetes.Name, fmt.Sprintf("Fork the repository belongs to, one of: %s", strings.Join(fork.Names(), ", ")))
+	flags.StringVar(&o.ForkConfig, "fork-config", o.ForkConfig, "YAML, or JSON, file describing upstream and openshift repositories of a fork not known to the tool and its rebase procedure, eg. the order of stages")
+	flags.StringVar(&o.UpstreamRemote, "upstream-remote", o.UpstreamRemote, "Name of the git remote of the upstream repository, overrides the fork")
+	flags.StringVar(&o.OpenShiftRemote, "openshift-remote", o.OpenShiftRemote, "Name of the git remote of the openshift repository, overrides the fork")
+	flags.BoolVar(&o.Container, "container", o.Container, fmt.Sprintf("Run non-interactively inside of a container, ignoring the host git configuration and reading credentials from *_FILE variables or %s, also enabled by %s=true", container.SecretsDir, container.EnabledEnv))
+	flags.StringVar(&o.GitBackend, "git-backend", git.BackendGoGit, fmt.Sprintf("Backend reading commits and configuration of the repository, one of: %s, cli is faster on very large repositories", strings.Join(git.Backends(), ", ")))
+	flags.StringVar(&o.StateDir, "state-dir", o.StateDir, "Directory, eg. a mounted volume, where the state of the run is persisted instead of the git directory")
+}
+
+func (o *Common) Complete() error {
+	if err := o.CompleteRepository(); err != nil {
+		return err
+	}
+	if len(o.From) == 0 {
+		return fmt.Errorf(`Error: required flag(s) "from" not set`)
+	}
+	if err := o.ValidateRefs("from", o.From); err != nil {
+		return err
+	}
+	return o.CompleteMarkerSearch()
+}
+
+// ValidateRefs checks that the refs given by the user in the flag resolve to
+// commits, failing fast instead of with a baffling error of a later step,
+// empty refs are not set and skipped.
+func (o *Common) ValidateRefs(flag string, refs ...string) error {
+	for _, ref := range refs {
+		if len(ref) == 0 {
+			continue
+		}
+		if _, err := git.ResolveRef(o.RepositoryDir, ref); err != nil {
+			return fmt.Errorf("Error resolving --%s: %w", flag, err)
+		}
+	}
+	return nil
+}
+
+// CompleteMarkerSearch resolves the bounds of the search of the rebase marker.
+func (o *Common) CompleteMarkerSearch() error {
+	search := git.MarkerSearch{MaxCommits: o.MarkerDepth, FirstParent: o.FirstParent}
+	if len(o.MarkerSince) > 0 {
+		since, err := time.Parse(time.DateOnly, o.MarkerSince)
+		if err != nil {
+			return fmt.Errorf("Error parsing --marker-since: %w", err)
+		}
+		search.Since = since
+	}
+	o.MarkerSearch = search
+	return nil
+}
+
+// CompleteRepository defaults the repository to current working directory,
+// prepares the container mode and the state directory, and selects the fork
+// the repository belongs to.
+func (o *Common) CompleteRepository() error {
+	if len(o.RepositoryDir) == 0 {
+		var err error
+		o.RepositoryDir, err = os.Getwd()
+		if err != nil {
+			return err
+		}
+	}
+	if len(o.GitBackend) > 0 {
+		if err := git.SetBackend(o.GitBackend); err != nil {
+			return err
+		}
+	}
+	if o.Container || container.Requested() {
+		if err := container.Setup(); err != nil {
+			return err
+		}
+	}
+	if len(o.StateDir) > 0 {
+		if err := os.MkdirAll(o.StateDir, 0755); err != nil {
+			return fmt.Errorf("Error creating state directory: %w", err)
+		}
+	}
+	f := fork.Kubernetes
+	if len(o.ForkName) > 0 || len(o.ForkConfig) > 0 {
+		var err error
+		if f, err = fork.Load(o.ForkName, o.ForkConfig); err != nil {
+			return err
+		}
+	}
+	if len(o.UpstreamRemote) > 0 {
+		f.Remotes.Upstream = o.UpstreamRemote
+	}
+	if len(o.OpenShiftRemote) > 0 {
+		f.Remotes.OpenShift = o.OpenShiftRemote
+	}
+	o.Fork = f
+	return nil
+}
@@ -0,0 +1,111 @@
+package options
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/yaml"
+)
+
+// Config holds defaults of flags, so that long invocations don't need to
+// repeat them, flags given on the command line take precedence.
+type Config struct {
+	// Flags are values of flags, by their names, used by all commands having them
+	Flags map[string]interface{} `json:"flags,omitempty"`
+	// Commands are values of flags used only by a command, by its path, eg. "verify diff"
+	Commands map[string]map[string]interface{} `json:"commands,omitempty"`
+	// Env are environment variables set when not set already, eg. GITHUB_TOKEN
+	Env map[string]string `json:"env,omitempty"`
+}
+
+// DefaultConfigPath returns the location of the user's config file.
+func DefaultConfigPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "openshift-rebase", "config.yaml")
+}
+
+// LoadConfig reads a YAML config file, a missing file is an empty config
+// unless required. Numbers are kept as written, so that they are passed to
+// flags without being reformatted, eg. 1000000 as 1e+06.
+func LoadConfig(path string, required bool) (*Config, error) {
+	config := &Config{}
+	if len(path) == 0 {
+		return config, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) && !required {
+		return config, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := yaml.Unmarshal(data, config, func(d *json.Decoder) *json.Decoder {
+		d.UseNumber()
+		return d
+	}); err != nil {
+		return nil, fmt.Errorf("malformed config file %s: %w", path, err)
+	}
+	klog.V(2).Infof("Using config file %s", path)
+	return config, nil
+}
+
+// Apply sets flags of the command, which were not given on the command line,
+// and the environment variables, which are not set.
+func (c *Config) Apply(cmd *cobra.Command) error {
+	for name, value := range c.Env {
+		if _, ok := os.LookupEnv(name); ok {
+			continue
+		}
+		if err := os.Setenv(name, value); err != nil {
+			return err
+		}
+	}
+	path := cmd.CommandPath()
+	if cmd.HasParent() {
+		path = strings.TrimPrefix(path, cmd.Root().Name()+" ")
+	}
+	for _, values := range []map[string]interface{}{c.Flags, c.Commands[path]} {
+		for name, value := range values {
+			flag := cmd.Flags().Lookup(name)
+			if flag == nil || flag.Changed {
+				continue
+			}
+			if err := setFlag(cmd.Flags(), flag, value); err != nil {
+				return fmt.Errorf("Error setting %s from config: %w", name, err)
+			}
+			// the value is not coming from the command line, so that values
+			// of the command section can override the common ones
+			flag.Changed = false
+		}
+	}
+	return nil
+}
+
+// setFlag sets a flag to a config value, lists set every element separately
+func setFlag(flags *pflag.FlagSet, flag *pflag.Flag, value interface{}) error {
+	values, ok := value.([]interface{})
+	if !ok {
+		values = []interface{}{value}
+	}
+	if sliceValue, ok := flag.Value.(pflag.SliceValue); ok {
+		list := make([]string, 0, len(values))
+		for _, v := range values {
+			list = append(list, fmt.Sprint(v))
+		}
+		return sliceValue.Replace(list)
+	}
+	if len(values) != 1 {
+		return fmt.Errorf("expected a single value, got %d", len(values))
+	}
+	return flags.Set(flag.Name, fmt.Sprint(values[0]))
+}
@@ -0,0 +1,53 @@
+package options
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"k8s.io/klog/v2"
+)
+
+// EnvPrefix prefixes environment variables holding values of flags, eg.
+// OPENSHIFT_REBASE_REPOSITORY sets --repository.
+const EnvPrefix = "OPENSHIFT_REBASE_"
+
+// prefixedEnvs are credentials read by the tool, which can be also passed
+// with EnvPrefix, eg. OPENSHIFT_REBASE_GITHUB_TOKEN
+var prefixedEnvs = []string{"GITHUB_TOKEN", "JIRA_TOKEN", "SMTP_USERNAME", "SMTP_PASSWORD"}
+
+// FlagEnv returns the environment variable setting the flag.
+func FlagEnv(name string) string {
+	return EnvPrefix + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
+
+// ApplyEnv sets flags of the command, which were not given on the command
+// line, from their environment variables, and exports prefixed credentials
+// under their usual names. Flags set from the environment count as given on
+// the command line, so that they take precedence over the config file.
+func ApplyEnv(cmd *cobra.Command) error {
+	for _, name := range prefixedEnvs {
+		if value, ok := os.LookupEnv(EnvPrefix + name); ok {
+			if err := os.Setenv(name, value); err != nil {
+				return err
+			}
+		}
+	}
+	var err error
+	cmd.Flags().VisitAll(func(flag *pflag.Flag) {
+		if err != nil || flag.Changed {
+			return
+		}
+		value, ok := os.LookupEnv(FlagEnv(flag.Name))
+		if !ok {
+			return
+		}
+		klog.V(2).Infof("Setting --%s from %s", flag.Name, FlagEnv(flag.Name))
+		if setErr := cmd.Flags().Set(flag.Name, value); setErr != nil {
+			err = fmt.Errorf("Error setting --%s from %s: %w", flag.Name, FlagEnv(flag.Name), setErr)
+		}
+	})
+	return err
+}
@@ -0,0 +1,184 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Machine-readable output formats, with stable schemas given by JSON tags
+// of the written values.
+const (
+	JSON = "json"
+	YAML = "yaml"
+)
+
+// Validate checks that the format is empty, meaning human-readable output,
+// one of the machine-readable formats, or one of the additional formats.
+func Validate(format string, additional ...string) error {
+	allowed := append([]string{JSON, YAML}, additional...)
+	if len(format) == 0 {
+		return nil
+	}
+	for _, a := range allowed {
+		if format == a {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid output format %q, expected one of: %s", format, strings.Join(allowed, ", "))
+}
+
+// IsMachine returns true for machine-readable formats.
+func IsMachine(format string) bool {
+	return format == JSON || format == YAML
+}
+
+// Write writes the value to out as JSON or YAML.
+func Write(out io.Writer, format string, v interface{}) error {
+	var data bytes.Buffer
+	encoder := json.NewEncoder(&data)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(v); err != nil {
+		return err
+	}
+	switch format {
+	case JSON:
+		_, err := out.Write(data.Bytes())
+		return err
+	case YAML:
+		decoder := json.NewDecoder(&data)
+		decoder.UseNumber()
+		n, err := decode(decoder)
+		if err != nil {
+			return err
+		}
+		var b strings.Builder
+		writeYAML(&b, n, "", "")
+		_, err = io.WriteString(out, b.String())
+		return err
+	}
+	return fmt.Errorf("unknown output format %q", format)
+}
+
+// node is a decoded JSON value, keeping the order of object keys
+type node struct {
+	// scalar is the YAML representation of a scalar value
+	scalar string
+	list   []node
+	keys   []string
+	values []node
+	kind   byte
+}
+
+const (
+	scalarKind = 's'
+	listKind   = 'l'
+	objectKind = 'o'
+)
+
+func decode(decoder *json.Decoder) (node, error) {
+	token, err := decoder.Token()
+	if err != nil {
+		return node{}, err
+	}
+	switch t := token.(type) {
+	case json.Delim:
+		if t == '[' {
+			n := node{kind: listKind}
+			for decoder.More() {
+				item, err := decode(decoder)
+				if err != nil {
+					return node{}, err
+				}
+				n.list = append(n.list, item)
+			}
+			_, err := decoder.Token()
+			return n, err
+		}
+		n := node{kind: objectKind}
+		for decoder.More() {
+			key, err := decoder.Token()
+			if err != nil {
+				return node{}, err
+			}
+			value, err := decode(decoder)
+			if err != nil {
+				return node{}, err
+			}
+			n.keys = append(n.keys, yamlKey(fmt.Sprint(key)))
+			n.values = append(n.values, value)
+		}
+		_, err := decoder.Token()
+		return n, err
+	case string:
+		// double-quoted strings use escape sequences compatible with YAML
+		return node{kind: scalarKind, scalar: strconv.Quote(t)}, nil
+	case nil:
+		return node{kind: scalarKind, scalar: "null"}, nil
+	default:
+		return node{kind: scalarKind, scalar: fmt.Sprint(t)}, nil
+	}
+}
+
+// inline returns the representation of scalars and empty collections, which
+// are written on the line of their key
+func (n node) inline() (string, bool) {
+	switch {
+	case n.kind == scalarKind:
+		return n.scalar, true
+	case n.kind == listKind && len(n.list) == 0:
+		return "[]", true
+	case n.kind == objectKind && len(n.keys) == 0:
+		return "{}", true
+	}
+	return "", false
+}
+
+// writeYAML writes the node in block style, the first line is prefixed with
+// first, the others with indent
+func writeYAML(b *strings.Builder, n node, indent, first string) {
+	if value, ok := n.inline(); ok {
+		fmt.Fprintf(b, "%s%s\n", first, value)
+		return
+	}
+	prefix := first
+	if n.kind == listKind {
+		for _, item := range n.list {
+			if value, ok := item.inline(); ok {
+				fmt.Fprintf(b, "%s- %s\n", prefix, value)
+			} else {
+				writeYAML(b, item, indent+"  ", prefix+"- ")
+			}
+			prefix = indent
+		}
+		return
+	}
+	for i, key := range n.keys {
+		value := n.values[i]
+		if inline, ok := value.inline(); ok {
+			fmt.Fprintf(b, "%s%s: %s\n", prefix, key, inline)
+		} else if value.kind == listKind {
+			fmt.Fprintf(b, "%s%s:\n", prefix, key)
+			writeYAML(b, value, indent, indent)
+		} else {
+			fmt.Fprintf(b, "%s%s:\n", prefix, key)
+			writeYAML(b, value, indent+"  ", indent+"  ")
+		}
+		prefix = indent
+	}
+}
+
+var plainKeyRE = regexp.MustCompile(`^[A-Za-z0-9_./-]+$`)
+
+// yamlKey quotes keys, which are not plain words
+func yamlKey(key string) string {
+	if plainKeyRE.MatchString(key) {
+		return key
+	}
+	return strconv.Quote(key)
+}
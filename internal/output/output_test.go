@@ -0,0 +1,92 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"sigs.k8s.io/yaml"
+)
+
+func TestWriteYAML(t *testing.T) {
+	type step struct {
+		SHA     string   `json:"sha"`
+		Files   []string `json:"files"`
+		Skipped bool     `json:"skipped,omitempty"`
+	}
+	tests := []struct {
+		name     string
+		value    interface{}
+		expected string
+	}{
+		{
+			name:     "scalar",
+			value:    "UPSTREAM: <carry>: a: b",
+			expected: "\"UPSTREAM: <carry>: a: b\"\n",
+		},
+		{
+			name: "object keeping the order of fields",
+			value: struct {
+				Name    string      `json:"name"`
+				Count   int         `json:"count"`
+				Size    int64       `json:"size"`
+				Ratio   float64     `json:"ratio"`
+				Missing interface{} `json:"missing"`
+			}{Name: "kubernetes", Count: 2, Size: 1000000, Ratio: 0.5},
+			expected: "name: \"kubernetes\"\ncount: 2\nsize: 1000000\nratio: 0.5\nmissing: null\n",
+		},
+		{
+			name:     "empty collections",
+			value:    map[string]interface{}{"list": []string{}, "object": map[string]string{}},
+			expected: "list: []\nobject: {}\n",
+		},
+		{
+			name: "list of objects",
+			value: []step{
+				{SHA: "abc", Files: []string{"go.mod", "go.sum"}},
+				{SHA: "def", Files: []string{}, Skipped: true},
+			},
+			expected: "- sha: \"abc\"\n  files:\n  - \"go.mod\"\n  - \"go.sum\"\n- sha: \"def\"\n  files: []\n  skipped: true\n",
+		},
+		{
+			name: "nested objects and lists of lists",
+			value: map[string]interface{}{
+				"steps":  map[string]interface{}{"first": map[string]int{"commits": 1}},
+				"matrix": [][]int{{1, 2}, {3}},
+			},
+			expected: "matrix:\n- - 1\n  - 2\n- - 3\nsteps:\n  first:\n    commits: 1\n",
+		},
+		{
+			name:     "keys which are not plain words",
+			value:    map[string]string{"pkg/kubelet": "a", "key with: colon": "b\n\"c\""},
+			expected: "\"key with: colon\": \"b\\n\\\"c\\\"\"\npkg/kubelet: \"a\"\n",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var out bytes.Buffer
+			if err := Write(&out, YAML, test.value); err != nil {
+				t.Fatal(err)
+			}
+			if out.String() != test.expected {
+				t.Errorf("expected\n%s\ngot\n%s", test.expected, out.String())
+			}
+			// the YAML must hold the same data as the JSON output
+			var fromYAML, fromJSON interface{}
+			if err := yaml.Unmarshal(out.Bytes(), &fromYAML); err != nil {
+				t.Fatalf("invalid YAML: %v", err)
+			}
+			data, err := json.Marshal(test.value)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := json.Unmarshal(data, &fromJSON); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(fromYAML, fromJSON) {
+				t.Errorf("YAML holds %v, expected %v", fromYAML, fromJSON)
+			}
+		})
+	}
+}
@@ -0,0 +1,15 @@
+//go:build linux || darwin
+
+package preflight
+
+import "syscall"
+
+// freeSpace returns the space in bytes available to the user on the
+// filesystem of the path
+func freeSpace(path string) (uint64, error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return 0, err
+	}
+	return stat.Bavail * uint64(stat.Bsize), nil
+}
@@ -0,0 +1,215 @@
+package preflight
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/openshift/rebase/internal/fork"
+	"github.com/openshift/rebase/internal/git"
+)
+
+// Names of the checks of the environment.
+const (
+	CheckWorktree   = "worktree"
+	CheckInProgress = "in-progress"
+	CheckGitVersion = "git-version"
+	CheckDiskSpace  = "disk-space"
+	CheckRemotes    = "remotes"
+)
+
+// Names returns the names of all checks.
+func Names() []string {
+	return []string{CheckWorktree, CheckInProgress, CheckGitVersion, CheckDiskSpace, CheckRemotes}
+}
+
+const (
+	// MinGitVersion is the oldest git supported, which writes commit-graphs
+	// with changed paths
+	MinGitVersion = "2.27"
+	// MinGitVersionMergeTree is the oldest git simulating picks in memory
+	MinGitVersionMergeTree = "2.40"
+	// MinDiskSpace is the free space in bytes required in the git directory
+	MinDiskSpace = 1 << 30
+)
+
+// Options holds the settings of the checks.
+type Options struct {
+	// Skip are names of checks, which are not run.
+	Skip []string
+	// MinGitVersion is the oldest git version supported, defaults to MinGitVersion.
+	MinGitVersion string
+	// MinDiskSpace is the free space in bytes required, defaults to MinDiskSpace.
+	MinDiskSpace uint64
+	// Fork names the upstream and openshift remotes, which must be reachable.
+	Fork fork.Fork
+}
+
+// Problem describes a single problem of the environment.
+type Problem struct {
+	// Check is the name of the check which found the problem
+	Check string
+	// Message describes the problem and how to fix it
+	Message string
+}
+
+func (p Problem) String() string {
+	return fmt.Sprintf("%s: %s", p.Check, p.Message)
+}
+
+// Run runs all checks, which are not skipped, and returns all problems found,
+// so that they can be fixed at once rather than one by one.
+func Run(repository git.Git, options Options) []Problem {
+	if len(options.MinGitVersion) == 0 {
+		options.MinGitVersion = MinGitVersion
+	}
+	if options.MinDiskSpace == 0 {
+		options.MinDiskSpace = MinDiskSpace
+	}
+	checks := []struct {
+		name string
+		run  func() []string
+	}{
+		{name: CheckWorktree, run: func() []string { return checkWorktree(repository) }},
+		{name: CheckInProgress, run: func() []string { return checkInProgress(repository) }},
+		{name: CheckGitVersion, run: func() []string { return checkGitVersion(options.MinGitVersion) }},
+		{name: CheckDiskSpace, run: func() []string { return checkDiskSpace(repository, options.MinDiskSpace) }},
+		{name: CheckRemotes, run: func() []string { return checkRemotes(repository, options.Fork) }},
+	}
+	var problems []Problem
+	for _, c := range checks {
+		if skipped(c.name, options.Skip) {
+			continue
+		}
+		for _, message := range c.run() {
+			problems = append(problems, Problem{Check: c.name, Message: message})
+		}
+	}
+	return problems
+}
+
+// RunCheck runs only the named check and returns the problems found.
+func RunCheck(repository git.Git, name string, options Options) []Problem {
+	options.Skip = nil
+	for _, other := range Names() {
+		if other != name {
+			options.Skip = append(options.Skip, other)
+		}
+	}
+	return Run(repository, options)
+}
+
+// CompareVersions compares dot separated versions numerically, eg. git
+// versions, returning -1, 0 or 1.
+func CompareVersions(a, b string) int {
+	return compareVersions(a, b)
+}
+
+// Validate checks that the names of skipped checks are known.
+func Validate(skip []string) error {
+	for _, name := range skip {
+		if !skipped(name, Names()) {
+			return fmt.Errorf("unknown check %q, expected one of: %s", name, strings.Join(Names(), ", "))
+		}
+	}
+	return nil
+}
+
+func skipped(name string, skip []string) bool {
+	for _, s := range skip {
+		if s == name {
+			return true
+		}
+	}
+	return false
+}
+
+func checkWorktree(repository git.Git) []string {
+	files, err := repository.UncommittedFiles()
+	if err != nil {
+		return []string{fmt.Sprintf("reading the status of the working tree failed: %v", err)}
+	}
+	if len(files) == 0 {
+		return nil
+	}
+	if len(files) > 5 {
+		files = append(files[:5], fmt.Sprintf("and %d more", len(files)-5))
+	}
+	return []string{fmt.Sprintf("uncommitted changes in %s, commit or stash them", strings.Join(files, ", "))}
+}
+
+func checkInProgress(repository git.Git) []string {
+	operation, err := repository.InProgress()
+	if err != nil {
+		return []string{fmt.Sprintf("looking up in-progress operations failed: %v", err)}
+	}
+	if len(operation) > 0 {
+		return []string{fmt.Sprintf("%s is in progress, finish it or run git %s --abort", operation, operation)}
+	}
+	return nil
+}
+
+func checkGitVersion(minimum string) []string {
+	version, err := git.Version()
+	if err != nil {
+		return []string{fmt.Sprintf("running git failed: %v", err)}
+	}
+	if compareVersions(version, minimum) < 0 {
+		return []string{fmt.Sprintf("git %s is older than the required %s", version, minimum)}
+	}
+	return nil
+}
+
+func checkDiskSpace(repository git.Git, minimum uint64) []string {
+	gitDir, err := repository.GitDir()
+	if err != nil {
+		return []string{fmt.Sprintf("looking up the git directory failed: %v", err)}
+	}
+	available, err := freeSpace(gitDir)
+	if err != nil {
+		return []string{fmt.Sprintf("reading free space of %s failed: %v", gitDir, err)}
+	}
+	if available < minimum {
+		return []string{fmt.Sprintf("only %d MiB free in %s, at least %d MiB is required", available>>20, gitDir, minimum>>20)}
+	}
+	return nil
+}
+
+func checkRemotes(repository git.Git, f fork.Fork) []string {
+	var messages []string
+	for _, remote := range []string{f.UpstreamRemote(), f.OpenShiftRemote()} {
+		if err := repository.RemoteReachable(remote); err != nil {
+			messages = append(messages, fmt.Sprintf("%v, check the network and credentials", err))
+		}
+	}
+	return messages
+}
+
+// compareVersions compares dot separated versions numerically, suffixes like
+// .windows.1 or rc0 are ignored
+func compareVersions(a, b string) int {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		av, bv := versionPart(as, i), versionPart(bs, i)
+		if av != bv {
+			if av < bv {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// versionPart returns the leading number of the i-th part of the version
+func versionPart(parts []string, i int) int {
+	if i >= len(parts) {
+		return 0
+	}
+	digits := strings.IndexFunc(parts[i], func(r rune) bool { return r < '0' || r > '9' })
+	if digits < 0 {
+		digits = len(parts[i])
+	}
+	n, _ := strconv.Atoi(parts[i][:digits])
+	return n
+}
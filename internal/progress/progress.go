@@ -0,0 +1,100 @@
+package progress
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/openshift/rebase/internal/console"
+)
+
+// DefaultInterval is the default interval between progress log lines, when
+// not running in a terminal.
+const DefaultInterval = time.Minute
+
+// Progress tracks processing of a known number of commits. When attached to
+// a terminal it keeps a single, continuously updated progress line, otherwise
+// the progress is periodically logged, which works better in CI.
+type Progress struct {
+	out       io.Writer
+	tty       bool
+	interval  time.Duration
+	total     int
+	done      int
+	conflicts int
+	start     time.Time
+	lastLog   time.Time
+}
+
+// New creates a progress tracker for total commits, printing to out.
+// Interval controls how often progress is logged when out is not a terminal.
+func New(out io.Writer, total int, interval time.Duration) *Progress {
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	now := time.Now()
+	return &Progress{
+		out:      out,
+		tty:      isTerminal(out) && !console.HasLogger(),
+		interval: interval,
+		total:    total,
+		start:    now,
+		lastLog:  now,
+	}
+}
+
+// isTerminal checks if the writer is a terminal
+func isTerminal(out io.Writer) bool {
+	f, ok := out.(*os.File)
+	return ok && IsTerminal(f)
+}
+
+// IsTerminal checks if the file is a character device, ie. a terminal.
+func IsTerminal(f *os.File) bool {
+	fileInfo, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return fileInfo.Mode()&os.ModeCharDevice != 0
+}
+
+// Step marks a single commit as processed, conflict informs whether the commit
+// required resolving conflicts.
+func (p *Progress) Step(conflict bool) {
+	p.done++
+	if conflict {
+		p.conflicts++
+	}
+	if p.tty {
+		fmt.Fprintf(p.out, "\r\033[K%s", p.String())
+		return
+	}
+	if time.Since(p.lastLog) >= p.interval || p.done == p.total {
+		p.lastLog = time.Now()
+		console.Infof("Progress: %s", p.String())
+	}
+}
+
+// Finish terminates the progress line, when running in a terminal.
+func (p *Progress) Finish() {
+	if p.tty {
+		fmt.Fprintln(p.out)
+	}
+}
+
+// String returns the current progress, eg. 10/100 commits (10%), 2 conflicts, elapsed 1m0s, ETA 9m0s
+func (p *Progress) String() string {
+	elapsed := time.Since(p.start).Round(time.Second)
+	percent := 100
+	if p.total > 0 {
+		percent = p.done * 100 / p.total
+	}
+	eta := "unknown"
+	if p.done > 0 {
+		remaining := time.Duration(int64(time.Since(p.start)) / int64(p.done) * int64(p.total-p.done))
+		eta = remaining.Round(time.Second).String()
+	}
+	return fmt.Sprintf("%d/%d commits (%d%%), %d conflicts, elapsed %s, ETA %s",
+		p.done, p.total, percent, p.conflicts, elapsed, eta)
+}
@@ -0,0 +1,164 @@
+package report
+
+import (
+	"html/template"
+	"io"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/openshift/rebase/internal/fork"
+)
+
+// htmlTemplate is a self-contained page, with styles and the filtering
+// script inlined, so that it can be published as a CI artifact as is.
+var htmlTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"class":   htmlClass,
+	"short":   shortSHA,
+	"unknown": valueOrUnknown,
+	"round":   func(d time.Duration) time.Duration { return d.Round(time.Millisecond) },
+	"time":    func(t time.Time) string { return t.Format("2006-01-02 15:04:05 MST") },
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Rebase to {{unknown .Report.TargetVersion}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; width: 100%; margin-bottom: 2em; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; text-align: left; vertical-align: top; }
+th { background: #eee; }
+code { font-size: 90%; }
+.picked { background: #e6ffe6; }
+.conflicted { background: #fff5d6; }
+.failed { background: #ffe0e0; }
+.dropped { color: #777; }
+#filters { margin-bottom: 1em; }
+</style>
+</head>
+<body>
+<h1>Rebase to {{unknown .Report.TargetVersion}}</h1>
+<ul>
+<li>Starting version: <code>{{unknown .Report.From}}</code></li>
+<li>Upstream base: <code>{{unknown .Report.UpstreamSHA}}</code></li>
+<li>OpenShift base: <code>{{unknown .Report.OpenShiftSHA}}</code></li>
+<li>Rebase branch: <code>{{unknown .Report.Branch}}</code></li>
+{{- if .Report.PullRequest}}
+<li>Pull request: <a href="{{.Report.PullRequest}}">{{.Report.PullRequest}}</a></li>
+{{- end}}
+<li>Started: {{time .Report.Started}}, generated: {{time .Generated}}</li>
+<li>Carries: {{len .Report.Entries}} processed, {{.Picked}} picked, {{.Conflicted}} conflicted, {{.Failed}} requiring manual intervention</li>
+</ul>
+{{- if .Report.Stages}}
+<h2>Stages</h2>
+<table>
+<tr><th>Stage</th><th>Duration</th></tr>
+{{- range .Report.Stages}}
+<tr><td>{{.Name}}</td><td>{{round .Duration}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+<h2>Carries</h2>
+<div id="filters">
+<label>Disposition <select id="disposition" onchange="filter()">
+<option value="">all</option>
+{{- range .Dispositions}}
+<option>{{.}}</option>
+{{- end}}
+</select></label>
+<label>Search <input id="search" type="text" oninput="filter()"></label>
+</div>
+<table id="carries">
+<tr><th>Commit</th><th>New commit</th><th>Disposition</th><th>Message</th><th>Duration</th><th>Details</th></tr>
+{{- range .Report.Entries}}
+<tr class="{{class .Disposition}}" data-disposition="{{.Disposition}}">
+<td><a href="{{$.Fork.CommitURL .Original}}"><code>{{short .Original}}</code></a></td>
+<td><code>{{short .New}}</code></td>
+<td>{{.Disposition}}</td>
+<td>{{.Message}}</td>
+<td>{{round .Duration}}</td>
+<td>
+{{- if .Reason}}{{.Reason}}<br>{{end}}
+{{- if .PullRequest}}<a href="{{.PullRequest}}">{{.PullRequest}}</a>{{if .Author}} by @{{.Author}}{{end}}<br>{{end}}
+{{- range .Bugs}}<a href="{{.URL}}">{{.Key}}</a>{{if .Status}} ({{.Status}}){{end}} {{end}}
+{{- if .Conflicts}}<br>conflicts: {{range $i, $f := .Conflicts}}{{if $i}}, {{end}}{{$f}}{{end}}{{end}}
+{{- if .Assignees}}<br>suggested assignees: {{range $i, $a := .Assignees}}{{if $i}}, {{end}}@{{$a}}{{end}}{{end}}
+</td>
+</tr>
+{{- end}}
+</table>
+<script>
+function filter() {
+  var disposition = document.getElementById("disposition").value;
+  var search = document.getElementById("search").value.toLowerCase();
+  var rows = document.querySelectorAll("#carries tr[data-disposition]");
+  for (var i = 0; i < rows.length; i++) {
+    var row = rows[i];
+    var visible = (!disposition || row.dataset.disposition === disposition) &&
+      (!search || row.textContent.toLowerCase().indexOf(search) >= 0);
+    row.style.display = visible ? "" : "none";
+  }
+}
+</script>
+</body>
+</html>
+`))
+
+// WriteHTML writes a self-contained html page describing the rebase of the
+// fork, suitable for publishing as a CI artifact.
+func (r *Report) WriteHTML(path string, f fork.Fork) error {
+	out, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	if err := r.HTML(out, f); err != nil {
+		return err
+	}
+	return out.Close()
+}
+
+// HTML writes a self-contained html page describing the rebase of the fork
+// to out.
+func (r *Report) HTML(out io.Writer, f fork.Fork) error {
+	data := struct {
+		Report       *Report
+		Fork         fork.Fork
+		Generated    time.Time
+		Dispositions []Disposition
+		Picked       int
+		Conflicted   int
+		Failed       int
+	}{Report: r, Fork: f, Generated: time.Now()}
+	seen := make(map[Disposition]bool)
+	for _, e := range r.Entries {
+		if !seen[e.Disposition] {
+			seen[e.Disposition] = true
+			data.Dispositions = append(data.Dispositions, e.Disposition)
+		}
+		switch {
+		case e.Disposition == Failed || e.Disposition == Deferred:
+			data.Failed++
+			data.Conflicted++
+		case e.Disposition.Conflicted():
+			data.Conflicted++
+		case e.Disposition == Picked:
+			data.Picked++
+		}
+	}
+	sort.Slice(data.Dispositions, func(i, j int) bool { return data.Dispositions[i] < data.Dispositions[j] })
+	return htmlTemplate.Execute(out, data)
+}
+
+// htmlClass returns the css class of a table row for a given disposition
+func htmlClass(d Disposition) string {
+	switch {
+	case d == Failed || d == Deferred:
+		return "failed"
+	case d.Conflicted():
+		return "conflicted"
+	case d == Picked:
+		return "picked"
+	}
+	return "dropped"
+}
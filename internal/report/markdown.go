@@ -0,0 +1,130 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/openshift/rebase/internal/fork"
+)
+
+// WriteMarkdown writes a markdown document describing the rebase of the fork,
+// suitable as a rebase PR description.
+func (r *Report) WriteMarkdown(path string, f fork.Fork) error {
+	out, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	r.Markdown(out, f)
+	return out.Close()
+}
+
+// Markdown writes a markdown document describing the rebase of the fork to out.
+func (r *Report) Markdown(out io.Writer, f fork.Fork) {
+	fmt.Fprintf(out, "# Rebase to %s\n\n", valueOrUnknown(r.TargetVersion))
+	fmt.Fprintf(out, "- Starting version: `%s`\n", valueOrUnknown(r.From))
+	fmt.Fprintf(out, "- Upstream base: `%s`\n", valueOrUnknown(r.UpstreamSHA))
+	fmt.Fprintf(out, "- OpenShift base: `%s`\n", valueOrUnknown(r.OpenShiftSHA))
+	fmt.Fprintf(out, "- Rebase branch: `%s`\n", valueOrUnknown(r.Branch))
+	fmt.Fprintf(out, "- Started: %s\n\n", r.Started.Format("2006-01-02 15:04:05 MST"))
+
+	var picked, dropped, conflicted, failed []Entry
+	for _, e := range r.Entries {
+		if e.Disposition == Failed || e.Disposition == Deferred {
+			failed = append(failed, e)
+		}
+		switch {
+		case e.Disposition.Conflicted():
+			conflicted = append(conflicted, e)
+		case e.Disposition == Picked:
+			picked = append(picked, e)
+		default:
+			dropped = append(dropped, e)
+		}
+	}
+	markdownTable(out, f, "Picked carries", picked)
+	markdownTable(out, f, "Conflicted carries", conflicted)
+	markdownTable(out, f, "Dropped and skipped carries", dropped)
+	markdownBugs(out, f, r.Entries)
+
+	fmt.Fprintf(out, "## Manual steps remaining\n\n")
+	for _, e := range failed {
+		assignees := ""
+		if len(e.Assignees) > 0 {
+			assignees = " (suggested assignees: @" + strings.Join(e.Assignees, ", @") + ")"
+		}
+		conflicts := ""
+		if len(e.Conflicts) > 0 {
+			conflicts = " conflicting in `" + strings.Join(e.Conflicts, "`, `") + "`"
+		}
+		fmt.Fprintf(out, "- [ ] Resolve [%s](%s) %s%s%s\n", shortSHA(e.Original), f.CommitURL(e.Original), EscapeMarkdown(e.Message), conflicts, assignees)
+	}
+	for _, s := range f.ManualSteps {
+		fmt.Fprintf(out, "- [ ] %s\n", s)
+	}
+}
+
+func markdownBugs(out io.Writer, f fork.Fork, entries []Entry) {
+	var rows []string
+	for _, e := range entries {
+		for _, b := range e.Bugs {
+			rows = append(rows, fmt.Sprintf("| [%s](%s) | %s | %s | [%s](%s) %s | %s |", b.Key, b.URL,
+				valueOrUnknown(b.Status), valueOrUnknown(b.Priority), shortSHA(e.Original), f.CommitURL(e.Original),
+				EscapeMarkdown(e.Message), e.Disposition))
+		}
+	}
+	if len(rows) == 0 {
+		return
+	}
+	fmt.Fprintf(out, "## Bugs attached to carries (%d)\n\n", len(rows))
+	fmt.Fprintf(out, "| Bug | Status | Priority | Carry | Disposition |\n")
+	fmt.Fprintf(out, "|---|---|---|---|---|\n")
+	for _, row := range rows {
+		fmt.Fprintln(out, row)
+	}
+	fmt.Fprintln(out)
+}
+
+func markdownTable(out io.Writer, f fork.Fork, title string, entries []Entry) {
+	fmt.Fprintf(out, "## %s (%d)\n\n", title, len(entries))
+	if len(entries) == 0 {
+		fmt.Fprintf(out, "None.\n\n")
+		return
+	}
+	fmt.Fprintf(out, "| Commit | New commit | Disposition | Message |\n")
+	fmt.Fprintf(out, "|---|---|---|---|\n")
+	for _, e := range entries {
+		message := EscapeMarkdown(e.Message)
+		if len(e.Reason) > 0 {
+			message += " (" + EscapeMarkdown(e.Reason) + ")"
+		}
+		if origin := e.Origin(); len(origin) > 0 {
+			message += "<br>" + EscapeMarkdown(origin)
+		}
+		fmt.Fprintf(out, "| [%s](%s) | %s | %s | %s |\n", shortSHA(e.Original), f.CommitURL(e.Original),
+			shortSHA(e.New), e.Disposition, message)
+	}
+	fmt.Fprintln(out)
+}
+
+func shortSHA(sha string) string {
+	if len(sha) > 10 {
+		return sha[:10]
+	}
+	return sha
+}
+
+func valueOrUnknown(value string) string {
+	if len(value) == 0 {
+		return "unknown"
+	}
+	return value
+}
+
+// EscapeMarkdown escapes characters which break markdown tables or are
+// interpreted as html, such as <carry>, and joins lines.
+func EscapeMarkdown(s string) string {
+	return strings.NewReplacer("|", "\\|", "<", "&lt;", ">", "&gt;", "\n", " ").Replace(s)
+}
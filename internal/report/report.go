@@ -0,0 +1,264 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Disposition describes what happened with a carry commit during apply.
+type Disposition string
+
+const (
+	// Picked carry was cherry-picked cleanly
+	Picked Disposition = "picked"
+	// PickedTheirs carry was cherry-picked with recursive strategy and theirs option
+	PickedTheirs Disposition = "picked-theirs"
+	// PickedOurs carry was cherry-picked with ours strategy option, because
+	// conflicts were limited to designated paths
+	PickedOurs Disposition = "picked-ours"
+	// PickedRegenerated carry was cherry-picked with conflicts limited to
+	// generated files, which were regenerated
+	PickedRegenerated Disposition = "picked-regenerated"
+	// PickedResolved carry was cherry-picked with conflicts resolved by a
+	// configured resolver
+	PickedResolved Disposition = "picked-resolved"
+	// Fixed carry was replaced with a fixed carry patch
+	Fixed Disposition = "fixed"
+	// FixedFuzz carry was replaced with a fixed carry patch applied with reduced context
+	FixedFuzz Disposition = "fixed-fuzz"
+	// Fixed3Way carry was replaced with a fixed carry patch applied with 3-way merge
+	Fixed3Way Disposition = "fixed-3way"
+	// Skipped carry was skipped due to an empty fixed carry, or because it
+	// had no changes left on top of upstream
+	Skipped Disposition = "skipped"
+	// Dropped carry was marked with drop action
+	Dropped Disposition = "dropped"
+	// Merged carry was already merged upstream
+	Merged Disposition = "merged"
+	// Unknown carry had an unknown action
+	Unknown Disposition = "unknown"
+	// Failed carry requires manual intervention
+	Failed Disposition = "failed"
+	// Manual carry was resolved manually
+	Manual Disposition = "manual"
+	// Deferred carry did not apply cleanly and was queued for the manual pass
+	Deferred Disposition = "deferred"
+)
+
+// Conflicted returns true if the carry could not be picked cleanly.
+func (d Disposition) Conflicted() bool {
+	switch d {
+	case PickedTheirs, PickedOurs, PickedRegenerated, PickedResolved, Fixed, FixedFuzz, Fixed3Way, Failed, Manual, Deferred:
+		return true
+	}
+	return false
+}
+
+// Lands returns true if the carry ends up as a commit on the rebase branch,
+// either picked or applied from a fixed carry, or resolved manually.
+func (d Disposition) Lands() bool {
+	switch d {
+	case Picked, PickedTheirs, PickedOurs, PickedRegenerated, PickedResolved, Fixed, FixedFuzz, Fixed3Way, Manual:
+		return true
+	}
+	return false
+}
+
+// Entry describes the result of processing a single carry commit.
+type Entry struct {
+	// Original is the SHA of the carry commit on openshift/master
+	Original string `json:"original"`
+	// New is the SHA of the resulting commit on the rebase branch, empty if none was created
+	New string `json:"new,omitempty"`
+	// Disposition is the action taken on the commit
+	Disposition Disposition `json:"disposition"`
+	// Message is the first line of the commit message
+	Message string `json:"message"`
+	// Reason explains the disposition, when it is not obvious
+	Reason string `json:"reason,omitempty"`
+	// Backport is set for upstream commits picked in addition to the carries
+	Backport bool `json:"backport,omitempty"`
+	// UnknownAction is set when the commit had an unknown UPSTREAM action
+	UnknownAction bool `json:"unknownAction,omitempty"`
+	// Duration is how long processing the commit took
+	Duration time.Duration `json:"duration,omitempty"`
+	// PullRequest is the URL of the openshift/kubernetes pull request which introduced the carry
+	PullRequest string `json:"pullRequest,omitempty"`
+	// Author is the github login of the author of the pull request
+	Author string `json:"author,omitempty"`
+	// Reviewers are the github logins of the reviewers of the pull request
+	Reviewers []string `json:"reviewers,omitempty"`
+	// Bugs are the bugs referenced in the commit message
+	Bugs []Bug `json:"bugs,omitempty"`
+	// Assignees are the suggested approvers of the conflicted paths
+	Assignees []string `json:"assignees,omitempty"`
+	// Conflicts are the files left with conflicts for manual resolution
+	Conflicts []string `json:"conflicts,omitempty"`
+}
+
+// Bug is a jira or bugzilla bug referenced by a carry.
+type Bug struct {
+	// Key identifies the bug, eg. OCPBUGS-1234 or BZ-2001234
+	Key string `json:"key"`
+	// URL links the bug
+	URL string `json:"url"`
+	// Status is the status of the bug, when it was looked up
+	Status string `json:"status,omitempty"`
+	// Priority is the priority of the bug, when it was looked up
+	Priority string `json:"priority,omitempty"`
+}
+
+// Origin describes who introduced the carry, empty if unknown.
+func (e Entry) Origin() string {
+	if len(e.PullRequest) == 0 {
+		return ""
+	}
+	origin := e.PullRequest
+	if len(e.Author) > 0 {
+		origin += " by @" + e.Author
+	}
+	if len(e.Reviewers) > 0 {
+		origin += ", reviewed by @" + strings.Join(e.Reviewers, ", @")
+	}
+	return origin
+}
+
+const (
+	// StageLog is reading the carries log
+	StageLog = "log"
+	// StagePreflight is the preflight check
+	StagePreflight = "preflight"
+	// StageMerge is creating the rebase branch and merging openshift/master
+	StageMerge = "merge"
+	// StageCarries is picking carries
+	StageCarries = "carries"
+	// StageBackports is picking additional upstream backports
+	StageBackports = "backports"
+	// StageAdditional is applying additional carries
+	StageAdditional = "additional"
+	// StageQueue is processing the manual queue
+	StageQueue = "queue"
+)
+
+// Stage describes how long a single stage of the run took.
+type Stage struct {
+	Name     string        `json:"name"`
+	Duration time.Duration `json:"duration"`
+}
+
+// Report gathers the results of an apply run.
+type Report struct {
+	// From is the kubernetes tag the run started from
+	From string `json:"from,omitempty"`
+	// TargetVersion is the kubernetes version being rebased to
+	TargetVersion string `json:"targetVersion,omitempty"`
+	// UpstreamSHA is the upstream commit the rebase branch is based on
+	UpstreamSHA string `json:"upstreamSHA,omitempty"`
+	// OpenShiftSHA is the openshift commit carries were read from
+	OpenShiftSHA string `json:"openshiftSHA,omitempty"`
+	// Branch is the name of the rebase branch
+	Branch string `json:"branch,omitempty"`
+	// PullRequest is the URL of the rebase pull request
+	PullRequest string `json:"pullRequest,omitempty"`
+
+	Started time.Time `json:"started"`
+	Stages  []Stage   `json:"stages,omitempty"`
+	Entries []Entry   `json:"entries"`
+}
+
+// slowestCount is the number of slowest commits listed in the summary
+const slowestCount = 10
+
+// Add appends the entry to the report.
+func (r *Report) Add(entry Entry) {
+	r.Entries = append(r.Entries, entry)
+}
+
+// Find returns the last entry for a given original commit, or nil if not found.
+func (r *Report) Find(original string) *Entry {
+	for i := len(r.Entries) - 1; i >= 0; i-- {
+		if r.Entries[i].Original == original {
+			return &r.Entries[i]
+		}
+	}
+	return nil
+}
+
+// AddStage records a stage which started at a given time and finished now.
+func (r *Report) AddStage(name string, start time.Time) {
+	r.Stages = append(r.Stages, Stage{Name: name, Duration: time.Since(start)})
+}
+
+// Summary prints total and per-stage times, the slowest commits and
+// conflicts by category.
+func (r *Report) Summary(out io.Writer) {
+	fmt.Fprintf(out, "Run summary: %d commits processed in %s\n", len(r.Entries), time.Since(r.Started).Round(time.Second))
+	if len(r.PullRequest) > 0 {
+		fmt.Fprintf(out, "  pull request %s\n", r.PullRequest)
+	}
+	for _, s := range r.Stages {
+		fmt.Fprintf(out, "  %-12s %s\n", s.Name, s.Duration.Round(time.Millisecond))
+	}
+
+	conflicts := make(map[Disposition]int)
+	conflictsTime := make(map[Disposition]time.Duration)
+	for _, e := range r.Entries {
+		if e.Disposition.Conflicted() {
+			conflicts[e.Disposition]++
+			conflictsTime[e.Disposition] += e.Duration
+		}
+	}
+	if len(conflicts) > 0 {
+		categories := make([]string, 0, len(conflicts))
+		for d := range conflicts {
+			categories = append(categories, string(d))
+		}
+		sort.Strings(categories)
+		fmt.Fprintf(out, "Conflicts by category:\n")
+		for _, c := range categories {
+			d := Disposition(c)
+			fmt.Fprintf(out, "  %-18s %d (%s)\n", d, conflicts[d], conflictsTime[d].Round(time.Millisecond))
+		}
+	}
+
+	var unknown []Entry
+	for _, e := range r.Entries {
+		if e.UnknownAction {
+			unknown = append(unknown, e)
+		}
+	}
+	if len(unknown) > 0 {
+		fmt.Fprintf(out, "WARNING: %d commits with unknown action, make sure no carry was lost:\n", len(unknown))
+		for _, e := range unknown {
+			fmt.Fprintf(out, "  %s\t%s\t%s\n", e.Disposition, e.Original, e.Message)
+		}
+	}
+
+	slowest := make([]Entry, len(r.Entries))
+	copy(slowest, r.Entries)
+	sort.SliceStable(slowest, func(i, j int) bool { return slowest[i].Duration > slowest[j].Duration })
+	if len(slowest) > slowestCount {
+		slowest = slowest[:slowestCount]
+	}
+	if len(slowest) > 0 {
+		fmt.Fprintf(out, "Slowest commits:\n")
+		for _, e := range slowest {
+			fmt.Fprintf(out, "  %s\t%s\t%s\t%s\n", e.Duration.Round(time.Millisecond), e.Disposition, e.Original, e.Message)
+		}
+	}
+}
+
+// WriteMapping writes a machine-readable mapping of original carry SHAs to the
+// new SHAs on the rebase branch, along with their dispositions.
+func (r *Report) WriteMapping(path string) error {
+	data, err := json.MarshalIndent(r.Entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
@@ -0,0 +1,172 @@
+package risk
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/openshift/rebase/internal/apply"
+	"github.com/openshift/rebase/internal/carry"
+	"github.com/openshift/rebase/internal/fork"
+	"github.com/openshift/rebase/internal/git"
+	"github.com/openshift/rebase/internal/output"
+	"github.com/openshift/rebase/internal/utils"
+)
+
+const (
+	// linesPerCommit is how many changed lines weigh as much as a single commit in the score
+	linesPerCommit = 50
+	// topPackages is the number of the most changed packages listed for every carry
+	topPackages = 3
+)
+
+// churn is how much upstream changed a package
+type churn struct {
+	commits map[string]bool
+	lines   int
+}
+
+// Score describes how much upstream changed the packages touched by a carry.
+type Score struct {
+	SHA      string   `json:"sha"`
+	Message  string   `json:"message"`
+	Score    int      `json:"score"`
+	Commits  int      `json:"commits"`
+	Lines    int      `json:"lines"`
+	Packages []string `json:"packages"`
+}
+
+// Risk scores carries by upstream churn in the packages they touch, between
+// the starting version and the new upstream version, so that reviewers know
+// which carries need careful re-testing even when they applied cleanly.
+type Risk struct {
+	from          string
+	to            string
+	carriesTo     string
+	fork          fork.Fork
+	repositoryDir string
+	output        string
+	markerSearch  git.MarkerSearch
+}
+
+// NewRisk returns the risk analysis, printed in the output format, either
+// json, yaml or empty for a human-readable list.
+func NewRisk(from, repositoryDir string, f fork.Fork, to, output string) *Risk {
+	if len(to) == 0 {
+		to = f.UpstreamRef()
+	}
+	return &Risk{
+		from:          from,
+		to:            to,
+		fork:          f,
+		repositoryDir: repositoryDir,
+		output:        output,
+	}
+}
+
+// SetCarriesTo reads carries from the range between the merge base of the
+// starting tag and the ref.
+func (r *Risk) SetCarriesTo(ref string) {
+	r.carriesTo = ref
+}
+
+// SetMarkerSearch bounds the search of the rebase marker.
+func (r *Risk) SetMarkerSearch(search git.MarkerSearch) {
+	r.markerSearch = search
+}
+
+func (r *Risk) Run() error {
+	repository, err := git.OpenGit(r.repositoryDir, r.fork)
+	if err != nil {
+		return err
+	}
+	scores, err := r.Scores(repository)
+	if err != nil {
+		return err
+	}
+	if output.IsMachine(r.output) {
+		return output.Write(os.Stdout, r.output, scores)
+	}
+	fmt.Printf("Upstream churn in packages touched by carries between %s and %s:\n", r.from, r.to)
+	for _, s := range scores {
+		fmt.Printf("%d\t%d commits\t%d lines\t%s\t%s\t%s\n", s.Score, s.Commits, s.Lines, s.SHA, s.Message, strings.Join(s.Packages, ", "))
+	}
+	return nil
+}
+
+// Scores returns the carries sorted from the riskiest one.
+func (r *Risk) Scores(repository git.Git) ([]Score, error) {
+	changes, err := repository.LogNumStat(r.from, r.to)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading upstream changes between %s and %s: %w", r.from, r.to, err)
+	}
+	packages := packageChurn(changes)
+	log := carry.NewLog(r.from, r.repositoryDir, r.fork)
+	log.SetTo(r.carriesTo)
+	log.SetMarkerSearch(r.markerSearch)
+	commits, err := log.GetCommits(repository)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading carries: %w", err)
+	}
+	scores := []Score{}
+	for _, commit := range commits {
+		if apply.IsDropped(r.fork, commit.Message) {
+			continue
+		}
+		files, err := repository.ChangedFiles(commit.Hash.String())
+		if err != nil {
+			return nil, err
+		}
+		score := scoreFiles(packages, files)
+		score.SHA, score.Message = commit.Hash.String(), utils.FormatMessage(commit.Message)
+		scores = append(scores, score)
+	}
+	sort.SliceStable(scores, func(i, j int) bool { return scores[i].Score > scores[j].Score })
+	return scores, nil
+}
+
+// packageChurn sums the upstream changes of files by their packages
+func packageChurn(changes []git.FileChange) map[string]*churn {
+	packages := make(map[string]*churn)
+	for _, c := range changes {
+		dir := path.Dir(c.Path)
+		if packages[dir] == nil {
+			packages[dir] = &churn{commits: make(map[string]bool)}
+		}
+		packages[dir].commits[c.Commit] = true
+		packages[dir].lines += c.Added + c.Deleted
+	}
+	return packages
+}
+
+// scoreFiles scores a carry changing the files by the upstream churn in their
+// packages, listing the most changed ones
+func scoreFiles(packages map[string]*churn, files []string) Score {
+	score := Score{Packages: []string{}}
+	seen := make(map[string]bool)
+	touched := make(map[string]bool)
+	for _, f := range files {
+		dir := path.Dir(f)
+		p, ok := packages[dir]
+		if !ok || touched[dir] {
+			continue
+		}
+		touched[dir] = true
+		score.Packages = append(score.Packages, dir)
+		score.Lines += p.lines
+		for sha := range p.commits {
+			seen[sha] = true
+		}
+	}
+	score.Commits = len(seen)
+	score.Score = score.Commits + score.Lines/linesPerCommit
+	sort.Slice(score.Packages, func(i, j int) bool {
+		return packages[score.Packages[i]].lines > packages[score.Packages[j]].lines
+	})
+	if len(score.Packages) > topPackages {
+		score.Packages = score.Packages[:topPackages]
+	}
+	return score
+}
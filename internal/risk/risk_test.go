@@ -0,0 +1,55 @@
+package risk
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/openshift/rebase/internal/git"
+)
+
+func TestScoreFiles(t *testing.T) {
+	changes := []git.FileChange{
+		{Commit: "u1", Path: "pkg/kubelet/kubelet.go", Added: 80, Deleted: 20},
+		{Commit: "u1", Path: "pkg/kubelet/pod.go", Added: 10},
+		{Commit: "u2", Path: "pkg/kubelet/kubelet.go", Added: 5, Deleted: 5},
+		{Commit: "u2", Path: "pkg/scheduler/scheduler.go", Added: 40},
+		{Commit: "u3", Path: "pkg/apis/core/types.go", Deleted: 10},
+		{Commit: "u4", Path: "pkg/proxy/proxier.go", Added: 1},
+		{Commit: "u5", Path: "README.md", Added: 3},
+	}
+	tests := []struct {
+		name     string
+		files    []string
+		expected Score
+	}{
+		{
+			name:     "package without upstream changes",
+			files:    []string{"openshift-hack/test.sh", "pkg/kubelet/openshift/hook.go"},
+			expected: Score{Packages: []string{}},
+		},
+		{
+			name:     "commits of a package counted once",
+			files:    []string{"pkg/kubelet/kubelet.go", "pkg/kubelet/pod.go"},
+			expected: Score{Score: 4, Commits: 2, Lines: 120, Packages: []string{"pkg/kubelet"}},
+		},
+		{
+			name:     "commits shared by packages counted once",
+			files:    []string{"pkg/scheduler/scheduler.go", "pkg/kubelet/kubelet.go"},
+			expected: Score{Score: 5, Commits: 2, Lines: 160, Packages: []string{"pkg/kubelet", "pkg/scheduler"}},
+		},
+		{
+			name:  "most changed packages",
+			files: []string{"README.md", "pkg/proxy/proxier.go", "pkg/apis/core/types.go", "pkg/scheduler/scheduler.go", "pkg/kubelet/kubelet.go"},
+			expected: Score{Score: 5 + 174/linesPerCommit, Commits: 5, Lines: 174,
+				Packages: []string{"pkg/kubelet", "pkg/scheduler", "pkg/apis/core"}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			score := scoreFiles(packageChurn(changes), test.files)
+			if !reflect.DeepEqual(score, test.expected) {
+				t.Errorf("expected %+v, got %+v", test.expected, score)
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package rollback
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/openshift/rebase/internal/console"
+	"github.com/openshift/rebase/internal/fork"
+	"github.com/openshift/rebase/internal/git"
+	"github.com/openshift/rebase/internal/state"
+)
+
+type Rollback struct {
+	repositoryDir string
+	stateDir      string
+	fork          fork.Fork
+	keepBranch    bool
+}
+
+func NewRollback(repositoryDir string, f fork.Fork) *Rollback {
+	return &Rollback{
+		repositoryDir: repositoryDir,
+		fork:          f,
+	}
+}
+
+// SetStateDir reads the state of the run from a given directory instead of
+// the git directory.
+func (r *Rollback) SetStateDir(dir string) {
+	r.stateDir = dir
+}
+
+// SetKeepBranch keeps the rebase branch with the carries picked so far,
+// instead of deleting it.
+func (r *Rollback) SetKeepBranch(keep bool) {
+	r.keepBranch = keep
+}
+
+// Run reverts the repository to the state before the apply run, it aborts
+// any in-progress operation, restores the original checkout, deletes
+// the rebase branch, unless it should be kept, and clears the state file.
+func (r *Rollback) Run() error {
+	repository, err := git.OpenGit(r.repositoryDir, r.fork)
+	if err != nil {
+		return err
+	}
+	gitDir, err := repository.GitDir()
+	if err != nil {
+		return err
+	}
+	unlock, err := state.Acquire(gitDir, "rollback")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	stateDir := state.DirOf(gitDir, r.stateDir)
+	runState, err := state.Load(stateDir)
+	if err != nil {
+		if errors.Is(err, state.ErrNotFound) {
+			return fmt.Errorf("Nothing to roll back: %w", err)
+		}
+		return err
+	}
+	if err := repository.AbortInProgress(); err != nil {
+		return fmt.Errorf("Error aborting in-progress operation: %w", err)
+	}
+	if len(runState.OriginalRef) > 0 {
+		console.Infof("Restoring %s...", runState.OriginalRef)
+		if err := repository.Checkout(runState.OriginalRef); err != nil {
+			return fmt.Errorf("Error restoring %s: %w", runState.OriginalRef, err)
+		}
+	}
+	if r.keepBranch && len(runState.Branch) > 0 {
+		console.Infof("Keeping rebase branch %s", runState.Branch)
+	} else if len(runState.Branch) > 0 && runState.Branch != runState.OriginalRef {
+		console.Infof("Deleting rebase branch %s...", runState.Branch)
+		if err := repository.DeleteBranch(runState.Branch); err != nil {
+			return fmt.Errorf("Error deleting rebase branch %s: %w", runState.Branch, err)
+		}
+	}
+	return state.Remove(stateDir)
+}
@@ -0,0 +1,227 @@
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"k8s.io/klog/v2"
+)
+
+// lockFile is created in the git directory for the duration of a run
+const lockFile = "openshift-rebase.lock"
+
+// foreignLockTimeout is how long a lock of another host is respected, whether
+// its process is running cannot be checked, eg. of a killed container
+// sharing the repository
+const foreignLockTimeout = 24 * time.Hour
+
+// ErrNotLocked is returned by Unlock when no run holds the lock.
+var ErrNotLocked = errors.New("the repository is not locked")
+
+// Lock describes the process holding the lock of the repository.
+type Lock struct {
+	// PID is the process ID of the holder
+	PID int `json:"pid"`
+	// Host is the host name of the machine the holder runs on
+	Host string `json:"host"`
+	// Command is the command holding the lock, eg. apply
+	Command string `json:"command"`
+	// Started is when the lock was taken
+	Started time.Time `json:"started"`
+}
+
+// LockedError is returned when another run holds the lock of the repository.
+type LockedError struct {
+	// Path is the lock file
+	Path string
+	// Holder is the process holding the lock
+	Holder Lock
+}
+
+func (e *LockedError) Error() string {
+	return fmt.Sprintf("another %s run (pid %d on %s, started %s) is using the repository, wait for it to finish or run 'rebase unlock' to remove %s if it is no longer running",
+		e.Holder.Command, e.Holder.PID, e.Holder.Host, e.Holder.Started.Format(time.RFC3339), e.Path)
+}
+
+// LockPath returns the location of the lock file in a given git directory, the
+// lock is always kept in the git directory, which is shared by all runs.
+func LockPath(gitDir string) string {
+	return filepath.Join(gitDir, lockFile)
+}
+
+// Acquire takes the lock of the repository for the command, so that two runs
+// do not fight over the sequencer and branches, the returned function releases
+// it. A lock left by a process which is no longer running, or by another host
+// longer ago than foreignLockTimeout, is taken over.
+func Acquire(gitDir, command string) (func(), error) {
+	path := LockPath(gitDir)
+	host, _ := os.Hostname()
+	ours := Lock{PID: os.Getpid(), Host: host, Command: command, Started: time.Now()}
+	data, err := json.Marshal(ours)
+	if err != nil {
+		return nil, err
+	}
+	// the lock is written aside and then linked into place, so that others
+	// never read a partially written lock
+	temp, err := os.CreateTemp(gitDir, lockFile+".*")
+	if err != nil {
+		return nil, fmt.Errorf("Error writing lock: %w", err)
+	}
+	defer os.Remove(temp.Name())
+	if _, err := temp.Write(data); err != nil {
+		temp.Close()
+		return nil, fmt.Errorf("Error writing lock %s: %w", temp.Name(), err)
+	}
+	if err := temp.Close(); err != nil {
+		return nil, fmt.Errorf("Error writing lock %s: %w", temp.Name(), err)
+	}
+	release := func() {
+		holder, err := readLock(path)
+		if err != nil {
+			klog.Errorf("Releasing lock %s failed: %v", path, err)
+			return
+		}
+		if !holder.same(ours) {
+			klog.Warningf("Leaving lock %s in place, it was taken over by %s run (pid %d)", path, holder.Command, holder.PID)
+			return
+		}
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			klog.Errorf("Removing lock %s failed: %v", path, err)
+		}
+	}
+	// further attempts follow the removal of a stale lock or waiting for
+	// another run taking it over
+	for attempt := 0; attempt < takeoverAttempts; attempt++ {
+		err := os.Link(temp.Name(), path)
+		if err == nil {
+			return release, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, fmt.Errorf("Error creating lock %s: %w", path, err)
+		}
+		holder, err := readLock(path)
+		if errors.Is(err, os.ErrNotExist) {
+			// released in the meantime
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if holder.alive(host) {
+			return nil, &LockedError{Path: path, Holder: holder}
+		}
+		taken, err := takeOver(path, holder, temp.Name())
+		if err != nil {
+			return nil, err
+		}
+		if taken {
+			return release, nil
+		}
+		time.Sleep(takeoverWait)
+	}
+	return nil, fmt.Errorf("Error taking over stale lock %s, remove %s%s if no other run is using the repository", path, path, takeoverSuffix)
+}
+
+const (
+	// takeoverSuffix names the marker held while a stale lock is replaced
+	takeoverSuffix = ".takeover"
+	// takeoverAttempts and takeoverWait bound waiting for another run
+	// taking over a stale lock
+	takeoverAttempts = 20
+	takeoverWait     = 50 * time.Millisecond
+)
+
+// takeOver replaces the stale lock of holder with temp, while holding the
+// exclusive takeover marker, so that only one of the runs finding the same
+// stale lock replaces it. It returns false when another run is taking it over.
+func takeOver(path string, holder Lock, temp string) (bool, error) {
+	marker, err := os.OpenFile(path+takeoverSuffix, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if errors.Is(err, os.ErrExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("Error taking over stale lock %s: %w", path, err)
+	}
+	marker.Close()
+	defer os.Remove(marker.Name())
+	// another run may have taken over the stale lock before the marker was
+	// created, it is replaced only while it still holds the same process
+	current, err := readLock(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if !current.same(holder) {
+		return false, nil
+	}
+	klog.Warningf("Taking over stale lock %s of %s run (pid %d on %s, started %s), which is no longer running",
+		path, holder.Command, holder.PID, holder.Host, holder.Started.Format(time.RFC3339))
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return false, fmt.Errorf("Error removing stale lock %s: %w", path, err)
+	}
+	// a run not taking over may link its lock right after the removal,
+	// it then holds the lock
+	if err := os.Link(temp, path); err != nil {
+		if errors.Is(err, os.ErrExist) {
+			return false, nil
+		}
+		return false, fmt.Errorf("Error taking over stale lock %s: %w", path, err)
+	}
+	return true, nil
+}
+
+// Unlock removes the lock of the repository regardless of its holder, eg. one
+// left by a killed container on another host, which is not taken over before
+// it times out, and returns the removed lock, which is empty when malformed.
+func Unlock(gitDir string) (Lock, error) {
+	path := LockPath(gitDir)
+	holder, err := readLock(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return Lock{}, ErrNotLocked
+	}
+	if err != nil {
+		klog.Warningf("Removing unreadable lock: %v", err)
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return Lock{}, fmt.Errorf("Error removing lock %s: %w", path, err)
+	}
+	// a takeover interrupted by a killed run would keep others from taking
+	// over stale locks
+	if err := os.Remove(path + takeoverSuffix); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return holder, fmt.Errorf("Error removing %s%s: %w", path, takeoverSuffix, err)
+	}
+	return holder, nil
+}
+
+// alive checks if the holder may still be running on the host, a lock of
+// another host is respected until it times out
+func (l Lock) alive(host string) bool {
+	if l.Host != host {
+		return time.Since(l.Started) < foreignLockTimeout
+	}
+	return processAlive(l.PID)
+}
+
+// same checks if both locks are held by the same process
+func (l Lock) same(other Lock) bool {
+	return l.PID == other.PID && l.Host == other.Host && l.Started.Equal(other.Started)
+}
+
+// readLock reads the holder of the lock
+func readLock(path string) (Lock, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Lock{}, fmt.Errorf("Error reading lock %s: %w", path, err)
+	}
+	holder := Lock{}
+	if err := json.Unmarshal(data, &holder); err != nil {
+		return Lock{}, fmt.Errorf("malformed lock %s, remove it if no other run is using the repository: %w", path, err)
+	}
+	return holder, nil
+}
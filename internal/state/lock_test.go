@@ -0,0 +1,187 @@
+//go:build linux || darwin
+
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"os/exec"
+	"sync"
+	"testing"
+	"time"
+)
+
+// writeLock leaves a lock of another process in the git directory
+func writeLock(t *testing.T, gitDir string, holder Lock) {
+	t.Helper()
+	data, err := json.Marshal(holder)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(LockPath(gitDir), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// deadPID returns the process ID of a process which is no longer running
+func deadPID(t *testing.T) int {
+	t.Helper()
+	cmd := exec.Command("true")
+	if err := cmd.Run(); err != nil {
+		t.Skipf("running a process failed: %v", err)
+	}
+	return cmd.Process.Pid
+}
+
+func TestAcquire(t *testing.T) {
+	gitDir := t.TempDir()
+	unlock, err := Acquire(gitDir, "apply")
+	if err != nil {
+		t.Fatal(err)
+	}
+	holder, err := readLock(LockPath(gitDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if holder.PID != os.Getpid() || holder.Command != "apply" {
+		t.Errorf("expected the lock of apply run pid %d, got %+v", os.Getpid(), holder)
+	}
+	if _, err := Acquire(gitDir, "continue"); !errors.As(err, new(*LockedError)) {
+		t.Errorf("expected the repository to be locked, got %v", err)
+	}
+	unlock()
+	if _, err := os.Stat(LockPath(gitDir)); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected the lock to be released, got %v", err)
+	}
+}
+
+func TestAcquireStale(t *testing.T) {
+	gitDir := t.TempDir()
+	host, _ := os.Hostname()
+	writeLock(t, gitDir, Lock{PID: deadPID(t), Host: host, Command: "apply", Started: time.Now().Add(-time.Hour)})
+	unlock, err := Acquire(gitDir, "continue")
+	if err != nil {
+		t.Fatalf("expected the stale lock to be taken over, got %v", err)
+	}
+	holder, err := readLock(LockPath(gitDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if holder.PID != os.Getpid() || holder.Command != "continue" {
+		t.Errorf("expected the lock of continue run pid %d, got %+v", os.Getpid(), holder)
+	}
+	unlock()
+	if _, err := os.Stat(LockPath(gitDir)); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected the lock to be released, got %v", err)
+	}
+	if entries, _ := os.ReadDir(gitDir); len(entries) != 0 {
+		t.Errorf("expected no files left in the git directory, got %d", len(entries))
+	}
+}
+
+func TestAcquireStaleConcurrently(t *testing.T) {
+	gitDir := t.TempDir()
+	host, _ := os.Hostname()
+	writeLock(t, gitDir, Lock{PID: deadPID(t), Host: host, Command: "apply", Started: time.Now().Add(-time.Hour)})
+	const runs = 8
+	var wg sync.WaitGroup
+	unlocks := make(chan func(), runs)
+	for i := 0; i < runs; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			unlock, err := Acquire(gitDir, "continue")
+			if err == nil {
+				unlocks <- unlock
+			} else if !errors.As(err, new(*LockedError)) {
+				t.Errorf("expected the repository to be locked, got %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	close(unlocks)
+	if len(unlocks) != 1 {
+		t.Fatalf("expected exactly one run to take over the stale lock, got %d", len(unlocks))
+	}
+	(<-unlocks)()
+	if entries, _ := os.ReadDir(gitDir); len(entries) != 0 {
+		t.Errorf("expected no files left in the git directory, got %d", len(entries))
+	}
+}
+
+func TestAcquireOtherHost(t *testing.T) {
+	tests := []struct {
+		name    string
+		started time.Time
+		locked  bool
+	}{
+		{
+			name:    "recent lock",
+			started: time.Now().Add(-time.Hour),
+			locked:  true,
+		},
+		{
+			name:    "timed out lock",
+			started: time.Now().Add(-foreignLockTimeout - time.Minute),
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			gitDir := t.TempDir()
+			writeLock(t, gitDir, Lock{PID: deadPID(t), Host: "other-host", Command: "apply", Started: test.started})
+			unlock, err := Acquire(gitDir, "continue")
+			if locked := errors.As(err, new(*LockedError)); locked != test.locked {
+				t.Fatalf("expected locked %v, got %v", test.locked, err)
+			}
+			if err == nil {
+				unlock()
+			}
+		})
+	}
+}
+
+func TestUnlock(t *testing.T) {
+	gitDir := t.TempDir()
+	if _, err := Unlock(gitDir); !errors.Is(err, ErrNotLocked) {
+		t.Errorf("expected %v, got %v", ErrNotLocked, err)
+	}
+	other := Lock{PID: os.Getppid(), Host: "other-host", Command: "apply", Started: time.Now()}
+	writeLock(t, gitDir, other)
+	if err := os.WriteFile(LockPath(gitDir)+takeoverSuffix, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	holder, err := Unlock(gitDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !holder.same(other) {
+		t.Errorf("expected the lock of the other run, got %+v", holder)
+	}
+	if entries, _ := os.ReadDir(gitDir); len(entries) != 0 {
+		t.Errorf("expected no files left in the git directory, got %d", len(entries))
+	}
+	unlock, err := Acquire(gitDir, "continue")
+	if err != nil {
+		t.Fatalf("expected the repository to be unlocked, got %v", err)
+	}
+	unlock()
+}
+
+func TestReleaseTakenOver(t *testing.T) {
+	gitDir := t.TempDir()
+	unlock, err := Acquire(gitDir, "apply")
+	if err != nil {
+		t.Fatal(err)
+	}
+	other := Lock{PID: os.Getppid(), Host: "other-host", Command: "continue", Started: time.Now()}
+	writeLock(t, gitDir, other)
+	unlock()
+	holder, err := readLock(LockPath(gitDir))
+	if err != nil {
+		t.Fatalf("expected the lock of the other run to be kept, got %v", err)
+	}
+	if !holder.same(other) {
+		t.Errorf("expected the lock of the other run, got %+v", holder)
+	}
+}
@@ -0,0 +1,8 @@
+//go:build !linux && !darwin
+
+package state
+
+// processAlive is not known on other platforms, the process is assumed to run
+func processAlive(pid int) bool {
+	return true
+}
@@ -0,0 +1,18 @@
+//go:build linux || darwin
+
+package state
+
+import (
+	"errors"
+	"syscall"
+)
+
+// processAlive checks if the process with the PID runs on this machine
+func processAlive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	// EPERM means the process exists, but belongs to another user
+	err := syscall.Kill(pid, 0)
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
@@ -0,0 +1,189 @@
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"k8s.io/klog/v2"
+
+	"github.com/openshift/rebase/internal/fork"
+	"github.com/openshift/rebase/internal/report"
+)
+
+const stateFile = "openshift-rebase-state.json"
+
+// logDir holds a log file of every apply and continue
+const logDir = "openshift-rebase-logs"
+
+const queueFile = "openshift-rebase-queue.txt"
+
+const (
+	// PhaseMerge is creating the rebase branch and merging openshift/master
+	PhaseMerge = "merge"
+	// PhaseCarries is picking carries
+	PhaseCarries = "carries"
+	// PhaseBackports is picking additional upstream backports
+	PhaseBackports = "backports"
+	// PhaseAdditional is applying additional carries
+	PhaseAdditional = "additional"
+	// PhaseQueue is processing carries deferred to the manual queue
+	PhaseQueue = "queue"
+	// PhaseDone means the run finished
+	PhaseDone = "done"
+)
+
+// ErrNotFound is returned when there is no state file in the repository.
+var ErrNotFound = errors.New("no rebase run in progress")
+
+// State describes an apply run, which is persisted inside of the repository's
+// git directory, so that it can be resumed or rolled back.
+type State struct {
+	// From is the kubernetes tag the run started from
+	From string `json:"from"`
+	// OriginalRef is the branch, or commit when detached, checked out before the run
+	OriginalRef string `json:"originalRef"`
+	// Branch is the rebase branch created by the run
+	Branch string `json:"branch"`
+	// Phase is the current phase of the run
+	Phase string `json:"phase"`
+	// Options are the serialized apply options, used when resuming the run
+	Options json.RawMessage `json:"options,omitempty"`
+	// Commits is the ordered list of carry commits being processed
+	Commits []string `json:"commits,omitempty"`
+	// Next is the index of the next commit to process
+	Next int `json:"next"`
+	// BackportsNext is the index of the next upstream backport to pick
+	BackportsNext int `json:"backportsNext"`
+	// Queue is the ordered list of carries deferred for the manual pass
+	Queue []string `json:"queue,omitempty"`
+	// QueueNext is the index of the next deferred carry to process
+	QueueNext int `json:"queueNext"`
+	// Current is the commit which stopped the run and requires manual intervention
+	Current string `json:"current,omitempty"`
+	// StoppedAt is the HEAD of the rebase branch when the run stopped
+	StoppedAt string `json:"stoppedAt,omitempty"`
+	// Committed is set when the run stopped after committing Current, eg. on
+	// failed validation or a failed post-pick hook
+	Committed bool `json:"committed,omitempty"`
+	// Report holds the results of processed commits
+	Report *report.Report `json:"report,omitempty"`
+	// Fork is the fork the run was started on, with its remotes
+	Fork *fork.Fork `json:"fork,omitempty"`
+}
+
+// RunFork returns the fork the run was started on, which is used instead of
+// the fork selected by flags. Runs started by older versions did not record
+// it, selected is returned for them.
+func (s *State) RunFork(selected fork.Fork) fork.Fork {
+	if s.Fork == nil {
+		return selected
+	}
+	if selected.Name != s.Fork.Name {
+		klog.V(2).Infof("Using the %s fork the run was started on instead of %s", s.Fork.Name, selected.Name)
+	}
+	return *s.Fork
+}
+
+// DirOf returns the directory holding the state of runs in the repository
+// with a given git directory, which is the git directory, unless dir is set,
+// eg. to a volume of a container.
+func DirOf(gitDir, dir string) string {
+	if len(dir) > 0 {
+		return dir
+	}
+	return gitDir
+}
+
+// Path returns the location of the state file in a given state directory.
+func Path(dir string) string {
+	return filepath.Join(dir, stateFile)
+}
+
+// LogDir returns the directory holding logs of runs, next to the state file.
+func LogDir(dir string) string {
+	return filepath.Join(dir, logDir)
+}
+
+// QueuePath returns the default location of the manual queue, next to the
+// state file.
+func QueuePath(dir string) string {
+	return filepath.Join(dir, queueFile)
+}
+
+// Load reads the state file from a given state directory, returns ErrNotFound
+// when there is none.
+func Load(dir string) (*State, error) {
+	data, err := os.ReadFile(Path(dir))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	s := &State{}
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, fmt.Errorf("malformed state file %s: %w", Path(dir), err)
+	}
+	return s, nil
+}
+
+// Save writes the state file to a given state directory.
+func (s *State) Save(dir string) error {
+	data, err := s.Marshal()
+	if err != nil {
+		return err
+	}
+	return Write(dir, data)
+}
+
+// Marshal encodes the state the way it is saved in the state file.
+func (s *State) Marshal() ([]byte, error) {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// writing serializes writes of the state file, which is saved by the run and
+// by the handler of a second interrupt
+var writing sync.Mutex
+
+// Write writes the state encoded by Marshal to a given state directory.
+func Write(dir string, data []byte) error {
+	writing.Lock()
+	defer writing.Unlock()
+	path := Path(dir)
+	// written through a temporary file, so that an interrupted write never
+	// leaves a partial state behind
+	tmp, err := os.CreateTemp(filepath.Dir(path), stateFile+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Remove deletes the state file from a given state directory.
+func Remove(dir string) error {
+	writing.Lock()
+	defer writing.Unlock()
+	if err := os.Remove(Path(dir)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,209 @@
+package status
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing"
+
+	"github.com/openshift/rebase/internal/fork"
+	"github.com/openshift/rebase/internal/git"
+	"github.com/openshift/rebase/internal/output"
+	"github.com/openshift/rebase/internal/report"
+	"github.com/openshift/rebase/internal/state"
+	"github.com/openshift/rebase/internal/utils"
+)
+
+// Local describes the run in progress in a repository, read from the state
+// file and the repository's sequencer state.
+type Local struct {
+	// InProgress is set when there is a run in the repository
+	InProgress bool `json:"inProgress"`
+	// From is the kubernetes tag the run started from
+	From string `json:"from,omitempty"`
+	// Branch is the rebase branch
+	Branch string `json:"branch,omitempty"`
+	// CheckedOut is the branch currently checked out, empty when HEAD is detached
+	CheckedOut string `json:"checkedOut,omitempty"`
+	// Phase is the current phase of the run
+	Phase string `json:"phase,omitempty"`
+	// Commits is the number of carry commits
+	Commits int `json:"commits"`
+	// CommitsProcessed is the number of carry commits already processed
+	CommitsProcessed int `json:"commitsProcessed"`
+	// Queue is the number of carries deferred to the manual queue
+	Queue int `json:"queue,omitempty"`
+	// QueueProcessed is the number of queued carries already processed
+	QueueProcessed int `json:"queueProcessed,omitempty"`
+	// Current is the commit which stopped the run
+	Current string `json:"current,omitempty"`
+	// CurrentMessage is the summary of the commit which stopped the run
+	CurrentMessage string `json:"currentMessage,omitempty"`
+	// Operation is the git operation in progress, eg. cherry-pick
+	Operation string `json:"operation,omitempty"`
+	// Conflicts are the files with unresolved conflicts
+	Conflicts []string `json:"conflicts,omitempty"`
+	// Dispositions counts processed commits by disposition
+	Dispositions map[report.Disposition]int `json:"dispositions,omitempty"`
+	// Next is what to do to move the run forward
+	Next string `json:"next"`
+}
+
+// Show prints where the run in a repository is and what to run next.
+type Show struct {
+	repositoryDir string
+	stateDir      string
+	fork          fork.Fork
+	output        string
+}
+
+// NewShow returns the status of the run in a repository, printed in the
+// output format, either json, yaml or empty for a human-readable summary.
+func NewShow(repositoryDir string, f fork.Fork, output string) *Show {
+	return &Show{
+		repositoryDir: repositoryDir,
+		fork:          f,
+		output:        output,
+	}
+}
+
+// SetStateDir reads the state of the run from a given directory instead of
+// the git directory.
+func (s *Show) SetStateDir(dir string) {
+	s.stateDir = dir
+}
+
+func (s *Show) Run() error {
+	repository, err := git.OpenGit(s.repositoryDir, s.fork)
+	if err != nil {
+		return err
+	}
+	local, err := s.Local(repository)
+	if err != nil {
+		return err
+	}
+	if output.IsMachine(s.output) {
+		return output.Write(os.Stdout, s.output, local)
+	}
+	if !local.InProgress {
+		fmt.Println("No rebase run in progress.")
+		fmt.Printf("Next: %s\n", local.Next)
+		return nil
+	}
+	fmt.Printf("Rebase from %s on branch %s", local.From, local.Branch)
+	if local.CheckedOut != local.Branch {
+		if len(local.CheckedOut) == 0 {
+			fmt.Print(", HEAD is detached")
+		} else {
+			fmt.Printf(", %s is checked out", local.CheckedOut)
+		}
+	}
+	fmt.Println()
+	fmt.Printf("Phase: %s\n", local.Phase)
+	fmt.Printf("Carries: %d/%d done, %d remaining\n", local.CommitsProcessed, local.Commits, local.Commits-local.CommitsProcessed)
+	if local.Queue > 0 {
+		fmt.Printf("Queue: %d/%d done, %d remaining\n", local.QueueProcessed, local.Queue, local.Queue-local.QueueProcessed)
+	}
+	if len(local.Dispositions) > 0 {
+		dispositions := make([]string, 0, len(local.Dispositions))
+		for d, count := range local.Dispositions {
+			dispositions = append(dispositions, fmt.Sprintf("%d %s", count, d))
+		}
+		sort.Strings(dispositions)
+		fmt.Printf("Processed: %s\n", strings.Join(dispositions, ", "))
+	}
+	if len(local.Current) > 0 {
+		fmt.Printf("Stopped on: %s %s\n", local.Current, local.CurrentMessage)
+	}
+	if len(local.Operation) > 0 {
+		fmt.Printf("In progress: %s\n", local.Operation)
+	}
+	if len(local.Conflicts) > 0 {
+		fmt.Println("Unresolved conflicts:")
+		for _, f := range local.Conflicts {
+			fmt.Printf("  %s\n", f)
+		}
+	}
+	fmt.Printf("Next: %s\n", local.Next)
+	return nil
+}
+
+// Local reads the state of the run in the repository.
+func (s *Show) Local(repository git.Git) (*Local, error) {
+	gitDir, err := repository.GitDir()
+	if err != nil {
+		return nil, err
+	}
+	runState, err := state.Load(state.DirOf(gitDir, s.stateDir))
+	if errors.Is(err, state.ErrNotFound) {
+		return &Local{Next: "start a run with 'rebase apply'"}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	local := &Local{
+		InProgress:       true,
+		From:             runState.From,
+		Branch:           runState.Branch,
+		Phase:            runState.Phase,
+		Commits:          len(runState.Commits),
+		CommitsProcessed: runState.Next,
+		Queue:            len(runState.Queue),
+		QueueProcessed:   runState.QueueNext,
+		Current:          runState.Current,
+	}
+	if local.CheckedOut, err = repository.CurrentBranch(); err != nil {
+		return nil, err
+	}
+	if len(runState.Current) > 0 {
+		if commit, err := repository.Commit(plumbing.NewHash(runState.Current)); err == nil {
+			local.CurrentMessage = utils.FormatMessage(commit.Message)
+		}
+	}
+	if runState.Report != nil {
+		local.Dispositions = make(map[report.Disposition]int)
+		for _, entry := range runState.Report.Entries {
+			local.Dispositions[entry.Disposition]++
+		}
+	}
+	if local.Operation, err = repository.InProgress(); err != nil {
+		return nil, err
+	}
+	if len(local.Operation) > 0 {
+		if local.Conflicts, err = repository.ConflictedFiles(); err != nil {
+			return nil, err
+		}
+	}
+	committed := false
+	if len(runState.StoppedAt) > 0 {
+		head, err := repository.RevParse("HEAD")
+		if err != nil {
+			return nil, err
+		}
+		committed = head != runState.StoppedAt
+	}
+	local.Next = next(local, committed)
+	return local, nil
+}
+
+// next describes what to do to move the run forward, committed is set when
+// a commit was added on top of the one the run stopped at
+func next(local *Local, committed bool) string {
+	switch {
+	case local.Phase == state.PhaseDone && len(local.Operation) > 0:
+		return fmt.Sprintf("the run finished with a %s in progress, finish it with 'git %s --continue' or '--abort'", local.Operation, local.Operation)
+	case local.Phase == state.PhaseDone:
+		return fmt.Sprintf("the run finished, check the result with 'rebase verify --from=%s'", local.From)
+	case local.Phase == state.PhaseMerge:
+		return "the run stopped before picking carries, run 'rebase rollback' and start again"
+	case len(local.Conflicts) > 0:
+		return "resolve the conflicts, stage the files and run 'rebase continue'"
+	case len(local.Current) > 0 && len(local.Operation) == 0 && !committed:
+		return "commit the resolution, or leave HEAD unchanged to skip the carry, and run 'rebase continue'"
+	default:
+		return "run 'rebase continue'"
+	}
+}
@@ -0,0 +1,109 @@
+package status
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"k8s.io/klog/v2"
+
+	"github.com/openshift/rebase/internal/report"
+	"github.com/openshift/rebase/internal/utils"
+)
+
+// Status is a snapshot of an in-progress run.
+type Status struct {
+	// Branch is the rebase branch
+	Branch string `json:"branch"`
+	// Phase is the current phase of the run
+	Phase string `json:"phase"`
+	// Commits is the number of carry commits
+	Commits int `json:"commits"`
+	// CommitsProcessed is the number of carry commits already processed
+	CommitsProcessed int `json:"commitsProcessed"`
+	// Backports is the number of additional upstream backports
+	Backports int `json:"backports,omitempty"`
+	// BackportsProcessed is the number of upstream backports already processed
+	BackportsProcessed int `json:"backportsProcessed,omitempty"`
+	// Queue is the number of carries deferred to the manual queue
+	Queue int `json:"queue,omitempty"`
+	// QueueProcessed is the number of queued carries already processed
+	QueueProcessed int `json:"queueProcessed,omitempty"`
+	// Current is the commit being processed
+	Current string `json:"current,omitempty"`
+	// Stopped is set when the run stopped on Current for manual intervention
+	Stopped bool `json:"stopped,omitempty"`
+	// Pending are the conflicting commits still waiting for manual resolution
+	Pending []string `json:"pending,omitempty"`
+	// Dispositions counts processed commits by disposition
+	Dispositions map[report.Disposition]int `json:"dispositions,omitempty"`
+	// Started is when the run started
+	Started time.Time `json:"started"`
+	// Elapsed is how long the run has been going
+	Elapsed time.Duration `json:"elapsed"`
+	// AverageCommit is the average time spent on a single commit
+	AverageCommit time.Duration `json:"averageCommit,omitempty"`
+	// Remaining is the estimated time needed for the remaining carries
+	Remaining time.Duration `json:"remaining,omitempty"`
+	// Stages are the finished stages of the run
+	Stages []report.Stage `json:"stages,omitempty"`
+	// Updated is when the status was last updated
+	Updated time.Time `json:"updated"`
+}
+
+// Server serves the status of the run as JSON over HTTP.
+type Server struct {
+	lock   sync.Mutex
+	status Status
+	server *utils.HTTPServer
+}
+
+func New(address string) *Server {
+	s := &Server{}
+	s.server = utils.NewHTTPServer("run status", address, "/", s.serveStatus)
+	return s
+}
+
+// Start listens on the address and serves the status in the background.
+func (s *Server) Start() error {
+	return s.server.Start()
+}
+
+// Stop closes the server.
+func (s *Server) Stop() {
+	if s == nil {
+		return
+	}
+	s.server.Stop()
+}
+
+// Update replaces the served status.
+func (s *Server) Update(status Status) {
+	if s == nil {
+		return
+	}
+	status.Updated = time.Now()
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.status = status
+}
+
+func (s *Server) serveStatus(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" && r.URL.Path != "/status" {
+		http.NotFound(w, r)
+		return
+	}
+	s.lock.Lock()
+	status := s.status
+	s.lock.Unlock()
+	if !status.Started.IsZero() {
+		status.Elapsed = time.Since(status.Started)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(status); err != nil {
+		klog.Warningf("Writing run status failed: %v", err)
+	}
+}
@@ -0,0 +1,169 @@
+package table
+
+import (
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/openshift/rebase/internal/progress"
+	"github.com/openshift/rebase/internal/report"
+)
+
+// Color is the terminal escape sequence coloring a row.
+type Color string
+
+const (
+	None   Color = ""
+	Red    Color = "\033[31m"
+	Green  Color = "\033[32m"
+	Yellow Color = "\033[33m"
+	Grey   Color = "\033[90m"
+
+	reset = "\033[0m"
+	// padding separates columns
+	padding = 2
+)
+
+// DispositionColor returns the color of a disposition: red for carries
+// requiring manual intervention, yellow for resolved conflicts, green for
+// clean picks and grey for carries which were not picked.
+func DispositionColor(d report.Disposition) Color {
+	switch {
+	case d == report.Failed || d == report.Deferred || d == report.Unknown:
+		return Red
+	case d.Conflicted():
+		return Yellow
+	case d == report.Picked:
+		return Green
+	}
+	return Grey
+}
+
+type row struct {
+	color Color
+	cells []string
+}
+
+// Table writes rows aligned in columns. When writing to a terminal, rows are
+// colored and the last column is wrapped to the terminal width, otherwise
+// the output is plain, so that it can be piped.
+type Table struct {
+	out    io.Writer
+	tty    bool
+	width  int
+	header []string
+	rows   []row
+}
+
+// New returns a table with the column names in header, which is omitted when
+// there are no rows.
+func New(out io.Writer, header ...string) *Table {
+	t := &Table{out: out, header: header}
+	if f, ok := out.(*os.File); ok && progress.IsTerminal(f) {
+		t.tty = true
+		t.width = width(f)
+	}
+	return t
+}
+
+// AddRow adds a row of cells, colored when writing to a terminal.
+func (t *Table) AddRow(color Color, cells ...string) {
+	t.rows = append(t.rows, row{color: color, cells: cells})
+}
+
+// Flush writes the table to the output.
+func (t *Table) Flush() error {
+	if len(t.rows) == 0 {
+		return nil
+	}
+	rows := t.rows
+	if len(t.header) > 0 {
+		rows = append([]row{{cells: t.header}}, rows...)
+	}
+	var widths []int
+	for _, r := range rows {
+		for i, cell := range r.cells[:len(r.cells)-1] {
+			if i == len(widths) {
+				widths = append(widths, 0)
+			}
+			if n := utf8.RuneCountInString(cell); n > widths[i] {
+				widths[i] = n
+			}
+		}
+	}
+	var b strings.Builder
+	for _, r := range rows {
+		var line strings.Builder
+		indent := 0
+		for i, cell := range r.cells[:len(r.cells)-1] {
+			line.WriteString(cell)
+			line.WriteString(strings.Repeat(" ", widths[i]-utf8.RuneCountInString(cell)+padding))
+			indent += widths[i] + padding
+		}
+		last := r.cells[len(r.cells)-1]
+		if t.tty {
+			last = wrap(last, t.width-indent, indent)
+		}
+		line.WriteString(last)
+		if t.tty && len(r.color) > 0 && colorEnabled() {
+			b.WriteString(string(r.color) + line.String() + reset + "\n")
+		} else {
+			b.WriteString(line.String() + "\n")
+		}
+	}
+	_, err := io.WriteString(t.out, b.String())
+	return err
+}
+
+// wrap breaks text into lines of at most width runes, the continuation lines
+// are indented to align with the column
+func wrap(text string, width, indent int) string {
+	if width < 20 {
+		// not enough space left, let the terminal wrap the line
+		return text
+	}
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		runes := []rune(line)
+		for len(runes) > width {
+			cut := width
+			if space := strings.LastIndex(string(runes[:width]), " "); space > 0 {
+				cut = utf8.RuneCountInString(string(runes[:width])[:space])
+			}
+			lines = append(lines, strings.TrimRight(string(runes[:cut]), " "))
+			runes = []rune(strings.TrimLeft(string(runes[cut:]), " "))
+		}
+		lines = append(lines, string(runes))
+	}
+	return strings.Join(lines, "\n"+strings.Repeat(" ", indent))
+}
+
+// colorEnabled respects the NO_COLOR convention
+func colorEnabled() bool {
+	return len(os.Getenv("NO_COLOR")) == 0
+}
+
+// width returns the number of columns of the terminal, COLUMNS takes
+// precedence, defaulting to 80
+func width(f *os.File) int {
+	if columns, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && columns > 0 {
+		return columns
+	}
+	cmd := exec.Command("stty", "size")
+	cmd.Stdin = f
+	output, err := cmd.Output()
+	if err != nil {
+		return 80
+	}
+	fields := strings.Fields(string(output))
+	if len(fields) != 2 {
+		return 80
+	}
+	if columns, err := strconv.Atoi(fields[1]); err == nil && columns > 0 {
+		return columns
+	}
+	return 80
+}
@@ -0,0 +1,127 @@
+package tui
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/openshift/rebase/internal/apply"
+	"github.com/openshift/rebase/internal/progress"
+)
+
+// errPickerAborted is returned when the selection is abandoned
+var errPickerAborted = errors.New("selection of carries was aborted")
+
+// SelectCarries shows a checkbox list of the planned commits, deselected
+// commits are dropped from the plan and selected dropped ones are picked.
+func SelectCarries(plan *apply.Plan) error {
+	if !progress.IsTerminal(os.Stdin) || !progress.IsTerminal(os.Stdout) {
+		return fmt.Errorf("selecting carries requires a terminal")
+	}
+	selected := make([]bool, len(plan.Steps))
+	for i, s := range plan.Steps {
+		selected[i] = s.Command != apply.PlanDrop
+	}
+	term, err := newTerminal(os.Stdin)
+	if err != nil {
+		return fmt.Errorf("Error switching terminal into raw mode: %w", err)
+	}
+	defer term.restore()
+	p := &picker{steps: plan.Steps, selected: selected}
+	reader := bufio.NewReader(os.Stdin)
+	for {
+		p.draw(os.Stdout, term)
+		key, err := reader.ReadByte()
+		if err != nil {
+			return err
+		}
+		switch key {
+		case '\r', '\n':
+			fmt.Fprint(os.Stdout, clearScreen)
+			for i := range plan.Steps {
+				switch {
+				case !selected[i]:
+					plan.Steps[i].Command = apply.PlanDrop
+				case plan.Steps[i].Command == apply.PlanDrop:
+					plan.Steps[i].Command = apply.PlanPick
+				}
+			}
+			return nil
+		case 'q':
+			fmt.Fprint(os.Stdout, clearScreen)
+			return errPickerAborted
+		default:
+			p.handle(key)
+		}
+	}
+}
+
+// picker is the state of the checkbox list
+type picker struct {
+	steps    []apply.PlanStep
+	selected []bool
+	cursor   int
+}
+
+func (p *picker) handle(key byte) {
+	switch key {
+	case 'j':
+		if p.cursor < len(p.steps)-1 {
+			p.cursor++
+		}
+	case 'k':
+		if p.cursor > 0 {
+			p.cursor--
+		}
+	case 'g':
+		p.cursor = 0
+	case 'G':
+		p.cursor = len(p.steps) - 1
+	case ' ':
+		if len(p.steps) > 0 {
+			p.selected[p.cursor] = !p.selected[p.cursor]
+		}
+	case 'a', 'n':
+		for i := range p.selected {
+			p.selected[i] = key == 'a'
+		}
+	}
+}
+
+func (p *picker) draw(out io.Writer, term *terminal) {
+	rows, cols := term.size()
+	var b strings.Builder
+	b.WriteString(clearScreen)
+	count := 0
+	for _, s := range p.selected {
+		if s {
+			count++
+		}
+	}
+	fmt.Fprintf(&b, "%s\r\n", truncate(fmt.Sprintf("Select carries to pick, %d/%d selected", count, len(p.steps)), cols))
+	height := rows - 3
+	if height < 1 {
+		height = 1
+	}
+	first := 0
+	if p.cursor >= height {
+		first = p.cursor - height + 1
+	}
+	for i := first; i < len(p.steps) && i < first+height; i++ {
+		box := "[ ]"
+		if p.selected[i] {
+			box = "[x]"
+		}
+		line := truncate(fmt.Sprintf("%s %s %s", box, p.steps[i].SHA[:10], p.steps[i].Message), cols)
+		if i == p.cursor {
+			fmt.Fprintf(&b, "%s%s%s\r\n", reverse, line, reset)
+		} else {
+			fmt.Fprintf(&b, "%s\r\n", line)
+		}
+	}
+	fmt.Fprintf(&b, "\r\n%s\r\n", truncate("j/k move  g/G first/last  space toggle  a/n all/none  enter start  q abort", cols))
+	io.WriteString(out, b.String())
+}
@@ -0,0 +1,57 @@
+package tui
+
+import (
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// terminal switches the controlling terminal into raw mode using stty, so
+// that single key presses are read without waiting for enter.
+type terminal struct {
+	in    *os.File
+	saved string
+}
+
+func newTerminal(in *os.File) (*terminal, error) {
+	saved, err := stty(in, "-g")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := stty(in, "-icanon", "-echo", "min", "1"); err != nil {
+		return nil, err
+	}
+	return &terminal{in: in, saved: strings.TrimSpace(saved)}, nil
+}
+
+// restore brings back the terminal settings from before entering raw mode
+func (t *terminal) restore() error {
+	_, err := stty(t.in, t.saved)
+	return err
+}
+
+// size returns the number of rows and columns of the terminal, defaulting to 24x80
+func (t *terminal) size() (int, int) {
+	output, err := stty(t.in, "size")
+	if err != nil {
+		return 24, 80
+	}
+	fields := strings.Fields(output)
+	if len(fields) != 2 {
+		return 24, 80
+	}
+	rows, err1 := strconv.Atoi(fields[0])
+	cols, err2 := strconv.Atoi(fields[1])
+	if err1 != nil || err2 != nil || rows == 0 || cols == 0 {
+		return 24, 80
+	}
+	return rows, cols
+}
+
+func stty(in *os.File, args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = in
+	output, err := cmd.Output()
+	return string(output), err
+}
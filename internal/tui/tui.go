@@ -0,0 +1,463 @@
+package tui
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"k8s.io/klog/v2"
+
+	"github.com/openshift/rebase/internal/apply"
+	"github.com/openshift/rebase/internal/console"
+	"github.com/openshift/rebase/internal/fork"
+	"github.com/openshift/rebase/internal/git"
+	"github.com/openshift/rebase/internal/progress"
+	"github.com/openshift/rebase/internal/report"
+	"github.com/openshift/rebase/internal/state"
+	"github.com/openshift/rebase/internal/table"
+	"github.com/openshift/rebase/internal/utils"
+)
+
+const (
+	logFile         = "openshift-rebase.log"
+	refreshInterval = 500 * time.Millisecond
+
+	// ANSI escape sequences used for drawing
+	clearScreen = "\033[H\033[2J"
+	reverse     = "\033[7m"
+	reset       = "\033[0m"
+)
+
+// carry is a single line of the carry list
+type carry struct {
+	sha     string
+	message string
+	entry   *report.Entry
+}
+
+// TUI drives an apply run interactively, showing the carries with their live
+// status, while the run logs into a file inside of the git directory.
+type TUI struct {
+	from          string
+	repositoryDir string
+	stateDir      string
+	fork          fork.Fork
+	options       apply.Options
+	markerSearch  git.MarkerSearch
+
+	// log receives messages, progress and the summary of the run, which would
+	// break the screen
+	log io.Writer
+	// cancel stops the run in the background, saving its progress
+	cancel context.CancelFunc
+	ctx    context.Context
+
+	lock     sync.Mutex
+	carries  []carry
+	branch   string
+	phase    string
+	current  string
+	stopped  bool
+	running  bool
+	quitting bool
+	selected int
+	notice   string
+	messages map[string]string
+}
+
+func NewTUI(from, repositoryDir string, f fork.Fork, options apply.Options) *TUI {
+	return &TUI{
+		from:          from,
+		repositoryDir: repositoryDir,
+		fork:          f,
+		options:       options,
+		messages:      make(map[string]string),
+	}
+}
+
+// SetStateDir reads and persists the state of the run in a given directory
+// instead of the git directory.
+func (t *TUI) SetStateDir(dir string) {
+	t.stateDir = dir
+}
+
+// SetMarkerSearch bounds the search of the rebase marker.
+func (t *TUI) SetMarkerSearch(search git.MarkerSearch) {
+	t.markerSearch = search
+}
+
+func (t *TUI) Run() error {
+	if !progress.IsTerminal(os.Stdin) || !progress.IsTerminal(os.Stdout) {
+		return fmt.Errorf("the interactive mode requires a terminal")
+	}
+	repository, err := git.OpenGit(t.repositoryDir, t.fork)
+	if err != nil {
+		return err
+	}
+	gitDir, err := repository.GitDir()
+	if err != nil {
+		return err
+	}
+	runState, err := state.Load(state.DirOf(gitDir, t.stateDir))
+	if err != nil && !errors.Is(err, state.ErrNotFound) {
+		return err
+	}
+	if runState != nil {
+		if runFork := runState.RunFork(t.fork); runFork.Name != t.fork.Name {
+			// the remotes are checked against the fork the run was started on
+			t.fork = runFork
+			if repository, err = git.OpenGit(t.repositoryDir, t.fork); err != nil {
+				return err
+			}
+		}
+	}
+	if runState == nil && len(t.from) == 0 {
+		return fmt.Errorf("no rebase run in progress, use --from to start one")
+	}
+	// the run prints its logs, progress and summary, which would break
+	// the screen, so they are all written into the log file
+	log, err := os.OpenFile(filepath.Join(gitDir, logFile), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	defer log.Close()
+	if err := console.SetOutput(log); err != nil {
+		return err
+	}
+	t.log = log
+	t.ctx, t.cancel = context.WithCancel(context.Background())
+	defer t.cancel()
+	out := os.Stdout
+	term, err := newTerminal(os.Stdin)
+	if err != nil {
+		return fmt.Errorf("Error switching terminal into raw mode: %w", err)
+	}
+	defer term.restore()
+
+	if runState != nil {
+		t.observe(repository, runState, runState.Current)
+		t.setNotice("Loaded the run on %s, logs are written to %s", runState.Branch, log.Name())
+	} else {
+		t.start(repository, func() error {
+			applyAction := apply.NewApply(t.from, t.repositoryDir, t.fork, t.options)
+			applyAction.SetRepository(repository)
+			applyAction.SetStateDir(t.stateDir)
+			applyAction.SetMarkerSearch(t.markerSearch)
+			applyAction.SetObserver(func(s *state.State, current string) { t.observe(repository, s, current) })
+			applyAction.SetContext(t.ctx)
+			applyAction.SetOutput(t.log, t.log)
+			return applyAction.Run()
+		}, "Started the run from %s, logs are written to %s", t.from, log.Name())
+	}
+
+	keys := make(chan byte)
+	go func() {
+		reader := bufio.NewReader(os.Stdin)
+		for {
+			key, err := reader.ReadByte()
+			if err != nil {
+				close(keys)
+				return
+			}
+			keys <- key
+		}
+	}()
+	ticker := time.NewTicker(refreshInterval)
+	defer ticker.Stop()
+	for {
+		if t.quit() {
+			fmt.Fprint(out, clearScreen)
+			return nil
+		}
+		t.draw(out, term)
+		select {
+		case <-ticker.C:
+		case key, ok := <-keys:
+			if !ok || key == 'q' {
+				t.stop()
+				continue
+			}
+			t.handle(repository, gitDir, key)
+		}
+	}
+}
+
+// stop asks the run in the background to stop, the UI quits once it saved
+// its progress, or right away when nothing runs
+func (t *TUI) stop() {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if t.quitting {
+		return
+	}
+	t.quitting = true
+	if t.running {
+		t.notice = "Stopping the run, its progress is saved and it is resumed with 'rebase continue'..."
+		t.cancel()
+	}
+}
+
+// quit returns true once the UI can quit, when it was asked to and the run
+// in the background stopped
+func (t *TUI) quit() bool {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	return t.quitting && !t.running
+}
+
+// start runs the action in the background, the carry list is updated by the observer
+func (t *TUI) start(repository git.Git, action func() error, format string, args ...interface{}) {
+	t.lock.Lock()
+	if t.running || t.quitting {
+		t.lock.Unlock()
+		t.setNotice("The run is in progress, wait for it to stop")
+		return
+	}
+	t.running = true
+	t.lock.Unlock()
+	t.setNotice(format, args...)
+	go func() {
+		err := action()
+		t.lock.Lock()
+		t.running = false
+		t.lock.Unlock()
+		if err != nil {
+			klog.Errorf("The run stopped: %v", err)
+			t.setNotice("The run stopped: %v", err)
+			return
+		}
+		t.setNotice("The run finished")
+	}()
+}
+
+// observe updates the carry list from the state of the run, it is invoked
+// by the run, so the state is copied
+func (t *TUI) observe(repository git.Git, runState *state.State, current string) {
+	entries := make(map[string]report.Entry)
+	if runState.Report != nil {
+		for _, e := range runState.Report.Entries {
+			entries[e.Original] = e
+		}
+	}
+	shas := append([]string{}, runState.Commits...)
+	for _, sha := range runState.Queue {
+		if _, ok := entries[sha]; !ok {
+			shas = append(shas, sha)
+		}
+	}
+	carries := make([]carry, 0, len(shas))
+	for _, sha := range shas {
+		c := carry{sha: sha, message: t.message(repository, sha)}
+		if e, ok := entries[sha]; ok {
+			c.entry = &e
+			c.message = e.Message
+		}
+		carries = append(carries, c)
+	}
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.carries = carries
+	t.branch = runState.Branch
+	t.phase = runState.Phase
+	t.current = current
+	t.stopped = len(runState.Current) > 0
+	if t.stopped {
+		t.current = runState.Current
+	}
+}
+
+// message returns the summary of a commit, the lookups are cached
+func (t *TUI) message(repository git.Git, sha string) string {
+	if message, ok := t.messages[sha]; ok {
+		return message
+	}
+	message := ""
+	if commit, err := repository.Commit(plumbing.NewHash(sha)); err == nil {
+		message = utils.FormatMessage(commit.Message)
+	}
+	t.messages[sha] = message
+	return message
+}
+
+func (t *TUI) setNotice(format string, args ...interface{}) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.notice = fmt.Sprintf(format, args...)
+}
+
+// handle reacts on a key press
+func (t *TUI) handle(repository git.Git, gitDir string, key byte) {
+	t.lock.Lock()
+	switch key {
+	case 'j':
+		if t.selected < len(t.carries)-1 {
+			t.selected++
+		}
+	case 'k':
+		if t.selected > 0 {
+			t.selected--
+		}
+	case 'g':
+		t.selected = 0
+	case 'G':
+		if t.selected = len(t.carries) - 1; t.selected < 0 {
+			t.selected = 0
+		}
+	case 'c':
+		t.selected = t.nextConflict()
+	case 'd':
+		if t.selected >= 0 && t.selected < len(t.carries) {
+			t.notice = t.details(repository, t.carries[t.selected])
+		}
+	}
+	t.lock.Unlock()
+	switch key {
+	case 'r':
+		if !t.isStopped() {
+			t.setNotice("The run is not stopped on a conflict")
+			return
+		}
+		t.start(repository, t.continueRun(repository), "Continuing with the manual resolution...")
+	case 's':
+		if !t.isStopped() {
+			t.setNotice("The run is not stopped on a conflict")
+			return
+		}
+		t.start(repository, func() error {
+			runState, err := state.Load(gitDir)
+			if err != nil {
+				return err
+			}
+			// dropping the in-progress pick makes continue record the carry as
+			// skipped, a carry committed already is reset away
+			skipTo := runState.StoppedAt
+			if runState.Committed {
+				skipTo += "^"
+			}
+			if err := repository.AbortInProgress(); err != nil {
+				return err
+			}
+			if err := repository.ResetHard(skipTo); err != nil {
+				return err
+			}
+			return t.continueRun(repository)()
+		}, "Skipping the carry...")
+	}
+}
+
+func (t *TUI) isStopped() bool {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	return t.stopped && !t.running
+}
+
+func (t *TUI) continueRun(repository git.Git) func() error {
+	return func() error {
+		continueAction := apply.NewContinue(t.repositoryDir, t.fork)
+		continueAction.SetRepository(repository)
+		continueAction.SetStateDir(t.stateDir)
+		continueAction.SetObserver(func(s *state.State, current string) { t.observe(repository, s, current) })
+		continueAction.SetContext(t.ctx)
+		continueAction.SetOutput(t.log, t.log)
+		return continueAction.Run()
+	}
+}
+
+// nextConflict returns the index of the next carry requiring manual
+// intervention after the selected one, wrapping around
+func (t *TUI) nextConflict() int {
+	for i := 1; i <= len(t.carries); i++ {
+		index := (t.selected + i) % len(t.carries)
+		if c := t.carries[index]; c.sha == t.current && t.stopped ||
+			c.entry != nil && (c.entry.Disposition == report.Failed || c.entry.Disposition == report.Deferred) {
+			return index
+		}
+	}
+	return t.selected
+}
+
+// details describes the carry, including conflicted files of the stopped one
+func (t *TUI) details(repository git.Git, c carry) string {
+	details := fmt.Sprintf("%s %s", c.sha, c.message)
+	if c.entry != nil && len(c.entry.Reason) > 0 {
+		details += ": " + c.entry.Reason
+	}
+	if c.sha == t.current && t.stopped {
+		if files, err := repository.ConflictedFiles(); err == nil && len(files) > 0 {
+			details += "\nconflicts: " + strings.Join(files, ", ")
+		}
+	}
+	return details
+}
+
+// status returns the displayed status of a carry with its color
+func (t *TUI) status(c carry) (string, string) {
+	switch {
+	case c.sha == t.current && t.stopped:
+		return "conflict", string(table.Red)
+	case c.sha == t.current:
+		return "picking", string(table.Yellow)
+	case c.entry == nil:
+		return "pending", ""
+	default:
+		return string(c.entry.Disposition), string(table.DispositionColor(c.entry.Disposition))
+	}
+}
+
+func (t *TUI) draw(out io.Writer, term *terminal) {
+	rows, cols := term.size()
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	var b strings.Builder
+	b.WriteString(clearScreen)
+	processed := 0
+	for _, c := range t.carries {
+		if c.entry != nil {
+			processed++
+		}
+	}
+	running := "stopped"
+	if t.running {
+		running = "running"
+	}
+	fmt.Fprintf(&b, "%s\r\n", truncate(fmt.Sprintf("Branch %s, phase %s, %s, %d/%d carries processed", t.branch, t.phase, running, processed, len(t.carries)), cols))
+	messageLines := strings.Split(t.notice, "\n")
+	height := rows - 3 - len(messageLines)
+	if height < 1 {
+		height = 1
+	}
+	first := 0
+	if t.selected >= height {
+		first = t.selected - height + 1
+	}
+	for i := first; i < len(t.carries) && i < first+height; i++ {
+		c := t.carries[i]
+		status, color := t.status(c)
+		line := truncate(fmt.Sprintf("%-18s %s %s", status, c.sha[:10], c.message), cols)
+		if i == t.selected {
+			fmt.Fprintf(&b, "%s%s%s\r\n", reverse, line, reset)
+		} else {
+			fmt.Fprintf(&b, "%s%s%s\r\n", color, line, reset)
+		}
+	}
+	fmt.Fprintf(&b, "\r\n%s\r\n", truncate("j/k move  g/G first/last  c next conflict  d details  r mark resolved  s skip  q quit", cols))
+	for _, l := range messageLines {
+		fmt.Fprintf(&b, "%s\r\n", truncate(l, cols))
+	}
+	fmt.Fprint(out, b.String())
+}
+
+func truncate(s string, width int) string {
+	if len(s) <= width {
+		return s
+	}
+	return s[:width]
+}
@@ -0,0 +1,39 @@
+package utils
+
+import (
+	"context"
+	"os"
+	"os/exec"
+
+	"k8s.io/klog/v2"
+)
+
+// RunCommand runs the command in dir and returns its combined output, for
+// tools run outside of the git wrapper, eg. go, gofmt and scripts of the
+// repository.
+func RunCommand(dir, name string, args ...string) (string, error) {
+	return RunCommandWithEnv(dir, nil, name, args...)
+}
+
+// RunCommandWithEnv runs the command the same as RunCommand, with the
+// environment variables in key=value form added to the current environment.
+func RunCommandWithEnv(dir string, env []string, name string, args ...string) (string, error) {
+	return RunCommandContext(context.Background(), dir, env, name, args...)
+}
+
+// RunCommandContext runs the command the same as RunCommandWithEnv, the
+// command is killed once the context, eg. of the run, is cancelled.
+func RunCommandContext(ctx context.Context, dir string, env []string, name string, args ...string) (string, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	cmd := exec.CommandContext(ctx, name, args...)
+	klog.V(2).Infof("Invoking %s...", cmd)
+	cmd.Dir = dir
+	if len(env) > 0 {
+		cmd.Env = append(os.Environ(), env...)
+	}
+	output, err := cmd.CombinedOutput()
+	klog.V(3).Info(string(output))
+	return string(output), err
+}
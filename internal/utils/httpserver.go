@@ -0,0 +1,55 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"k8s.io/klog/v2"
+
+	"github.com/openshift/rebase/internal/console"
+)
+
+// HTTPServer serves a single handler in the background, eg. the run status or
+// metrics of the watch daemon.
+type HTTPServer struct {
+	name    string
+	pattern string
+	server  *http.Server
+}
+
+// NewHTTPServer returns a server of the handler on the pattern, the name
+// describes what is served in messages.
+func NewHTTPServer(name, address, pattern string, handler http.HandlerFunc) *HTTPServer {
+	mux := http.NewServeMux()
+	mux.HandleFunc(pattern, handler)
+	return &HTTPServer{
+		name:    name,
+		pattern: pattern,
+		server:  &http.Server{Addr: address, Handler: mux, ReadHeaderTimeout: 10 * time.Second},
+	}
+}
+
+// Start listens on the address and serves the handler in the background.
+func (s *HTTPServer) Start() error {
+	listener, err := net.Listen("tcp", s.server.Addr)
+	if err != nil {
+		return fmt.Errorf("Error listening on %s: %w", s.server.Addr, err)
+	}
+	console.Infof("Serving %s on http://%s%s", s.name, listener.Addr().String(), s.pattern)
+	go func() {
+		if err := s.server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			klog.Warningf("Serving %s failed: %v", s.name, err)
+		}
+	}()
+	return nil
+}
+
+// Stop closes the server.
+func (s *HTTPServer) Stop() {
+	if err := s.server.Close(); err != nil {
+		klog.Warningf("Stopping %s server failed: %v", s.name, err)
+	}
+}
@@ -0,0 +1,61 @@
+package utils
+
+import (
+	"runtime"
+	"sync"
+)
+
+// DefaultConcurrency is the number of workers used when none is requested.
+func DefaultConcurrency() int {
+	return runtime.NumCPU()
+}
+
+// Parallel calls fn for every index from 0 to n-1 using at most concurrency
+// workers, concurrency below 1 selects DefaultConcurrency. After the first
+// error no further indexes are handed out, the error of the lowest index is
+// returned. Results are meant to be stored by fn at the index, which keeps
+// their order.
+func Parallel(n, concurrency int, fn func(i int) error) error {
+	if concurrency < 1 {
+		concurrency = DefaultConcurrency()
+	}
+	if concurrency > n {
+		concurrency = n
+	}
+	var (
+		lock   sync.Mutex
+		next   int
+		failed bool
+		errs   = make([]error, n)
+		wg     sync.WaitGroup
+	)
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				lock.Lock()
+				if failed || next >= n {
+					lock.Unlock()
+					return
+				}
+				i := next
+				next++
+				lock.Unlock()
+				if err := fn(i); err != nil {
+					lock.Lock()
+					errs[i] = err
+					failed = true
+					lock.Unlock()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
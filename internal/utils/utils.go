@@ -18,3 +18,20 @@ func FormatMessage(message string) string {
 	}
 	return msg[:max]
 }
+
+// HasPathPrefix checks if all files are placed under one of the prefixes.
+func HasPathPrefix(files, prefixes []string) bool {
+	for _, f := range files {
+		matched := false
+		for _, p := range prefixes {
+			if strings.HasPrefix(f, p) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,198 @@
+package verify
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/openshift/rebase/internal/git"
+)
+
+// verifyAPIDiff compares exported Go APIs of packages carried by openshift
+// between the previous and the verified rebase branch, reporting removed or
+// changed declarations, which break repositories depending on them. Added
+// declarations are compatible, except for interface methods, which are
+// covered by comparing interfaces as a whole.
+func (v *Verify) verifyAPIDiff(repository git.Git) ([]Finding, error) {
+	packages := v.options.APIPackages
+	if len(packages) == 0 {
+		var err error
+		if packages, err = v.carriedPackages(repository); err != nil {
+			return nil, err
+		}
+	}
+	var findings []Finding
+	for _, pkg := range packages {
+		old, err := exportedAPI(repository, v.options.PreviousBranch, pkg)
+		if err != nil {
+			return nil, fmt.Errorf("Error reading API of %s on %s: %w", pkg, v.options.PreviousBranch, err)
+		}
+		if len(old) == 0 {
+			continue
+		}
+		current, err := exportedAPI(repository, v.options.Branch, pkg)
+		if err != nil {
+			return nil, fmt.Errorf("Error reading API of %s on %s: %w", pkg, v.options.Branch, err)
+		}
+		names := make([]string, 0, len(old))
+		for name := range old {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			declaration, ok := current[name]
+			switch {
+			case !ok:
+				findings = append(findings, Finding{Check: "apidiff", Message: fmt.Sprintf("%s.%s was removed", pkg, name)})
+			case declaration != old[name]:
+				findings = append(findings, Finding{Check: "apidiff", Message: fmt.Sprintf("%s.%s changed from %q to %q", pkg, name, old[name], declaration)})
+			}
+		}
+	}
+	return findings, nil
+}
+
+// carriedPackages returns directories with go files changed by carries on
+// the previous rebase branch, except for vendor
+func (v *Verify) carriedPackages(repository git.Git) ([]string, error) {
+	commits, err := v.carriesOn(repository, v.options.PreviousBranch)
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool)
+	var packages []string
+	for _, c := range commits {
+		files, err := repository.ChangedFiles(c.Hash.String())
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range files {
+			dir := path.Dir(f)
+			if !isAPIFile(f) || strings.HasPrefix(f, "vendor/") || strings.Contains(f, "/vendor/") || seen[dir] {
+				continue
+			}
+			seen[dir] = true
+			packages = append(packages, dir)
+		}
+	}
+	sort.Strings(packages)
+	return packages, nil
+}
+
+func isAPIFile(name string) bool {
+	return strings.HasSuffix(name, ".go") && !strings.HasSuffix(name, "_test.go")
+}
+
+// exportedAPI returns exported declarations of a package at a revision, keyed
+// by their name, eg. Type.Method, with their printed types as values. Commands
+// and missing packages have no API.
+func exportedAPI(repository git.Git, rev, dir string) (map[string]string, error) {
+	names, err := repository.ListTree(rev, dir)
+	if err != nil {
+		// the package does not exist at the revision
+		return nil, nil
+	}
+	api := make(map[string]string)
+	fset := token.NewFileSet()
+	for _, name := range names {
+		if !isAPIFile(name) {
+			continue
+		}
+		content, err := repository.ShowFile(rev, path.Join(dir, name))
+		if err != nil {
+			// a directory named like a go file
+			continue
+		}
+		file, err := parser.ParseFile(fset, name, content, parser.SkipObjectResolution)
+		if err != nil {
+			return nil, err
+		}
+		if file.Name.Name == "main" {
+			return nil, nil
+		}
+		for _, decl := range file.Decls {
+			addDeclaration(api, fset, decl)
+		}
+	}
+	return api, nil
+}
+
+// addDeclaration adds exported parts of a declaration to the api
+func addDeclaration(api map[string]string, fset *token.FileSet, decl ast.Decl) {
+	switch d := decl.(type) {
+	case *ast.FuncDecl:
+		if !d.Name.IsExported() {
+			return
+		}
+		name := d.Name.Name
+		if d.Recv != nil && len(d.Recv.List) > 0 {
+			receiver := receiverName(d.Recv.List[0].Type)
+			if !ast.IsExported(receiver) {
+				return
+			}
+			name = receiver + "." + name
+		}
+		api[name] = "func" + strings.TrimPrefix(format(fset, d.Type), "func")
+	case *ast.GenDecl:
+		for _, spec := range d.Specs {
+			switch s := spec.(type) {
+			case *ast.TypeSpec:
+				if !s.Name.IsExported() {
+					continue
+				}
+				if structType, ok := s.Type.(*ast.StructType); ok {
+					api[s.Name.Name] = "struct"
+					for _, field := range structType.Fields.List {
+						for _, n := range field.Names {
+							if n.IsExported() {
+								api[s.Name.Name+"."+n.Name] = format(fset, field.Type)
+							}
+						}
+					}
+					continue
+				}
+				api[s.Name.Name] = format(fset, s.Type)
+			case *ast.ValueSpec:
+				for _, n := range s.Names {
+					if !n.IsExported() {
+						continue
+					}
+					api[n.Name] = d.Tok.String()
+					if s.Type != nil {
+						api[n.Name] += " " + format(fset, s.Type)
+					}
+				}
+			}
+		}
+	}
+}
+
+// receiverName returns the type name of a method receiver
+func receiverName(expr ast.Expr) string {
+	switch e := expr.(type) {
+	case *ast.StarExpr:
+		return receiverName(e.X)
+	case *ast.IndexExpr:
+		return receiverName(e.X)
+	case *ast.IndexListExpr:
+		return receiverName(e.X)
+	case *ast.Ident:
+		return e.Name
+	}
+	return ""
+}
+
+// format prints the node on a single line
+func format(fset *token.FileSet, node ast.Node) string {
+	var b bytes.Buffer
+	if err := printer.Fprint(&b, fset, node); err != nil {
+		return ""
+	}
+	return strings.Join(strings.Fields(b.String()), " ")
+}
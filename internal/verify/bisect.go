@@ -0,0 +1,102 @@
+package verify
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"k8s.io/klog/v2"
+
+	"github.com/openshift/rebase/internal/console"
+	"github.com/openshift/rebase/internal/git"
+	"github.com/openshift/rebase/internal/utils"
+)
+
+const (
+	defaultBuildCommand = "go build ./..."
+	// buildOutputLines limits the build output included in the finding
+	buildOutputLines = 20
+)
+
+// verifyBisectBuild builds the rebase branch and when the build fails, bisects
+// the commits on top of upstream to find the first one breaking the build.
+// The builds are run in a temporary worktree.
+func (v *Verify) verifyBisectBuild(repository git.Git) ([]Finding, error) {
+	shas, err := repository.RevList("--reverse", "--first-parent", v.options.Branch, "^"+v.options.Upstream)
+	if err != nil {
+		return nil, err
+	}
+	if len(shas) == 0 {
+		return nil, nil
+	}
+	dir, err := os.MkdirTemp("", "rebase-bisect-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	worktree := filepath.Join(dir, "worktree")
+	if err := repository.AddWorktree(worktree, shas[len(shas)-1]); err != nil {
+		return nil, fmt.Errorf("Error creating worktree: %w", err)
+	}
+	defer func() {
+		if err := repository.RemoveWorktree(worktree); err != nil {
+			klog.Errorf("Removing worktree %s failed: %v", worktree, err)
+		}
+	}()
+	outputs := make(map[string]string)
+	builds := func(sha string) (bool, error) {
+		console.VerbosefContext(v.ctx, "Building %s...", sha)
+		if output, err := utils.RunCommand(worktree, "git", "checkout", "--quiet", "--detach", sha); err != nil {
+			return false, fmt.Errorf("Error checking out %s: %w\n%s", sha, err, output)
+		}
+		output, err := utils.RunCommand(worktree, "sh", "-c", v.options.BuildCommand)
+		outputs[sha] = output
+		return err == nil, nil
+	}
+	ok, err := builds(shas[len(shas)-1])
+	if err != nil || ok {
+		return nil, err
+	}
+	// the tip is known to be broken, find the first broken commit assuming
+	// all commits after it are broken as well
+	first := 0
+	if ok, err := builds(shas[0]); err != nil {
+		return nil, err
+	} else if ok {
+		good, bad := 0, len(shas)-1
+		for bad-good > 1 {
+			mid := (good + bad) / 2
+			ok, err := builds(shas[mid])
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				good = mid
+			} else {
+				bad = mid
+			}
+		}
+		first = bad
+	}
+	sha := shas[first]
+	commit, err := repository.Commit(plumbing.NewHash(sha))
+	if err != nil {
+		return nil, fmt.Errorf("Error reading commit %s: %w", sha, err)
+	}
+	message := fmt.Sprintf("first commit breaking %q: %s", v.options.BuildCommand, utils.FormatMessage(commit.Message))
+	if output := lastLines(outputs[sha], buildOutputLines); len(output) > 0 {
+		message += "\n" + output
+	}
+	return []Finding{{Check: "bisect-build", Commit: sha, Message: message}}, nil
+}
+
+// lastLines returns at most n last lines of output
+func lastLines(output string, n int) string {
+	lines := strings.Split(strings.TrimRight(output, "\n"), "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return strings.Join(lines, "\n")
+}
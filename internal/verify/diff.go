@@ -0,0 +1,138 @@
+package verify
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/go-git/go-git/v5/plumbing"
+
+	"github.com/openshift/rebase/internal/console"
+	"github.com/openshift/rebase/internal/fork"
+	"github.com/openshift/rebase/internal/git"
+)
+
+// Diff compares two candidate rebase branches.
+type Diff struct {
+	fork          fork.Fork
+	repositoryDir string
+	branches      [2]string
+	options       Options
+}
+
+func NewDiff(repositoryDir, branch, other string, f fork.Fork, options Options) *Diff {
+	return &Diff{
+		fork:          f,
+		repositoryDir: repositoryDir,
+		branches:      [2]string{branch, other},
+		options:       options,
+	}
+}
+
+// diffCarry is a carry on one of the compared branches
+type diffCarry struct {
+	sha     string
+	patchID string
+}
+
+// Run prints carries picked on only one of the branches, or resolved
+// differently on each of them.
+func (d *Diff) Run() error {
+	repository, err := git.OpenGit(d.repositoryDir, d.fork)
+	if err != nil {
+		return err
+	}
+	if len(d.options.Upstream) == 0 {
+		d.options.Upstream = d.fork.UpstreamRef()
+	}
+	var carries [2]map[string][]diffCarry
+	var order []string
+	for i, branch := range d.branches {
+		console.Verbosef("Reading carries on %s...", branch)
+		if carries[i], order, err = d.branchCarries(repository, branch, order); err != nil {
+			return err
+		}
+	}
+	result := &Result{Branch: fmt.Sprintf("%s...%s", d.branches[0], d.branches[1]), Checks: []string{"diff"}}
+	for _, summary := range order {
+		// carries sharing a summary, eg. UPSTREAM: <drop>: ones, are paired
+		// by their changes first and the rest in order of the branches
+		as, bs := matchCarries(carries[0][summary], carries[1][summary])
+		for i := 0; i < len(as) || i < len(bs); i++ {
+			switch {
+			case i >= len(bs):
+				result.Findings = append(result.Findings, Finding{Check: "diff", Commit: as[i].sha,
+					Message: fmt.Sprintf("only on %s: %s", d.branches[0], summary)})
+			case i >= len(as):
+				result.Findings = append(result.Findings, Finding{Check: "diff", Commit: bs[i].sha,
+					Message: fmt.Sprintf("only on %s: %s", d.branches[1], summary)})
+			default:
+				result.Findings = append(result.Findings, Finding{Check: "diff", Commit: as[i].sha,
+					Message: fmt.Sprintf("resolved differently than %s on %s: %s", bs[i].sha, d.branches[1], summary)})
+			}
+		}
+	}
+	if err := result.Write(os.Stdout, d.options.Format); err != nil {
+		return err
+	}
+	if len(result.Findings) > 0 {
+		return fmt.Errorf("Found %d differences between %s and %s", len(result.Findings), d.branches[0], d.branches[1])
+	}
+	console.Infof("No differences between %s and %s", d.branches[0], d.branches[1])
+	return nil
+}
+
+// matchCarries drops carries with the same changes on both branches,
+// returning the remaining ones
+func matchCarries(as, bs []diffCarry) ([]diffCarry, []diffCarry) {
+	var onlyA []diffCarry
+	matched := make([]bool, len(bs))
+	for _, a := range as {
+		found := false
+		for j, b := range bs {
+			if !matched[j] && a.patchID == b.patchID {
+				matched[j], found = true, true
+				break
+			}
+		}
+		if !found {
+			onlyA = append(onlyA, a)
+		}
+	}
+	var onlyB []diffCarry
+	for j, b := range bs {
+		if !matched[j] {
+			onlyB = append(onlyB, b)
+		}
+	}
+	return onlyA, onlyB
+}
+
+// branchCarries returns carries on a branch by their summaries, extending
+// order with summaries not seen yet
+func (d *Diff) branchCarries(repository git.Git, branch string, order []string) (map[string][]diffCarry, []string, error) {
+	shas, err := repository.RevList("--reverse", "--no-merges", branch, "^"+d.options.Upstream, "^"+d.fork.OpenShiftRef())
+	if err != nil {
+		return nil, nil, err
+	}
+	seen := make(map[string]bool, len(order))
+	for _, summary := range order {
+		seen[summary] = true
+	}
+	carries := make(map[string][]diffCarry, len(shas))
+	for _, sha := range shas {
+		commit, err := repository.Commit(plumbing.NewHash(sha))
+		if err != nil {
+			return nil, nil, fmt.Errorf("Error reading commit %s: %w", sha, err)
+		}
+		keys, err := newCarryKeys(repository, commit)
+		if err != nil {
+			return nil, nil, err
+		}
+		carries[keys.summary] = append(carries[keys.summary], diffCarry{sha: sha, patchID: keys.patchID})
+		if !seen[keys.summary] {
+			seen[keys.summary] = true
+			order = append(order, keys.summary)
+		}
+	}
+	return carries, order, nil
+}
@@ -0,0 +1,41 @@
+package verify
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMatchCarries(t *testing.T) {
+	tests := []struct {
+		name         string
+		as, bs       []diffCarry
+		onlyA, onlyB []diffCarry
+	}{
+		{
+			name: "same drops",
+			as:   []diffCarry{{sha: "a1", patchID: "p1"}, {sha: "a2", patchID: "p2"}},
+			bs:   []diffCarry{{sha: "b1", patchID: "p2"}, {sha: "b2", patchID: "p1"}},
+		},
+		{
+			name:  "one drop resolved differently",
+			as:    []diffCarry{{sha: "a1", patchID: "p1"}, {sha: "a2", patchID: "p2"}},
+			bs:    []diffCarry{{sha: "b1", patchID: "p1"}, {sha: "b2", patchID: "p3"}},
+			onlyA: []diffCarry{{sha: "a2", patchID: "p2"}},
+			onlyB: []diffCarry{{sha: "b2", patchID: "p3"}},
+		},
+		{
+			name:  "drop only on one branch",
+			as:    []diffCarry{{sha: "a1", patchID: "p1"}, {sha: "a2", patchID: "p1"}},
+			bs:    []diffCarry{{sha: "b1", patchID: "p1"}},
+			onlyA: []diffCarry{{sha: "a2", patchID: "p1"}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			onlyA, onlyB := matchCarries(test.as, test.bs)
+			if !reflect.DeepEqual(onlyA, test.onlyA) || !reflect.DeepEqual(onlyB, test.onlyB) {
+				t.Errorf("expected %v and %v, got %v and %v", test.onlyA, test.onlyB, onlyA, onlyB)
+			}
+		})
+	}
+}
@@ -0,0 +1,47 @@
+package verify
+
+import (
+	"fmt"
+
+	"github.com/go-git/go-git/v5/plumbing"
+
+	"github.com/openshift/rebase/internal/git"
+)
+
+// verifyDuplicates reports commits on the rebase branch with the same patch-id,
+// or the same cherry picked from trailer, as an earlier one, which happens
+// when a carry is picked again while resuming manual work.
+func (v *Verify) verifyDuplicates(repository git.Git) ([]Finding, error) {
+	shas, err := repository.RevList("--reverse", "--no-merges", v.options.Branch, "^"+v.options.Upstream, "^"+v.openshiftBranch())
+	if err != nil {
+		return nil, err
+	}
+	patchIDs := make(map[string]string)
+	origins := make(map[string]string)
+	var findings []Finding
+	for _, sha := range shas {
+		commit, err := repository.Commit(plumbing.NewHash(sha))
+		if err != nil {
+			return nil, fmt.Errorf("Error reading commit %s: %w", sha, err)
+		}
+		keys, err := newCarryKeys(repository, commit)
+		if err != nil {
+			return nil, err
+		}
+		if first, ok := patchIDs[keys.patchID]; ok {
+			findings = append(findings, Finding{Check: "duplicates", Commit: sha,
+				Message: fmt.Sprintf("same patch as %s: %s", first, keys.summary)})
+		} else if first, ok := origins[keys.origin]; ok {
+			findings = append(findings, Finding{Check: "duplicates", Commit: sha,
+				Message: fmt.Sprintf("cherry picked from %s, same as %s: %s", keys.origin, first, keys.summary)})
+		}
+		// empty commits have no patch-id and are not duplicates of each other
+		if _, ok := patchIDs[keys.patchID]; !ok && len(keys.patchID) > 0 {
+			patchIDs[keys.patchID] = sha
+		}
+		if _, ok := origins[keys.origin]; !ok && len(keys.origin) > 0 {
+			origins[keys.origin] = sha
+		}
+	}
+	return findings, nil
+}
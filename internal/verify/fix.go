@@ -0,0 +1,151 @@
+package verify
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+
+	"github.com/openshift/rebase/internal/console"
+	"github.com/openshift/rebase/internal/git"
+	"github.com/openshift/rebase/internal/utils"
+)
+
+// fix rewrites commit messages on the rebase branch, which can be fixed
+// mechanically, using an interactive rebase starting at the first fixed commit.
+func (v *Verify) fix(repository git.Git) error {
+	if _, err := repository.RevParse("refs/heads/" + v.options.Branch); err != nil {
+		return fmt.Errorf("fixing requires a branch, %s is not one", v.options.Branch)
+	}
+	overrides := Overrides{}
+	if len(v.options.OverridesFile) > 0 {
+		var err error
+		if overrides, err = LoadOverrides(v.options.OverridesFile); err != nil {
+			return err
+		}
+	}
+	originals, err := v.carriesOn(repository, v.openshiftBranch())
+	if err != nil {
+		return err
+	}
+	bySummary := make(map[string]*object.Commit, len(originals))
+	byPatchID := make(map[string]*object.Commit, len(originals))
+	for _, o := range originals {
+		keys, err := newCarryKeys(repository, o)
+		if err != nil {
+			return err
+		}
+		bySummary[keys.summary] = o
+		if len(keys.patchID) > 0 {
+			byPatchID[keys.patchID] = o
+		}
+	}
+	shas, err := repository.RevList("--reverse", "--first-parent", v.options.Branch, "^"+v.options.Upstream)
+	if err != nil {
+		return err
+	}
+	fixed := make(map[string]string)
+	first := -1
+	for i, sha := range shas {
+		commit, err := repository.Commit(plumbing.NewHash(sha))
+		if err != nil {
+			return fmt.Errorf("Error reading commit %s: %w", sha, err)
+		}
+		if len(commit.ParentHashes) > 1 {
+			if first >= 0 {
+				return fmt.Errorf("merge commit %s found among commits to fix", sha)
+			}
+			continue
+		}
+		keys, err := newCarryKeys(repository, commit)
+		if err != nil {
+			return err
+		}
+		original := bySummary[keys.summary]
+		if original == nil {
+			original = byPatchID[keys.patchID]
+		}
+		message := fixMessage(commit.Message, original, overrides)
+		if message == commit.Message {
+			continue
+		}
+		console.VerbosefContext(v.ctx, "Fixing message of %s: %s", sha, utils.FormatMessage(message))
+		fixed[sha] = message
+		if first < 0 {
+			first = i
+		}
+	}
+	if first < 0 {
+		console.InfofContext(v.ctx, "No commit messages to fix on %s", v.options.Branch)
+		return nil
+	}
+	for _, sha := range shas[first:] {
+		commit, err := repository.Commit(plumbing.NewHash(sha))
+		if err != nil {
+			return fmt.Errorf("Error reading commit %s: %w", sha, err)
+		}
+		if len(commit.ParentHashes) > 1 {
+			return fmt.Errorf("merge commit %s found among commits to fix", sha)
+		}
+	}
+	dir, err := os.MkdirTemp("", "rebase-fix-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	var todo strings.Builder
+	for _, sha := range shas[first:] {
+		fmt.Fprintf(&todo, "pick %s\n", sha)
+		message, ok := fixed[sha]
+		if !ok {
+			continue
+		}
+		messageFile := filepath.Join(dir, sha)
+		if err := os.WriteFile(messageFile, []byte(message), 0644); err != nil {
+			return err
+		}
+		fmt.Fprintf(&todo, "exec git commit --quiet --amend --allow-empty --cleanup=verbatim --file '%s'\n", messageFile)
+	}
+	todoFile := filepath.Join(dir, "todo")
+	if err := os.WriteFile(todoFile, []byte(todo.String()), 0644); err != nil {
+		return err
+	}
+	if err := repository.RebaseWithTodo(shas[first]+"^", v.options.Branch, todoFile); err != nil {
+		return err
+	}
+	console.InfofContext(v.ctx, "Fixed messages of %d commits on %s", len(fixed), v.options.Branch)
+	return nil
+}
+
+// fixMessage returns the commit message with the summary taken from the override
+// or with fixed UPSTREAM prefix, cherry picked from trailer of the original carry
+// and without trailing whitespace
+func fixMessage(message string, original *object.Commit, overrides Overrides) string {
+	lines := strings.Split(message, "\n")
+	for i := range lines {
+		lines[i] = strings.TrimRight(lines[i], " \t\r")
+	}
+	for len(lines) > 1 && len(lines[len(lines)-1]) == 0 {
+		lines = lines[:len(lines)-1]
+	}
+	if override, ok := overrides[originalSHA(original)]; ok && len(override.Message) > 0 {
+		lines[0] = utils.FormatMessage(override.Message)
+	} else if !wellFormedRE.MatchString(lines[0]) {
+		lines[0] = suggestSummary(lines[0])
+	}
+	fixed := strings.Join(lines, "\n")
+	if original != nil && !cherryPickedRE.MatchString(fixed) {
+		fixed += fmt.Sprintf("\n\n(cherry picked from commit %s)", original.Hash.String())
+	}
+	return fixed + "\n"
+}
+
+func originalSHA(original *object.Commit) string {
+	if original == nil {
+		return ""
+	}
+	return original.Hash.String()
+}
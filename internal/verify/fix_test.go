@@ -0,0 +1,64 @@
+package verify
+
+import (
+	"testing"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+func TestFixMessage(t *testing.T) {
+	original := &object.Commit{Hash: plumbing.NewHash("0123456789abcdef0123456789abcdef01234567")}
+	trailer := "(cherry picked from commit 0123456789abcdef0123456789abcdef01234567)"
+	tests := []struct {
+		name      string
+		message   string
+		original  *object.Commit
+		overrides Overrides
+		expected  string
+	}{
+		{
+			name:     "well-formed message",
+			message:  "UPSTREAM: <carry>: add a carry\n\nBody\n",
+			expected: "UPSTREAM: <carry>: add a carry\n\nBody\n",
+		},
+		{
+			name:     "malformed summary and trailing whitespace",
+			message:  "upstream <carry> add a carry  \n\nBody\t\n\n\n",
+			expected: "UPSTREAM: <carry>: add a carry\n\nBody\n",
+		},
+		{
+			name:     "trailer of the original carry",
+			message:  "UPSTREAM: <carry>: add a carry\n",
+			original: original,
+			expected: "UPSTREAM: <carry>: add a carry\n\n" + trailer + "\n",
+		},
+		{
+			name:     "trailer kept once",
+			message:  "UPSTREAM: <carry>: add a carry\n\n" + trailer + "\n",
+			original: original,
+			expected: "UPSTREAM: <carry>: add a carry\n\n" + trailer + "\n",
+		},
+		{
+			name:      "summary of the override",
+			message:   "carry\n\nBody\n",
+			original:  original,
+			overrides: Overrides{original.Hash.String(): {Message: "UPSTREAM: <carry>: overridden\n\nignored body"}},
+			expected:  "UPSTREAM: <carry>: overridden\n\nBody\n\n" + trailer + "\n",
+		},
+		{
+			name:      "override without a message",
+			message:   "carry\n",
+			original:  original,
+			overrides: Overrides{original.Hash.String(): {Reason: "kept"}},
+			expected:  "UPSTREAM: <carry>: carry\n\n" + trailer + "\n",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if message := fixMessage(test.message, test.original, test.overrides); message != test.expected {
+				t.Errorf("expected %q, got %q", test.expected, message)
+			}
+		})
+	}
+}
@@ -0,0 +1,190 @@
+package verify
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+
+	"k8s.io/klog/v2"
+
+	"github.com/openshift/rebase/internal/console"
+	"github.com/openshift/rebase/internal/fork"
+	"github.com/openshift/rebase/internal/git"
+	"github.com/openshift/rebase/internal/report"
+)
+
+var shaRE = regexp.MustCompile(`^[0-9a-f]{7,40}$`)
+
+// csvColumns maps the names of spreadsheet columns, lowercased, to the
+// override fields they hold
+var csvColumns = map[string]string{
+	"sha":         "sha",
+	"commit":      "sha",
+	"carry":       "sha",
+	"disposition": "disposition",
+	"action":      "disposition",
+	"status":      "disposition",
+	"decision":    "disposition",
+	"message":     "message",
+	"summary":     "message",
+	"title":       "message",
+	"notes":       "reason",
+	"note":        "reason",
+	"reason":      "reason",
+	"comment":     "reason",
+	"comments":    "reason",
+}
+
+// csvDispositions maps the wording used in spreadsheets to dispositions
+var csvDispositions = map[string]report.Disposition{
+	"carry":    report.Picked,
+	"carried":  report.Picked,
+	"pick":     report.Picked,
+	"picked":   report.Picked,
+	"keep":     report.Picked,
+	"drop":     report.Dropped,
+	"dropped":  report.Dropped,
+	"remove":   report.Dropped,
+	"removed":  report.Dropped,
+	"skip":     report.Skipped,
+	"skipped":  report.Skipped,
+	"merged":   report.Merged,
+	"upstream": report.Merged,
+	"manual":   report.Manual,
+	"fixed":    report.Fixed,
+}
+
+// Import converts rows of a rebase tracking spreadsheet, exported as CSV,
+// into overrides, merging them with an existing overrides file.
+type Import struct {
+	fork          fork.Fork
+	repositoryDir string
+	csvFile       string
+	overridesFile string
+}
+
+func NewImport(repositoryDir, csvFile, overridesFile string, f fork.Fork) *Import {
+	return &Import{
+		fork:          f,
+		repositoryDir: repositoryDir,
+		csvFile:       csvFile,
+		overridesFile: overridesFile,
+	}
+}
+
+// Run reads the spreadsheet and writes the overrides file, overrides already
+// in the file take precedence over the imported ones.
+func (i *Import) Run() error {
+	repository, err := git.OpenGit(i.repositoryDir, i.fork)
+	if err != nil {
+		return err
+	}
+	overrides, err := LoadOverrides(i.overridesFile)
+	if errors.Is(err, os.ErrNotExist) {
+		overrides = Overrides{}
+	} else if err != nil {
+		return err
+	}
+	f, err := os.Open(i.csvFile)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	imported, err := readCSVOverrides(repository, f)
+	if err != nil {
+		return fmt.Errorf("Error reading %s: %w", i.csvFile, err)
+	}
+	added := 0
+	for sha, o := range imported {
+		if _, ok := overrides[sha]; ok {
+			klog.Warningf("Keeping existing override of %s", sha)
+			continue
+		}
+		overrides[sha] = o
+		added++
+	}
+	if err := overrides.Save(i.overridesFile); err != nil {
+		return err
+	}
+	console.Infof("Imported %d overrides from %s into %s", added, i.csvFile, i.overridesFile)
+	return nil
+}
+
+// readCSVOverrides reads overrides from CSV with a header row naming the
+// columns, short SHAs are expanded using the repository
+func readCSVOverrides(repository git.Git, in io.Reader) (Overrides, error) {
+	reader := csv.NewReader(in)
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("Error reading the header: %w", err)
+	}
+	columns := make(map[string]int)
+	for index, name := range header {
+		if field, ok := csvColumns[strings.ToLower(strings.TrimSpace(name))]; ok {
+			if _, seen := columns[field]; !seen {
+				columns[field] = index
+			}
+		}
+	}
+	if _, ok := columns["sha"]; !ok {
+		return nil, fmt.Errorf("no sha or commit column in the header %q", strings.Join(header, ","))
+	}
+	value := func(record []string, field string) string {
+		index, ok := columns[field]
+		if !ok || index >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[index])
+	}
+	overrides := Overrides{}
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		sha := strings.ToLower(value(record, "sha"))
+		if !shaRE.MatchString(sha) {
+			klog.Warningf("Skipping line %d, %q is not a commit SHA", line, sha)
+			continue
+		}
+		if len(sha) < 40 {
+			full, err := repository.RevParse(sha + "^{commit}")
+			if err != nil {
+				klog.Warningf("Skipping line %d, %s not found in the repository", line, sha)
+				continue
+			}
+			sha = full
+		}
+		o := Override{Message: value(record, "message"), Reason: value(record, "reason")}
+		if disposition := value(record, "disposition"); len(disposition) > 0 {
+			var ok bool
+			if o.Disposition, ok = csvDispositions[strings.ToLower(disposition)]; !ok {
+				klog.Warningf("Unknown disposition %q on line %d, recording it in the reason", disposition, line)
+				o.Reason = strings.TrimSpace(fmt.Sprintf("%s (%s)", o.Reason, disposition))
+			}
+		}
+		if o == (Override{}) {
+			continue
+		}
+		overrides[sha] = o
+	}
+	return overrides, nil
+}
+
+// Save writes the overrides into a JSON file.
+func (o Overrides) Save(path string) error {
+	data, err := json.MarshalIndent(o, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
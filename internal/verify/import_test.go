@@ -0,0 +1,133 @@
+package verify
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/openshift/rebase/internal/git/gittest"
+	"github.com/openshift/rebase/internal/report"
+)
+
+func TestReadCSVOverrides(t *testing.T) {
+	repository := gittest.New(t.TempDir())
+	sha := repository.Commits("main", "UPSTREAM: <carry>: openshift: add a carry")[0]
+	tests := []struct {
+		name     string
+		csv      string
+		expected Overrides
+		wantErr  bool
+	}{
+		{
+			name: "full SHAs",
+			csv: "SHA,Disposition,Message,Notes\n" +
+				"0123456789abcdef0123456789abcdef01234567,Drop,,fixed upstream\n" +
+				"89abcdef0123456789abcdef0123456789abcdef,carry,UPSTREAM: <carry>: new summary,\n",
+			expected: Overrides{
+				"0123456789abcdef0123456789abcdef01234567": {Disposition: report.Dropped, Reason: "fixed upstream"},
+				"89abcdef0123456789abcdef0123456789abcdef": {Disposition: report.Picked, Message: "UPSTREAM: <carry>: new summary"},
+			},
+		},
+		{
+			name:     "short SHA expanded by the repository",
+			csv:      "Commit,Status\n" + sha[:8] + ",merged\n",
+			expected: Overrides{sha: {Disposition: report.Merged}},
+		},
+		{
+			name:     "first of the columns with the same meaning and extra columns",
+			csv:      "Owner, Carry ,Action,Decision\nteam,0123456789ABCDEF0123456789ABCDEF01234567,skip,drop\n",
+			expected: Overrides{"0123456789abcdef0123456789abcdef01234567": {Disposition: report.Skipped}},
+		},
+		{
+			name:     "unknown disposition kept in the reason",
+			csv:      "sha,disposition,reason\n0123456789abcdef0123456789abcdef01234567,ask the team,unclear\n",
+			expected: Overrides{"0123456789abcdef0123456789abcdef01234567": {Reason: "unclear (ask the team)"}},
+		},
+		{
+			name: "skipped rows",
+			csv: "sha,disposition\n" +
+				"not a sha,drop\n" +
+				"deadbeef,drop\n" +
+				"0123456789abcdef0123456789abcdef01234567\n",
+			expected: Overrides{},
+		},
+		{
+			name:    "no SHA column",
+			csv:     "message,disposition\nUPSTREAM: <carry>: a carry,drop\n",
+			wantErr: true,
+		},
+		{
+			name:    "empty",
+			wantErr: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			overrides, err := readCSVOverrides(repository, strings.NewReader(test.csv))
+			if (err != nil) != test.wantErr {
+				t.Fatalf("readCSVOverrides() error = %v, wantErr %v", err, test.wantErr)
+			}
+			if !test.wantErr && !reflect.DeepEqual(overrides, test.expected) {
+				t.Errorf("expected %v, got %v", test.expected, overrides)
+			}
+		})
+	}
+}
+
+func TestImportedOverridesVerify(t *testing.T) {
+	const (
+		fixed   = "0123456789abcdef0123456789abcdef01234567"
+		manual  = "89abcdef0123456789abcdef0123456789abcdef"
+		dropped = "fedcba9876543210fedcba9876543210fedcba98"
+	)
+	csv := "sha,disposition,notes\n" +
+		fixed + ",fixed,fixed carry patch\n" +
+		manual + ",manual,resolved by hand\n" +
+		dropped + ",drop,fixed upstream\n"
+	planned := []Descriptor{
+		{Original: fixed, Summary: "UPSTREAM: <carry>: fixed", Disposition: report.Picked},
+		{Original: manual, Summary: "UPSTREAM: <carry>: manual", Disposition: report.Picked},
+		{Original: dropped, Summary: "UPSTREAM: <carry>: dropped", Disposition: report.Picked},
+	}
+	tests := []struct {
+		name     string
+		actual   []Descriptor
+		expected []Finding
+	}{
+		{
+			name:   "branch matching the spreadsheet",
+			actual: []Descriptor{{Original: "n1", Summary: "UPSTREAM: <carry>: fixed"}, {Original: "n2", Summary: "UPSTREAM: <carry>: manual"}},
+		},
+		{
+			name:   "lost manual carry and a dropped one present",
+			actual: []Descriptor{{Original: "n1", Summary: "UPSTREAM: <carry>: fixed"}, {Original: "n3", Summary: "UPSTREAM: <carry>: dropped"}},
+			expected: []Finding{
+				{Check: "overrides", Commit: manual, Message: "planned to be picked, but missing: UPSTREAM: <carry>: manual"},
+				{Check: "overrides", Commit: dropped, Message: "planned as dropped, but present as n3: UPSTREAM: <carry>: dropped"},
+			},
+		},
+	}
+	repository := gittest.New(t.TempDir())
+	imported, err := readCSVOverrides(repository, strings.NewReader(csv))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the overrides are read by verify from the file written by import
+	path := filepath.Join(t.TempDir(), "overrides.json")
+	if err := imported.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	overrides, err := LoadOverrides(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			findings := compareDescriptors(overrides.Transform(planned), test.actual)
+			if !reflect.DeepEqual(findings, test.expected) {
+				t.Errorf("expected %v, got %v", test.expected, findings)
+			}
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package verify
+
+import (
+	"encoding/json"
+	"os"
+
+	"k8s.io/klog/v2"
+
+	"github.com/openshift/rebase/internal/cache"
+	"github.com/openshift/rebase/internal/console"
+	"github.com/openshift/rebase/internal/git"
+)
+
+const (
+	// checksCache is the kind of cached findings of whole checks
+	checksCache = "verified-checks"
+	// commitsCache is the kind of cached findings of single commits
+	commitsCache = "verified-commits"
+)
+
+// checkKey returns the key of the findings of a check, built from the SHAs of
+// all refs and the options the check depends on, the second return value is
+// false when a ref can not be resolved and the findings must not be cached
+func (v *Verify) checkKey(repository git.Git, name string) (string, bool) {
+	options := v.options
+	// these do not affect the findings
+	options.Format, options.Checks, options.Fix = "", nil, false
+	data, err := json.Marshal(options)
+	if err != nil {
+		return "", false
+	}
+	parts := []string{name, v.from, v.fork.Name, v.fork.Marker, string(data)}
+	refs := []string{v.options.Branch, v.options.Upstream, v.openshiftBranch()}
+	if len(v.options.PreviousBranch) > 0 {
+		refs = append(refs, v.options.PreviousBranch)
+	}
+	for _, ref := range refs {
+		sha, err := repository.RevParse(ref)
+		if err != nil {
+			return "", false
+		}
+		parts = append(parts, sha)
+	}
+	if len(v.options.OverridesFile) > 0 {
+		overrides, err := os.ReadFile(v.options.OverridesFile)
+		if err != nil {
+			return "", false
+		}
+		parts = append(parts, string(overrides))
+	}
+	return cache.Key(parts...), true
+}
+
+// runCheck runs the check, unless it already ran on the same commits, in which
+// case its previous findings are returned
+func (v *Verify) runCheck(repository git.Git, c check) ([]Finding, error) {
+	var key string
+	ok := v.cache != nil && !c.uncached
+	if ok {
+		key, ok = v.checkKey(repository, c.name)
+	}
+	if ok {
+		var findings []Finding
+		if v.cache.Get(checksCache, key, &findings) {
+			console.InfofContext(v.ctx, "Skipping %s check, %s was already verified at the same commits", c.name, v.options.Branch)
+			return findings, nil
+		}
+	}
+	console.InfofContext(v.ctx, "Running %s check of %s...", c.name, v.options.Branch)
+	findings, err := c.run(repository)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		v.cache.Put(checksCache, key, findings)
+	}
+	return findings, nil
+}
+
+// commitFindings returns the findings of a check on a single commit, commits
+// already verified at the same SHA are not examined again, so that repeated
+// runs only examine new or amended commits
+func (v *Verify) commitFindings(name, sha string, run func() ([]Finding, error)) ([]Finding, error) {
+	key := cache.Key(name, sha, v.fork.Name, v.fork.Marker)
+	var findings []Finding
+	if v.cache != nil && v.cache.Get(commitsCache, key, &findings) {
+		klog.V(4).Infof("Skipping %s check of %s, already verified", name, sha)
+		return findings, nil
+	}
+	findings, err := run()
+	if err != nil {
+		return nil, err
+	}
+	if v.cache != nil {
+		v.cache.Put(commitsCache, key, findings)
+	}
+	return findings, nil
+}
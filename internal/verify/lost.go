@@ -0,0 +1,98 @@
+package verify
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+
+	"github.com/openshift/rebase/internal/apply"
+	"github.com/openshift/rebase/internal/git"
+	"github.com/openshift/rebase/internal/utils"
+)
+
+var cherryPickedRE = regexp.MustCompile(`\(cherry picked from commit ([0-9a-f]{40})\)`)
+
+// carryKeys identifies a carry independently of its SHA
+type carryKeys struct {
+	patchID string
+	origin  string
+	summary string
+}
+
+func newCarryKeys(repository git.Git, commit *object.Commit) (carryKeys, error) {
+	patchID, err := repository.PatchID(commit.Hash.String())
+	if err != nil {
+		return carryKeys{}, fmt.Errorf("Error computing patch-id of %s: %w", commit.Hash.String(), err)
+	}
+	keys := carryKeys{patchID: patchID, summary: utils.FormatMessage(commit.Message)}
+	if matches := cherryPickedRE.FindStringSubmatch(commit.Message); matches != nil {
+		keys.origin = matches[1]
+	}
+	return keys, nil
+}
+
+// verifyLostCarries reports carries from the previous rebase branch, which are
+// missing on the verified branch without an explicit drop record, ie. drop
+// action or an override.
+func (v *Verify) verifyLostCarries(repository git.Git) ([]Finding, error) {
+	overrides := Overrides{}
+	if len(v.options.OverridesFile) > 0 {
+		var err error
+		if overrides, err = LoadOverrides(v.options.OverridesFile); err != nil {
+			return nil, err
+		}
+	}
+	previous, err := v.carriesOn(repository, v.options.PreviousBranch)
+	if err != nil {
+		return nil, err
+	}
+	shas, err := repository.RevList("--no-merges", v.options.Branch, "^"+v.options.Upstream, "^"+v.openshiftBranch(), "^"+v.options.PreviousBranch)
+	if err != nil {
+		return nil, err
+	}
+	patchIDs := make(map[string]bool)
+	origins := make(map[string]bool)
+	summaries := make(map[string]bool)
+	for _, sha := range shas {
+		commit, err := repository.Commit(plumbing.NewHash(sha))
+		if err != nil {
+			return nil, fmt.Errorf("Error reading commit %s: %w", sha, err)
+		}
+		keys, err := newCarryKeys(repository, commit)
+		if err != nil {
+			return nil, err
+		}
+		if len(keys.patchID) > 0 {
+			patchIDs[keys.patchID] = true
+		}
+		if len(keys.origin) > 0 {
+			origins[keys.origin] = true
+		}
+		summaries[keys.summary] = true
+	}
+	var findings []Finding
+	for _, commit := range previous {
+		disposition, err := apply.PlannedDisposition(repository, v.fork, commit, v.options.Mainline)
+		if err != nil {
+			return nil, err
+		}
+		if override, ok := overrides[commit.Hash.String()]; ok && len(override.Disposition) > 0 {
+			disposition = override.Disposition
+		}
+		if !disposition.Lands() {
+			continue
+		}
+		keys, err := newCarryKeys(repository, commit)
+		if err != nil {
+			return nil, err
+		}
+		if patchIDs[keys.patchID] || origins[commit.Hash.String()] || summaries[keys.summary] {
+			continue
+		}
+		findings = append(findings, Finding{Check: "lost-carries", Commit: commit.Hash.String(),
+			Message: fmt.Sprintf("carried on %s, but missing without a drop record: %s", v.options.PreviousBranch, keys.summary)})
+	}
+	return findings, nil
+}
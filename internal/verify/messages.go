@@ -0,0 +1,82 @@
+package verify
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing"
+
+	"github.com/openshift/rebase/internal/git"
+	"github.com/openshift/rebase/internal/utils"
+)
+
+var (
+	// wellFormedRE matches summaries with a valid UPSTREAM prefix
+	wellFormedRE = regexp.MustCompile(`^UPSTREAM: (<carry>|<drop>|[0-9]+): \S`)
+	// looseRE matches summaries with a malformed UPSTREAM prefix, capturing the action
+	// and the rest of the summary
+	looseRE = regexp.MustCompile(`(?i)^\s*upstream\s*:?\s*<?\s*#?([a-z0-9]+)\s*>?\s*:?\s*(.*)$`)
+	// upstreamRE matches the UPSTREAM word at the start of a summary
+	upstreamRE = regexp.MustCompile(`(?i)^\s*upstream\s*:?\s*`)
+	numberRE   = regexp.MustCompile(`^[0-9]+$`)
+)
+
+// verifyMessages checks that every commit on the rebase branch has a well-formed
+// UPSTREAM prefix, except for the merge of the openshift branch.
+func (v *Verify) verifyMessages(repository git.Git) ([]Finding, error) {
+	shas, err := repository.RevList("--reverse", v.options.Branch, "^"+v.options.Upstream, "^"+v.openshiftBranch())
+	if err != nil {
+		return nil, err
+	}
+	var findings []Finding
+	for _, sha := range shas {
+		sha := sha
+		commitFindings, err := v.commitFindings("messages", sha, func() ([]Finding, error) {
+			return v.verifyMessage(repository, sha)
+		})
+		if err != nil {
+			return nil, err
+		}
+		findings = append(findings, commitFindings...)
+	}
+	return findings, nil
+}
+
+// verifyMessage checks the message of a single commit
+func (v *Verify) verifyMessage(repository git.Git, sha string) ([]Finding, error) {
+	commit, err := repository.Commit(plumbing.NewHash(sha))
+	if err != nil {
+		return nil, fmt.Errorf("Error reading commit %s: %w", sha, err)
+	}
+	summary := utils.FormatMessage(commit.Message)
+	if len(commit.ParentHashes) > 1 {
+		if !v.fork.IsMarker(commit.Message) {
+			return []Finding{{Check: "messages", Commit: sha,
+				Message: fmt.Sprintf("unexpected merge commit %q, only the merge of the openshift branch is allowed", summary)}}, nil
+		}
+		return nil, nil
+	}
+	if wellFormedRE.MatchString(summary) {
+		return nil, nil
+	}
+	return []Finding{{Check: "messages", Commit: sha,
+		Message: fmt.Sprintf("malformed summary %q, suggested: %q", summary, suggestSummary(summary))}}, nil
+}
+
+// suggestSummary proposes a well-formed summary for a malformed one
+func suggestSummary(summary string) string {
+	matches := looseRE.FindStringSubmatch(summary)
+	if matches == nil {
+		return "UPSTREAM: <carry>: " + strings.TrimSpace(summary)
+	}
+	action, rest := strings.ToLower(matches[1]), strings.TrimSpace(matches[2])
+	switch {
+	case action == "carry" || action == "drop":
+		return fmt.Sprintf("UPSTREAM: <%s>: %s", action, rest)
+	case numberRE.MatchString(action):
+		return fmt.Sprintf("UPSTREAM: %s: %s", action, rest)
+	}
+	// the word following UPSTREAM is not an action, but a part of the summary
+	return "UPSTREAM: <carry>: " + upstreamRE.ReplaceAllString(summary, "")
+}
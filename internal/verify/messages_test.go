@@ -0,0 +1,51 @@
+package verify
+
+import (
+	"testing"
+)
+
+func TestSuggestSummary(t *testing.T) {
+	tests := []struct {
+		name     string
+		summary  string
+		expected string
+	}{
+		{
+			name:     "lowercase carry",
+			summary:  "upstream: <CARRY>: openshift: add a carry",
+			expected: "UPSTREAM: <carry>: openshift: add a carry",
+		},
+		{
+			name:     "missing colons and brackets",
+			summary:  "UPSTREAM drop regenerate files",
+			expected: "UPSTREAM: <drop>: regenerate files",
+		},
+		{
+			name:     "pull request with a hash",
+			summary:  "UPSTREAM: #12345: fix a bug",
+			expected: "UPSTREAM: 12345: fix a bug",
+		},
+		{
+			name:     "pull request in brackets",
+			summary:  "UPSTREAM: <12345>:fix a bug",
+			expected: "UPSTREAM: 12345: fix a bug",
+		},
+		{
+			name:     "no action",
+			summary:  "UPSTREAM: fix the kubelet",
+			expected: "UPSTREAM: <carry>: fix the kubelet",
+		},
+		{
+			name:     "no prefix",
+			summary:  "  openshift: add a carry",
+			expected: "UPSTREAM: <carry>: openshift: add a carry",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if summary := suggestSummary(test.summary); summary != test.expected {
+				t.Errorf("expected %q, got %q", test.expected, summary)
+			}
+		})
+	}
+}
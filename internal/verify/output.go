@@ -0,0 +1,129 @@
+package verify
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/openshift/rebase/internal/output"
+	"github.com/openshift/rebase/internal/report"
+	"github.com/openshift/rebase/internal/table"
+)
+
+const (
+	FormatTable    = "table"
+	FormatJSON     = output.JSON
+	FormatYAML     = output.YAML
+	FormatMarkdown = "markdown"
+	FormatJUnit    = "junit"
+)
+
+// Result holds the findings of all checks run against the rebase branch.
+type Result struct {
+	// Branch is the verified rebase branch
+	Branch string `json:"branch"`
+	// Checks are the names of the checks which were run
+	Checks []string `json:"checks"`
+	// Findings are the problems found by the checks
+	Findings []Finding `json:"findings"`
+}
+
+// Write writes the result to out in a given format.
+func (r *Result) Write(out io.Writer, format string) error {
+	if r.Findings == nil {
+		r.Findings = []Finding{}
+	}
+	switch format {
+	case FormatJSON, FormatYAML:
+		return output.Write(out, format, r)
+	case FormatMarkdown:
+		return r.writeMarkdown(out)
+	case FormatJUnit:
+		return r.writeJUnit(out)
+	case FormatTable, "":
+		return r.writeTable(out)
+	}
+	return fmt.Errorf("unknown output format %q", format)
+}
+
+type junitTestSuite struct {
+	XMLName  xml.Name        `xml:"testsuite"`
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// writeJUnit writes the result as a JUnit XML test suite, with a test case
+// for every finding, and a passing test case for every check without findings
+func (r *Result) writeJUnit(out io.Writer) error {
+	suite := junitTestSuite{Name: "verify " + r.Branch}
+	for _, c := range r.Checks {
+		failed := false
+		for _, f := range r.Findings {
+			if f.Check != c {
+				continue
+			}
+			failed = true
+			name := c
+			if len(f.Commit) > 0 {
+				name += " " + f.Commit
+			}
+			summary, _, _ := strings.Cut(f.Message, "\n")
+			suite.Cases = append(suite.Cases, junitTestCase{Name: name, ClassName: c,
+				Failure: &junitFailure{Message: summary, Text: f.Message}})
+		}
+		if !failed {
+			suite.Cases = append(suite.Cases, junitTestCase{Name: c, ClassName: c})
+		}
+	}
+	suite.Tests = len(suite.Cases)
+	suite.Failures = len(r.Findings)
+	if _, err := io.WriteString(out, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(out)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(suite); err != nil {
+		return err
+	}
+	_, err := io.WriteString(out, "\n")
+	return err
+}
+
+func (r *Result) writeMarkdown(out io.Writer) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "## Verification of `%s`\n\n", r.Branch)
+	fmt.Fprintf(&b, "Checks: %s\n\n", strings.Join(r.Checks, ", "))
+	if len(r.Findings) == 0 {
+		fmt.Fprintf(&b, "No problems found.\n")
+	} else {
+		fmt.Fprintf(&b, "| Check | Commit | Problem |\n")
+		fmt.Fprintf(&b, "|-------|--------|---------|\n")
+		for _, f := range r.Findings {
+			fmt.Fprintf(&b, "| %s | %s | %s |\n", f.Check, f.Commit, report.EscapeMarkdown(f.Message))
+		}
+	}
+	_, err := io.WriteString(out, b.String())
+	return err
+}
+
+func (r *Result) writeTable(out io.Writer) error {
+	t := table.New(out, "CHECK", "COMMIT", "PROBLEM")
+	for _, f := range r.Findings {
+		t.AddRow(table.Red, f.Check, f.Commit, f.Message)
+	}
+	return t.Flush()
+}
@@ -0,0 +1,176 @@
+package verify
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"k8s.io/klog/v2"
+
+	"github.com/openshift/rebase/internal/apply"
+	"github.com/openshift/rebase/internal/git"
+	"github.com/openshift/rebase/internal/report"
+	"github.com/openshift/rebase/internal/utils"
+)
+
+// Override records a deliberate change to the planned outcome of a carry,
+// eg. a carry dropped or reworded during the rebase.
+type Override struct {
+	// Disposition replaces the planned disposition, when set
+	Disposition report.Disposition `json:"disposition,omitempty"`
+	// Message replaces the summary of the carry, when set
+	Message string `json:"message,omitempty"`
+	// Reason explains why the override exists
+	Reason string `json:"reason,omitempty"`
+}
+
+// Overrides maps original carry SHAs to their overrides.
+type Overrides map[string]Override
+
+// Descriptor describes the planned outcome of a single carry.
+type Descriptor struct {
+	// Original is the SHA of the carry on openshift/master
+	Original string
+	// Summary is the expected first line of the carry on the rebase branch
+	Summary string
+	// Disposition is the planned disposition of the carry
+	Disposition report.Disposition
+}
+
+// LoadOverrides reads overrides from a JSON file.
+func LoadOverrides(path string) (Overrides, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	overrides := Overrides{}
+	if err := json.Unmarshal(data, &overrides); err != nil {
+		return nil, fmt.Errorf("malformed overrides file %s: %w", path, err)
+	}
+	return overrides, nil
+}
+
+// Transform applies the overrides to the descriptors of the original carries.
+func (o Overrides) Transform(descriptors []Descriptor) []Descriptor {
+	transformed := make([]Descriptor, 0, len(descriptors))
+	for _, d := range descriptors {
+		if override, ok := o[d.Original]; ok {
+			if len(override.Disposition) > 0 {
+				d.Disposition = override.Disposition
+			}
+			if len(override.Message) > 0 {
+				d.Summary = utils.FormatMessage(override.Message)
+			}
+		}
+		transformed = append(transformed, d)
+	}
+	return transformed
+}
+
+// verifyOverrides compares the planned dispositions of the original carries,
+// with the overrides applied, against the commits on the rebase branch.
+func (v *Verify) verifyOverrides(repository git.Git) ([]Finding, error) {
+	overrides := Overrides{}
+	if len(v.options.OverridesFile) > 0 {
+		var err error
+		if overrides, err = LoadOverrides(v.options.OverridesFile); err != nil {
+			return nil, err
+		}
+	}
+	planned, err := v.plannedCarries(repository)
+	if err != nil {
+		return nil, err
+	}
+	for sha := range overrides {
+		found := false
+		for _, d := range planned {
+			if d.Original == sha {
+				found = true
+				break
+			}
+		}
+		if !found {
+			klog.Warningf("Override for %s does not match any carry", sha)
+		}
+	}
+	actual, err := v.branchCarries(repository)
+	if err != nil {
+		return nil, err
+	}
+	return compareDescriptors(overrides.Transform(planned), actual), nil
+}
+
+// plannedCarries returns descriptors of the original carries from openshift/master
+func (v *Verify) plannedCarries(repository git.Git) ([]Descriptor, error) {
+	commits, err := v.carriesOn(repository, v.openshiftBranch())
+	if err != nil {
+		return nil, err
+	}
+	descriptors := make([]Descriptor, 0, len(commits))
+	for _, c := range commits {
+		disposition, err := apply.PlannedDisposition(repository, v.fork, c, v.options.Mainline)
+		if err != nil {
+			return nil, err
+		}
+		descriptors = append(descriptors, Descriptor{
+			Original:    c.Hash.String(),
+			Summary:     utils.FormatMessage(c.Message),
+			Disposition: disposition,
+		})
+	}
+	return descriptors, nil
+}
+
+// branchCarries returns descriptors of the commits added on top of upstream
+// and openshift/master on the rebase branch
+func (v *Verify) branchCarries(repository git.Git) ([]Descriptor, error) {
+	shas, err := repository.RevList("--reverse", "--no-merges", v.options.Branch, "^"+v.options.Upstream, "^"+v.openshiftBranch())
+	if err != nil {
+		return nil, err
+	}
+	descriptors := make([]Descriptor, 0, len(shas))
+	for _, sha := range shas {
+		commit, err := repository.Commit(plumbing.NewHash(sha))
+		if err != nil {
+			return nil, fmt.Errorf("Error reading commit %s: %w", sha, err)
+		}
+		descriptors = append(descriptors, Descriptor{Original: sha, Summary: utils.FormatMessage(commit.Message), Disposition: report.Picked})
+	}
+	return descriptors, nil
+}
+
+// compareDescriptors matches planned and actual carries by their summaries,
+// reporting planned carries missing from the branch, carries present despite
+// being planned to be left out, and commits not matching any planned carry.
+func compareDescriptors(planned, actual []Descriptor) []Finding {
+	present := make(map[string][]string)
+	for _, a := range actual {
+		present[a.Summary] = append(present[a.Summary], a.Original)
+	}
+	var findings []Finding
+	for _, p := range planned {
+		shas := present[p.Summary]
+		switch {
+		case p.Disposition.Lands() && len(shas) == 0:
+			findings = append(findings, Finding{Check: "overrides", Commit: p.Original,
+				Message: fmt.Sprintf("planned to be picked, but missing: %s", p.Summary)})
+		case p.Disposition.Lands():
+			present[p.Summary] = shas[1:]
+		case len(shas) > 0:
+			findings = append(findings, Finding{Check: "overrides", Commit: p.Original,
+				Message: fmt.Sprintf("planned as %s, but present as %s: %s", p.Disposition, shas[0], p.Summary)})
+			present[p.Summary] = shas[1:]
+		}
+	}
+	for _, a := range actual {
+		shas := present[a.Summary]
+		if len(shas) == 0 || shas[0] != a.Original {
+			continue
+		}
+		present[a.Summary] = shas[1:]
+		findings = append(findings, Finding{Check: "overrides", Commit: a.Original,
+			Message: fmt.Sprintf("not matching any planned carry: %s", a.Summary)})
+	}
+	return findings
+}
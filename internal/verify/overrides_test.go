@@ -0,0 +1,100 @@
+package verify
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/openshift/rebase/internal/report"
+)
+
+func TestCompareDescriptors(t *testing.T) {
+	tests := []struct {
+		name     string
+		planned  []Descriptor
+		actual   []Descriptor
+		expected []Finding
+	}{
+		{
+			name:    "branch matching the plan",
+			planned: []Descriptor{{Original: "o1", Summary: "UPSTREAM: <carry>: a", Disposition: report.Picked}, {Original: "o2", Summary: "UPSTREAM: <drop>: b", Disposition: report.Dropped}},
+			actual:  []Descriptor{{Original: "n1", Summary: "UPSTREAM: <carry>: a"}},
+		},
+		{
+			name:    "missing picked carry",
+			planned: []Descriptor{{Original: "o1", Summary: "UPSTREAM: <carry>: a", Disposition: report.Picked}},
+			expected: []Finding{
+				{Check: "overrides", Commit: "o1", Message: "planned to be picked, but missing: UPSTREAM: <carry>: a"},
+			},
+		},
+		{
+			name:    "carry present despite being dropped",
+			planned: []Descriptor{{Original: "o1", Summary: "UPSTREAM: 123: a", Disposition: report.Merged}},
+			actual:  []Descriptor{{Original: "n1", Summary: "UPSTREAM: 123: a"}},
+			expected: []Finding{
+				{Check: "overrides", Commit: "o1", Message: "planned as merged, but present as n1: UPSTREAM: 123: a"},
+			},
+		},
+		{
+			name: "fixed and manual carries present",
+			planned: []Descriptor{
+				{Original: "o1", Summary: "UPSTREAM: <carry>: fixed", Disposition: report.Fixed},
+				{Original: "o2", Summary: "UPSTREAM: <carry>: manual", Disposition: report.Manual},
+				{Original: "o3", Summary: "UPSTREAM: <carry>: resolved", Disposition: report.PickedResolved},
+			},
+			actual: []Descriptor{
+				{Original: "n1", Summary: "UPSTREAM: <carry>: fixed"},
+				{Original: "n2", Summary: "UPSTREAM: <carry>: manual"},
+				{Original: "n3", Summary: "UPSTREAM: <carry>: resolved"},
+			},
+		},
+		{
+			name: "fixed and manual carries missing",
+			planned: []Descriptor{
+				{Original: "o1", Summary: "UPSTREAM: <carry>: fixed", Disposition: report.Fixed3Way},
+				{Original: "o2", Summary: "UPSTREAM: <carry>: manual", Disposition: report.Manual},
+			},
+			expected: []Finding{
+				{Check: "overrides", Commit: "o1", Message: "planned to be picked, but missing: UPSTREAM: <carry>: fixed"},
+				{Check: "overrides", Commit: "o2", Message: "planned to be picked, but missing: UPSTREAM: <carry>: manual"},
+			},
+		},
+		{
+			name:    "unplanned commit",
+			planned: []Descriptor{{Original: "o1", Summary: "UPSTREAM: <carry>: a", Disposition: report.Picked}},
+			actual:  []Descriptor{{Original: "n1", Summary: "UPSTREAM: <carry>: a"}, {Original: "n2", Summary: "UPSTREAM: <carry>: b"}},
+			expected: []Finding{
+				{Check: "overrides", Commit: "n2", Message: "not matching any planned carry: UPSTREAM: <carry>: b"},
+			},
+		},
+		{
+			name: "carries sharing a summary matched one to one",
+			planned: []Descriptor{
+				{Original: "o1", Summary: "UPSTREAM: <carry>: a", Disposition: report.Picked},
+				{Original: "o2", Summary: "UPSTREAM: <carry>: a", Disposition: report.Picked},
+				{Original: "o3", Summary: "UPSTREAM: <carry>: a", Disposition: report.Dropped},
+			},
+			actual: []Descriptor{{Original: "n1", Summary: "UPSTREAM: <carry>: a"}, {Original: "n2", Summary: "UPSTREAM: <carry>: a"}, {Original: "n3", Summary: "UPSTREAM: <carry>: a"}},
+			expected: []Finding{
+				{Check: "overrides", Commit: "o3", Message: "planned as dropped, but present as n3: UPSTREAM: <carry>: a"},
+			},
+		},
+		{
+			name: "more commits sharing a summary than planned",
+			planned: []Descriptor{
+				{Original: "o1", Summary: "UPSTREAM: <carry>: a", Disposition: report.Picked},
+			},
+			actual: []Descriptor{{Original: "n1", Summary: "UPSTREAM: <carry>: a"}, {Original: "n2", Summary: "UPSTREAM: <carry>: a"}},
+			expected: []Finding{
+				{Check: "overrides", Commit: "n2", Message: "not matching any planned carry: UPSTREAM: <carry>: a"},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			findings := compareDescriptors(test.planned, test.actual)
+			if !reflect.DeepEqual(findings, test.expected) {
+				t.Errorf("expected %v, got %v", test.expected, findings)
+			}
+		})
+	}
+}
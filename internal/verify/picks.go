@@ -0,0 +1,85 @@
+package verify
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"github.com/go-git/go-git/v5/plumbing"
+
+	"github.com/openshift/rebase/internal/fork"
+	"github.com/openshift/rebase/internal/git"
+	"github.com/openshift/rebase/internal/github"
+	"github.com/openshift/rebase/internal/utils"
+)
+
+// numericRE matches summaries of picks of upstream pull requests
+var numericRE = regexp.MustCompile(`^UPSTREAM: ([0-9]+): `)
+
+// verifyUpstreamPicks checks that every UPSTREAM: <number>: commit on the rebase
+// branch references an existing upstream pull request, and that the commit
+// modifies mostly the same files as the pull request.
+func (v *Verify) verifyUpstreamPicks(repository git.Git) ([]Finding, error) {
+	shas, err := repository.RevList("--reverse", "--no-merges", v.options.Branch, "^"+v.options.Upstream, "^"+v.openshiftBranch())
+	if err != nil {
+		return nil, err
+	}
+	var findings []Finding
+	// pull requests are read from GitHub, where they change, so that the
+	// findings of commits are not cached either
+	for _, sha := range shas {
+		commitFindings, err := verifyUpstreamPick(repository, v.fork.Upstream, sha)
+		if err != nil {
+			return nil, err
+		}
+		findings = append(findings, commitFindings...)
+	}
+	return findings, nil
+}
+
+// verifyUpstreamPick checks a single commit against the upstream pull request
+// referenced in its summary
+func verifyUpstreamPick(repository git.Git, upstream fork.Repository, sha string) ([]Finding, error) {
+	commit, err := repository.Commit(plumbing.NewHash(sha))
+	if err != nil {
+		return nil, fmt.Errorf("Error reading commit %s: %w", sha, err)
+	}
+	summary := utils.FormatMessage(commit.Message)
+	matches := numericRE.FindStringSubmatch(summary)
+	if matches == nil {
+		return nil, nil
+	}
+	number, err := strconv.Atoi(matches[1])
+	if err != nil {
+		return nil, err
+	}
+	prFiles, err := github.PullRequestFiles(upstream, number)
+	if github.IsNotFound(err) {
+		return []Finding{{Check: "upstream-picks", Commit: sha,
+			Message: fmt.Sprintf("upstream pull request %d does not exist: %s", number, summary)}}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Error reading upstream pull request %d: %w", number, err)
+	}
+	files, err := repository.ChangedFiles(sha)
+	if err != nil {
+		return nil, err
+	}
+	inPR := make(map[string]bool, len(prFiles))
+	for _, f := range prFiles {
+		inPR[f] = true
+	}
+	common := 0
+	for _, f := range files {
+		if inPR[f] {
+			common++
+		}
+	}
+	// most of the files modified by a pick are expected to be modified
+	// by the upstream pull request as well
+	if len(files) > 0 && common*2 < len(files) {
+		return []Finding{{Check: "upstream-picks", Commit: sha,
+			Message: fmt.Sprintf("only %d of %d modified files are modified by upstream pull request %d: %s", common, len(files), number, summary)}}, nil
+	}
+	return nil, nil
+}
@@ -0,0 +1,61 @@
+package verify
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/openshift/rebase/internal/git"
+)
+
+// modulePlaceholder is replaced with the staging directory name in the URL
+// of published staging repositories
+const modulePlaceholder = "{module}"
+
+// verifyPublishedStaging fetches the published staging repositories and
+// compares their content with the staging directories on the rebase branch,
+// catching drift of the publishing pipeline.
+func (v *Verify) verifyPublishedStaging(repository git.Git) ([]Finding, error) {
+	dirs, err := repository.ListTree(v.options.Branch, stagingDir)
+	if err != nil {
+		return nil, fmt.Errorf("Error listing %s: %w", stagingDir, err)
+	}
+	var findings []Finding
+	add := func(format string, args ...interface{}) {
+		findings = append(findings, Finding{Check: "published-staging", Message: fmt.Sprintf(format, args...)})
+	}
+	for _, dir := range dirs {
+		staging := path.Join(stagingDir, dir)
+		if _, err := repository.ShowFile(v.options.Branch, path.Join(staging, "go.mod")); err != nil {
+			// not a module, so it is not published
+			continue
+		}
+		url := strings.ReplaceAll(v.options.PublishedStaging, modulePlaceholder, dir)
+		published, err := repository.FetchRef(url, v.options.PublishedRef)
+		if err != nil {
+			add("fetching %s from %s failed: %v", v.options.PublishedRef, url, err)
+			continue
+		}
+		local := v.options.Branch + ":" + staging
+		publishedTree, err := repository.RevParse(published + "^{tree}")
+		if err != nil {
+			return nil, err
+		}
+		localTree, err := repository.RevParse(local)
+		if err != nil {
+			return nil, err
+		}
+		if publishedTree == localTree {
+			continue
+		}
+		files, err := repository.DiffNames(local, publishedTree)
+		if err != nil {
+			return nil, fmt.Errorf("Error comparing %s with %s: %w", staging, url, err)
+		}
+		if len(files) > maxVendorFindings {
+			files = append(files[:maxVendorFindings], fmt.Sprintf("and %d more", len(files)-maxVendorFindings))
+		}
+		add("%s differs from %s %s (%s): %s", staging, url, v.options.PublishedRef, published, strings.Join(files, ", "))
+	}
+	return findings, nil
+}
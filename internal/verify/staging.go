@@ -0,0 +1,81 @@
+package verify
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"golang.org/x/mod/modfile"
+	"k8s.io/klog/v2"
+
+	"github.com/openshift/rebase/internal/git"
+)
+
+const stagingDir = "staging/src/k8s.io"
+
+// parseReplaces returns the replace directives from go.mod, mapping module
+// paths to their replacements, the module version on the left side is
+// irrelevant for staging
+func parseReplaces(goMod *modfile.File) map[string]string {
+	replaces := make(map[string]string, len(goMod.Replace))
+	for _, r := range goMod.Replace {
+		replaces[r.Old.Path] = r.New.Path
+	}
+	return replaces
+}
+
+// verifyStaging checks that go.mod replace directives of k8s.io staging modules
+// point at existing staging directories, and that every staging module has
+// a replace directive.
+func (v *Verify) verifyStaging(repository git.Git) ([]Finding, error) {
+	goMod, err := repository.ShowFile(v.options.Branch, "go.mod")
+	if err != nil {
+		klog.Warningf("Skipping staging check, go.mod could not be read: %v", err)
+		return nil, nil
+	}
+	parsed, err := modfile.ParseLax("go.mod", goMod, nil)
+	if err != nil {
+		klog.Warningf("Skipping staging check, go.mod could not be parsed: %v", err)
+		return nil, nil
+	}
+	replaces := parseReplaces(parsed)
+	modules := make([]string, 0, len(replaces))
+	for module := range replaces {
+		modules = append(modules, module)
+	}
+	sort.Strings(modules)
+	var findings []Finding
+	add := func(format string, args ...interface{}) {
+		findings = append(findings, Finding{Check: "staging", Message: fmt.Sprintf(format, args...)})
+	}
+	for _, module := range modules {
+		replacement := replaces[module]
+		if !strings.HasPrefix(module, "k8s.io/") || !strings.HasPrefix(replacement, "./staging/") {
+			continue
+		}
+		if _, err := repository.ShowFile(v.options.Branch, path.Join(replacement, "go.mod")); err != nil {
+			add("replace of %s points at %s, which is not a module", module, replacement)
+		}
+	}
+	dirs, err := repository.ListTree(v.options.Branch, stagingDir)
+	if err != nil {
+		return nil, fmt.Errorf("Error listing %s: %w", stagingDir, err)
+	}
+	for _, dir := range dirs {
+		stagingGoMod, err := repository.ShowFile(v.options.Branch, path.Join(stagingDir, dir, "go.mod"))
+		if err != nil {
+			// not a module
+			continue
+		}
+		module := modfile.ModulePath(stagingGoMod)
+		expected := "./" + path.Join(stagingDir, dir)
+		switch replacement, ok := replaces[module]; {
+		case !ok:
+			add("staging module %s has no replace directive, expected %s => %s", module, module, expected)
+		case replacement != expected:
+			add("staging module %s is replaced with %s, expected %s", module, replacement, expected)
+		}
+	}
+	return findings, nil
+}
@@ -0,0 +1,74 @@
+package verify
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing"
+
+	"github.com/openshift/rebase/internal/git"
+	"github.com/openshift/rebase/internal/utils"
+)
+
+// verifyStructure checks the shape of the rebase branch: the upstream commit,
+// followed by a single merge of the openshift branch with the documented message,
+// whose tree is the upstream tree, followed only by UPSTREAM commits.
+func (v *Verify) verifyStructure(repository git.Git) ([]Finding, error) {
+	upstream, err := repository.RevParse(v.options.Upstream + "^{commit}")
+	if err != nil {
+		return nil, err
+	}
+	openshift, err := repository.RevParse(v.openshiftBranch())
+	if err != nil {
+		return nil, err
+	}
+	shas, err := repository.RevList("--reverse", "--first-parent", v.options.Branch, "^"+upstream)
+	if err != nil {
+		return nil, err
+	}
+	var findings []Finding
+	add := func(sha, format string, args ...interface{}) {
+		findings = append(findings, Finding{Check: "structure", Commit: sha, Message: fmt.Sprintf(format, args...)})
+	}
+	merges := 0
+	for i, sha := range shas {
+		commit, err := repository.Commit(plumbing.NewHash(sha))
+		if err != nil {
+			return nil, fmt.Errorf("Error reading commit %s: %w", sha, err)
+		}
+		summary := utils.FormatMessage(commit.Message)
+		if len(commit.ParentHashes) < 2 {
+			if merges == 0 {
+				add(sha, "commit before the merge of the openshift branch: %s", summary)
+			} else if !strings.HasPrefix(summary, upstreamPrefix) {
+				add(sha, "commit without UPSTREAM prefix after the merge of the openshift branch: %s", summary)
+			}
+			continue
+		}
+		merges++
+		if merges > 1 {
+			add(sha, "unexpected merge commit, only a single merge of the openshift branch is allowed: %s", summary)
+			continue
+		}
+		if i != 0 || commit.ParentHashes[0].String() != upstream {
+			add(sha, "first parent of the merge is %s, expected upstream %s", commit.ParentHashes[0].String(), upstream)
+		}
+		if commit.ParentHashes[1].String() != openshift {
+			add(sha, "second parent of the merge is %s, expected the openshift branch %s", commit.ParentHashes[1].String(), openshift)
+		}
+		if !v.fork.IsMarker(commit.Message) {
+			add(sha, "merge message %q does not match the rebase marker", summary)
+		}
+		parent, err := repository.Commit(commit.ParentHashes[0])
+		if err != nil {
+			return nil, fmt.Errorf("Error reading commit %s: %w", commit.ParentHashes[0].String(), err)
+		}
+		if commit.TreeHash != parent.TreeHash {
+			add(sha, "merge tree differs from its first parent, it must be created with ours strategy")
+		}
+	}
+	if merges == 0 {
+		add("", "no merge of the openshift branch found on top of upstream %s", upstream)
+	}
+	return findings, nil
+}
@@ -0,0 +1,48 @@
+package verify
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing"
+
+	"github.com/openshift/rebase/internal/git"
+)
+
+const upstreamPrefix = "UPSTREAM: "
+
+// verifyUpstreamContent checks that the rebase branch consists of the upstream
+// tree and the carries only, ie. re-applying the diffs of all UPSTREAM commits
+// on top of upstream results in the tree of the rebase branch. Any difference
+// means upstream content was modified outside of carries, eg. during resolution
+// of conflicts in the merge commit.
+func (v *Verify) verifyUpstreamContent(repository git.Git) ([]Finding, error) {
+	shas, err := repository.RevList("--reverse", "--no-merges", v.options.Branch, "^"+v.options.Upstream, "^"+v.openshiftBranch())
+	if err != nil {
+		return nil, err
+	}
+	var carries []string
+	for _, sha := range shas {
+		commit, err := repository.Commit(plumbing.NewHash(sha))
+		if err != nil {
+			return nil, fmt.Errorf("Error reading commit %s: %w", sha, err)
+		}
+		if strings.HasPrefix(commit.Message, upstreamPrefix) {
+			carries = append(carries, sha)
+		}
+	}
+	tree, err := repository.TreeWithCommits(v.options.Upstream, carries)
+	if err != nil {
+		return []Finding{{Check: "upstream-content", Message: fmt.Sprintf("carries do not apply on top of %s: %v", v.options.Upstream, err)}}, nil
+	}
+	files, err := repository.DiffNames(tree, v.options.Branch)
+	if err != nil {
+		return nil, err
+	}
+	var findings []Finding
+	for _, f := range files {
+		findings = append(findings, Finding{Check: "upstream-content",
+			Message: fmt.Sprintf("%s differs from %s outside of carries", f, v.options.Upstream)})
+	}
+	return findings, nil
+}
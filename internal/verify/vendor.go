@@ -0,0 +1,59 @@
+package verify
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"k8s.io/klog/v2"
+
+	"github.com/openshift/rebase/internal/git"
+	"github.com/openshift/rebase/internal/utils"
+)
+
+const (
+	defaultVendorCommand = "go mod vendor"
+	// maxVendorFindings limits the number of reported files, a broken vendor
+	// directory easily differs in thousands of them
+	maxVendorFindings = 20
+)
+
+// verifyVendor re-vendors the dependencies of the rebase branch in a temporary
+// worktree and reports all files changed by that, which means vendor/, go.mod,
+// go.sum and vendor/modules.txt are not consistent.
+func (v *Verify) verifyVendor(repository git.Git) ([]Finding, error) {
+	dir, err := os.MkdirTemp("", "rebase-vendor-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	worktree := filepath.Join(dir, "worktree")
+	if err := repository.AddWorktree(worktree, v.options.Branch); err != nil {
+		return nil, fmt.Errorf("Error creating worktree: %w", err)
+	}
+	defer func() {
+		if err := repository.RemoveWorktree(worktree); err != nil {
+			klog.Errorf("Removing worktree %s failed: %v", worktree, err)
+		}
+	}()
+	if output, err := utils.RunCommand(worktree, "sh", "-c", v.options.VendorCommand); err != nil {
+		return []Finding{{Check: "vendor", Message: fmt.Sprintf("%q failed: %v\n%s", v.options.VendorCommand, err, output)}}, nil
+	}
+	output, err := utils.RunCommand(worktree, "git", "status", "--porcelain", "--untracked-files=all")
+	if err != nil {
+		return nil, fmt.Errorf("Error reading worktree status: %w\n%s", err, output)
+	}
+	var findings []Finding
+	for _, l := range strings.Split(output, "\n") {
+		if len(strings.TrimSpace(l)) == 0 {
+			continue
+		}
+		if len(findings) == maxVendorFindings {
+			findings = append(findings, Finding{Check: "vendor", Message: "more files changed by re-vendoring, run it locally to see all of them"})
+			break
+		}
+		findings = append(findings, Finding{Check: "vendor", Message: fmt.Sprintf("changed by %q: %s", v.options.VendorCommand, strings.TrimSpace(l))})
+	}
+	return findings, nil
+}
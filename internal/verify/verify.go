@@ -0,0 +1,293 @@
+package verify
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing/object"
+
+	"github.com/openshift/rebase/internal/cache"
+	"github.com/openshift/rebase/internal/carry"
+	"github.com/openshift/rebase/internal/console"
+	"github.com/openshift/rebase/internal/fork"
+	"github.com/openshift/rebase/internal/git"
+	"github.com/openshift/rebase/internal/state"
+)
+
+// Options holds the settings controlling the verification.
+type Options struct {
+	// Branch is the rebase branch being verified, defaults to HEAD.
+	Branch string
+	// Upstream is the upstream revision the rebase branch is based on.
+	Upstream string
+	// OverridesFile is a JSON file with overrides of planned carry dispositions.
+	OverridesFile string
+	// PreviousBranch is the rebase branch of the previous release, carries
+	// on it are expected to be present on the verified branch.
+	PreviousBranch string
+	// Vendor enables checking that vendor directory is consistent with go.mod.
+	Vendor bool
+	// VendorCommand is the command re-vendoring the dependencies.
+	VendorCommand string
+	// BisectBuild enables building the branch and bisecting the first commit
+	// breaking the build, when it fails.
+	BisectBuild bool
+	// BuildCommand is the command building the tree.
+	BuildCommand string
+	// TargetVersion is the kubernetes version of the rebase, enables checking
+	// that openshift files do not reference the starting version anymore.
+	TargetVersion string
+	// Fix enables rewriting commit messages on the branch, which can be fixed
+	// mechanically, before running the checks.
+	Fix bool
+	// Checks limits the run to the named checks, all enabled checks run when empty.
+	Checks []string
+	// Format is the output format of the results, one of table, json, yaml, markdown or junit.
+	Format string
+	// Mainline is the parent number used for picking merge commits.
+	Mainline int
+	// PublishedStaging is the URL of published staging repositories, where
+	// {module} is replaced with the staging directory name, eg. api, enables
+	// comparing them with the staging directories.
+	PublishedStaging string
+	// PublishedRef is the branch or tag of published staging repositories.
+	PublishedRef string
+	// APIDiff enables comparing exported Go APIs of carried packages with
+	// the previous rebase branch.
+	APIDiff bool
+	// APIPackages are the packages compared by APIDiff, defaults to the ones
+	// changed by carries on the previous rebase branch.
+	APIPackages []string
+}
+
+// Finding describes a single problem found on the rebase branch.
+type Finding struct {
+	// Check is the name of the check which reported the problem
+	Check string `json:"check"`
+	// Commit is the commit the problem relates to, if any
+	Commit string `json:"commit,omitempty"`
+	// Message describes the problem
+	Message string `json:"message"`
+}
+
+// CheckNames are the names of all checks, some of them run only when enabled.
+var CheckNames = []string{"structure", "overrides", "upstream-content", "messages", "duplicates", "upstream-picks", "staging",
+	"versions", "vendor", "bisect-build", "lost-carries", "published-staging", "apidiff"}
+
+// check is a single verification of the rebase branch
+type check struct {
+	name string
+	run  func(repository git.Git) ([]Finding, error)
+	// uncached checks depend on the network or the environment rather than
+	// only on the commits, their findings are never cached
+	uncached bool
+}
+
+type Verify struct {
+	log           *carry.Log
+	from          string
+	fork          fork.Fork
+	repositoryDir string
+	repository    git.Git
+	options       Options
+	// cache holds findings of previous runs, nil when the git directory is unknown
+	cache *cache.Cache
+	ctx   context.Context
+}
+
+func NewVerify(from, repositoryDir string, f fork.Fork, options Options) *Verify {
+	return &Verify{
+		log:           carry.NewLog(from, repositoryDir, f),
+		from:          from,
+		fork:          f,
+		repositoryDir: repositoryDir,
+		options:       options,
+	}
+}
+
+// SetMarkerSearch bounds the search of the rebase marker.
+func (v *Verify) SetMarkerSearch(search git.MarkerSearch) {
+	v.log.SetMarkerSearch(search)
+}
+
+// SetContext stops the verification before the next check, and reading
+// carries, once the context is cancelled.
+func (v *Verify) SetContext(ctx context.Context) {
+	v.ctx = ctx
+	v.log.SetContext(ctx)
+}
+
+// SetRepository shares an already opened repository.
+func (v *Verify) SetRepository(repository git.Git) {
+	v.repository = repository
+	v.log.SetRepository(repository)
+}
+
+// Run verifies the rebase branch, printing all problems found.
+func (v *Verify) Run() error {
+	result, err := v.Check()
+	if err != nil {
+		return err
+	}
+	if err := result.Write(os.Stdout, v.options.Format); err != nil {
+		return err
+	}
+	if len(result.Findings) > 0 {
+		return fmt.Errorf("Verification of %s found %d problems", v.options.Branch, len(result.Findings))
+	}
+	console.InfofContext(v.ctx, "Verification of %s found no problems", v.options.Branch)
+	return nil
+}
+
+// Check verifies the rebase branch and returns the problems found, fixing
+// them first when requested.
+func (v *Verify) Check() (*Result, error) {
+	repository, err := git.OpenShared(v.repository, v.repositoryDir, v.fork)
+	if err != nil {
+		return nil, err
+	}
+	if err := v.complete(repository); err != nil {
+		return nil, err
+	}
+	gitDir, err := repository.GitDir()
+	if err != nil {
+		return nil, err
+	}
+	// the branch must not move in the middle of another run, fixing it
+	// rewrites the branch
+	unlock, err := state.Acquire(gitDir, "verify")
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	v.cache = cache.New(gitDir)
+	if v.options.Fix {
+		if err := v.fix(repository); err != nil {
+			return nil, fmt.Errorf("Error fixing %s: %w", v.options.Branch, err)
+		}
+	}
+	result := &Result{Branch: v.options.Branch}
+	for _, c := range v.checks() {
+		if v.ctx != nil {
+			if err := v.ctx.Err(); err != nil {
+				return nil, err
+			}
+		}
+		findings, err := v.runCheck(repository, c)
+		if err != nil {
+			return nil, fmt.Errorf("Error running %s check: %w", c.name, err)
+		}
+		result.Checks = append(result.Checks, c.name)
+		result.Findings = append(result.Findings, findings...)
+	}
+	return result, nil
+}
+
+// complete resolves the branch to verify and fills in defaults
+func (v *Verify) complete(repository git.Git) error {
+	switch v.options.Format {
+	case "":
+		v.options.Format = FormatTable
+	case FormatTable, FormatJSON, FormatYAML, FormatMarkdown, FormatJUnit:
+	default:
+		return fmt.Errorf("invalid output format %q, expected one of: %s, %s, %s, %s, %s",
+			v.options.Format, FormatTable, FormatJSON, FormatYAML, FormatMarkdown, FormatJUnit)
+	}
+	known := make(map[string]bool, len(CheckNames))
+	for _, name := range CheckNames {
+		known[name] = true
+	}
+	for _, name := range v.options.Checks {
+		if !known[name] {
+			return fmt.Errorf("unknown check %q, expected one of: %s", name, strings.Join(CheckNames, ", "))
+		}
+	}
+	if len(v.options.BuildCommand) == 0 {
+		v.options.BuildCommand = defaultBuildCommand
+	}
+	if len(v.options.VendorCommand) == 0 {
+		v.options.VendorCommand = defaultVendorCommand
+	}
+	if len(v.options.Upstream) == 0 {
+		v.options.Upstream = v.fork.UpstreamRef()
+	}
+	if v.options.APIDiff && len(v.options.PreviousBranch) == 0 {
+		return fmt.Errorf("comparing APIs requires the previous rebase branch")
+	}
+	if len(v.options.PublishedStaging) > 0 && len(v.options.PublishedRef) == 0 {
+		return fmt.Errorf("comparing published staging repositories requires their branch or tag")
+	}
+	if len(v.options.Branch) == 0 {
+		branch, err := repository.CurrentBranch()
+		if err != nil {
+			return err
+		}
+		if len(branch) == 0 {
+			if branch, err = repository.RevParse("HEAD"); err != nil {
+				return err
+			}
+		}
+		v.options.Branch = branch
+	}
+	return nil
+}
+
+// openshiftBranch is the branch of the openshift fork holding the carries
+func (v *Verify) openshiftBranch() string {
+	return v.fork.OpenShiftRef()
+}
+
+// carriesOn returns carries on a given branch
+func (v *Verify) carriesOn(repository git.Git, branch string) ([]*object.Commit, error) {
+	commits, err := v.log.GetCommitsOn(repository, branch)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading carries on %s: %w", branch, err)
+	}
+	return commits, nil
+}
+
+// checks returns the list of checks to run
+func (v *Verify) checks() []check {
+	checks := []check{
+		{name: "structure", run: v.verifyStructure},
+		{name: "overrides", run: v.verifyOverrides},
+		{name: "upstream-content", run: v.verifyUpstreamContent},
+		{name: "messages", run: v.verifyMessages},
+		{name: "duplicates", run: v.verifyDuplicates},
+		{name: "upstream-picks", run: v.verifyUpstreamPicks, uncached: true},
+		{name: "staging", run: v.verifyStaging},
+	}
+	if v.options.Vendor {
+		checks = append(checks, check{name: "vendor", run: v.verifyVendor, uncached: true})
+	}
+	if len(v.options.TargetVersion) > 0 {
+		checks = append(checks, check{name: "versions", run: v.verifyVersions})
+	}
+	if v.options.BisectBuild {
+		checks = append(checks, check{name: "bisect-build", run: v.verifyBisectBuild, uncached: true})
+	}
+	if len(v.options.PreviousBranch) > 0 {
+		checks = append(checks, check{name: "lost-carries", run: v.verifyLostCarries})
+	}
+	if len(v.options.PublishedStaging) > 0 {
+		checks = append(checks, check{name: "published-staging", run: v.verifyPublishedStaging, uncached: true})
+	}
+	if v.options.APIDiff {
+		checks = append(checks, check{name: "apidiff", run: v.verifyAPIDiff})
+	}
+	if len(v.options.Checks) == 0 {
+		return checks
+	}
+	var selected []check
+	for _, c := range checks {
+		for _, name := range v.options.Checks {
+			if c.name == name {
+				selected = append(selected, c)
+				break
+			}
+		}
+	}
+	return selected
+}
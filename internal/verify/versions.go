@@ -0,0 +1,39 @@
+package verify
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/openshift/rebase/internal/git"
+)
+
+// verifyVersions checks that files owned by openshift, ie. the ones differing
+// from upstream, do not reference the starting kubernetes version anymore,
+// eg. in openshift-hack files, image labels or CI configuration.
+func (v *Verify) verifyVersions(repository git.Git) ([]Finding, error) {
+	old := strings.TrimPrefix(v.from, "v")
+	target := strings.TrimPrefix(v.options.TargetVersion, "v")
+	if len(old) == 0 || old == target {
+		return nil, nil
+	}
+	files, err := repository.DiffNames(v.options.Upstream, v.options.Branch)
+	if err != nil {
+		return nil, err
+	}
+	var paths []string
+	for _, f := range files {
+		if !strings.HasPrefix(f, "vendor/") {
+			paths = append(paths, f)
+		}
+	}
+	stale, err := repository.GrepFiles(v.options.Branch, old, paths)
+	if err != nil {
+		return nil, err
+	}
+	var findings []Finding
+	for _, f := range stale {
+		findings = append(findings, Finding{Check: "versions",
+			Message: fmt.Sprintf("%s still references %s, expected %s", f, old, target)})
+	}
+	return findings, nil
+}
@@ -0,0 +1,58 @@
+package version
+
+import (
+	"runtime"
+	"runtime/debug"
+)
+
+// goGitModule is the module path of go-git, whose version is reported
+const goGitModule = "github.com/go-git/go-git/v5"
+
+// Set at build time with:
+//
+//	-ldflags "-X github.com/openshift/rebase/internal/version.version=v0.1.0
+//	  -X github.com/openshift/rebase/internal/version.commit=$(git rev-parse HEAD)
+//	  -X github.com/openshift/rebase/internal/version.buildDate=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+var (
+	version   = "unknown"
+	commit    = ""
+	buildDate = ""
+)
+
+// Info describes the build of the binary.
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildDate string `json:"buildDate"`
+	GoGit     string `json:"goGit"`
+	Go        string `json:"go"`
+	Platform  string `json:"platform"`
+}
+
+// Get returns the build information, the commit defaults to the one recorded
+// by the go toolchain when not set at build time.
+func Get() Info {
+	info := Info{
+		Version:   version,
+		Commit:    commit,
+		BuildDate: buildDate,
+		GoGit:     "unknown",
+		Go:        runtime.Version(),
+		Platform:  runtime.GOOS + "/" + runtime.GOARCH,
+	}
+	build, ok := debug.ReadBuildInfo()
+	if !ok {
+		return info
+	}
+	for _, dep := range build.Deps {
+		if dep.Path == goGitModule {
+			info.GoGit = dep.Version
+		}
+	}
+	for _, s := range build.Settings {
+		if s.Key == "vcs.revision" && len(info.Commit) == 0 {
+			info.Commit = s.Value
+		}
+	}
+	return info
+}
@@ -0,0 +1,263 @@
+// Package rebase is the API for embedding the rebase engine in other tools.
+// It lists carries, plans and applies a rebase and verifies the rebase
+// branch, the same way the commands do, without parsing flags or configuring
+// logging. The implementation lives in internal packages, only the functions
+// and types here are stable.
+//
+// Progress of the engine is written to the output or passed to the logger of
+// the Repository, per call, nothing is printed to stdout or stderr. Diagnostics
+// are logged with klog, which is left configured by the embedding tool.
+package rebase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/go-logr/logr"
+
+	"github.com/openshift/rebase/internal/apply"
+	"github.com/openshift/rebase/internal/carry"
+	"github.com/openshift/rebase/internal/console"
+	"github.com/openshift/rebase/internal/fork"
+	"github.com/openshift/rebase/internal/git"
+	"github.com/openshift/rebase/internal/state"
+	"github.com/openshift/rebase/internal/verify"
+)
+
+// ErrInterrupted is returned when the context of a rebase was cancelled, the
+// progress is saved, so that it is resumed with Continue.
+var ErrInterrupted = apply.ErrInterrupted
+
+// Repository is the kubernetes fork being rebased.
+type Repository struct {
+	// Dir is the working tree of the repository.
+	Dir string
+	// From is the upstream tag the rebase starts from, eg. v1.31.0.
+	From string
+	// To is the ref carries are read from, defaults to the openshift branch.
+	To string
+	// Fork is the fork being rebased, defaults to kubernetes. Continue uses
+	// the fork the stopped run was started on.
+	Fork Fork
+	// StateDir holds the state of runs, defaults to the git directory.
+	StateDir string
+	// GitBackend serves read operations of the repository, one of go-git
+	// and cli, defaults to go-git.
+	GitBackend string
+	// Out receives the summary and progress of runs, they are discarded when
+	// nil.
+	Out io.Writer
+	// Logger receives progress messages instead of Out, when set.
+	Logger logr.Logger
+}
+
+// output returns the writer of the summary and progress of runs
+func (r Repository) output() io.Writer {
+	if r.Out == nil {
+		return io.Discard
+	}
+	return r.Out
+}
+
+// runContext returns ctx directing progress messages of the engine to the
+// logger or the output of the repository
+func (r Repository) runContext(ctx context.Context) context.Context {
+	if r.Logger.GetSink() != nil {
+		return console.WithLogger(ctx, r.Logger)
+	}
+	return console.WithOutput(ctx, r.output())
+}
+
+// open opens the repository with its git backend, checking the remotes of
+// the fork
+func (r Repository) open(f fork.Fork) (git.Git, error) {
+	backend := r.GitBackend
+	if len(backend) == 0 {
+		backend = git.BackendGoGit
+	}
+	return git.OpenGitWithBackend(r.Dir, f, backend)
+}
+
+// Carries returns the carries of the repository matching the options.
+func Carries(ctx context.Context, repository Repository, options CarryOptions) ([]Carry, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	f, err := repository.Fork.load()
+	if err != nil {
+		return nil, err
+	}
+	opened, err := repository.open(f)
+	if err != nil {
+		return nil, err
+	}
+	originalRef, err := opened.CurrentBranch()
+	if err != nil {
+		return nil, err
+	}
+	if len(originalRef) == 0 {
+		if originalRef, err = opened.RevParse("HEAD"); err != nil {
+			return nil, err
+		}
+	}
+	list := carry.NewList(repository.From, repository.Dir, f, options.internal(), "")
+	list.SetTo(repository.To)
+	list.SetRepository(opened)
+	list.SetContext(repository.runContext(ctx))
+	entries, err := list.Entries(opened)
+	// reading carries checks out the openshift branch
+	if checkoutErr := opened.Checkout(originalRef); checkoutErr != nil {
+		return nil, errors.Join(err, fmt.Errorf("Error restoring %s: %w", originalRef, checkoutErr))
+	}
+	if err != nil {
+		return nil, err
+	}
+	return newCarries(entries), nil
+}
+
+// MakePlan returns the plan of rebasing the repository with the options,
+// without modifying the repository.
+func MakePlan(ctx context.Context, repository Repository, options ApplyOptions) (*Plan, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	f, err := repository.Fork.load()
+	if err != nil {
+		return nil, err
+	}
+	opened, err := repository.open(f)
+	if err != nil {
+		return nil, err
+	}
+	planner := apply.NewPlanner(repository.From, repository.Dir, f, options.internal(repository.To), "")
+	planner.SetRepository(opened)
+	planner.SetContext(repository.runContext(ctx))
+	plan, err := planner.Plan()
+	if err != nil {
+		return nil, err
+	}
+	return newPlan(plan), nil
+}
+
+// Apply rebases the repository with the options onto a new rebase branch and
+// returns the report of the run. The report is returned also when the run
+// stopped with StoppedError, or ErrInterrupted once the context is cancelled.
+func Apply(ctx context.Context, repository Repository, options ApplyOptions) (*Report, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	f, err := repository.Fork.load()
+	if err != nil {
+		return nil, err
+	}
+	opened, err := repository.open(f)
+	if err != nil {
+		return nil, err
+	}
+	run := apply.NewApply(repository.From, repository.Dir, f, options.internal(repository.To))
+	run.SetStateDir(repository.StateDir)
+	run.SetRepository(opened)
+	run.SetOutput(repository.output(), repository.output())
+	run.SetContext(repository.runContext(ctx))
+	return runReport(repository, run.Run())
+}
+
+// Continue resumes the stopped rebase of the repository, once the commit it
+// stopped on is resolved, and returns the report of the run.
+func Continue(ctx context.Context, repository Repository) (*Report, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	f, err := repository.Fork.load()
+	if err != nil {
+		return nil, err
+	}
+	// the remotes are checked against the fork the stopped run was started on
+	f, err = runFork(repository, f)
+	if err != nil {
+		return nil, err
+	}
+	opened, err := repository.open(f)
+	if err != nil {
+		return nil, err
+	}
+	run := apply.NewContinue(repository.Dir, f)
+	run.SetStateDir(repository.StateDir)
+	run.SetRepository(opened)
+	run.SetOutput(repository.output(), repository.output())
+	run.SetContext(repository.runContext(ctx))
+	return runReport(repository, run.Run())
+}
+
+// Verify checks the rebase branch of the repository and returns the problems
+// found, which are not returned as an error.
+func Verify(ctx context.Context, repository Repository, options VerifyOptions) (*VerifyResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	f, err := repository.Fork.load()
+	if err != nil {
+		return nil, err
+	}
+	opened, err := repository.open(f)
+	if err != nil {
+		return nil, err
+	}
+	run := verify.NewVerify(repository.From, repository.Dir, f, options.internal())
+	run.SetRepository(opened)
+	run.SetContext(repository.runContext(ctx))
+	result, err := run.Check()
+	if err != nil {
+		return nil, err
+	}
+	return newVerifyResult(result), nil
+}
+
+// runReport returns the report of the last run of the repository, along with
+// the error of the run
+func runReport(repository Repository, runErr error) (*Report, error) {
+	runErr = stoppedError(runErr)
+	gitDir, err := git.GitDir(repository.Dir)
+	if err != nil {
+		return nil, errors.Join(runErr, err)
+	}
+	runState, err := state.Load(state.DirOf(gitDir, repository.StateDir))
+	if errors.Is(err, state.ErrNotFound) {
+		// the run failed before it started
+		return nil, runErr
+	}
+	if err != nil {
+		return nil, errors.Join(runErr, fmt.Errorf("Error reading the report of the run: %w", err))
+	}
+	return newReport(runState.Report), runErr
+}
+
+// runFork returns the fork the stopped run of the repository was started on,
+// or f when there is no run
+func runFork(repository Repository, f fork.Fork) (fork.Fork, error) {
+	gitDir, err := git.GitDir(repository.Dir)
+	if err != nil {
+		return f, err
+	}
+	runState, err := state.Load(state.DirOf(gitDir, repository.StateDir))
+	if errors.Is(err, state.ErrNotFound) {
+		// continue reports the missing run
+		return f, nil
+	}
+	if err != nil {
+		return f, err
+	}
+	return runState.RunFork(f), nil
+}
+
+// stoppedError converts the error of a run which stopped on a commit to
+// StoppedError
+func stoppedError(err error) error {
+	var stopped *apply.StoppedError
+	if !errors.As(err, &stopped) {
+		return err
+	}
+	return &StoppedError{Phase: stopped.Phase, Commit: stopped.Commit, Conflicts: stopped.Conflicts(), Err: stopped.Err}
+}
@@ -0,0 +1,400 @@
+package rebase
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/openshift/rebase/internal/apply"
+	"github.com/openshift/rebase/internal/carry"
+	"github.com/openshift/rebase/internal/fork"
+	"github.com/openshift/rebase/internal/report"
+	"github.com/openshift/rebase/internal/verify"
+)
+
+// Fork selects the fork being rebased and its upstream.
+type Fork struct {
+	// Name is the name of a builtin fork, eg. kubernetes, defaults to kubernetes.
+	Name string
+	// ConfigFile is a YAML file describing a fork not known to the tool,
+	// overrides Name.
+	ConfigFile string
+	// UpstreamRemote and OpenShiftRemote override the git remotes of the fork.
+	UpstreamRemote  string
+	OpenShiftRemote string
+}
+
+// ForkNames returns the names of the builtin forks.
+func ForkNames() []string {
+	return fork.Names()
+}
+
+// load returns the fork described by f
+func (f Fork) load() (fork.Fork, error) {
+	loaded := fork.Kubernetes
+	if len(f.Name) > 0 || len(f.ConfigFile) > 0 {
+		var err error
+		if loaded, err = fork.Load(f.Name, f.ConfigFile); err != nil {
+			return fork.Fork{}, err
+		}
+	}
+	if len(f.UpstreamRemote) > 0 {
+		loaded.Remotes.Upstream = f.UpstreamRemote
+	}
+	if len(f.OpenShiftRemote) > 0 {
+		loaded.Remotes.OpenShift = f.OpenShiftRemote
+	}
+	return loaded, nil
+}
+
+// CarryOptions filters the listed carries.
+type CarryOptions struct {
+	// Action matches the upstream action of a carry, eg. carry or drop.
+	Action string
+	// Path matches carries changing at least one file under the path.
+	Path string
+	// Author matches a part of the author name or email, ignoring case.
+	Author string
+	// Concurrency is the number of carries read in parallel, defaults to the
+	// number of CPUs.
+	Concurrency int
+}
+
+func (o CarryOptions) internal() carry.ListOptions {
+	return carry.ListOptions{
+		Action:      o.Action,
+		Path:        o.Path,
+		Author:      o.Author,
+		Concurrency: o.Concurrency,
+	}
+}
+
+// Carry is a listed carry commit.
+type Carry struct {
+	SHA       string `json:"sha"`
+	Action    string `json:"action"`
+	Summary   string `json:"summary"`
+	Component string `json:"component"`
+	Files     int    `json:"files"`
+	Author    string `json:"author"`
+}
+
+func newCarries(entries []carry.Entry) []Carry {
+	carries := make([]Carry, 0, len(entries))
+	for _, e := range entries {
+		carries = append(carries, Carry{
+			SHA:       e.SHA,
+			Action:    e.Action,
+			Summary:   e.Summary,
+			Component: e.Component,
+			Files:     e.Files,
+			Author:    e.Author,
+		})
+	}
+	return carries
+}
+
+// ApplyOptions holds the settings of planning and applying a rebase.
+type ApplyOptions struct {
+	// TargetVersion is the kubernetes version being rebased to, eg. v1.31,
+	// used for looking up fixed carries in per-version directories.
+	TargetVersion string
+	// Preflight enables checking that all carries apply before creating the
+	// rebase branch.
+	Preflight bool
+	// Mainline is the parent number used when picking merge commits, merge
+	// commits are skipped when 0.
+	Mainline int
+	// UnknownAction decides what happens with commits with unknown UPSTREAM
+	// action, one of fail, carry or skip, defaults to skip.
+	UnknownAction string
+	// PlanFile is a plan, written by the plan command, executed instead of
+	// computing the actions from the carries.
+	PlanFile string
+	// Concurrency is the number of carries classified in parallel when
+	// making a plan, defaults to the number of CPUs.
+	Concurrency int
+	// CarriesDirs are directories holding fixed carries.
+	CarriesDirs []string
+	// Backports are upstream commit SHAs or pull request numbers picked
+	// after the carries.
+	Backports []string
+	// OursPaths are path prefixes, a carry with conflicts limited to them is
+	// picked with ours strategy option.
+	OursPaths []string
+	// Resolvers are name=command pairs resolving conflicts of carries in
+	// the working tree.
+	Resolvers []string
+	// Regenerate enables resolving conflicts limited to generated files by
+	// accepting GeneratedSide, ours or theirs, and running Generators, which
+	// are pattern=command pairs.
+	Regenerate    bool
+	GeneratedSide string
+	Generators    []string
+	// DeferConflicts queues carries which do not apply cleanly for a manual
+	// pass, instead of stopping on them.
+	DeferConflicts bool
+	// Validate enables running gofmt and go vet on packages touched by every
+	// picked carry.
+	Validate bool
+	// PrePickHooks, PostPickHooks and PostPhaseHooks are commands run around
+	// picks and phases, HookPolicy is warn or stop on their failure.
+	PrePickHooks   []string
+	PostPickHooks  []string
+	PostPhaseHooks []string
+	HookPolicy     string
+	// Annotate, Jira and SuggestAssignees enrich the report with the pull
+	// requests introducing carries, the state of referenced bugs and
+	// approvers of conflicted paths.
+	Annotate         bool
+	Jira             bool
+	SuggestAssignees bool
+	// MappingFile, ReportFile and HTMLReportFile are where the mapping of
+	// carries to new commits and the reports are written.
+	MappingFile    string
+	ReportFile     string
+	HTMLReportFile string
+	// ConflictsDir is where conflicted files of every carry are saved.
+	ConflictsDir string
+	// SkipChecks are names of environment checks not run before starting.
+	SkipChecks []string
+	// Force allows starting on a protected branch.
+	Force bool
+	// Resume continues the run on the rebase branch of a previous run.
+	Resume bool
+}
+
+// internal returns the options of the engine, carries are read from to
+// unless set otherwise
+func (o ApplyOptions) internal(to string) apply.Options {
+	return apply.Options{
+		TargetVersion:    o.TargetVersion,
+		Preflight:        o.Preflight,
+		Mainline:         o.Mainline,
+		UnknownAction:    o.UnknownAction,
+		PlanFile:         o.PlanFile,
+		Concurrency:      o.Concurrency,
+		CarriesDirs:      o.CarriesDirs,
+		Backports:        o.Backports,
+		OursPaths:        o.OursPaths,
+		Resolvers:        o.Resolvers,
+		Regenerate:       o.Regenerate,
+		GeneratedSide:    o.GeneratedSide,
+		Generators:       o.Generators,
+		DeferConflicts:   o.DeferConflicts,
+		Validate:         o.Validate,
+		PrePickHooks:     o.PrePickHooks,
+		PostPickHooks:    o.PostPickHooks,
+		PostPhaseHooks:   o.PostPhaseHooks,
+		HookPolicy:       o.HookPolicy,
+		Annotate:         o.Annotate,
+		Jira:             o.Jira,
+		SuggestAssignees: o.SuggestAssignees,
+		MappingFile:      o.MappingFile,
+		ReportFile:       o.ReportFile,
+		HTMLReportFile:   o.HTMLReportFile,
+		ConflictsDir:     o.ConflictsDir,
+		SkipChecks:       o.SkipChecks,
+		Force:            o.Force,
+		Resume:           o.Resume,
+		ToRef:            to,
+	}
+}
+
+// Plan is the ordered list of actions of a rebase.
+type Plan struct {
+	// From is the starting tag, Upstream and OpenShift are the commits the
+	// plan was made against.
+	From      string
+	Upstream  string
+	OpenShift string
+	// Steps are the actions taken on carries, in order.
+	Steps []PlanStep
+	// Stages are the stages run after picking carries.
+	Stages     []string
+	Backports  []string
+	Additional []string
+}
+
+// PlanStep is the action taken on a single carry, eg. pick or drop.
+type PlanStep struct {
+	Command string
+	SHA     string
+	Message string
+}
+
+func newPlan(p *apply.Plan) *Plan {
+	plan := &Plan{
+		From:       p.From,
+		Upstream:   p.Upstream,
+		OpenShift:  p.OpenShift,
+		Stages:     p.Stages,
+		Backports:  p.Backports,
+		Additional: p.Additional,
+	}
+	for _, s := range p.Steps {
+		plan.Steps = append(plan.Steps, PlanStep{Command: s.Command, SHA: s.SHA, Message: s.Message})
+	}
+	return plan
+}
+
+// Report describes what happened with every commit of a rebase.
+type Report struct {
+	From          string        `json:"from,omitempty"`
+	TargetVersion string        `json:"targetVersion,omitempty"`
+	UpstreamSHA   string        `json:"upstreamSHA,omitempty"`
+	OpenShiftSHA  string        `json:"openshiftSHA,omitempty"`
+	Branch        string        `json:"branch,omitempty"`
+	PullRequest   string        `json:"pullRequest,omitempty"`
+	Started       time.Time     `json:"started"`
+	Entries       []ReportEntry `json:"entries"`
+}
+
+// ReportEntry is the result of processing a single commit.
+type ReportEntry struct {
+	// Original is the carry, New the resulting commit on the rebase branch,
+	// empty when none was created.
+	Original string `json:"original"`
+	New      string `json:"new,omitempty"`
+	// Disposition is what happened with the commit, eg. picked or dropped,
+	// explained by Reason when it is not obvious.
+	Disposition string `json:"disposition"`
+	Reason      string `json:"reason,omitempty"`
+	Message     string `json:"message"`
+	// Backport is set for upstream commits picked in addition to carries.
+	Backport bool `json:"backport,omitempty"`
+	// Conflicted is set when the commit did not apply cleanly, Conflicts
+	// lists the files left for manual resolution.
+	Conflicted bool     `json:"conflicted,omitempty"`
+	Conflicts  []string `json:"conflicts,omitempty"`
+}
+
+func newReport(r *report.Report) *Report {
+	if r == nil {
+		return nil
+	}
+	converted := &Report{
+		From:          r.From,
+		TargetVersion: r.TargetVersion,
+		UpstreamSHA:   r.UpstreamSHA,
+		OpenShiftSHA:  r.OpenShiftSHA,
+		Branch:        r.Branch,
+		PullRequest:   r.PullRequest,
+		Started:       r.Started,
+		Entries:       make([]ReportEntry, 0, len(r.Entries)),
+	}
+	for _, e := range r.Entries {
+		converted.Entries = append(converted.Entries, ReportEntry{
+			Original:    e.Original,
+			New:         e.New,
+			Disposition: string(e.Disposition),
+			Reason:      e.Reason,
+			Message:     e.Message,
+			Backport:    e.Backport,
+			Conflicted:  e.Disposition.Conflicted(),
+			Conflicts:   e.Conflicts,
+		})
+	}
+	return converted
+}
+
+// StoppedError is returned when a rebase stopped on a commit requiring
+// manual intervention, it is resumed with Continue.
+type StoppedError struct {
+	// Phase is the phase of the run, eg. carries or backports.
+	Phase string
+	// Commit is the commit which stopped the run.
+	Commit string
+	// Conflicts are the files with unresolved conflicts left by the commit.
+	Conflicts []string
+	// Err is the cause.
+	Err error
+}
+
+func (e *StoppedError) Error() string {
+	return fmt.Sprintf("The run stopped in %s phase on %s: %v", e.Phase, e.Commit, e.Err)
+}
+
+func (e *StoppedError) Unwrap() error {
+	return e.Err
+}
+
+// VerifyOptions holds the settings of verifying a rebase branch.
+type VerifyOptions struct {
+	// Branch is the rebase branch being verified, defaults to HEAD.
+	Branch string
+	// Upstream is the upstream revision the branch is based on, defaults to
+	// the upstream branch of the fork.
+	Upstream string
+	// PreviousBranch is the rebase branch of the previous release, carries
+	// on it are expected to be present on the verified branch.
+	PreviousBranch string
+	// OverridesFile is a JSON file with overrides of planned dispositions.
+	OverridesFile string
+	// TargetVersion is the kubernetes version of the rebase.
+	TargetVersion string
+	// Mainline is the parent number used for picking merge commits.
+	Mainline int
+	// Checks limits the run to the named checks, all enabled checks run
+	// when empty.
+	Checks []string
+	// Vendor, BisectBuild and APIDiff enable the checks of the vendor
+	// directory, the build and exported APIs of carried packages.
+	Vendor        bool
+	VendorCommand string
+	BisectBuild   bool
+	BuildCommand  string
+	APIDiff       bool
+	APIPackages   []string
+	// PublishedStaging is the URL of published staging repositories, where
+	// {module} is replaced with the staging directory name, at PublishedRef.
+	PublishedStaging string
+	PublishedRef     string
+}
+
+func (o VerifyOptions) internal() verify.Options {
+	return verify.Options{
+		Branch:           o.Branch,
+		Upstream:         o.Upstream,
+		PreviousBranch:   o.PreviousBranch,
+		OverridesFile:    o.OverridesFile,
+		TargetVersion:    o.TargetVersion,
+		Mainline:         o.Mainline,
+		Checks:           o.Checks,
+		Vendor:           o.Vendor,
+		VendorCommand:    o.VendorCommand,
+		BisectBuild:      o.BisectBuild,
+		BuildCommand:     o.BuildCommand,
+		APIDiff:          o.APIDiff,
+		APIPackages:      o.APIPackages,
+		PublishedStaging: o.PublishedStaging,
+		PublishedRef:     o.PublishedRef,
+	}
+}
+
+// VerifyResult holds the problems found on a rebase branch.
+type VerifyResult struct {
+	// Branch is the verified rebase branch.
+	Branch string `json:"branch"`
+	// Checks are the names of the checks which were run.
+	Checks []string `json:"checks"`
+	// Findings are the problems found by the checks.
+	Findings []Finding `json:"findings"`
+}
+
+// Finding is a single problem found on the rebase branch.
+type Finding struct {
+	// Check is the name of the check which reported the problem.
+	Check string `json:"check"`
+	// Commit is the commit the problem relates to, if any.
+	Commit string `json:"commit,omitempty"`
+	// Message describes the problem.
+	Message string `json:"message"`
+}
+
+func newVerifyResult(r *verify.Result) *VerifyResult {
+	result := &VerifyResult{Branch: r.Branch, Checks: r.Checks, Findings: make([]Finding, 0, len(r.Findings))}
+	for _, f := range r.Findings {
+		result.Findings = append(result.Findings, Finding{Check: f.Check, Commit: f.Commit, Message: f.Message})
+	}
+	return result
+}